@@ -125,6 +125,14 @@ var (
 		Value: true,
 		Usage: "enable return data output",
 	}
+	TracerFlag = &cli.StringFlag{
+		Name:  "tracer",
+		Usage: "name of a native tracer to run the code with, printing its result (e.g. gasProfiler)",
+	}
+	TracerConfigFlag = &cli.StringFlag{
+		Name:  "tracer.config",
+		Usage: "JSON configuration of the native tracer",
+	}
 )
 
 var stateTransitionCommand = &cli.Command{
@@ -210,6 +218,8 @@ func init() {
 		DisableStackFlag,
 		DisableStorageFlag,
 		DisableReturnDataFlag,
+		TracerFlag,
+		TracerConfigFlag,
 	}
 	app.Commands = []*cli.Command{
 		compileCommand,
@@ -37,7 +37,9 @@ import (
 	"github.com/ethereum/go-ethereum/core/types"
 	"github.com/ethereum/go-ethereum/core/vm"
 	"github.com/ethereum/go-ethereum/core/vm/runtime"
+	"github.com/ethereum/go-ethereum/eth/tracers"
 	"github.com/ethereum/go-ethereum/eth/tracers/logger"
+	_ "github.com/ethereum/go-ethereum/eth/tracers/native"
 	"github.com/ethereum/go-ethereum/internal/flags"
 	"github.com/ethereum/go-ethereum/log"
 	"github.com/ethereum/go-ethereum/params"
@@ -122,6 +124,7 @@ func runCmd(ctx *cli.Context) error {
 	var (
 		tracer        vm.EVMLogger
 		debugLogger   *logger.StructLogger
+		nativeTracer  tracers.Tracer
 		statedb       *state.StateDB
 		chainConfig   *params.ChainConfig
 		sender        = common.BytesToAddress([]byte("sender"))
@@ -130,7 +133,20 @@ func runCmd(ctx *cli.Context) error {
 		preimages     = ctx.Bool(DumpFlag.Name)
 		blobHashes    []common.Hash // TODO (MariusVanDerWijden) implement blob hashes in state tests
 	)
-	if ctx.Bool(MachineFlag.Name) {
+	if name := ctx.String(TracerFlag.Name); name != "" {
+		if tracers.DefaultDirectory.IsJS(name) {
+			return fmt.Errorf("unknown native tracer %q", name)
+		}
+		var cfg json.RawMessage
+		if config := ctx.String(TracerConfigFlag.Name); config != "" {
+			cfg = json.RawMessage(config)
+		}
+		t, err := tracers.DefaultDirectory.New(name, new(tracers.Context), cfg)
+		if err != nil {
+			return fmt.Errorf("failed to create tracer %q: %v", name, err)
+		}
+		nativeTracer, tracer = t, t
+	} else if ctx.Bool(MachineFlag.Name) {
 		tracer = logger.NewJSONLogger(logconfig, os.Stdout)
 	} else if ctx.Bool(DebugFlag.Name) {
 		debugLogger = logger.NewStructLogger(logconfig)
@@ -314,6 +330,19 @@ allocations:     %d
 allocated bytes: %d
 `, initialGas-leftOverGas, stats.time, stats.allocs, stats.bytesAllocated)
 	}
+	if nativeTracer != nil {
+		res, err := nativeTracer.GetResult()
+		if err != nil {
+			return fmt.Errorf("failed to retrieve trace result: %v", err)
+		}
+		// Print textual results, such as folded stacks, verbatim
+		var text string
+		if err := json.Unmarshal(res, &text); err == nil {
+			fmt.Println(text)
+		} else {
+			fmt.Println(string(res))
+		}
+	}
 	if tracer == nil {
 		fmt.Printf("%#x\n", output)
 		if err != nil {
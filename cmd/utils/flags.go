@@ -42,6 +42,7 @@ import (
 	"github.com/ethereum/go-ethereum/core"
 	"github.com/ethereum/go-ethereum/core/rawdb"
 	"github.com/ethereum/go-ethereum/core/txpool"
+	"github.com/ethereum/go-ethereum/core/txpool/blobpool"
 	"github.com/ethereum/go-ethereum/core/types"
 	"github.com/ethereum/go-ethereum/core/vm"
 	"github.com/ethereum/go-ethereum/crypto"
@@ -242,6 +243,39 @@ var (
 		Value:    ethconfig.Defaults.TxLookupLimit,
 		Category: flags.EthCategory,
 	}
+	WitnessStartFlag = &cli.Uint64Flag{
+		Name:     "witness.start",
+		Usage:    "First block number to record transaction witnesses for",
+		Value:    ethconfig.Defaults.WitnessStart,
+		Category: flags.EthCategory,
+	}
+	WitnessEndFlag = &cli.Uint64Flag{
+		Name:     "witness.end",
+		Usage:    "Last block number to record transaction witnesses for (0 = no witnesses recorded)",
+		Value:    ethconfig.Defaults.WitnessEnd,
+		Category: flags.EthCategory,
+	}
+	WitnessNoLimitFlag = &cli.BoolFlag{
+		Name:     "witness.nolimit",
+		Usage:    "Record transaction witnesses for every block, ignoring --witness.start and --witness.end",
+		Category: flags.EthCategory,
+	}
+	TraceIndexFlag = &cli.BoolFlag{
+		Name:     "traceindex",
+		Usage:    "Index the call traces of imported blocks, serving them without re-execution",
+		Category: flags.EthCategory,
+	}
+	TraceIndexHistoryFlag = &cli.Uint64Flag{
+		Name:     "traceindex.history",
+		Usage:    "Number of recent blocks to keep the call trace index for, backfilled as far as historical state is available (0 = only newly imported blocks, never pruned)",
+		Value:    ethconfig.Defaults.TraceIndexHistory,
+		Category: flags.EthCategory,
+	}
+	TraceDirFlag = &flags.DirectoryFlag{
+		Name:     "tracedir",
+		Usage:    "Directory debug_traceChainToFile writes into (default = inside the datadir)",
+		Category: flags.EthCategory,
+	}
 	LightKDFFlag = &cli.BoolFlag{
 		Name:     "lightkdf",
 		Usage:    "Reduce key-derivation RAM & CPU usage at some expense of KDF strength",
@@ -343,6 +377,18 @@ var (
 		Value:    txpool.DefaultConfig.Rejournal,
 		Category: flags.TxPoolCategory,
 	}
+	TxPoolSnapshotFlag = &cli.StringFlag{
+		Name:     "txpool.snapshot",
+		Usage:    "Disk snapshot of all pooled transactions, remote ones included, to survive node restarts (disabled if empty)",
+		Value:    txpool.DefaultConfig.Snapshot,
+		Category: flags.TxPoolCategory,
+	}
+	TxPoolResnapshotFlag = &cli.DurationFlag{
+		Name:     "txpool.resnapshot",
+		Usage:    "Time interval to regenerate the transaction pool snapshot",
+		Value:    txpool.DefaultConfig.Resnapshot,
+		Category: flags.TxPoolCategory,
+	}
 	TxPoolPriceLimitFlag = &cli.Uint64Flag{
 		Name:     "txpool.pricelimit",
 		Usage:    "Minimum gas price limit to enforce for acceptance into the pool",
@@ -385,6 +431,43 @@ var (
 		Value:    ethconfig.Defaults.TxPool.Lifetime,
 		Category: flags.TxPoolCategory,
 	}
+	TxPoolPrivateExpiryFlag = &cli.Uint64Flag{
+		Name:     "txpool.privateexpiry",
+		Usage:    "Number of blocks after which unmined private transactions are dropped",
+		Value:    ethconfig.Defaults.TxPool.PrivateExpiry,
+		Category: flags.TxPoolCategory,
+	}
+	TxPoolGlobalBundlesFlag = &cli.Uint64Flag{
+		Name:     "txpool.globalbundles",
+		Usage:    "Maximum number of transaction bundles tracked for upcoming blocks",
+		Value:    ethconfig.Defaults.TxPool.GlobalBundles,
+		Category: flags.TxPoolCategory,
+	}
+	// Blob transaction pool settings
+	BlobPoolDataDirFlag = &cli.StringFlag{
+		Name:     "blobpool.datadir",
+		Usage:    "Data directory to store blob transactions in",
+		Value:    ethconfig.Defaults.BlobPool.Datadir,
+		Category: flags.BlobPoolCategory,
+	}
+	BlobPoolDataCapFlag = &cli.Uint64Flag{
+		Name:     "blobpool.datacap",
+		Usage:    "Disk space to allocate for pending blob transactions (soft limit)",
+		Value:    ethconfig.Defaults.BlobPool.Datacap,
+		Category: flags.BlobPoolCategory,
+	}
+	BlobPoolPriceBumpFlag = &cli.Uint64Flag{
+		Name:     "blobpool.pricebump",
+		Usage:    "Price bump percentage to replace an already existing blob transaction",
+		Value:    ethconfig.Defaults.BlobPool.PriceBump,
+		Category: flags.BlobPoolCategory,
+	}
+	BlobPoolAccountBlobsFlag = &cli.Uint64Flag{
+		Name:     "blobpool.accountblobs",
+		Usage:    "Maximum number of blobs pooled per account",
+		Value:    ethconfig.Defaults.BlobPool.AccountBlobs,
+		Category: flags.BlobPoolCategory,
+	}
 
 	// Performance tuning settings
 	CacheFlag = &cli.IntFlag{
@@ -469,6 +552,30 @@ var (
 		Value:    ethconfig.Defaults.Miner.GasCeil,
 		Category: flags.MinerCategory,
 	}
+	MinerGasFloorFlag = &cli.Uint64Flag{
+		Name:     "miner.gasfloor",
+		Usage:    "Target gas floor for mined blocks, when targeting the gas utilisation (0 = genesis gas limit)",
+		Value:    ethconfig.Defaults.Miner.GasFloor,
+		Category: flags.MinerCategory,
+	}
+	MinerGasWindowFlag = &cli.Uint64Flag{
+		Name:     "miner.gaswindow",
+		Usage:    "Number of recent blocks to measure the gas utilisation over (0 = always target the gas ceiling)",
+		Value:    ethconfig.Defaults.Miner.GasWindow,
+		Category: flags.MinerCategory,
+	}
+	MinerGasTargetFlag = &cli.Float64Flag{
+		Name:     "miner.gastarget",
+		Usage:    "Gas utilisation to target by moving the gas limit between the floor and the ceiling (keep above the EIP-1559 target of 0.5)",
+		Value:    ethconfig.Defaults.Miner.GasTarget,
+		Category: flags.MinerCategory,
+	}
+	MinerGasReserveFlag = &cli.Uint64Flag{
+		Name:     "miner.gasreserve",
+		Usage:    "Gas reserved in each mined block for system transactions",
+		Value:    ethconfig.Defaults.Miner.GasReserve,
+		Category: flags.MinerCategory,
+	}
 	MinerGasPriceFlag = &flags.BigFlag{
 		Name:     "miner.gasprice",
 		Usage:    "Minimum gas price for mining a transaction",
@@ -491,6 +598,12 @@ var (
 		Value:    ethconfig.Defaults.Miner.Recommit,
 		Category: flags.MinerCategory,
 	}
+	MinerOrderingFlag = &cli.StringFlag{
+		Name:     "miner.ordering",
+		Usage:    "Strategy to order the transactions of mined blocks with (price, fifo)",
+		Value:    miner.PriceOrdering,
+		Category: flags.MinerCategory,
+	}
 	MinerNewPayloadTimeout = &cli.DurationFlag{
 		Name:     "miner.newpayload-timeout",
 		Usage:    "Specify the maximum time allowance for creating a new payload",
@@ -1500,6 +1613,12 @@ func setTxPool(ctx *cli.Context, cfg *txpool.Config) {
 	if ctx.IsSet(TxPoolRejournalFlag.Name) {
 		cfg.Rejournal = ctx.Duration(TxPoolRejournalFlag.Name)
 	}
+	if ctx.IsSet(TxPoolSnapshotFlag.Name) {
+		cfg.Snapshot = ctx.String(TxPoolSnapshotFlag.Name)
+	}
+	if ctx.IsSet(TxPoolResnapshotFlag.Name) {
+		cfg.Resnapshot = ctx.Duration(TxPoolResnapshotFlag.Name)
+	}
 	if ctx.IsSet(TxPoolPriceLimitFlag.Name) {
 		cfg.PriceLimit = ctx.Uint64(TxPoolPriceLimitFlag.Name)
 	}
@@ -1521,6 +1640,27 @@ func setTxPool(ctx *cli.Context, cfg *txpool.Config) {
 	if ctx.IsSet(TxPoolLifetimeFlag.Name) {
 		cfg.Lifetime = ctx.Duration(TxPoolLifetimeFlag.Name)
 	}
+	if ctx.IsSet(TxPoolPrivateExpiryFlag.Name) {
+		cfg.PrivateExpiry = ctx.Uint64(TxPoolPrivateExpiryFlag.Name)
+	}
+	if ctx.IsSet(TxPoolGlobalBundlesFlag.Name) {
+		cfg.GlobalBundles = ctx.Uint64(TxPoolGlobalBundlesFlag.Name)
+	}
+}
+
+func setBlobPool(ctx *cli.Context, cfg *blobpool.Config) {
+	if ctx.IsSet(BlobPoolDataDirFlag.Name) {
+		cfg.Datadir = ctx.String(BlobPoolDataDirFlag.Name)
+	}
+	if ctx.IsSet(BlobPoolDataCapFlag.Name) {
+		cfg.Datacap = ctx.Uint64(BlobPoolDataCapFlag.Name)
+	}
+	if ctx.IsSet(BlobPoolPriceBumpFlag.Name) {
+		cfg.PriceBump = ctx.Uint64(BlobPoolPriceBumpFlag.Name)
+	}
+	if ctx.IsSet(BlobPoolAccountBlobsFlag.Name) {
+		cfg.AccountBlobs = ctx.Uint64(BlobPoolAccountBlobsFlag.Name)
+	}
 }
 
 func setMiner(ctx *cli.Context, cfg *miner.Config) {
@@ -1530,12 +1670,27 @@ func setMiner(ctx *cli.Context, cfg *miner.Config) {
 	if ctx.IsSet(MinerGasLimitFlag.Name) {
 		cfg.GasCeil = ctx.Uint64(MinerGasLimitFlag.Name)
 	}
+	if ctx.IsSet(MinerGasFloorFlag.Name) {
+		cfg.GasFloor = ctx.Uint64(MinerGasFloorFlag.Name)
+	}
+	if ctx.IsSet(MinerGasWindowFlag.Name) {
+		cfg.GasWindow = ctx.Uint64(MinerGasWindowFlag.Name)
+	}
+	if ctx.IsSet(MinerGasTargetFlag.Name) {
+		cfg.GasTarget = ctx.Float64(MinerGasTargetFlag.Name)
+	}
+	if ctx.IsSet(MinerGasReserveFlag.Name) {
+		cfg.GasReserve = ctx.Uint64(MinerGasReserveFlag.Name)
+	}
 	if ctx.IsSet(MinerGasPriceFlag.Name) {
 		cfg.GasPrice = flags.GlobalBig(ctx, MinerGasPriceFlag.Name)
 	}
 	if ctx.IsSet(MinerRecommitIntervalFlag.Name) {
 		cfg.Recommit = ctx.Duration(MinerRecommitIntervalFlag.Name)
 	}
+	if ctx.IsSet(MinerOrderingFlag.Name) {
+		cfg.Ordering = ctx.String(MinerOrderingFlag.Name)
+	}
 	if ctx.IsSet(MinerNewPayloadTimeout.Name) {
 		cfg.NewPayloadTimeout = ctx.Duration(MinerNewPayloadTimeout.Name)
 	}
@@ -1626,6 +1781,7 @@ func SetEthConfig(ctx *cli.Context, stack *node.Node, cfg *ethconfig.Config) {
 	setEtherbase(ctx, cfg)
 	setGPO(ctx, &cfg.GPO, ctx.String(SyncModeFlag.Name) == "light")
 	setTxPool(ctx, &cfg.TxPool)
+	setBlobPool(ctx, &cfg.BlobPool)
 	setMiner(ctx, &cfg.Miner)
 	setRequiredBlocks(ctx, cfg)
 	setLes(ctx, cfg)
@@ -1682,6 +1838,27 @@ func SetEthConfig(ctx *cli.Context, stack *node.Node, cfg *ethconfig.Config) {
 	if ctx.IsSet(TxLookupLimitFlag.Name) {
 		cfg.TxLookupLimit = ctx.Uint64(TxLookupLimitFlag.Name)
 	}
+	if ctx.IsSet(WitnessStartFlag.Name) {
+		cfg.WitnessStart = ctx.Uint64(WitnessStartFlag.Name)
+	}
+	if ctx.IsSet(WitnessEndFlag.Name) {
+		cfg.WitnessEnd = ctx.Uint64(WitnessEndFlag.Name)
+	}
+	if ctx.IsSet(WitnessNoLimitFlag.Name) {
+		cfg.WitnessNoLimit = ctx.Bool(WitnessNoLimitFlag.Name)
+	}
+	if cfg.WitnessStart > cfg.WitnessEnd && !cfg.WitnessNoLimit {
+		log.Warn("Transaction witness window is empty", "start", cfg.WitnessStart, "end", cfg.WitnessEnd)
+	}
+	if ctx.IsSet(TraceIndexFlag.Name) {
+		cfg.TraceIndex = ctx.Bool(TraceIndexFlag.Name)
+	}
+	if ctx.IsSet(TraceIndexHistoryFlag.Name) {
+		cfg.TraceIndexHistory = ctx.Uint64(TraceIndexHistoryFlag.Name)
+	}
+	if ctx.IsSet(TraceDirFlag.Name) {
+		cfg.TraceDir = ctx.String(TraceDirFlag.Name)
+	}
 	if ctx.IsSet(CacheFlag.Name) || ctx.IsSet(CacheTrieFlag.Name) {
 		cfg.TrieCleanCache = ctx.Int(CacheFlag.Name) * ctx.Int(CacheTrieFlag.Name) / 100
 	}
@@ -2118,6 +2295,9 @@ func MakeChain(ctx *cli.Context, stack *node.Node, readonly bool) (*core.BlockCh
 		TrieTimeLimit:       ethconfig.Defaults.TrieTimeout,
 		SnapshotLimit:       ethconfig.Defaults.SnapshotCache,
 		Preimages:           ctx.Bool(CachePreimagesFlag.Name),
+		WitnessStart:        ctx.Uint64(WitnessStartFlag.Name),
+		WitnessEnd:          ctx.Uint64(WitnessEndFlag.Name),
+		WitnessNoLimit:      ctx.Bool(WitnessNoLimitFlag.Name),
 	}
 	if cache.TrieDirtyDisabled && !cache.Preimages {
 		cache.Preimages = true
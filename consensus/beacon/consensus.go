@@ -281,6 +281,13 @@ func (beacon *Beacon) verifyHeader(chain consensus.ChainHeaderReader, header, pa
 			return err
 		}
 	}
+	// Verify the existence / non-existence of parentBeaconRoot
+	if !cancun && header.ParentBeaconRoot != nil {
+		return fmt.Errorf("invalid parentBeaconRoot: have %x, expected nil", *header.ParentBeaconRoot)
+	}
+	if cancun && header.ParentBeaconRoot == nil {
+		return errors.New("header is missing parentBeaconRoot")
+	}
 	return nil
 }
 
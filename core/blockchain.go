@@ -141,6 +141,20 @@ type CacheConfig struct {
 
 	SnapshotNoBuild bool // Whether the background generation is allowed
 	SnapshotWait    bool // Wait for snapshot construction on startup. TODO(karalabe): This is a dirty hack for testing, nuke it
+
+	WitnessStart   uint64 // First block number to record transaction witnesses for
+	WitnessEnd     uint64 // Last block number to record transaction witnesses for (0 = disabled)
+	WitnessNoLimit bool   // Whether to record transaction witnesses for every block
+}
+
+// witnessEnabled reports whether transaction witnesses are to be recorded while
+// processing the block with the given number. Nothing is recorded unless either
+// a window ending past genesis is configured, or the window is lifted entirely.
+func (c *CacheConfig) witnessEnabled(number uint64) bool {
+	if c.WitnessNoLimit {
+		return true
+	}
+	return c.WitnessEnd != 0 && c.WitnessStart <= number && number <= c.WitnessEnd
 }
 
 // defaultCacheConfig are the default caching values if none are specified by the
@@ -722,6 +736,13 @@ func (bc *BlockChain) setHeadBeyondRoot(head uint64, time uint64, root common.Ha
 	}
 	// Rewind the header chain, deleting all block bodies until then
 	delFn := func(db ethdb.KeyValueWriter, hash common.Hash, num uint64) {
+		// Drop the transaction witnesses of the block, they live in the
+		// active store regardless of whether the block itself is frozen.
+		if body := rawdb.ReadBody(bc.db, hash, num); body != nil {
+			for _, tx := range body.Transactions {
+				rawdb.DeleteTxWitness(db, hash, tx.Hash())
+			}
+		}
 		// Ignore the error here since light client won't hit this path
 		frozen, _ := bc.db.Ancients()
 		if num+1 <= frozen {
@@ -1352,6 +1373,7 @@ func (bc *BlockChain) writeBlockWithState(block *types.Block, receipts []*types.
 	rawdb.WriteBlock(blockBatch, block)
 	rawdb.WriteReceipts(blockBatch, block.Hash(), block.NumberU64(), receipts)
 	rawdb.WritePreimages(blockBatch, state.Preimages())
+	rawdb.WriteTxWitnesses(blockBatch, block.Hash(), state.TxWitnesses())
 	if err := blockBatch.Write(); err != nil {
 		log.Crit("Failed to write block into disk", "err", err)
 	}
@@ -1735,24 +1757,23 @@ func (bc *BlockChain) insertChain(chain types.Blocks, setHead bool) (int, error)
 		// transactions and probabilistically some of the account/storage trie nodes.
 		var followupInterrupt atomic.Bool
 		if !bc.cacheConfig.TrieCleanNoPrefetch {
-			throwaway, _ := state.New(parent.Root, bc.stateCache, bc.snaps)
-			bc.prefetcher.Prefetch(block, throwaway, bc.vmConfig, &followupInterrupt)
-			//if followup, err := it.peek(); followup != nil && err == nil {
-			//	//go func(start time.Time, followup *types.Block, throwaway *state.StateDB) {
-			//	//	bc.prefetcher.Prefetch(followup, throwaway, bc.vmConfig, &followupInterrupt)
-			//	//
-			//	//	blockPrefetchExecuteTimer.Update(time.Since(start))
-			//	//	if followupInterrupt.Load() {
-			//	//		blockPrefetchInterruptMeter.Mark(1)
-			//	//	}
-			//	//}(time.Now(), followup, throwaway)
-			//}
-		}
-		//log.Info("---------------------------------------------------")
+			if followup, err := it.peek(); followup != nil && err == nil {
+				throwaway, _ := state.New(parent.Root, bc.stateCache, bc.snaps)
+
+				go func(start time.Time, followup *types.Block, throwaway *state.StateDB) {
+					bc.prefetcher.Prefetch(followup, throwaway, bc.vmConfig, &followupInterrupt)
+
+					blockPrefetchExecuteTimer.Update(time.Since(start))
+					if followupInterrupt.Load() {
+						blockPrefetchInterruptMeter.Mark(1)
+					}
+				}(time.Now(), followup, throwaway)
+			}
+		}
+
 		// Process block using the parent state as reference point
 		pstart := time.Now()
 		receipts, logs, usedGas, err := bc.processor.Process(block, statedb, bc.vmConfig)
-		block.Coinbase()
 		if err != nil {
 			bc.reportBlock(block, receipts, err)
 			followupInterrupt.Store(true)
@@ -2172,7 +2193,6 @@ func (bc *BlockChain) reorg(oldHead *types.Header, newHead *types.Block) error {
 	for _, tx := range types.HashDifference(deletedTxs, addedTxs) {
 		rawdb.DeleteTxLookupEntry(indexesBatch, tx)
 	}
-
 	// Delete all hash markers that are not part of the new canonical chain.
 	// Because the reorg function does not handle new chain head, all hash
 	// markers greater than or equal to new chain head should be deleted.
@@ -362,6 +362,12 @@ func (bc *BlockChain) GetVMConfig() *vm.Config {
 	return &bc.vmConfig
 }
 
+// WitnessEnabled reports whether transaction witnesses are to be recorded for
+// the block with the given number.
+func (bc *BlockChain) WitnessEnabled(number uint64) bool {
+	return bc.cacheConfig.witnessEnabled(number)
+}
+
 // SetTxLookupLimit is responsible for updating the txlookup limit to the
 // original one stored in db if the new mismatches with the old one.
 func (bc *BlockChain) SetTxLookupLimit(limit uint64) {
@@ -0,0 +1,91 @@
+// Copyright 2023 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package rawdb
+
+import (
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/ethdb"
+	"github.com/ethereum/go-ethereum/log"
+	"github.com/ethereum/go-ethereum/rlp"
+)
+
+// ReadTxWitnessRLP retrieves the state witness of a transaction, as executed
+// within the given block, in RLP encoding.
+func ReadTxWitnessRLP(db ethdb.KeyValueReader, blockHash common.Hash, hash common.Hash) rlp.RawValue {
+	data, _ := db.Get(txWitnessKey(blockHash, hash))
+	return data
+}
+
+// ReadTxWitness retrieves the state witness recorded for the given transaction
+// when it was executed within the given block. Witnesses are recorded for every
+// processed block, so it's up to the caller to ensure the block is canonical.
+func ReadTxWitness(db ethdb.KeyValueReader, blockHash common.Hash, hash common.Hash) *types.TxExtra {
+	data := ReadTxWitnessRLP(db, blockHash, hash)
+	if len(data) == 0 {
+		return nil
+	}
+	witness := new(types.TxExtra)
+	if err := rlp.DecodeBytes(data, witness); err != nil {
+		log.Error("Invalid transaction witness RLP", "hash", hash, "err", err)
+		return nil
+	}
+	return witness
+}
+
+// HasTxWitness checks whether a state witness was recorded for the given
+// transaction within the given block.
+func HasTxWitness(db ethdb.KeyValueReader, blockHash common.Hash, hash common.Hash) bool {
+	ok, _ := db.Has(txWitnessKey(blockHash, hash))
+	return ok
+}
+
+// WriteTxWitness stores the state witness of a transaction executed within the
+// given block into the database.
+func WriteTxWitness(db ethdb.KeyValueWriter, blockHash common.Hash, witness *types.TxExtra) {
+	data, err := rlp.EncodeToBytes(witness)
+	if err != nil {
+		log.Crit("Failed to encode transaction witness", "err", err)
+	}
+	if err := db.Put(txWitnessKey(blockHash, witness.TxHash), data); err != nil {
+		log.Crit("Failed to store transaction witness", "err", err)
+	}
+}
+
+// WriteTxWitnesses stores the state witnesses of the transactions of a block into
+// the database.
+func WriteTxWitnesses(db ethdb.KeyValueWriter, blockHash common.Hash, witnesses []*types.TxExtra) {
+	for _, witness := range witnesses {
+		WriteTxWitness(db, blockHash, witness)
+	}
+}
+
+// DeleteTxWitness removes the state witness of a transaction executed within the
+// given block.
+func DeleteTxWitness(db ethdb.KeyValueWriter, blockHash common.Hash, hash common.Hash) {
+	if err := db.Delete(txWitnessKey(blockHash, hash)); err != nil {
+		log.Crit("Failed to delete transaction witness", "err", err)
+	}
+}
+
+// DeleteTxWitnesses removes the state witnesses of all the given transactions
+// executed within the given block.
+func DeleteTxWitnesses(db ethdb.KeyValueWriter, blockHash common.Hash, hashes []common.Hash) {
+	for _, hash := range hashes {
+		DeleteTxWitness(db, blockHash, hash)
+	}
+}
@@ -0,0 +1,66 @@
+// Copyright 2023 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package rawdb
+
+import (
+	"math/big"
+	"reflect"
+	"testing"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core/types"
+)
+
+// Tests transaction witness storage and retrieval operations.
+func TestTxWitnessStorage(t *testing.T) {
+	db := NewMemoryDatabase()
+
+	blockHash := common.Hash{0xff}
+	witness := types.NewTxExtra(common.Hash{0x01})
+	witness.SetPreStateRoot(common.Hash{0x02})
+	witness.SetPostStateRoot(common.Hash{0x03})
+	witness.AddPreState(common.Address{0x04}, &types.StateAccount{
+		Nonce:    1,
+		Balance:  big.NewInt(100),
+		Root:     types.EmptyRootHash,
+		CodeHash: types.EmptyCodeHash.Bytes(),
+	})
+	witness.AddPreStorage(common.Address{0x05}, common.Hash{0x06}, common.Hash{0x07})
+	witness.AddPreCode(common.Address{0x05}, []byte{0x60, 0x00})
+
+	if entry := ReadTxWitness(db, blockHash, witness.TxHash); entry != nil {
+		t.Fatalf("Non existent witness returned: %v", entry)
+	}
+	WriteTxWitness(db, blockHash, witness)
+	if !HasTxWitness(db, blockHash, witness.TxHash) {
+		t.Fatalf("Stored witness not found")
+	}
+	entry := ReadTxWitness(db, blockHash, witness.TxHash)
+	if entry == nil {
+		t.Fatalf("Stored witness not found")
+	}
+	if !reflect.DeepEqual(entry, witness) {
+		t.Fatalf("Retrieved witness mismatch: have %v, want %v", entry, witness)
+	}
+	if HasTxWitness(db, common.Hash{0xfe}, witness.TxHash) {
+		t.Fatalf("Witness returned for another block")
+	}
+	DeleteTxWitness(db, blockHash, witness.TxHash)
+	if entry := ReadTxWitness(db, blockHash, witness.TxHash); entry != nil {
+		t.Fatalf("Deleted witness returned: %v", entry)
+	}
+}
@@ -0,0 +1,285 @@
+// Copyright 2023 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package state
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core/rawdb"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/crypto"
+	"github.com/ethereum/go-ethereum/ethdb"
+	"github.com/ethereum/go-ethereum/trie"
+)
+
+// AccessSet is the set of accounts, storage slots and contract codes accessed
+// while it was being recorded, typically during the execution of a single
+// transaction. Every entry holds the value as of the creation of the set, i.e.
+// before the transaction modified it, regardless of when it was accessed.
+//
+// Unlike the access list, the access set is not journalled: accesses made by
+// reverted call frames are retained, since re-executing the transaction needs
+// exactly the same state.
+//
+// AccessSet implements vm.StateWitnessRecorder.
+type AccessSet struct {
+	Accounts map[common.Address]*types.StateAccount // Nil if the account did not exist
+	Storage  map[common.Address]map[common.Hash]common.Hash
+	Code     map[common.Address][]byte
+
+	state   *StateDB
+	trie    Trie                    // Account trie as of the creation of the set
+	storage map[common.Address]Trie // Storage tries as of the creation of the set
+}
+
+// newAccessSet creates an empty access set on top of the given account trie.
+func newAccessSet(state *StateDB, trie Trie) *AccessSet {
+	return &AccessSet{
+		Accounts: make(map[common.Address]*types.StateAccount),
+		Storage:  make(map[common.Address]map[common.Hash]common.Hash),
+		Code:     make(map[common.Address][]byte),
+		state:    state,
+		trie:     trie,
+		storage:  make(map[common.Address]Trie),
+	}
+}
+
+// RecordAccount records the access of an account, a nil entry marking an
+// account absent from the state.
+func (a *AccessSet) RecordAccount(addr common.Address) {
+	if _, ok := a.Accounts[addr]; ok {
+		return
+	}
+	account, err := a.trie.GetAccount(addr)
+	if err != nil {
+		a.state.setError(fmt.Errorf("access set: failed to read account %x: %w", addr, err))
+		return
+	}
+	a.Accounts[addr] = account
+}
+
+// RecordStorage records the access of a storage slot.
+func (a *AccessSet) RecordStorage(addr common.Address, key common.Hash) {
+	a.RecordAccount(addr)
+	if _, ok := a.Storage[addr][key]; ok {
+		return
+	}
+	var value common.Hash
+	if account := a.Accounts[addr]; account != nil {
+		tr, err := a.storageTrie(addr, account)
+		if err != nil {
+			a.state.setError(fmt.Errorf("access set: failed to open storage trie %x: %w", addr, err))
+			return
+		}
+		enc, err := tr.GetStorage(addr, key.Bytes())
+		if err != nil {
+			a.state.setError(fmt.Errorf("access set: failed to read slot %x of %x: %w", key, addr, err))
+			return
+		}
+		value.SetBytes(enc)
+	}
+	if a.Storage[addr] == nil {
+		a.Storage[addr] = make(map[common.Hash]common.Hash)
+	}
+	a.Storage[addr][key] = value
+}
+
+// RecordCode records the access of a contract code. Code deployed after the
+// creation of the set is not part of the pre-state and is ignored.
+func (a *AccessSet) RecordCode(addr common.Address) {
+	a.RecordAccount(addr)
+	if _, ok := a.Code[addr]; ok {
+		return
+	}
+	account := a.Accounts[addr]
+	if account == nil {
+		return
+	}
+	// Prefer the live object, the code might not be committed to disk yet
+	hash := common.BytesToHash(account.CodeHash)
+	if obj := a.state.stateObjects[addr]; obj != nil && common.BytesToHash(obj.CodeHash()) == hash {
+		a.Code[addr] = obj.Code(a.state.db)
+		return
+	}
+	if hash == types.EmptyCodeHash {
+		a.Code[addr] = nil
+		return
+	}
+	code, err := a.state.db.ContractCode(crypto.Keccak256Hash(addr.Bytes()), hash)
+	if err != nil {
+		a.state.setError(fmt.Errorf("access set: failed to load code %x: %w", hash, err))
+		return
+	}
+	a.Code[addr] = code
+}
+
+// storageTrie returns the storage trie of an account as of the creation of the
+// set. Storage tries updated by earlier transactions might only live in memory,
+// so the live object's trie is copied if it still matches the account.
+func (a *AccessSet) storageTrie(addr common.Address, account *types.StateAccount) (Trie, error) {
+	if tr, ok := a.storage[addr]; ok {
+		return tr, nil
+	}
+	var tr Trie
+	if obj := a.state.stateObjects[addr]; obj != nil && obj.trie != nil && obj.data.Root == account.Root {
+		tr = a.state.db.CopyTrie(obj.trie)
+	} else {
+		var err error
+		if tr, err = a.state.db.OpenStorageTrie(a.state.originalRoot, crypto.Keccak256Hash(addr.Bytes()), account.Root); err != nil {
+			return nil, err
+		}
+	}
+	a.storage[addr] = tr
+	return tr, nil
+}
+
+// AccountProof returns the Merkle proof of an account against the state root as
+// of the creation of the set.
+func (a *AccessSet) AccountProof(addr common.Address) ([][]byte, error) {
+	var proof proofList
+	err := a.trie.Prove(crypto.Keccak256(addr.Bytes()), 0, &proof)
+	return proof, err
+}
+
+// StorageProof returns the Merkle proof of a storage slot against the storage
+// root of its account as of the creation of the set.
+func (a *AccessSet) StorageProof(addr common.Address, key common.Hash) ([][]byte, error) {
+	account := a.Accounts[addr]
+	if account == nil {
+		return nil, errors.New("account not present in the access set")
+	}
+	tr, err := a.storageTrie(addr, account)
+	if err != nil {
+		return nil, err
+	}
+	var proof proofList
+	err = tr.Prove(crypto.Keccak256(key.Bytes()), 0, &proof)
+	return proof, err
+}
+
+// DeletionNodes returns the trie nodes, besides the pre-state proofs of the given
+// witness, needed to delete the accounts and storage slots the witness reports
+// as removed by the transaction. Deleting a key may leave a branch node with a
+// single child, which is then collapsed into it: that child has to be resolved
+// even if none of the witnessed keys leads through it.
+//
+// The nodes are found by replaying the deletions on top of the witness alone,
+// fetching every node it misses from the tries as of the creation of the set.
+func (a *AccessSet) DeletionNodes(witness *types.TxExtra) ([][]byte, error) {
+	db := rawdb.NewMemoryDatabase()
+	for _, proof := range witness.PreStateProof {
+		writeTrieNodes(db, proof)
+	}
+	for _, proofs := range witness.PreStorageProof {
+		for _, proof := range proofs {
+			writeTrieNodes(db, proof)
+		}
+	}
+	var (
+		triedb = trie.NewDatabase(db)
+		nodes  [][]byte
+	)
+	// deleteKey deletes a key from a trie backed by the witness, resolving the
+	// nodes missing from it out of the source trie
+	deleteKey := func(del func() error, src Trie) error {
+		for {
+			err := del()
+			var missing *trie.MissingNodeError
+			if !errors.As(err, &missing) {
+				return err
+			}
+			if ok, _ := db.Has(missing.NodeHash.Bytes()); ok {
+				return err // Resolved already, something else is broken
+			}
+			proof, err := nodeProof(src, missing.Path)
+			if err != nil {
+				return err
+			}
+			writeTrieNodes(db, proof)
+			nodes = append(nodes, proof...)
+		}
+	}
+	tr, err := trie.NewStateTrie(trie.StateTrieID(witness.PreStateRoot), triedb)
+	if err != nil {
+		return nil, err
+	}
+	for addr, account := range witness.PreState {
+		if post, ok := witness.PostState[addr]; account == nil || !ok {
+			continue
+		} else if post == nil {
+			addr := addr
+			if err := deleteKey(func() error { return tr.DeleteAccount(addr) }, a.trie); err != nil {
+				return nil, err
+			}
+			continue // Storage of deleted accounts is dropped wholesale
+		}
+		src, err := a.storageTrie(addr, account)
+		if err != nil {
+			return nil, err
+		}
+		var str *trie.StateTrie
+		for key, value := range witness.PreStorage[addr] {
+			if value == (common.Hash{}) || witness.PostStorage[addr][key] != (common.Hash{}) {
+				continue
+			}
+			if str == nil {
+				id := trie.StorageTrieID(witness.PreStateRoot, crypto.Keccak256Hash(addr.Bytes()), account.Root)
+				if str, err = trie.NewStateTrie(id, triedb); err != nil {
+					return nil, err
+				}
+			}
+			addr, key := addr, key
+			if err := deleteKey(func() error { return str.DeleteStorage(addr, key.Bytes()) }, src); err != nil {
+				return nil, err
+			}
+		}
+	}
+	return nodes, nil
+}
+
+// nodeProof returns the trie nodes leading to the node at the given path within
+// a trie, down to the first leaf beneath it.
+func nodeProof(tr Trie, path []byte) ([][]byte, error) {
+	start := make([]byte, (len(path)+1)/2)
+	for i, nibble := range path {
+		start[i/2] |= nibble << (4 * (1 - i%2))
+	}
+	it := tr.NodeIterator(start)
+	for it.Next(true) {
+		if !it.Leaf() {
+			continue
+		}
+		if !bytes.HasPrefix(it.Path(), path) {
+			break
+		}
+		return it.LeafProof(), nil
+	}
+	if err := it.Error(); err != nil {
+		return nil, err
+	}
+	return nil, fmt.Errorf("trie node at path %x not found", path)
+}
+
+// writeTrieNodes stores trie nodes into the database, keyed by their hashes.
+func writeTrieNodes(db ethdb.KeyValueWriter, nodes [][]byte) {
+	for _, node := range nodes {
+		rawdb.WriteLegacyTrieNode(db, crypto.Keccak256Hash(node), node)
+	}
+}
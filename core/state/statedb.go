@@ -18,7 +18,6 @@
 package state
 
 import (
-	"bytes"
 	"errors"
 	"fmt"
 	"math/big"
@@ -99,6 +98,9 @@ type StateDB struct {
 
 	preimages map[common.Hash][]byte
 
+	// Per-transaction state witnesses recorded by the block processor
+	witnesses []*types.TxExtra
+
 	// Per-transaction access list
 	accessList *accessList
 
@@ -240,6 +242,16 @@ func (s *StateDB) Preimages() map[common.Hash][]byte {
 	return s.preimages
 }
 
+// AddTxWitness records the state witness of a processed transaction.
+func (s *StateDB) AddTxWitness(witness *types.TxExtra) {
+	s.witnesses = append(s.witnesses, witness)
+}
+
+// TxWitnesses returns the transaction state witnesses that have been recorded.
+func (s *StateDB) TxWitnesses() []*types.TxExtra {
+	return s.witnesses
+}
+
 // AddRefund adds gas to the refund counter
 func (s *StateDB) AddRefund(gas uint64) {
 	s.journal.append(refundChange{prev: s.refund})
@@ -780,6 +792,10 @@ func (s *StateDB) Copy() *StateDB {
 	for hash, preimage := range s.preimages {
 		state.preimages[hash] = preimage
 	}
+	if len(s.witnesses) > 0 {
+		state.witnesses = make([]*types.TxExtra, len(s.witnesses))
+		copy(state.witnesses, s.witnesses)
+	}
 	// Do we need to copy the access list and transient storage?
 	// In practice: No. At the start of a transaction, these two lists are empty.
 	// In practice, we only ever copy state _between_ transactions/blocks, never
@@ -1035,6 +1051,13 @@ func (s *StateDB) SetTxContext(thash common.Hash, ti int) {
 	s.txIndex = ti
 }
 
+// NewAccessSet creates an empty access set recording values against the current
+// state. The state should have been hashed with IntermediateRoot beforehand,
+// otherwise the recorded values and proofs will not match the state root.
+func (s *StateDB) NewAccessSet() *AccessSet {
+	return newAccessSet(s, s.db.CopyTrie(s.trie))
+}
+
 func (s *StateDB) clearJournalAndRefund() {
 	if len(s.journal.entries) > 0 {
 		s.journal = newJournal()
@@ -1259,20 +1282,9 @@ func (s *StateDB) convertAccountSet(set map[common.Address]struct{}) map[common.
 	return ret
 }
 
-// new
-
-func (s *StateDB) GetAccountEnc(addr common.Address) ([]byte, error) {
-	data, err := s.trie.GetAccount(addr)
-	if err != nil {
-		return nil, err
-	}
-	if data == nil {
-		return nil, err
-	}
-	buf := new(bytes.Buffer)
-	err2 := data.EncodeRLP(buf)
-	if err2 != nil {
-		return nil, err2
-	}
-	return buf.Bytes(), nil
+// GetTrieAccount retrieves the account of the given address as it is currently
+// stored in the account trie, ignoring any changes not yet hashed into it by
+// IntermediateRoot. Nil is returned if the account does not exist.
+func (s *StateDB) GetTrieAccount(addr common.Address) (*types.StateAccount, error) {
+	return s.trie.GetAccount(addr)
 }
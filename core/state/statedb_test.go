@@ -1035,3 +1035,58 @@ func TestResetObject(t *testing.T) {
 		t.Fatalf("Unexpected storage slot value %v", slot)
 	}
 }
+
+func TestStateDBAccessSet(t *testing.T) {
+	var (
+		state, _ = New(types.EmptyRootHash, NewDatabase(rawdb.NewMemoryDatabase()), nil)
+		addr     = common.HexToAddress("0x1")
+		fresh    = common.HexToAddress("0x2")
+		slot     = common.HexToHash("0x1")
+		code     = []byte{0x60, 0x00}
+	)
+	// Initialize account with balance, code and storage in a first transaction.
+	state.SetBalance(addr, big.NewInt(1))
+	state.SetCode(addr, code)
+	state.SetState(addr, slot, common.HexToHash("0x11"))
+	root := state.IntermediateRoot(true)
+
+	// Mutate state in a second transaction, recording the accesses afterwards.
+	set := state.NewAccessSet()
+	state.SetBalance(addr, big.NewInt(3))
+	state.SetState(addr, slot, common.HexToHash("0x22"))
+	state.SetBalance(fresh, big.NewInt(2))
+	set.RecordStorage(addr, slot)
+	set.RecordCode(addr)
+	set.RecordAccount(fresh)
+
+	if len(set.Accounts) != 2 {
+		t.Fatalf("unexpected number of accounts: have %d, want 2", len(set.Accounts))
+	}
+	if account := set.Accounts[addr]; account == nil || account.Balance.Cmp(big.NewInt(1)) != 0 {
+		t.Fatalf("unexpected account pre-state: %v", account)
+	}
+	if account, ok := set.Accounts[fresh]; !ok || account != nil {
+		t.Fatalf("absent account not recorded as nil: %v", account)
+	}
+	if value := set.Storage[addr][slot]; value != common.HexToHash("0x11") {
+		t.Fatalf("unexpected slot pre-state: have %x, want %x", value, common.HexToHash("0x11"))
+	}
+	if !bytes.Equal(set.Code[addr], code) {
+		t.Fatalf("unexpected code pre-state: have %x, want %x", set.Code[addr], code)
+	}
+	// The proofs must be anchored at the root the recording was started at.
+	proof, err := set.AccountProof(addr)
+	if err != nil {
+		t.Fatalf("failed to prove account: %v", err)
+	}
+	proofDb := rawdb.NewMemoryDatabase()
+	for _, node := range proof {
+		proofDb.Put(crypto.Keccak256(node), node)
+	}
+	if _, err := trie.VerifyProof(root, crypto.Keccak256(addr.Bytes()), proofDb); err != nil {
+		t.Fatalf("invalid account proof: %v", err)
+	}
+	if _, err := set.StorageProof(addr, slot); err != nil {
+		t.Fatalf("failed to prove slot: %v", err)
+	}
+}
@@ -20,7 +20,6 @@ import (
 	"errors"
 	"fmt"
 	"math/big"
-	"strings"
 
 	"github.com/ethereum/go-ethereum/common"
 	"github.com/ethereum/go-ethereum/consensus"
@@ -29,6 +28,7 @@ import (
 	"github.com/ethereum/go-ethereum/core/types"
 	"github.com/ethereum/go-ethereum/core/vm"
 	"github.com/ethereum/go-ethereum/crypto"
+	"github.com/ethereum/go-ethereum/log"
 	"github.com/ethereum/go-ethereum/params"
 )
 
@@ -69,9 +69,6 @@ func (p *StateProcessor) Process(block *types.Block, statedb *state.StateDB, cfg
 		allLogs     []*types.Log
 		gp          = new(GasPool).AddGas(block.GasLimit())
 	)
-	if blockNumber.Uint64() > 3000000 {
-		return nil, nil, 0, fmt.Errorf("stop")
-	}
 	// Mutate the block and state according to any hard-fork specs
 	if p.config.DAOForkSupport && p.config.DAOForkBlock != nil && p.config.DAOForkBlock.Cmp(block.Number()) == 0 {
 		misc.ApplyDAOHardFork(statedb)
@@ -80,10 +77,8 @@ func (p *StateProcessor) Process(block *types.Block, statedb *state.StateDB, cfg
 		context = NewEVMBlockContext(header, p.bc, nil)
 		vmenv   = vm.NewEVM(context, vm.TxContext{}, statedb, p.config, cfg)
 		signer  = types.MakeSigner(p.config, header.Number, header.Time)
+		witness = p.bc.cacheConfig.witnessEnabled(blockNumber.Uint64())
 	)
-	if len(block.Transactions()) > 0 {
-		types.InitFile(blockNumber)
-	}
 	// Iterate over and process the individual transactions
 	for i, tx := range block.Transactions() {
 		msg, err := TransactionToMessage(tx, signer, header.BaseFee)
@@ -92,55 +87,19 @@ func (p *StateProcessor) Process(block *types.Block, statedb *state.StateDB, cfg
 		}
 		statedb.SetTxContext(tx.Hash(), i)
 
-		// minerExtra
-		types.WriteTxHash(blockNumber, tx.Hash())
-
-		types.WritePreRoot(blockNumber, statedb.IntermediateRoot(p.config.IsEIP158(blockNumber)))
-		l := types.ReadTxFile(blockNumber, tx.Hash())
-		temp := make(map[string]bool)
-		for _, s := range l {
-			sArr := strings.Split(s, "\t")
-			t := sArr[0]
-			switch t {
-			case "0":
-				addr := common.HexToAddress(sArr[1])
-				if temp["0"+sArr[1]] {
-					continue
-				}
-				if enc, error := statedb.GetAccountEnc(addr); error == nil {
-					types.WritePreState(blockNumber, addr, enc)
-					temp["0"+sArr[1]] = true
-				}
-			case "1":
-				if temp[sArr[1]+sArr[2]] {
-					continue
-				}
-				addr := common.HexToAddress(sArr[1])
-				hash := common.HexToHash(sArr[2])
-				val := statedb.GetState(addr, hash)
-				types.WritePreStorage(blockNumber, addr, hash, val)
-				temp[sArr[1]+sArr[2]] = true
-			case "2":
-				if temp["2"+sArr[1]] {
-					continue
-				}
-				addr := common.HexToAddress(sArr[1])
-				code := statedb.GetCode(addr)
-				types.WritePreCode(blockNumber, addr, code)
-				temp["2"+sArr[1]] = true
-			default:
+		// Record the state accessed by the transaction for its witness if requested
+		var receipt *types.Receipt
+		if witness {
+			var extra *types.TxExtra
+			if receipt, extra, err = applyTransactionWithWitness(msg, p.config, gp, statedb, blockNumber, blockHash, tx, usedGas, vmenv); err == nil {
+				statedb.AddTxWitness(extra)
 			}
+		} else {
+			receipt, err = applyTransaction(msg, p.config, gp, statedb, blockNumber, blockHash, tx, usedGas, vmenv)
 		}
-		if i == len(block.Transactions())-1 {
-			types.DelTxFile(blockNumber)
-		}
-		receipt, err := applyTransaction(msg, p.config, gp, statedb, blockNumber, blockHash, tx, usedGas, vmenv)
 		if err != nil {
 			return nil, nil, 0, fmt.Errorf("could not apply tx %d [%v]: %w", i, tx.Hash().Hex(), err)
 		}
-		//minerExtra
-		types.WritePostRoot(blockNumber, statedb.IntermediateRoot(p.config.IsEIP158(blockNumber)))
-		types.WriteTxHash(blockNumber, tx.Hash())
 
 		receipts = append(receipts, receipt)
 		allLogs = append(allLogs, receipt.Logs...)
@@ -156,6 +115,74 @@ func (p *StateProcessor) Process(block *types.Block, statedb *state.StateDB, cfg
 	return receipts, allLogs, *usedGas, nil
 }
 
+// fillTxWitness fills in the pre- and post-state of a transaction witness, along
+// with their proofs, from the state accessed by the transaction. The state must
+// have been hashed with IntermediateRoot after applying the transaction. Slots
+// of accounts absent from the state have no storage trie to prove against, their
+// absence is covered by the account proof. The trie nodes needed to apply the
+// deletions made by the transaction are added on top of the proofs.
+func fillTxWitness(witness *types.TxExtra, accessSet *state.AccessSet, statedb *state.StateDB) {
+	for addr, account := range accessSet.Accounts {
+		witness.AddPreState(addr, account)
+		if proof, err := accessSet.AccountProof(addr); err == nil {
+			witness.AddPreStateProof(addr, proof)
+		}
+		if account, err := statedb.GetTrieAccount(addr); err == nil {
+			witness.AddPostState(addr, account)
+		}
+		if proof, err := statedb.GetProof(addr); err == nil {
+			witness.AddPostStateProof(addr, proof)
+		}
+	}
+	for addr, slots := range accessSet.Storage {
+		for key, value := range slots {
+			witness.AddPreStorage(addr, key, value)
+			if proof, err := accessSet.StorageProof(addr, key); err == nil {
+				witness.AddPreStorageProof(addr, key, proof)
+			}
+			witness.AddPostStorage(addr, key, statedb.GetState(addr, key))
+			if proof, err := statedb.GetStorageProof(addr, key); err == nil {
+				witness.AddPostStorageProof(addr, key, proof)
+			}
+		}
+	}
+	for addr, code := range accessSet.Code {
+		witness.AddPreCode(addr, code)
+		witness.AddPostCode(addr, statedb.GetCode(addr))
+	}
+	if nodes, err := accessSet.DeletionNodes(witness); err == nil {
+		witness.AddPreStateNodes(nodes)
+	} else {
+		log.Warn("Failed to collect witness deletion nodes", "tx", witness.TxHash, "err", err)
+	}
+}
+
+// applyTransactionWithWitness applies a transaction like applyTransaction while
+// recording the state it accesses, returning the state witness of the transaction
+// along with its receipt.
+//
+// The state is hashed before applying the transaction, which invalidates any
+// snapshot taken beforehand. The state is thus reverted here if the transaction
+// cannot be applied.
+func applyTransactionWithWitness(msg *Message, config *params.ChainConfig, gp *GasPool, statedb *state.StateDB, blockNumber *big.Int, blockHash common.Hash, tx *types.Transaction, usedGas *uint64, evm *vm.EVM) (*types.Receipt, *types.TxExtra, error) {
+	witness := types.NewTxExtra(tx.Hash())
+	witness.SetPreStateRoot(statedb.IntermediateRoot(config.IsEIP158(blockNumber)))
+
+	accessSet := statedb.NewAccessSet()
+	defer func(prev vm.StateWitnessRecorder) { evm.Config.Witness = prev }(evm.Config.Witness)
+	evm.Config.Witness = accessSet
+
+	snap := statedb.Snapshot()
+	receipt, err := applyTransaction(msg, config, gp, statedb, blockNumber, blockHash, tx, usedGas, evm)
+	if err != nil {
+		statedb.RevertToSnapshot(snap)
+		return nil, nil, err
+	}
+	witness.SetPostStateRoot(statedb.IntermediateRoot(config.IsEIP158(blockNumber)))
+	fillTxWitness(witness, accessSet, statedb)
+	return receipt, witness, nil
+}
+
 func applyTransaction(msg *Message, config *params.ChainConfig, gp *GasPool, statedb *state.StateDB, blockNumber *big.Int, blockHash common.Hash, tx *types.Transaction, usedGas *uint64, evm *vm.EVM) (*types.Receipt, error) {
 	// Create a new context to be used in the EVM environment.
 	txContext := NewEVMTxContext(msg)
@@ -215,3 +242,19 @@ func ApplyTransaction(config *params.ChainConfig, bc ChainContext, author *commo
 	vmenv := vm.NewEVM(blockContext, vm.TxContext{}, statedb, config, cfg)
 	return applyTransaction(msg, config, gp, statedb, header.Number, header.Hash(), tx, usedGas, vmenv)
 }
+
+// ApplyTransactionWithWitness attempts to apply a transaction like ApplyTransaction,
+// additionally returning the state witness of the transaction. The state is hashed
+// before and after the transaction to anchor the witness to the state roots, so
+// any snapshot taken beforehand is invalidated; the state is instead reverted by
+// ApplyTransactionWithWitness itself if the transaction cannot be applied.
+func ApplyTransactionWithWitness(config *params.ChainConfig, bc ChainContext, author *common.Address, gp *GasPool, statedb *state.StateDB, header *types.Header, tx *types.Transaction, usedGas *uint64, cfg vm.Config) (*types.Receipt, *types.TxExtra, error) {
+	msg, err := TransactionToMessage(tx, types.MakeSigner(config, header.Number, header.Time), header.BaseFee)
+	if err != nil {
+		return nil, nil, err
+	}
+	// Create a new context to be used in the EVM environment
+	blockContext := NewEVMBlockContext(header, bc, author)
+	vmenv := vm.NewEVM(blockContext, vm.TxContext{}, statedb, config, cfg)
+	return applyTransactionWithWitness(msg, config, gp, statedb, header.Number, header.Hash(), tx, usedGas, vmenv)
+}
@@ -17,6 +17,7 @@
 package core
 
 import (
+	"bytes"
 	"crypto/ecdsa"
 	"math/big"
 	"testing"
@@ -31,7 +32,9 @@ import (
 	"github.com/ethereum/go-ethereum/core/types"
 	"github.com/ethereum/go-ethereum/core/vm"
 	"github.com/ethereum/go-ethereum/crypto"
+	"github.com/ethereum/go-ethereum/ethdb/memorydb"
 	"github.com/ethereum/go-ethereum/params"
+	"github.com/ethereum/go-ethereum/rlp"
 	"github.com/ethereum/go-ethereum/trie"
 	"golang.org/x/crypto/sha3"
 )
@@ -428,3 +431,229 @@ func GenerateBadBlock(parent *types.Block, engine consensus.Engine, txs types.Tr
 	}
 	return types.NewBlock(header, txs, nil, receipts, trie.NewStackTrie(nil))
 }
+
+// TestStateProcessorWitness tests that the transaction witnesses recorded during
+// block processing are persisted and carry valid proofs for every touched account
+// and storage slot against the pre- and post-state roots.
+func TestStateProcessorWitness(t *testing.T) {
+	var (
+		config   = params.TestChainConfig
+		signer   = types.LatestSigner(config)
+		key, _   = crypto.HexToECDSA("b71c71a67e1177ad4e901695e1b4b9ee17ae16c6668d313eac2f96dbcda3f291")
+		sender   = crypto.PubkeyToAddress(key.PublicKey)
+		contract = common.HexToAddress("0xc0de")
+		gspec    = &Genesis{
+			Config: config,
+			Alloc: GenesisAlloc{
+				sender: {Balance: big.NewInt(1000000000000000000)},
+				contract: {
+					Balance: big.NewInt(0),
+					// SLOAD(1), SSTORE(0, 1)
+					Code:    common.FromHex("0x600154506001600055"),
+					Storage: map[common.Hash]common.Hash{common.HexToHash("0x01"): common.HexToHash("0x02")},
+				},
+			},
+		}
+		engine = ethash.NewFaker()
+	)
+	_, blocks, _ := GenerateChainWithGenesis(gspec, engine, 1, func(i int, b *BlockGen) {
+		tx, _ := types.SignTx(types.NewTransaction(b.TxNonce(sender), contract, big.NewInt(1), 100000, b.BaseFee(), nil), signer, key)
+		b.AddTx(tx)
+	})
+	db := rawdb.NewMemoryDatabase()
+	cache := *defaultCacheConfig
+	cache.WitnessNoLimit = true
+
+	chain, err := NewBlockChain(db, &cache, gspec, nil, engine, vm.Config{}, nil, nil)
+	if err != nil {
+		t.Fatalf("failed to create chain: %v", err)
+	}
+	defer chain.Stop()
+	if _, err := chain.InsertChain(blocks); err != nil {
+		t.Fatalf("failed to insert chain: %v", err)
+	}
+	tx := blocks[0].Transactions()[0]
+	witness := rawdb.ReadTxWitness(db, blocks[0].Hash(), tx.Hash())
+	if witness == nil {
+		t.Fatalf("witness not persisted")
+	}
+	if witness.PreStateRoot != chain.Genesis().Root() {
+		t.Fatalf("pre-state root mismatch: have %x, want %x", witness.PreStateRoot, chain.Genesis().Root())
+	}
+	if account := witness.PreState[sender]; account == nil || account.Nonce != 0 || account.Balance.Cmp(gspec.Alloc[sender].Balance) != 0 {
+		t.Fatalf("sender pre-state mismatch: %v", account)
+	}
+	if witness.PostState[contract] == nil || witness.PostStorage[contract][common.Hash{}] != common.HexToHash("0x01") {
+		t.Fatalf("contract post-state not witnessed")
+	}
+	verify := func(root common.Hash, accounts map[common.Address]*types.StateAccount, storage map[common.Address]map[common.Hash]common.Hash,
+		accountProofs map[common.Address][][]byte, storageProofs map[common.Address]map[common.Hash][][]byte) {
+		for addr, account := range accounts {
+			val, err := trie.VerifyProof(root, crypto.Keccak256(addr.Bytes()), proofDB(accountProofs[addr]))
+			if err != nil {
+				t.Fatalf("invalid account proof for %x: %v", addr, err)
+			}
+			if account == nil {
+				if val != nil {
+					t.Fatalf("absent account %x proven present", addr)
+				}
+				continue
+			}
+			if want, _ := rlp.EncodeToBytes(account); !bytes.Equal(val, want) {
+				t.Fatalf("account %x mismatch: have %x, want %x", addr, val, want)
+			}
+			for key, value := range storage[addr] {
+				val, err := trie.VerifyProof(account.Root, crypto.Keccak256(key.Bytes()), proofDB(storageProofs[addr][key]))
+				if err != nil {
+					t.Fatalf("invalid storage proof for %x/%x: %v", addr, key, err)
+				}
+				var have common.Hash
+				if len(val) > 0 {
+					_, content, _, _ := rlp.Split(val)
+					have = common.BytesToHash(content)
+				}
+				if have != value {
+					t.Fatalf("slot %x/%x mismatch: have %x, want %x", addr, key, have, value)
+				}
+			}
+		}
+	}
+	verify(witness.PreStateRoot, witness.PreState, witness.PreStorage, witness.PreStateProof, witness.PreStorageProof)
+	verify(witness.PostStateRoot, witness.PostState, witness.PostStorage, witness.PostStateProof, witness.PostStorageProof)
+}
+
+func proofDB(proof [][]byte) *memorydb.Database {
+	db := memorydb.New()
+	for _, node := range proof {
+		db.Put(crypto.Keccak256(node), node)
+	}
+	return db
+}
+
+// Tests that blocks outside of the configured witness window are processed
+// normally, without recording any transaction witnesses.
+func TestStateProcessorWitnessWindow(t *testing.T) {
+	var (
+		config = params.TestChainConfig
+		signer = types.LatestSigner(config)
+		key, _ = crypto.HexToECDSA("b71c71a67e1177ad4e901695e1b4b9ee17ae16c6668d313eac2f96dbcda3f291")
+		sender = crypto.PubkeyToAddress(key.PublicKey)
+		gspec  = &Genesis{
+			Config: config,
+			Alloc:  GenesisAlloc{sender: {Balance: big.NewInt(1000000000000000000)}},
+		}
+		engine = ethash.NewFaker()
+	)
+	_, blocks, _ := GenerateChainWithGenesis(gspec, engine, 4, func(i int, b *BlockGen) {
+		tx, _ := types.SignTx(types.NewTransaction(b.TxNonce(sender), common.Address{0xaa}, big.NewInt(1), params.TxGas, b.BaseFee(), nil), signer, key)
+		b.AddTx(tx)
+	})
+	db := rawdb.NewMemoryDatabase()
+	cache := *defaultCacheConfig
+	cache.WitnessNoLimit, cache.WitnessStart, cache.WitnessEnd = false, 2, 3
+
+	chain, err := NewBlockChain(db, &cache, gspec, nil, engine, vm.Config{}, nil, nil)
+	if err != nil {
+		t.Fatalf("failed to create chain: %v", err)
+	}
+	defer chain.Stop()
+	if _, err := chain.InsertChain(blocks); err != nil {
+		t.Fatalf("failed to insert chain: %v", err)
+	}
+	for _, block := range blocks {
+		number := block.NumberU64()
+		want := number >= 2 && number <= 3
+		if have := rawdb.HasTxWitness(db, block.Hash(), block.Transactions()[0].Hash()); have != want {
+			t.Errorf("block %d: witness presence mismatch: have %v, want %v", number, have, want)
+		}
+	}
+}
+
+// Tests that no transaction witnesses are recorded unless configured.
+func TestStateProcessorWitnessDefault(t *testing.T) {
+	var (
+		config = params.TestChainConfig
+		signer = types.LatestSigner(config)
+		key, _ = crypto.HexToECDSA("b71c71a67e1177ad4e901695e1b4b9ee17ae16c6668d313eac2f96dbcda3f291")
+		sender = crypto.PubkeyToAddress(key.PublicKey)
+		gspec  = &Genesis{
+			Config: config,
+			Alloc:  GenesisAlloc{sender: {Balance: big.NewInt(1000000000000000000)}},
+		}
+		engine = ethash.NewFaker()
+	)
+	_, blocks, _ := GenerateChainWithGenesis(gspec, engine, 2, func(i int, b *BlockGen) {
+		tx, _ := types.SignTx(types.NewTransaction(b.TxNonce(sender), common.Address{0xaa}, big.NewInt(1), params.TxGas, b.BaseFee(), nil), signer, key)
+		b.AddTx(tx)
+	})
+	db := rawdb.NewMemoryDatabase()
+	chain, err := NewBlockChain(db, nil, gspec, nil, engine, vm.Config{}, nil, nil)
+	if err != nil {
+		t.Fatalf("failed to create chain: %v", err)
+	}
+	defer chain.Stop()
+	if _, err := chain.InsertChain(blocks); err != nil {
+		t.Fatalf("failed to insert chain: %v", err)
+	}
+	for _, block := range blocks {
+		if rawdb.HasTxWitness(db, block.Hash(), block.Transactions()[0].Hash()) {
+			t.Errorf("block %d: witness recorded without a configured window", block.NumberU64())
+		}
+	}
+}
+
+// Tests that executing a transaction within a side chain block doesn't clobber
+// the witness recorded for it within the canonical chain.
+func TestStateProcessorWitnessSideChain(t *testing.T) {
+	var (
+		config = params.TestChainConfig
+		signer = types.LatestSigner(config)
+		key, _ = crypto.HexToECDSA("b71c71a67e1177ad4e901695e1b4b9ee17ae16c6668d313eac2f96dbcda3f291")
+		sender = crypto.PubkeyToAddress(key.PublicKey)
+		gspec  = &Genesis{
+			Config: config,
+			Alloc:  GenesisAlloc{sender: {Balance: big.NewInt(1000000000000000000)}},
+		}
+		engine = ethash.NewFaker()
+		tx, _  = types.SignTx(types.NewTransaction(0, common.Address{0xaa}, big.NewInt(1), params.TxGas, big.NewInt(params.InitialBaseFee), nil), signer, key)
+	)
+	_, canon, _ := GenerateChainWithGenesis(gspec, engine, 2, func(i int, b *BlockGen) {
+		if i == 0 {
+			b.AddTx(tx)
+		}
+	})
+	// The side chain includes the same transaction with a different coinbase
+	_, side, _ := GenerateChainWithGenesis(gspec, engine, 1, func(i int, b *BlockGen) {
+		b.SetCoinbase(common.Address{0xbb})
+		b.AddTx(tx)
+	})
+	db := rawdb.NewMemoryDatabase()
+	cache := *defaultCacheConfig
+	cache.WitnessNoLimit = true
+
+	chain, err := NewBlockChain(db, &cache, gspec, nil, engine, vm.Config{}, nil, nil)
+	if err != nil {
+		t.Fatalf("failed to create chain: %v", err)
+	}
+	defer chain.Stop()
+	if _, err := chain.InsertChain(canon); err != nil {
+		t.Fatalf("failed to insert canonical chain: %v", err)
+	}
+	if _, err := chain.InsertChain(side); err != nil {
+		t.Fatalf("failed to insert side chain: %v", err)
+	}
+	if chain.CurrentBlock().Hash() != canon[1].Hash() {
+		t.Fatalf("side chain became canonical")
+	}
+	canonical := rawdb.ReadTxWitness(db, canon[0].Hash(), tx.Hash())
+	if canonical == nil {
+		t.Fatalf("canonical witness not recorded")
+	}
+	sidechain := rawdb.ReadTxWitness(db, side[0].Hash(), tx.Hash())
+	if sidechain == nil {
+		t.Fatalf("side chain witness not recorded")
+	}
+	if canonical.PostStateRoot == sidechain.PostStateRoot {
+		t.Fatalf("side chain witness identical to the canonical one")
+	}
+}
@@ -0,0 +1,243 @@
+// Copyright 2023 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+// Package stateless implements the re-execution of single transactions on top
+// of the state witnesses recorded for them, without access to the full state.
+package stateless
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core"
+	"github.com/ethereum/go-ethereum/core/rawdb"
+	"github.com/ethereum/go-ethereum/core/state"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/core/vm"
+	"github.com/ethereum/go-ethereum/crypto"
+	"github.com/ethereum/go-ethereum/ethdb"
+	"github.com/ethereum/go-ethereum/params"
+	"github.com/ethereum/go-ethereum/rlp"
+	"github.com/ethereum/go-ethereum/trie"
+)
+
+var (
+	// ErrWitnessMismatch is returned if the transaction the witness was recorded
+	// for is not the one being executed.
+	ErrWitnessMismatch = errors.New("witness recorded for another transaction")
+
+	// ErrInvalidProof is returned if the pre-state of the witness is not proven
+	// against its pre-state root.
+	ErrInvalidProof = errors.New("invalid witness proof")
+
+	// ErrPostStateMismatch is returned if the state root obtained by executing
+	// the transaction differs from the post-state root of the witness.
+	ErrPostStateMismatch = errors.New("post-state root mismatch")
+)
+
+// MissingStateError is returned if the execution of a transaction accessed some
+// state that is absent from its witness.
+type MissingStateError struct {
+	Address common.Address
+	Slot    *common.Hash // Storage slot accessed, nil for account and code accesses
+	Code    bool         // Whether the code of the account was accessed
+}
+
+func (e *MissingStateError) Error() string {
+	switch {
+	case e.Slot != nil:
+		return fmt.Sprintf("witness missing storage slot %x of account %x", *e.Slot, e.Address)
+	case e.Code:
+		return fmt.Sprintf("witness missing code of account %x", e.Address)
+	default:
+		return fmt.Sprintf("witness missing account %x", e.Address)
+	}
+}
+
+// NewState creates an in-memory state database holding nothing but the pre-state
+// of the given witness. The state is anchored at the pre-state root of the
+// witness, which every account and storage slot of the witness is verified
+// against, so that executing on top of it yields the genuine post-state root.
+func NewState(witness *types.TxExtra) (*state.StateDB, error) {
+	db := rawdb.NewMemoryDatabase()
+
+	// Fill the database with the trie nodes and codes from the witness. Nodes are
+	// keyed by hash, so the extra ones needed by deletions are not verified: only
+	// those referenced by the proven nodes are ever resolved
+	for _, proof := range witness.PreStateProof {
+		writeProof(db, proof)
+	}
+	for _, proofs := range witness.PreStorageProof {
+		for _, proof := range proofs {
+			writeProof(db, proof)
+		}
+	}
+	writeProof(db, witness.PreStateNodes)
+	for addr, code := range witness.PreCode {
+		rawdb.WriteCode(db, crypto.Keccak256Hash(code), code)
+		if account := witness.PreState[addr]; account != nil && !bytes.Equal(account.CodeHash, crypto.Keccak256(code)) {
+			return nil, fmt.Errorf("%w: code hash mismatch for account %x", ErrInvalidProof, addr)
+		}
+	}
+	// Ensure the recorded values are the ones proven by the trie nodes
+	for addr, account := range witness.PreState {
+		if _, ok := witness.PreStateProof[addr]; !ok {
+			return nil, &MissingStateError{Address: addr}
+		}
+		val, err := trie.VerifyProof(witness.PreStateRoot, crypto.Keccak256(addr.Bytes()), db)
+		if err != nil {
+			return nil, fmt.Errorf("%w: account %x: %v", ErrInvalidProof, addr, err)
+		}
+		var want []byte
+		if account != nil {
+			want, _ = rlp.EncodeToBytes(account)
+		}
+		if !bytes.Equal(val, want) {
+			return nil, fmt.Errorf("%w: account %x value mismatch", ErrInvalidProof, addr)
+		}
+	}
+	for addr, slots := range witness.PreStorage {
+		account, ok := witness.PreState[addr]
+		if !ok {
+			return nil, &MissingStateError{Address: addr}
+		}
+		if account == nil || account.Root == types.EmptyRootHash {
+			// Slots of absent accounts or empty storage are proven by the account
+			for key, value := range slots {
+				if value != (common.Hash{}) {
+					return nil, fmt.Errorf("%w: slot %x of account %x value mismatch", ErrInvalidProof, key, addr)
+				}
+			}
+			continue
+		}
+		for key, value := range slots {
+			if _, ok := witness.PreStorageProof[addr][key]; !ok {
+				key := key
+				return nil, &MissingStateError{Address: addr, Slot: &key}
+			}
+			val, err := trie.VerifyProof(account.Root, crypto.Keccak256(key.Bytes()), db)
+			if err != nil {
+				return nil, fmt.Errorf("%w: slot %x of account %x: %v", ErrInvalidProof, key, addr, err)
+			}
+			var want []byte
+			if value != (common.Hash{}) {
+				want, _ = rlp.EncodeToBytes(common.TrimLeftZeroes(value[:]))
+			}
+			if !bytes.Equal(val, want) {
+				return nil, fmt.Errorf("%w: slot %x of account %x value mismatch", ErrInvalidProof, key, addr)
+			}
+		}
+	}
+	return state.New(witness.PreStateRoot, state.NewDatabase(db), nil)
+}
+
+// writeProof stores the trie nodes of a Merkle proof into the database.
+func writeProof(db ethdb.KeyValueWriter, proof [][]byte) {
+	for _, node := range proof {
+		rawdb.WriteLegacyTrieNode(db, crypto.Keccak256Hash(node), node)
+	}
+}
+
+// Execute re-executes a transaction on top of the pre-state of its witness in
+// the given block context, and verifies that the resulting state root matches
+// the post-state root of the witness. If the witness is incomplete, the error
+// returned is a *MissingStateError reporting the first state accessed while
+// missing from the witness.
+func Execute(config *params.ChainConfig, context vm.BlockContext, tx *types.Transaction, witness *types.TxExtra) (*core.ExecutionResult, error) {
+	if witness.TxHash != tx.Hash() {
+		return nil, fmt.Errorf("%w: have %x, want %x", ErrWitnessMismatch, witness.TxHash, tx.Hash())
+	}
+	statedb, err := NewState(witness)
+	if err != nil {
+		return nil, err
+	}
+	msg, err := core.TransactionToMessage(tx, types.MakeSigner(config, context.BlockNumber, context.Time), context.BaseFee)
+	if err != nil {
+		return nil, err
+	}
+	// The sender is charged before the EVM reports any access, check it upfront
+	if _, ok := witness.PreState[msg.From]; !ok {
+		return nil, &MissingStateError{Address: msg.From}
+	}
+	var (
+		checker = &witnessChecker{witness: witness}
+		vmenv   = vm.NewEVM(context, core.NewEVMTxContext(msg), statedb, config, vm.Config{Witness: checker})
+	)
+	statedb.SetTxContext(tx.Hash(), 0)
+	result, err := core.ApplyMessage(vmenv, msg, new(core.GasPool).AddGas(tx.Gas()))
+
+	// Missing state surfaces as failed reads, report it before any other error
+	if checker.missing != nil {
+		return nil, checker.missing
+	}
+	if err := statedb.Error(); err != nil {
+		return nil, fmt.Errorf("incomplete witness: %w", err)
+	}
+	if err != nil {
+		return nil, err
+	}
+	root := statedb.IntermediateRoot(config.IsEIP158(context.BlockNumber))
+	if err := statedb.Error(); err != nil {
+		return nil, fmt.Errorf("incomplete witness: %w", err)
+	}
+	if root != witness.PostStateRoot {
+		return nil, fmt.Errorf("%w: have %x, want %x", ErrPostStateMismatch, root, witness.PostStateRoot)
+	}
+	return result, nil
+}
+
+// witnessChecker is a vm.StateWitnessRecorder tracking the first state accessed
+// by the EVM that is missing from a witness.
+type witnessChecker struct {
+	witness *types.TxExtra
+	missing *MissingStateError
+}
+
+// RecordAccount implements vm.StateWitnessRecorder.
+func (c *witnessChecker) RecordAccount(addr common.Address) {
+	if c.missing != nil {
+		return
+	}
+	if _, ok := c.witness.PreState[addr]; !ok {
+		c.missing = &MissingStateError{Address: addr}
+	}
+}
+
+// RecordStorage implements vm.StateWitnessRecorder.
+func (c *witnessChecker) RecordStorage(addr common.Address, key common.Hash) {
+	if c.RecordAccount(addr); c.missing != nil {
+		return
+	}
+	if _, ok := c.witness.PreStorage[addr][key]; !ok {
+		c.missing = &MissingStateError{Address: addr, Slot: &key}
+	}
+}
+
+// RecordCode implements vm.StateWitnessRecorder.
+func (c *witnessChecker) RecordCode(addr common.Address) {
+	if c.RecordAccount(addr); c.missing != nil {
+		return
+	}
+	account := c.witness.PreState[addr]
+	if account == nil || bytes.Equal(account.CodeHash, types.EmptyCodeHash.Bytes()) {
+		return
+	}
+	if _, ok := c.witness.PreCode[addr]; !ok {
+		c.missing = &MissingStateError{Address: addr, Code: true}
+	}
+}
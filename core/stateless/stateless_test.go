@@ -0,0 +1,250 @@
+// Copyright 2023 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package stateless
+
+import (
+	"errors"
+	"math/big"
+	"testing"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/consensus/ethash"
+	"github.com/ethereum/go-ethereum/core"
+	"github.com/ethereum/go-ethereum/core/rawdb"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/core/vm"
+	"github.com/ethereum/go-ethereum/crypto"
+	"github.com/ethereum/go-ethereum/ethdb"
+	"github.com/ethereum/go-ethereum/params"
+	"github.com/ethereum/go-ethereum/rlp"
+)
+
+// newTestChain creates a chain whose transactions read and write a storage slot
+// of a contract, returning it along with its database.
+func newTestChain(t *testing.T) (*core.BlockChain, []*types.Block, ethdb.Database) {
+	var (
+		key, _   = crypto.HexToECDSA("b71c71a67e1177ad4e901695e1b4b9ee17ae16c6668d313eac2f96dbcda3f291")
+		sender   = crypto.PubkeyToAddress(key.PublicKey)
+		contract = common.HexToAddress("0xc0de")
+		signer   = types.LatestSigner(params.TestChainConfig)
+		gspec    = &core.Genesis{
+			Config: params.TestChainConfig,
+			Alloc: core.GenesisAlloc{
+				sender: {Balance: big.NewInt(1000000000000000000)},
+				// SSTORE(CALLVALUE, SLOAD(CALLVALUE) + 1)
+				contract: {Balance: big.NewInt(0), Code: common.FromHex("0x600134540134550000")},
+			},
+		}
+		engine = ethash.NewFaker()
+	)
+	_, blocks, _ := core.GenerateChainWithGenesis(gspec, engine, 2, func(i int, b *core.BlockGen) {
+		for j := 0; j < 2; j++ {
+			tx, _ := types.SignTx(types.NewTransaction(b.TxNonce(sender), contract, big.NewInt(int64(j)), 100000, b.BaseFee(), nil), signer, key)
+			b.AddTx(tx)
+		}
+	})
+	db := rawdb.NewMemoryDatabase()
+	chain, err := core.NewBlockChain(db, &core.CacheConfig{TrieDirtyDisabled: true, WitnessNoLimit: true}, gspec, nil, engine, vm.Config{}, nil, nil)
+	if err != nil {
+		t.Fatalf("failed to create chain: %v", err)
+	}
+	if _, err := chain.InsertChain(blocks); err != nil {
+		t.Fatalf("failed to insert chain: %v", err)
+	}
+	return chain, blocks, db
+}
+
+// copyWitness deep copies a witness through its encoding.
+func copyWitness(t *testing.T, witness *types.TxExtra) *types.TxExtra {
+	enc, err := rlp.EncodeToBytes(witness)
+	if err != nil {
+		t.Fatalf("failed to encode witness: %v", err)
+	}
+	cpy := new(types.TxExtra)
+	if err := rlp.DecodeBytes(enc, cpy); err != nil {
+		t.Fatalf("failed to decode witness: %v", err)
+	}
+	return cpy
+}
+
+func TestExecute(t *testing.T) {
+	chain, blocks, db := newTestChain(t)
+	defer chain.Stop()
+
+	for _, block := range blocks {
+		context := core.NewEVMBlockContext(block.Header(), chain, nil)
+		for i, tx := range block.Transactions() {
+			witness := rawdb.ReadTxWitness(db, block.Hash(), tx.Hash())
+			if witness == nil {
+				t.Fatalf("block %d tx %d: witness not recorded", block.NumberU64(), i)
+			}
+			result, err := Execute(chain.Config(), context, tx, witness)
+			if err != nil {
+				t.Fatalf("block %d tx %d: failed to execute: %v", block.NumberU64(), i, err)
+			}
+			if result.Failed() {
+				t.Fatalf("block %d tx %d: execution failed: %v", block.NumberU64(), i, result.Err)
+			}
+		}
+	}
+}
+
+func TestExecuteIncompleteWitness(t *testing.T) {
+	chain, blocks, db := newTestChain(t)
+	defer chain.Stop()
+
+	var (
+		block    = blocks[1]
+		tx       = block.Transactions()[1]
+		context  = core.NewEVMBlockContext(block.Header(), chain, nil)
+		contract = *tx.To()
+		slot     = common.BigToHash(tx.Value())
+		recorded = rawdb.ReadTxWitness(db, block.Hash(), tx.Hash())
+	)
+	tests := []struct {
+		name   string
+		modify func(witness *types.TxExtra)
+		want   MissingStateError
+	}{
+		{
+			name:   "account",
+			modify: func(witness *types.TxExtra) { delete(witness.PreState, contract) },
+			want:   MissingStateError{Address: contract},
+		},
+		{
+			name:   "code",
+			modify: func(witness *types.TxExtra) { delete(witness.PreCode, contract) },
+			want:   MissingStateError{Address: contract, Code: true},
+		},
+		{
+			name:   "storage",
+			modify: func(witness *types.TxExtra) { delete(witness.PreStorage[contract], slot) },
+			want:   MissingStateError{Address: contract, Slot: &slot},
+		},
+	}
+	for _, test := range tests {
+		witness := copyWitness(t, recorded)
+		test.modify(witness)
+
+		_, err := Execute(chain.Config(), context, tx, witness)
+		var missing *MissingStateError
+		if !errors.As(err, &missing) {
+			t.Fatalf("%s: unexpected error: %v", test.name, err)
+		}
+		if missing.Error() != test.want.Error() {
+			t.Errorf("%s: missing state mismatch: have %q, want %q", test.name, missing, &test.want)
+		}
+	}
+	// Tampering with the post-state root must be detected
+	witness := copyWitness(t, recorded)
+	witness.PostStateRoot = common.Hash{0x01}
+	if _, err := Execute(chain.Config(), context, tx, witness); !errors.Is(err, ErrPostStateMismatch) {
+		t.Fatalf("unexpected error: have %v, want %v", err, ErrPostStateMismatch)
+	}
+	// Tampering with the pre-state must be detected
+	witness = copyWitness(t, recorded)
+	witness.PreStorage[contract][slot] = common.Hash{0x01}
+	if _, err := Execute(chain.Config(), context, tx, witness); !errors.Is(err, ErrInvalidProof) {
+		t.Fatalf("unexpected error: have %v, want %v", err, ErrInvalidProof)
+	}
+}
+
+// Tests that transactions deleting state, collapsing trie nodes into siblings
+// that none of the accessed keys lead to, are executed from their witnesses.
+func TestExecuteDeletion(t *testing.T) {
+	var (
+		key, _   = crypto.HexToECDSA("b71c71a67e1177ad4e901695e1b4b9ee17ae16c6668d313eac2f96dbcda3f291")
+		sender   = crypto.PubkeyToAddress(key.PublicKey)
+		coinbase = common.HexToAddress("0xc0ffee")
+		clearer  = common.HexToAddress("0xc1ea")
+		signer   = types.LatestSigner(params.TestChainConfig)
+		nibble   = func(addr common.Address) byte { return crypto.Keccak256(addr.Bytes())[0] >> 4 }
+	)
+	// Pick an empty account to delete sharing its subtrie with a single other
+	// account, so that the deletion collapses the subtrie into the latter
+	var sibling, empty common.Address
+	for i := 1; ; i++ {
+		sibling = common.BigToAddress(big.NewInt(int64(i)))
+		if n := nibble(sibling); n != nibble(sender) && n != nibble(coinbase) && n != nibble(clearer) {
+			break
+		}
+	}
+	for i := 1; ; i++ {
+		empty = common.BigToAddress(big.NewInt(int64(0x10000 + i)))
+		if nibble(empty) == nibble(sibling) {
+			break
+		}
+	}
+	gspec := &core.Genesis{
+		Config: params.TestChainConfig,
+		Alloc: core.GenesisAlloc{
+			sender:  {Balance: big.NewInt(1000000000000000000)},
+			sibling: {Balance: big.NewInt(1)},
+			empty:   {Balance: big.NewInt(0)},
+			// SSTORE(0, 0), clearing one of two slots
+			clearer: {
+				Balance: big.NewInt(0),
+				Code:    common.FromHex("0x6000600055"),
+				Storage: map[common.Hash]common.Hash{{}: {0x01}, {0x01}: {0x01}},
+			},
+		},
+	}
+	engine := ethash.NewFaker()
+	_, blocks, _ := core.GenerateChainWithGenesis(gspec, engine, 1, func(i int, b *core.BlockGen) {
+		b.SetCoinbase(coinbase)
+		for _, to := range []common.Address{clearer, empty} {
+			tx, _ := types.SignTx(types.NewTransaction(b.TxNonce(sender), to, new(big.Int), 100000, b.BaseFee(), nil), signer, key)
+			b.AddTx(tx)
+		}
+	})
+	db := rawdb.NewMemoryDatabase()
+	chain, err := core.NewBlockChain(db, &core.CacheConfig{TrieDirtyDisabled: true, WitnessNoLimit: true}, gspec, nil, engine, vm.Config{}, nil, nil)
+	if err != nil {
+		t.Fatalf("failed to create chain: %v", err)
+	}
+	defer chain.Stop()
+	if _, err := chain.InsertChain(blocks); err != nil {
+		t.Fatalf("failed to insert chain: %v", err)
+	}
+	block := blocks[0]
+	context := core.NewEVMBlockContext(block.Header(), chain, nil)
+	for i, tx := range block.Transactions() {
+		witness := rawdb.ReadTxWitness(db, block.Hash(), tx.Hash())
+		if witness == nil {
+			t.Fatalf("tx %d: witness not recorded", i)
+		}
+		if len(witness.PreStateNodes) == 0 {
+			t.Fatalf("tx %d: no deletion nodes recorded", i)
+		}
+		if _, err := Execute(chain.Config(), context, tx, witness); err != nil {
+			t.Fatalf("tx %d: failed to execute: %v", i, err)
+		}
+		// Without the extra nodes, the deletion cannot be applied
+		witness = copyWitness(t, witness)
+		witness.PreStateNodes = nil
+		if _, err := Execute(chain.Config(), context, tx, witness); err == nil {
+			t.Fatalf("tx %d: executed without deletion nodes", i)
+		}
+	}
+	statedb, err := chain.State()
+	if err != nil {
+		t.Fatalf("failed to open head state: %v", err)
+	}
+	if statedb.Exist(empty) || statedb.GetState(clearer, common.Hash{}) != (common.Hash{}) {
+		t.Fatalf("state not deleted")
+	}
+}
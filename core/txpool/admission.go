@@ -0,0 +1,470 @@
+// Copyright 2023 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package txpool
+
+import (
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/common/hexutil"
+	"github.com/ethereum/go-ethereum/core/types"
+)
+
+// ErrTxRejected is returned, wrapped in an AdmissionError, if an admission
+// policy refuses a transaction.
+var ErrTxRejected = errors.New("transaction rejected by admission policy")
+
+// Reasons reported by the built-in admission policies.
+const (
+	ReasonSenderDenied        = "sender-denied"
+	ReasonSenderNotAllowed    = "sender-not-allowed"
+	ReasonRecipientDenied     = "recipient-denied"
+	ReasonRecipientNotAllowed = "recipient-not-allowed"
+	ReasonMethodDenied        = "method-denied"
+	ReasonMethodNotAllowed    = "method-not-allowed"
+	ReasonCalldataTooLarge    = "calldata-too-large"
+	ReasonRateLimited         = "rate-limited"
+	ReasonRejected            = "rejected"
+)
+
+// AdmissionPolicy is a rule deciding whether a new transaction may enter the
+// pool, checked once the transaction passed the consensus and pool specific
+// validation rules. Policies are called concurrently and must be safe for
+// concurrent use.
+type AdmissionPolicy interface {
+	// Name returns the name of the policy, reported on rejections.
+	Name() string
+
+	// Admit returns an error if the transaction, sent by the given account,
+	// is not allowed into the pool. Local transactions were submitted by the
+	// node's own users or sent by accounts marked local. Rejections should
+	// be reported through an AdmissionError to give the submitter a structured
+	// reason.
+	Admit(tx *types.Transaction, from common.Address, local bool) error
+}
+
+// AdmissionRecorder is implemented by admission policies tracking the
+// transactions they admitted, e.g. to rate limit their senders. Since the pool
+// may still refuse a transaction after admitting it, Record is only called
+// once the transaction is accepted.
+type AdmissionRecorder interface {
+	// Record notes that an admitted transaction was accepted into the pool.
+	Record(tx *types.Transaction, from common.Address, local bool)
+}
+
+// AdmissionError is the structured reason of an admission policy rejecting a
+// transaction. It is returned verbatim to RPC callers submitting transactions,
+// with the reason details as the error data.
+type AdmissionError struct {
+	Policy  string         `json:"policy"`  // Name of the rejecting policy
+	Reason  string         `json:"reason"`  // Machine readable reason of the rejection
+	Sender  common.Address `json:"sender"`  // Sender of the rejected transaction
+	Tx      common.Hash    `json:"tx"`      // Hash of the rejected transaction
+	Message string         `json:"message"` // Human readable details of the rejection
+}
+
+// Error implements error.
+func (e *AdmissionError) Error() string {
+	return fmt.Sprintf("%v: %s policy: %s", ErrTxRejected, e.Policy, e.Message)
+}
+
+// Unwrap allows matching admission errors against ErrTxRejected.
+func (e *AdmissionError) Unwrap() error { return ErrTxRejected }
+
+// ErrorCode returns the JSON-RPC error code for rejected transactions.
+func (e *AdmissionError) ErrorCode() int { return -32003 }
+
+// ErrorData returns the structured reason of the rejection as JSON-RPC error data.
+func (e *AdmissionError) ErrorData() interface{} { return e }
+
+// reject assembles the admission error of a policy refusing a transaction.
+func reject(policy AdmissionPolicy, tx *types.Transaction, from common.Address, reason string, format string, args ...interface{}) *AdmissionError {
+	return &AdmissionError{
+		Policy:  policy.Name(),
+		Reason:  reason,
+		Sender:  from,
+		Tx:      tx.Hash(),
+		Message: fmt.Sprintf(format, args...),
+	}
+}
+
+// AdmissionChain is a list of admission policies, admitting a transaction only
+// if all of them do. Policies are checked in order, stopping at the first one
+// rejecting the transaction.
+type AdmissionChain []AdmissionPolicy
+
+// Name implements AdmissionPolicy.
+func (c AdmissionChain) Name() string { return "chain" }
+
+// Admit implements AdmissionPolicy.
+func (c AdmissionChain) Admit(tx *types.Transaction, from common.Address, local bool) error {
+	for _, policy := range c {
+		if err := policy.Admit(tx, from, local); err != nil {
+			var aerr *AdmissionError
+			if errors.As(err, &aerr) {
+				return aerr
+			}
+			return reject(policy, tx, from, ReasonRejected, "%v", err)
+		}
+	}
+	return nil
+}
+
+// Record implements AdmissionRecorder, forwarding to the policies tracking the
+// transactions they admitted.
+func (c AdmissionChain) Record(tx *types.Transaction, from common.Address, local bool) {
+	for _, policy := range c {
+		if recorder, ok := policy.(AdmissionRecorder); ok {
+			recorder.Record(tx, from, local)
+		}
+	}
+}
+
+// AdmissionConfig are the configuration parameters of the built-in admission
+// policies. The zero value admits every transaction.
+type AdmissionConfig struct {
+	SenderAllow    []common.Address // Senders allowed to submit transactions, everyone if empty
+	SenderDeny     []common.Address // Senders never allowed to submit transactions
+	RecipientAllow []common.Address // Recipients transactions may be sent to, anyone if empty
+	RecipientDeny  []common.Address // Recipients transactions may never be sent to
+
+	Methods []MethodFilter // Method selector filters of individual contracts
+
+	MaxCalldata     uint64          // Maximum calldata size of transactions from unclassified senders, unlimited if zero
+	CalldataClasses []CalldataClass // Address classes with their own calldata size limits
+
+	SenderRate uint64        // Maximum number of transactions a sender may submit per rate window, unlimited if zero
+	RateWindow time.Duration // Time window the sender rate limit applies to
+}
+
+// MethodFilter restricts the methods that may be called on a contract by the
+// transactions sent to it, identified by their 4 byte hex selectors.
+type MethodFilter struct {
+	Contract common.Address // Contract the filter applies to
+	Allow    []string       // Selectors that may be called, all if empty
+	Deny     []string       // Selectors that may never be called
+}
+
+// CalldataClass is a named set of senders sharing a calldata size limit.
+type CalldataClass struct {
+	Name    string           // Name of the class, reported on rejections
+	Senders []common.Address // Senders belonging to the class
+	MaxSize uint64           // Maximum calldata size, unlimited if zero
+}
+
+// NewAdmissionPolicies creates the built-in admission policies enabled by the
+// given configuration. Only transactions accepted into the pool count against
+// the sender rate limit, which local transactions are exempt from.
+func NewAdmissionPolicies(config AdmissionConfig) ([]AdmissionPolicy, error) {
+	var policies []AdmissionPolicy
+	if len(config.SenderAllow) > 0 || len(config.SenderDeny) > 0 {
+		policies = append(policies, newSenderPolicy(config.SenderAllow, config.SenderDeny))
+	}
+	if len(config.RecipientAllow) > 0 || len(config.RecipientDeny) > 0 {
+		policies = append(policies, newRecipientPolicy(config.RecipientAllow, config.RecipientDeny))
+	}
+	if len(config.Methods) > 0 {
+		policy, err := newMethodPolicy(config.Methods)
+		if err != nil {
+			return nil, err
+		}
+		policies = append(policies, policy)
+	}
+	if config.MaxCalldata > 0 || len(config.CalldataClasses) > 0 {
+		policy, err := newCalldataPolicy(config.MaxCalldata, config.CalldataClasses)
+		if err != nil {
+			return nil, err
+		}
+		policies = append(policies, policy)
+	}
+	if config.SenderRate > 0 {
+		if config.RateWindow <= 0 {
+			return nil, fmt.Errorf("invalid sender rate window %v", config.RateWindow)
+		}
+		policies = append(policies, newRatePolicy(config.SenderRate, config.RateWindow))
+	}
+	return policies, nil
+}
+
+// addressSet is a set of accounts.
+type addressSet map[common.Address]struct{}
+
+func newAddressSet(addrs []common.Address) addressSet {
+	set := make(addressSet, len(addrs))
+	for _, addr := range addrs {
+		set[addr] = struct{}{}
+	}
+	return set
+}
+
+func (s addressSet) contains(addr common.Address) bool {
+	_, ok := s[addr]
+	return ok
+}
+
+// senderPolicy admits transactions based on allow- and denylists of senders.
+type senderPolicy struct {
+	allow addressSet
+	deny  addressSet
+}
+
+func newSenderPolicy(allow, deny []common.Address) *senderPolicy {
+	return &senderPolicy{allow: newAddressSet(allow), deny: newAddressSet(deny)}
+}
+
+func (p *senderPolicy) Name() string { return "sender" }
+
+func (p *senderPolicy) Admit(tx *types.Transaction, from common.Address, local bool) error {
+	if p.deny.contains(from) {
+		return reject(p, tx, from, ReasonSenderDenied, "sender %v is denied", from)
+	}
+	if len(p.allow) > 0 && !p.allow.contains(from) {
+		return reject(p, tx, from, ReasonSenderNotAllowed, "sender %v is not allowed", from)
+	}
+	return nil
+}
+
+// recipientPolicy admits transactions based on allow- and denylists of
+// recipients. Contract creations have no recipient and are always admitted.
+type recipientPolicy struct {
+	allow addressSet
+	deny  addressSet
+}
+
+func newRecipientPolicy(allow, deny []common.Address) *recipientPolicy {
+	return &recipientPolicy{allow: newAddressSet(allow), deny: newAddressSet(deny)}
+}
+
+func (p *recipientPolicy) Name() string { return "recipient" }
+
+func (p *recipientPolicy) Admit(tx *types.Transaction, from common.Address, local bool) error {
+	to := tx.To()
+	if to == nil {
+		return nil
+	}
+	if p.deny.contains(*to) {
+		return reject(p, tx, from, ReasonRecipientDenied, "recipient %v is denied", *to)
+	}
+	if len(p.allow) > 0 && !p.allow.contains(*to) {
+		return reject(p, tx, from, ReasonRecipientNotAllowed, "recipient %v is not allowed", *to)
+	}
+	return nil
+}
+
+// selector is the 4 byte identifier of a contract method.
+type selector [4]byte
+
+// selectorFilter is the parsed method filter of a single contract.
+type selectorFilter struct {
+	allow map[selector]struct{}
+	deny  map[selector]struct{}
+}
+
+// methodPolicy admits transactions based on the method selectors they call on
+// filtered contracts. Calls without a full selector, e.g. plain transfers, are
+// only admitted to contracts without a method allowlist.
+type methodPolicy struct {
+	filters map[common.Address]*selectorFilter
+}
+
+func newMethodPolicy(filters []MethodFilter) (*methodPolicy, error) {
+	p := &methodPolicy{filters: make(map[common.Address]*selectorFilter)}
+	for _, filter := range filters {
+		parsed := p.filters[filter.Contract]
+		if parsed == nil {
+			parsed = &selectorFilter{
+				allow: make(map[selector]struct{}),
+				deny:  make(map[selector]struct{}),
+			}
+			p.filters[filter.Contract] = parsed
+		}
+		for _, sel := range filter.Allow {
+			id, err := parseSelector(sel)
+			if err != nil {
+				return nil, fmt.Errorf("contract %v: %w", filter.Contract, err)
+			}
+			parsed.allow[id] = struct{}{}
+		}
+		for _, sel := range filter.Deny {
+			id, err := parseSelector(sel)
+			if err != nil {
+				return nil, fmt.Errorf("contract %v: %w", filter.Contract, err)
+			}
+			parsed.deny[id] = struct{}{}
+		}
+	}
+	return p, nil
+}
+
+// parseSelector parses a hex encoded 4 byte method selector.
+func parseSelector(s string) (selector, error) {
+	var id selector
+	blob, err := hexutil.Decode(s)
+	if err != nil {
+		return id, fmt.Errorf("invalid method selector %q: %v", s, err)
+	}
+	if len(blob) != len(id) {
+		return id, fmt.Errorf("invalid method selector %q: have %d bytes, want %d", s, len(blob), len(id))
+	}
+	copy(id[:], blob)
+	return id, nil
+}
+
+func (p *methodPolicy) Name() string { return "method" }
+
+func (p *methodPolicy) Admit(tx *types.Transaction, from common.Address, local bool) error {
+	to := tx.To()
+	if to == nil {
+		return nil
+	}
+	filter := p.filters[*to]
+	if filter == nil {
+		return nil
+	}
+	data := tx.Data()
+	if len(data) < len(selector{}) {
+		if len(filter.allow) > 0 {
+			return reject(p, tx, from, ReasonMethodNotAllowed, "calls to %v without method selector are not allowed", *to)
+		}
+		return nil
+	}
+	var id selector
+	copy(id[:], data)
+
+	if _, ok := filter.deny[id]; ok {
+		return reject(p, tx, from, ReasonMethodDenied, "method %#x of %v is denied", id[:], *to)
+	}
+	if _, ok := filter.allow[id]; len(filter.allow) > 0 && !ok {
+		return reject(p, tx, from, ReasonMethodNotAllowed, "method %#x of %v is not allowed", id[:], *to)
+	}
+	return nil
+}
+
+// calldataClass is the class a sender is limited by.
+type calldataClass struct {
+	name    string
+	maxSize uint64
+}
+
+// calldataPolicy admits transactions based on the size of their calldata, with
+// limits depending on the class of their sender.
+type calldataPolicy struct {
+	classes  map[common.Address]*calldataClass
+	fallback *calldataClass
+}
+
+func newCalldataPolicy(maxSize uint64, classes []CalldataClass) (*calldataPolicy, error) {
+	p := &calldataPolicy{
+		classes:  make(map[common.Address]*calldataClass),
+		fallback: &calldataClass{name: "default", maxSize: maxSize},
+	}
+	for _, class := range classes {
+		parsed := &calldataClass{name: class.Name, maxSize: class.MaxSize}
+		for _, addr := range class.Senders {
+			if prev, ok := p.classes[addr]; ok {
+				return nil, fmt.Errorf("sender %v in multiple calldata classes: %q and %q", addr, prev.name, class.Name)
+			}
+			p.classes[addr] = parsed
+		}
+	}
+	return p, nil
+}
+
+func (p *calldataPolicy) Name() string { return "calldata" }
+
+func (p *calldataPolicy) Admit(tx *types.Transaction, from common.Address, local bool) error {
+	class := p.classes[from]
+	if class == nil {
+		class = p.fallback
+	}
+	if size := uint64(len(tx.Data())); class.maxSize > 0 && size > class.maxSize {
+		return reject(p, tx, from, ReasonCalldataTooLarge, "calldata size %d exceeds limit %d of class %q", size, class.maxSize, class.name)
+	}
+	return nil
+}
+
+// ratePolicy limits the number of transactions a single sender may get accepted
+// into the pool in a sliding time window. Local transactions are not limited.
+type ratePolicy struct {
+	limit  uint64
+	window time.Duration
+	now    func() time.Time // Clock, overridable in tests
+
+	lock    sync.Mutex
+	history map[common.Address][]time.Time // Acceptance times within the window, oldest first
+	cleaned time.Time                      // Last time stale senders were dropped
+}
+
+func newRatePolicy(limit uint64, window time.Duration) *ratePolicy {
+	return &ratePolicy{
+		limit:   limit,
+		window:  window,
+		now:     time.Now,
+		history: make(map[common.Address][]time.Time),
+	}
+}
+
+func (p *ratePolicy) Name() string { return "rate" }
+
+func (p *ratePolicy) Admit(tx *types.Transaction, from common.Address, local bool) error {
+	if local {
+		return nil
+	}
+	p.lock.Lock()
+	defer p.lock.Unlock()
+
+	var (
+		now    = p.now()
+		cutoff = now.Add(-p.window)
+	)
+	// Drop the senders without recent acceptances every now and then to avoid
+	// tracking every account ever seen
+	if now.Sub(p.cleaned) > p.window {
+		for addr, times := range p.history {
+			if !times[len(times)-1].After(cutoff) {
+				delete(p.history, addr)
+			}
+		}
+		p.cleaned = now
+	}
+	times := p.history[from]
+	for len(times) > 0 && !times[0].After(cutoff) {
+		times = times[1:]
+	}
+	if len(times) == 0 {
+		delete(p.history, from)
+	} else {
+		p.history[from] = times
+	}
+	if uint64(len(times)) >= p.limit {
+		return reject(p, tx, from, ReasonRateLimited, "sender %v exceeded %d transactions per %v", from, p.limit, p.window)
+	}
+	return nil
+}
+
+func (p *ratePolicy) Record(tx *types.Transaction, from common.Address, local bool) {
+	if local {
+		return
+	}
+	p.lock.Lock()
+	defer p.lock.Unlock()
+
+	p.history[from] = append(p.history[from], p.now())
+}
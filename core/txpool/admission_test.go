@@ -0,0 +1,304 @@
+// Copyright 2023 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package txpool
+
+import (
+	"errors"
+	"math/big"
+	"testing"
+	"time"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/crypto"
+)
+
+// admissionTx creates an unsigned transaction to the given recipient carrying
+// the given calldata, a contract creation if to is nil.
+func admissionTx(nonce uint64, to *common.Address, data []byte) *types.Transaction {
+	return types.NewTx(&types.LegacyTx{
+		Nonce:    nonce,
+		To:       to,
+		Gas:      100000,
+		GasPrice: big.NewInt(1),
+		Data:     data,
+	})
+}
+
+// checkAdmission checks whether a policy admits a transaction, and if not, that
+// it rejects it for the expected reason.
+func checkAdmission(t *testing.T, policy AdmissionPolicy, tx *types.Transaction, from common.Address, reason string) {
+	t.Helper()
+
+	err := policy.Admit(tx, from, false)
+	if reason == "" {
+		if err != nil {
+			t.Errorf("transaction rejected: %v", err)
+		} else if recorder, ok := policy.(AdmissionRecorder); ok {
+			recorder.Record(tx, from, false)
+		}
+		return
+	}
+	var aerr *AdmissionError
+	if !errors.As(err, &aerr) {
+		t.Errorf("rejection mismatch: have %v, want admission error", err)
+		return
+	}
+	if aerr.Reason != reason {
+		t.Errorf("rejection reason mismatch: have %s, want %s", aerr.Reason, reason)
+	}
+	if aerr.Sender != from || aerr.Tx != tx.Hash() {
+		t.Errorf("rejection subject mismatch: have %v/%v, want %v/%v", aerr.Sender, aerr.Tx, from, tx.Hash())
+	}
+	if !errors.Is(err, ErrTxRejected) {
+		t.Errorf("rejection not matching ErrTxRejected: %v", err)
+	}
+}
+
+func TestSenderRecipientPolicies(t *testing.T) {
+	var (
+		alice = common.Address{0xa1}
+		bob   = common.Address{0xb0}
+		carol = common.Address{0xca}
+	)
+	policies, err := NewAdmissionPolicies(AdmissionConfig{
+		SenderAllow:    []common.Address{alice, bob},
+		SenderDeny:     []common.Address{bob},
+		RecipientAllow: []common.Address{alice, bob},
+		RecipientDeny:  []common.Address{alice},
+	})
+	if err != nil {
+		t.Fatalf("failed to create policies: %v", err)
+	}
+	chain := AdmissionChain(policies)
+
+	checkAdmission(t, chain, admissionTx(0, &bob, nil), alice, "")
+	checkAdmission(t, chain, admissionTx(0, nil, nil), alice, "")
+	checkAdmission(t, chain, admissionTx(0, &bob, nil), bob, ReasonSenderDenied)
+	checkAdmission(t, chain, admissionTx(0, &bob, nil), carol, ReasonSenderNotAllowed)
+	checkAdmission(t, chain, admissionTx(0, &alice, nil), alice, ReasonRecipientDenied)
+	checkAdmission(t, chain, admissionTx(0, &carol, nil), alice, ReasonRecipientNotAllowed)
+}
+
+func TestMethodPolicy(t *testing.T) {
+	var (
+		sender   = common.Address{0x01}
+		open     = common.Address{0x02}
+		allowing = common.Address{0x03}
+		denying  = common.Address{0x04}
+
+		transfer = common.FromHex("0xa9059cbb")
+		approve  = common.FromHex("0x095ea7b3")
+	)
+	policies, err := NewAdmissionPolicies(AdmissionConfig{
+		Methods: []MethodFilter{
+			{Contract: allowing, Allow: []string{"0xa9059cbb"}},
+			{Contract: denying, Deny: []string{"0xa9059cbb"}},
+		},
+	})
+	if err != nil {
+		t.Fatalf("failed to create policies: %v", err)
+	}
+	chain := AdmissionChain(policies)
+
+	checkAdmission(t, chain, admissionTx(0, &open, approve), sender, "")
+	checkAdmission(t, chain, admissionTx(0, &allowing, append(transfer, 0x01)), sender, "")
+	checkAdmission(t, chain, admissionTx(0, &allowing, approve), sender, ReasonMethodNotAllowed)
+	checkAdmission(t, chain, admissionTx(0, &allowing, nil), sender, ReasonMethodNotAllowed)
+	checkAdmission(t, chain, admissionTx(0, &denying, approve), sender, "")
+	checkAdmission(t, chain, admissionTx(0, &denying, nil), sender, "")
+	checkAdmission(t, chain, admissionTx(0, &denying, transfer), sender, ReasonMethodDenied)
+
+	// Ensure malformed selectors are refused
+	for _, sel := range []string{"a9059cbb", "0xa9059c", "0xa9059cbb00"} {
+		if _, err := NewAdmissionPolicies(AdmissionConfig{Methods: []MethodFilter{{Contract: denying, Deny: []string{sel}}}}); err == nil {
+			t.Errorf("selector %q: expected error", sel)
+		}
+	}
+}
+
+func TestCalldataPolicy(t *testing.T) {
+	var (
+		plain = common.Address{0x01}
+		bulk  = common.Address{0x02}
+		free  = common.Address{0x03}
+	)
+	policies, err := NewAdmissionPolicies(AdmissionConfig{
+		MaxCalldata: 16,
+		CalldataClasses: []CalldataClass{
+			{Name: "bulk", Senders: []common.Address{bulk}, MaxSize: 64},
+			{Name: "free", Senders: []common.Address{free}},
+		},
+	})
+	if err != nil {
+		t.Fatalf("failed to create policies: %v", err)
+	}
+	chain := AdmissionChain(policies)
+
+	checkAdmission(t, chain, admissionTx(0, &plain, make([]byte, 16)), plain, "")
+	checkAdmission(t, chain, admissionTx(0, &plain, make([]byte, 17)), plain, ReasonCalldataTooLarge)
+	checkAdmission(t, chain, admissionTx(0, &plain, make([]byte, 64)), bulk, "")
+	checkAdmission(t, chain, admissionTx(0, &plain, make([]byte, 65)), bulk, ReasonCalldataTooLarge)
+	checkAdmission(t, chain, admissionTx(0, &plain, make([]byte, 4096)), free, "")
+
+	// Ensure senders can't be in multiple classes
+	_, err = NewAdmissionPolicies(AdmissionConfig{
+		CalldataClasses: []CalldataClass{
+			{Name: "a", Senders: []common.Address{bulk}, MaxSize: 1},
+			{Name: "b", Senders: []common.Address{bulk}, MaxSize: 2},
+		},
+	})
+	if err == nil {
+		t.Errorf("expected error for overlapping classes")
+	}
+}
+
+func TestRatePolicy(t *testing.T) {
+	var (
+		alice = common.Address{0xa1}
+		bob   = common.Address{0xb0}
+		now   = time.Unix(1000, 0)
+	)
+	policy := newRatePolicy(2, time.Minute)
+	policy.now = func() time.Time { return now }
+
+	checkAdmission(t, policy, admissionTx(0, &bob, nil), alice, "")
+	now = now.Add(10 * time.Second)
+	checkAdmission(t, policy, admissionTx(1, &bob, nil), alice, "")
+	checkAdmission(t, policy, admissionTx(2, &bob, nil), alice, ReasonRateLimited)
+	checkAdmission(t, policy, admissionTx(0, &alice, nil), bob, "")
+
+	// Move past the first admission, making room for a single transaction
+	now = now.Add(50 * time.Second)
+	checkAdmission(t, policy, admissionTx(2, &bob, nil), alice, "")
+	checkAdmission(t, policy, admissionTx(3, &bob, nil), alice, ReasonRateLimited)
+
+	// Move past the whole window, ensuring stale senders are dropped
+	now = now.Add(2 * time.Minute)
+	checkAdmission(t, policy, admissionTx(3, &bob, nil), alice, "")
+	if _, ok := policy.history[bob]; ok {
+		t.Errorf("stale sender still tracked")
+	}
+	// Ensure only recorded transactions count, and locals are never limited
+	if err := policy.Admit(admissionTx(4, &bob, nil), alice, false); err != nil {
+		t.Errorf("transaction rejected: %v", err)
+	}
+	if err := policy.Admit(admissionTx(4, &bob, nil), alice, false); err != nil {
+		t.Errorf("unrecorded transaction counted: %v", err)
+	}
+	for i := 0; i < 3; i++ {
+		if err := policy.Admit(admissionTx(uint64(5+i), &bob, nil), alice, true); err != nil {
+			t.Errorf("local transaction %d rejected: %v", i, err)
+		}
+		policy.Record(admissionTx(uint64(5+i), &bob, nil), alice, true)
+	}
+	if _, err := NewAdmissionPolicies(AdmissionConfig{SenderRate: 1}); err == nil {
+		t.Errorf("expected error for missing rate window")
+	}
+}
+
+// Tests that the pool runs new transactions through the admission policies and
+// returns structured rejections.
+func TestPoolAdmission(t *testing.T) {
+	t.Parallel()
+
+	pool, key := setupPool()
+	defer pool.Stop()
+
+	var (
+		sender  = crypto.PubkeyToAddress(key.PublicKey)
+		denied  = common.Address{0xde}
+		allowed = common.Address{0xa1}
+		signer  = types.HomesteadSigner{}
+	)
+	testAddBalance(pool, sender, big.NewInt(1000000000))
+
+	policies, err := NewAdmissionPolicies(AdmissionConfig{RecipientDeny: []common.Address{denied}})
+	if err != nil {
+		t.Fatalf("failed to create policies: %v", err)
+	}
+	pool.SetAdmissionPolicies(policies...)
+
+	tx := types.MustSignNewTx(key, signer, &types.LegacyTx{Nonce: 0, To: &denied, Gas: 100000, GasPrice: big.NewInt(1)})
+	err = pool.AddLocal(tx)
+	if !errors.Is(err, ErrTxRejected) {
+		t.Fatalf("rejection mismatch: have %v, want %v", err, ErrTxRejected)
+	}
+	aerr := err.(*AdmissionError)
+	if aerr.Policy != "recipient" || aerr.Reason != ReasonRecipientDenied || aerr.Sender != sender {
+		t.Errorf("rejection details mismatch: %+v", aerr)
+	}
+	if aerr.ErrorCode() != -32003 || aerr.ErrorData() != aerr {
+		t.Errorf("rpc error mismatch: code %d, data %v", aerr.ErrorCode(), aerr.ErrorData())
+	}
+	tx = types.MustSignNewTx(key, signer, &types.LegacyTx{Nonce: 0, To: &allowed, Gas: 100000, GasPrice: big.NewInt(1)})
+	if err := pool.AddLocal(tx); err != nil {
+		t.Fatalf("failed to add admitted transaction: %v", err)
+	}
+	// Ensure the policies can be lifted
+	pool.SetAdmissionPolicies()
+	tx = types.MustSignNewTx(key, signer, &types.LegacyTx{Nonce: 1, To: &denied, Gas: 100000, GasPrice: big.NewInt(1)})
+	if err := pool.AddLocal(tx); err != nil {
+		t.Fatalf("failed to add transaction without policies: %v", err)
+	}
+	if pending, _ := pool.Stats(); pending != 2 {
+		t.Errorf("pending transactions mismatch: have %d, want 2", pending)
+	}
+}
+
+// Tests that only transactions accepted into the pool count against the sender
+// rate limit, and that local transactions are exempt from it.
+func TestPoolAdmissionRate(t *testing.T) {
+	t.Parallel()
+
+	pool, key := setupPool()
+	defer pool.Stop()
+
+	var (
+		sender = crypto.PubkeyToAddress(key.PublicKey)
+		to     = common.Address{0xa1}
+		signer = types.HomesteadSigner{}
+	)
+	testAddBalance(pool, sender, big.NewInt(1000000000))
+
+	policies, err := NewAdmissionPolicies(AdmissionConfig{SenderRate: 1, RateWindow: time.Hour})
+	if err != nil {
+		t.Fatalf("failed to create policies: %v", err)
+	}
+	pool.SetAdmissionPolicies(policies...)
+
+	// Invalid and known transactions must not spend the sender's budget
+	invalid := types.MustSignNewTx(key, signer, &types.LegacyTx{Nonce: 0, To: &to, Value: big.NewInt(2000000000), Gas: 100000, GasPrice: big.NewInt(1)})
+	if err := pool.addRemoteSync(invalid); !errors.Is(err, core.ErrInsufficientFunds) {
+		t.Fatalf("invalid transaction error mismatch: have %v, want %v", err, core.ErrInsufficientFunds)
+	}
+	tx := types.MustSignNewTx(key, signer, &types.LegacyTx{Nonce: 0, To: &to, Gas: 100000, GasPrice: big.NewInt(1)})
+	if err := pool.addRemoteSync(tx); err != nil {
+		t.Fatalf("failed to add transaction: %v", err)
+	}
+	if err := pool.addRemoteSync(tx); !errors.Is(err, ErrAlreadyKnown) {
+		t.Fatalf("known transaction error mismatch: have %v, want %v", err, ErrAlreadyKnown)
+	}
+	tx = types.MustSignNewTx(key, signer, &types.LegacyTx{Nonce: 1, To: &to, Gas: 100000, GasPrice: big.NewInt(1)})
+	if err := pool.addRemoteSync(tx); !errors.Is(err, ErrTxRejected) {
+		t.Fatalf("rate limited transaction error mismatch: have %v, want %v", err, ErrTxRejected)
+	}
+	if err := pool.AddLocal(tx); err != nil {
+		t.Fatalf("failed to add local transaction: %v", err)
+	}
+}
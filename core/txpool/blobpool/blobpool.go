@@ -0,0 +1,780 @@
+// Copyright 2023 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+// Package blobpool implements the EIP-4844 blob transaction pool.
+package blobpool
+
+import (
+	"errors"
+	"math/big"
+	"sort"
+	"sync"
+	"sync/atomic"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/consensus/misc"
+	"github.com/ethereum/go-ethereum/core"
+	"github.com/ethereum/go-ethereum/core/rawdb"
+	"github.com/ethereum/go-ethereum/core/state"
+	"github.com/ethereum/go-ethereum/core/txpool"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/crypto/kzg4844"
+	"github.com/ethereum/go-ethereum/ethdb"
+	"github.com/ethereum/go-ethereum/event"
+	"github.com/ethereum/go-ethereum/log"
+	"github.com/ethereum/go-ethereum/metrics"
+	"github.com/ethereum/go-ethereum/params"
+	"github.com/ethereum/go-ethereum/rlp"
+)
+
+const (
+	// blobSize is the protocol constrained byte size of a single blob in a
+	// transaction. There can be multiple of these embedded into a single tx.
+	blobSize = uint64(len(kzg4844.Blob{}))
+
+	// txMaxSize is the maximum size a single transaction can have, outside
+	// the included blobs. Since blob transactions are pulled instead of pushed,
+	// and only a small metadata is kept in ram, the rest is on disk, there is
+	// no critical limit that should be enforced. Still, capping it to some sane
+	// limit can never hurt.
+	txMaxSize = 1024 * 1024
+
+	// chainHeadChanSize is the size of channel listening to ChainHeadEvent.
+	chainHeadChanSize = 10
+)
+
+var (
+	// ErrSidecarMissing is returned if a blob transaction is added to the pool
+	// without the blobs, commitments and proofs it references.
+	ErrSidecarMissing = errors.New("missing blob sidecar")
+
+	// ErrAccountLimitExceeded is returned if a transaction would push the number
+	// of blobs pooled for its sender above the configured per-account limit.
+	ErrAccountLimitExceeded = errors.New("account blob limit exceeded")
+)
+
+var (
+	datasizeGauge = metrics.NewRegisteredGauge("blobpool/datasize", nil)
+	txsGauge      = metrics.NewRegisteredGauge("blobpool/transactions", nil)
+
+	replaceMeter  = metrics.NewRegisteredMeter("blobpool/replace", nil)
+	evictMeter    = metrics.NewRegisteredMeter("blobpool/evict", nil)    // Dropped to make room for better priced ones
+	includedMeter = metrics.NewRegisteredMeter("blobpool/included", nil) // Dropped due to inclusion in the chain
+	dropMeter     = metrics.NewRegisteredMeter("blobpool/drop", nil)     // Dropped due to gaps, overdrafts or corruption
+)
+
+// blockChain provides the state of blockchain and current gas limit to do
+// some pre checks in the blob pool and event subscribers.
+type blockChain interface {
+	CurrentBlock() *types.Header
+	StateAt(root common.Hash) (*state.StateDB, error)
+
+	SubscribeChainHeadEvent(ch chan<- core.ChainHeadEvent) event.Subscription
+}
+
+// blobTxEntry is the on-disk representation of a pooled blob transaction.
+type blobTxEntry struct {
+	Tx      *types.Transaction
+	Sidecar *types.BlobTxSidecar
+	Local   bool
+}
+
+// blobTxMeta is the in-memory representation of a pooled blob transaction. The
+// blobs themselves are only kept on disk and loaded on demand.
+type blobTxMeta struct {
+	tx    *types.Transaction // Transaction stripped of its sidecar
+	size  uint64             // Size of the blob data held on disk
+	local bool               // Whether the transaction is exempt from eviction
+}
+
+// BlobPool is the transaction pool dedicated to EIP-4844 blob transactions.
+//
+// Blob transactions are special snowflakes: their sidecars are large, and they
+// are useless to the network until included. The pool thus keeps only a small
+// metadata about them in memory, and persists the sidecars on disk, where they
+// survive restarts.
+//
+// To keep the pool simple and its contents cheap to validate, the transactions
+// of an account must form a gapless nonce sequence starting at the account's
+// current nonce, and an account may only hold a limited number of blobs. When
+// the pool is full, the worst priced transactions are evicted from the end of
+// their account's sequence, leaving the sequence gapless.
+type BlobPool struct {
+	config      Config
+	chainconfig *params.ChainConfig
+	chain       blockChain
+	signer      types.Signer
+	store       ethdb.KeyValueStore // Persistent store of transactions and sidecars
+	gasTip      atomic.Pointer[big.Int]
+	txFeed      event.Feed
+	eventFeed   event.Feed
+	scope       event.SubscriptionScope
+	mu          sync.RWMutex
+
+	events []txpool.TxPoolEvent // Transaction events pending to be sent once the lock is released
+
+	head    *types.Header  // Current head of the blockchain
+	state   *state.StateDB // Current state in the blockchain head
+	baseFee *big.Int       // Base fee of the next block, nil before London
+	blobFee *big.Int       // Blob fee of the next block, nil before Cancun
+
+	index    map[common.Address][]*blobTxMeta // Transactions of each account, sorted by nonce
+	lookup   map[common.Hash]*blobTxMeta      // All transactions to allow lookups
+	datasize uint64                           // Blob data held by the pool, in bytes
+
+	chainHeadCh  chan core.ChainHeadEvent
+	chainHeadSub event.Subscription
+	wg           sync.WaitGroup
+}
+
+// New creates a new blob transaction pool, loading any previously persisted
+// transactions from its data directory.
+func New(config Config, chainconfig *params.ChainConfig, chain blockChain) (*BlobPool, error) {
+	// Sanitize the input to ensure no vulnerable gas prices are set
+	config = (&config).sanitize()
+
+	// Open the persistent store, falling back to memory if no directory is set
+	var store ethdb.KeyValueStore = rawdb.NewMemoryDatabase()
+	if config.Datadir != "" {
+		db, err := rawdb.NewLevelDBDatabase(config.Datadir, 16, 16, "eth/blobpool/", false)
+		if err != nil {
+			return nil, err
+		}
+		store = db
+	}
+	pool := &BlobPool{
+		config:      config,
+		chainconfig: chainconfig,
+		chain:       chain,
+		signer:      types.LatestSigner(chainconfig),
+		store:       store,
+		index:       make(map[common.Address][]*blobTxMeta),
+		lookup:      make(map[common.Hash]*blobTxMeta),
+		chainHeadCh: make(chan core.ChainHeadEvent, chainHeadChanSize),
+	}
+	pool.gasTip.Store(new(big.Int))
+	if err := pool.reset(chain.CurrentBlock()); err != nil {
+		store.Close()
+		return nil, err
+	}
+	pool.load()
+	pool.takeEvents() // Nobody is subscribed to the drops while loading
+
+	// Subscribe events from blockchain and start the main event loop.
+	pool.chainHeadSub = pool.chain.SubscribeChainHeadEvent(pool.chainHeadCh)
+	pool.wg.Add(1)
+	go pool.loop()
+
+	return pool, nil
+}
+
+// load reads the transactions persisted by a previous run into the pool, and
+// drops the ones that became invalid since, or were corrupted on disk.
+func (pool *BlobPool) load() {
+	var drop [][]byte
+
+	it := pool.store.NewIterator(nil, nil)
+	for it.Next() {
+		entry := new(blobTxEntry)
+		if err := rlp.DecodeBytes(it.Value(), entry); err != nil || entry.Sidecar == nil {
+			log.Error("Dropping corrupted blob transaction", "id", common.Bytes2Hex(it.Key()), "err", err)
+			drop = append(drop, common.CopyBytes(it.Key()))
+			continue
+		}
+		from, err := types.Sender(pool.signer, entry.Tx)
+		if err != nil || entry.Tx.Hash() != common.BytesToHash(it.Key()) {
+			log.Error("Dropping invalid blob transaction", "id", common.Bytes2Hex(it.Key()), "err", err)
+			drop = append(drop, common.CopyBytes(it.Key()))
+			continue
+		}
+		meta := &blobTxMeta{
+			tx:    entry.Tx,
+			size:  uint64(len(entry.Sidecar.Blobs)) * blobSize,
+			local: entry.Local,
+		}
+		pool.index[from] = append(pool.index[from], meta)
+		pool.lookup[entry.Tx.Hash()] = meta
+		pool.datasize += meta.size
+	}
+	it.Release()
+
+	for _, key := range drop {
+		pool.store.Delete(key)
+	}
+	dropMeter.Mark(int64(len(drop)))
+
+	// Sort the loaded transactions and drop everything that became unexecutable
+	for addr, txs := range pool.index {
+		sort.Slice(txs, func(i, j int) bool { return txs[i].tx.Nonce() < txs[j].tx.Nonce() })
+		pool.recheck(addr)
+	}
+	pool.updateGauges()
+	log.Info("Blob transaction pool loaded", "transactions", len(pool.lookup), "datasize", common.StorageSize(pool.datasize))
+}
+
+// loop is the blob pool's main event loop, waiting for and reacting to new
+// chain heads.
+func (pool *BlobPool) loop() {
+	defer pool.wg.Done()
+
+	for {
+		select {
+		// Handle ChainHeadEvent
+		case ev := <-pool.chainHeadCh:
+			if ev.Block != nil {
+				pool.mu.Lock()
+				if err := pool.reset(ev.Block.Header()); err != nil {
+					log.Error("Failed to reset blob pool state", "err", err)
+				}
+				events := pool.takeEvents()
+				pool.mu.Unlock()
+
+				pool.sendEvents(events)
+			}
+
+		// System shutdown.
+		case <-pool.chainHeadSub.Err():
+			return
+		}
+	}
+}
+
+// reset moves the pool onto a new chain head, dropping all the transactions
+// which got included or became unexecutable. Transactions of blocks reorged
+// out are not reinjected, since their sidecars are not part of the blocks.
+func (pool *BlobPool) reset(head *types.Header) error {
+	statedb, err := pool.chain.StateAt(head.Root)
+	if err != nil {
+		return err
+	}
+	pool.head, pool.state = head, statedb
+
+	next := new(big.Int).Add(head.Number, big.NewInt(1))
+	pool.baseFee, pool.blobFee = nil, nil
+	if pool.chainconfig.IsLondon(next) {
+		pool.baseFee = misc.CalcBaseFee(pool.chainconfig, head)
+	}
+	if head.ExcessDataGas != nil {
+		var used uint64
+		if head.DataGasUsed != nil {
+			used = *head.DataGasUsed
+		}
+		pool.blobFee = misc.CalcBlobFee(misc.CalcExcessDataGas(*head.ExcessDataGas, used))
+	}
+	for addr := range pool.index {
+		pool.recheck(addr)
+	}
+	pool.updateGauges()
+	return nil
+}
+
+// recheck drops the transactions of an account that cannot be executed anymore
+// on top of the current state: the ones already included, the ones following a
+// nonce gap or duplicating a nonce, and the ones the account cannot afford.
+func (pool *BlobPool) recheck(addr common.Address) {
+	var (
+		first   = pool.state.GetNonce(addr)
+		next    = first
+		balance = pool.state.GetBalance(addr)
+		spent   = new(big.Int)
+		keep    []*blobTxMeta
+	)
+	for i, meta := range pool.index[addr] {
+		nonce := meta.tx.Nonce()
+		if nonce < next {
+			// Nonce already used, either included in the chain or a duplicate
+			if nonce < first {
+				includedMeter.Mark(1)
+			} else {
+				dropMeter.Mark(1)
+			}
+			pool.drop(meta, txpool.DropStale)
+			continue
+		}
+		if nonce > next || spent.Add(spent, meta.tx.Cost()).Cmp(balance) > 0 {
+			// Nonce gap or overdraft, nothing from here on is executable
+			reason := txpool.DropUnpayable
+			if nonce > next {
+				reason = txpool.DropNonceGap
+			}
+			for _, meta := range pool.index[addr][i:] {
+				pool.drop(meta, reason)
+				dropMeter.Mark(1)
+
+				// Transactions following an overdraft are dropped for the gap
+				reason = txpool.DropNonceGap
+			}
+			break
+		}
+		keep = append(keep, meta)
+		next++
+	}
+	if len(keep) == 0 {
+		delete(pool.index, addr)
+	} else {
+		pool.index[addr] = keep
+	}
+}
+
+// drop removes a transaction from the lookup and the persistent store, recording
+// a drop event with the given reason unless it's empty, e.g. for replacements
+// reported separately. It's up to the caller to remove it from the account index.
+func (pool *BlobPool) drop(meta *blobTxMeta, reason txpool.DropReason) {
+	hash := meta.tx.Hash()
+	if err := pool.store.Delete(hash[:]); err != nil {
+		log.Error("Failed to delete blob transaction", "hash", hash, "err", err)
+	}
+	delete(pool.lookup, hash)
+	pool.datasize -= meta.size
+
+	if reason != "" {
+		pool.recordEvent(txpool.TxPoolEvent{Kind: txpool.TxDropped, Tx: meta.tx, Reason: reason})
+	}
+}
+
+// recordEvent queues a transaction event to be sent to subscribers once the pool
+// lock is released.
+//
+// The caller must hold the pool lock.
+func (pool *BlobPool) recordEvent(ev txpool.TxPoolEvent) {
+	ev.From, _ = types.Sender(pool.signer, ev.Tx) // already validated during insertion
+	pool.events = append(pool.events, ev)
+}
+
+// takeEvents retrieves and clears the queued transaction events.
+//
+// The caller must hold the pool lock.
+func (pool *BlobPool) takeEvents() []txpool.TxPoolEvent {
+	events := pool.events
+	pool.events = nil
+	return events
+}
+
+// sendEvents posts the given transaction events to the subscribers. It must not
+// be called with the pool lock held, since subscribers might call back into it.
+func (pool *BlobPool) sendEvents(events []txpool.TxPoolEvent) {
+	for _, ev := range events {
+		pool.eventFeed.Send(ev)
+	}
+}
+
+// updateGauges refreshes the metrics tracking the pool contents.
+func (pool *BlobPool) updateGauges() {
+	datasizeGauge.Update(int64(pool.datasize))
+	txsGauge.Update(int64(len(pool.lookup)))
+}
+
+// Stop terminates the blob transaction pool.
+func (pool *BlobPool) Stop() {
+	// Unsubscribe all subscriptions registered from the pool
+	pool.scope.Close()
+
+	// Unsubscribe subscriptions registered from blockchain
+	pool.chainHeadSub.Unsubscribe()
+	pool.wg.Wait()
+
+	if err := pool.store.Close(); err != nil {
+		log.Error("Failed to close blob pool store", "err", err)
+	}
+	log.Info("Blob transaction pool stopped")
+}
+
+// SubscribeNewTxsEvent registers a subscription of NewTxsEvent and
+// starts sending event to the given channel.
+func (pool *BlobPool) SubscribeNewTxsEvent(ch chan<- core.NewTxsEvent) event.Subscription {
+	return pool.scope.Track(pool.txFeed.Subscribe(ch))
+}
+
+// SubscribeTxPoolEvent registers a subscription of TxPoolEvent and starts
+// sending the state changes of the pooled transactions to the given channel.
+// Blob transactions are never queued, so they are only ever added, replaced
+// or dropped.
+func (pool *BlobPool) SubscribeTxPoolEvent(ch chan<- txpool.TxPoolEvent) event.Subscription {
+	return pool.scope.Track(pool.eventFeed.Subscribe(ch))
+}
+
+// SetGasTip updates the minimum gas tip required by the blob pool for a new
+// transaction. Pooled transactions below the threshold are kept, but are not
+// returned as pending to the miner unless local.
+func (pool *BlobPool) SetGasTip(tip *big.Int) {
+	pool.gasTip.Store(new(big.Int).Set(tip))
+}
+
+// Nonce returns the next nonce of an account, with all transactions pooled for
+// it already applied on top.
+func (pool *BlobPool) Nonce(addr common.Address) uint64 {
+	pool.mu.RLock()
+	defer pool.mu.RUnlock()
+
+	return pool.state.GetNonce(addr) + uint64(len(pool.index[addr]))
+}
+
+// HasAccount returns whether the pool holds any transaction from an account.
+func (pool *BlobPool) HasAccount(addr common.Address) bool {
+	pool.mu.RLock()
+	defer pool.mu.RUnlock()
+
+	return len(pool.index[addr]) > 0
+}
+
+// Stats retrieves the current pool stats, namely the number of pending and the
+// number of queued (non-executable) transactions. The blob pool does not keep
+// non-executable transactions.
+func (pool *BlobPool) Stats() (int, int) {
+	pool.mu.RLock()
+	defer pool.mu.RUnlock()
+
+	return len(pool.lookup), 0
+}
+
+// Content retrieves the data content of the blob pool, returning all the
+// pooled transactions, grouped by account and sorted by nonce.
+func (pool *BlobPool) Content() map[common.Address]types.Transactions {
+	pool.mu.RLock()
+	defer pool.mu.RUnlock()
+
+	content := make(map[common.Address]types.Transactions, len(pool.index))
+	for addr, txs := range pool.index {
+		content[addr] = flatten(txs)
+	}
+	return content
+}
+
+// ContentFrom retrieves the data content of the blob pool, returning all the
+// transactions pooled for an account, sorted by nonce.
+func (pool *BlobPool) ContentFrom(addr common.Address) types.Transactions {
+	pool.mu.RLock()
+	defer pool.mu.RUnlock()
+
+	return flatten(pool.index[addr])
+}
+
+// Pending retrieves all currently processable transactions, grouped by origin
+// account and sorted by nonce. Transactions not paying the blob fee of the next
+// block are not processable. The returned transactions are stripped of their
+// sidecars, which can be retrieved via Sidecar.
+//
+// The enforceTips parameter can be used to do an extra filtering on the pending
+// transactions and only return those whose **effective** tip is large enough in
+// the next pending execution environment.
+func (pool *BlobPool) Pending(enforceTips bool) map[common.Address]types.Transactions {
+	pool.mu.RLock()
+	defer pool.mu.RUnlock()
+
+	pending := make(map[common.Address]types.Transactions, len(pool.index))
+	for addr, txs := range pool.index {
+		var list types.Transactions
+		for _, meta := range txs {
+			if pool.blobFee != nil && meta.tx.BlobGasFeeCap().Cmp(pool.blobFee) < 0 {
+				break
+			}
+			if enforceTips && !meta.local && meta.tx.EffectiveGasTipIntCmp(pool.gasTip.Load(), pool.baseFee) < 0 {
+				break
+			}
+			list = append(list, meta.tx)
+		}
+		if len(list) > 0 {
+			pending[addr] = list
+		}
+	}
+	return pending
+}
+
+// Get returns a transaction, stripped of its sidecar, if it is contained in the
+// pool and nil otherwise.
+func (pool *BlobPool) Get(hash common.Hash) *types.Transaction {
+	pool.mu.RLock()
+	defer pool.mu.RUnlock()
+
+	if meta := pool.lookup[hash]; meta != nil {
+		return meta.tx
+	}
+	return nil
+}
+
+// Has returns an indicator whether the pool has a transaction cached with the
+// given hash.
+func (pool *BlobPool) Has(hash common.Hash) bool {
+	pool.mu.RLock()
+	defer pool.mu.RUnlock()
+
+	return pool.lookup[hash] != nil
+}
+
+// Sidecar retrieves the blobs, commitments and proofs of a pooled transaction
+// from disk, or nil if the transaction is not pooled.
+func (pool *BlobPool) Sidecar(hash common.Hash) *types.BlobTxSidecar {
+	pool.mu.RLock()
+	defer pool.mu.RUnlock()
+
+	if pool.lookup[hash] == nil {
+		return nil
+	}
+	blob, err := pool.store.Get(hash[:])
+	if err != nil {
+		log.Error("Blob transaction missing from store", "hash", hash, "err", err)
+		return nil
+	}
+	entry := new(blobTxEntry)
+	if err := rlp.DecodeBytes(blob, entry); err != nil {
+		log.Error("Blob transaction corrupted in store", "hash", hash, "err", err)
+		return nil
+	}
+	return entry.Sidecar
+}
+
+// Add inserts a set of blob transactions into the pool along with their
+// sidecars, if they are valid and there's room for them. Local transactions
+// bypass the minimum tip requirement and are never evicted.
+func (pool *BlobPool) Add(txs []*types.Transaction, sidecars []*types.BlobTxSidecar, local bool) []error {
+	var (
+		errs  = make([]error, len(txs))
+		added = make([]*types.Transaction, 0, len(txs))
+	)
+	pool.mu.Lock()
+	for i, tx := range txs {
+		var sidecar *types.BlobTxSidecar
+		if i < len(sidecars) {
+			sidecar = sidecars[i]
+		}
+		if errs[i] = pool.add(tx, sidecar, local); errs[i] == nil {
+			added = append(added, tx)
+		}
+	}
+	pool.updateGauges()
+	events := pool.takeEvents()
+	pool.mu.Unlock()
+
+	pool.sendEvents(events)
+	if len(added) > 0 {
+		pool.txFeed.Send(core.NewTxsEvent{Txs: added})
+	}
+	return errs
+}
+
+// add validates a single blob transaction and inserts it into the pool, either
+// appending it to its account's sequence or replacing an already pooled nonce.
+// If the pool is full, the worst priced transactions are evicted to make room.
+//
+// The caller must hold the pool lock.
+func (pool *BlobPool) add(tx *types.Transaction, sidecar *types.BlobTxSidecar, local bool) error {
+	// If the transaction is already known, discard it
+	hash := tx.Hash()
+	if pool.lookup[hash] != nil {
+		log.Trace("Discarding already known blob transaction", "hash", hash)
+		return txpool.ErrAlreadyKnown
+	}
+	if sidecar == nil {
+		return ErrSidecarMissing
+	}
+	if err := pool.validateTx(tx, sidecar, local); err != nil {
+		log.Trace("Discarding invalid blob transaction", "hash", hash, "err", err)
+		return err
+	}
+	// already validated by this point
+	from, _ := types.Sender(pool.signer, tx)
+
+	var (
+		txs   = pool.index[from]
+		first = pool.state.GetNonce(from)
+		prev  *blobTxMeta
+		meta  = &blobTxMeta{
+			tx:    tx,
+			size:  uint64(len(sidecar.Blobs)) * blobSize,
+			local: local,
+		}
+	)
+	// Ensure replacements bump all the fees of the pooled transaction
+	if offset := tx.Nonce() - first; offset < uint64(len(txs)) {
+		prev = txs[offset]
+		if !pool.bumped(prev.tx, tx) {
+			return txpool.ErrReplaceUnderpriced
+		}
+	}
+	// Ensure the account does not hoard more blobs than permitted
+	blobs := len(tx.BlobHashes())
+	for _, pooled := range txs {
+		if pooled != prev {
+			blobs += len(pooled.tx.BlobHashes())
+		}
+	}
+	if uint64(blobs) > pool.config.AccountBlobs {
+		return ErrAccountLimitExceeded
+	}
+	// If the pool is full, find the transactions to evict before changing anything
+	var (
+		victims []*blobTxMeta
+		size    = pool.datasize + meta.size
+	)
+	if prev != nil {
+		size -= prev.size
+	}
+	if size > pool.config.Datacap {
+		price := tx.BlobGasFeeCap()
+		for _, pooled := range txs[:tx.Nonce()-first] {
+			if pooled.tx.BlobGasFeeCap().Cmp(price) < 0 {
+				price = pooled.tx.BlobGasFeeCap()
+			}
+		}
+		var ok bool
+		if victims, ok = pool.evictionVictims(from, price, size-pool.config.Datacap, local); !ok {
+			return txpool.ErrUnderpriced
+		}
+	}
+	// Persist the transaction, then make room for it and track it
+	blob, err := rlp.EncodeToBytes(&blobTxEntry{Tx: tx, Sidecar: sidecar, Local: local})
+	if err != nil {
+		return err
+	}
+	if err := pool.store.Put(hash[:], blob); err != nil {
+		return err
+	}
+	for _, victim := range victims {
+		addr, _ := types.Sender(pool.signer, victim.tx)
+		log.Trace("Evicting underpriced blob transaction", "hash", victim.tx.Hash())
+
+		pool.drop(victim, txpool.DropUnderpriced)
+		if txs := pool.index[addr]; len(txs) == 1 {
+			delete(pool.index, addr)
+		} else {
+			pool.index[addr] = txs[:len(txs)-1]
+		}
+		evictMeter.Mark(1)
+	}
+	if prev != nil {
+		pool.drop(prev, "")
+		pool.index[from][tx.Nonce()-first] = meta
+		replaceMeter.Mark(1)
+
+		pool.recordEvent(txpool.TxPoolEvent{Kind: txpool.TxReplaced, Tx: prev.tx, Replacement: tx})
+	} else {
+		pool.index[from] = append(txs, meta)
+	}
+	pool.recordEvent(txpool.TxPoolEvent{Kind: txpool.TxAdded, Tx: tx})
+	pool.lookup[hash] = meta
+	pool.datasize += meta.size
+
+	log.Trace("Pooled new blob transaction", "hash", hash, "from", from, "nonce", tx.Nonce(), "blobs", len(sidecar.Blobs))
+	return nil
+}
+
+// validateTx checks whether a blob transaction is valid according to the
+// consensus rules and the current state, including the KZG proofs of its blobs.
+func (pool *BlobPool) validateTx(tx *types.Transaction, sidecar *types.BlobTxSidecar, local bool) error {
+	opts := &txpool.ValidationOptions{
+		Config:  pool.chainconfig,
+		Accept:  1 << types.BlobTxType,
+		MaxSize: txMaxSize,
+		MinTip:  pool.gasTip.Load(),
+	}
+	if local {
+		opts.MinTip = new(big.Int)
+	}
+	if err := txpool.ValidateTransaction(tx, sidecar.Blobs, sidecar.Commitments, sidecar.Proofs, pool.head, pool.signer, opts); err != nil {
+		return err
+	}
+	stateOpts := &txpool.ValidationOptionsWithState{
+		State: pool.state,
+
+		FirstNonceGap: func(addr common.Address) uint64 {
+			return pool.state.GetNonce(addr) + uint64(len(pool.index[addr]))
+		},
+		ExistingExpenditure: func(addr common.Address) *big.Int {
+			spent := new(big.Int)
+			for _, meta := range pool.index[addr] {
+				spent.Add(spent, meta.tx.Cost())
+			}
+			return spent
+		},
+		ExistingCost: func(addr common.Address, nonce uint64) *big.Int {
+			if offset := nonce - pool.state.GetNonce(addr); offset < uint64(len(pool.index[addr])) {
+				return pool.index[addr][offset].tx.Cost()
+			}
+			return nil
+		},
+	}
+	return txpool.ValidateTransactionWithState(tx, pool.signer, stateOpts)
+}
+
+// bumped returns whether a replacement transaction bumps every fee cap of the
+// pooled one by at least the configured percentage.
+func (pool *BlobPool) bumped(prev, tx *types.Transaction) bool {
+	bump := func(old, cur *big.Int) bool {
+		threshold := new(big.Int).Mul(old, big.NewInt(int64(100+pool.config.PriceBump)))
+		threshold.Div(threshold, big.NewInt(100))
+		return cur.Cmp(threshold) >= 0
+	}
+	return bump(prev.GasTipCap(), tx.GasTipCap()) &&
+		bump(prev.GasFeeCap(), tx.GasFeeCap()) &&
+		bump(prev.BlobGasFeeCap(), tx.BlobGasFeeCap())
+}
+
+// evictionVictims selects the transactions to evict to free up the requested
+// amount of blob data. Transactions are only evicted from the end of their
+// account's sequence, ranked by the lowest blob fee cap of the sequence up to
+// them, since they cannot be included before their predecessors. Only worse
+// priced transactions than the one being added are evicted, unless it's local.
+func (pool *BlobPool) evictionVictims(from common.Address, price *big.Int, need uint64, local bool) ([]*blobTxMeta, bool) {
+	var (
+		evicted = make(map[common.Address]int)
+		victims []*blobTxMeta
+	)
+	for need > 0 {
+		var (
+			victim *blobTxMeta
+			worst  *big.Int
+		)
+		for addr, txs := range pool.index {
+			if addr == from {
+				continue
+			}
+			txs = txs[:len(txs)-evicted[addr]]
+			if len(txs) == 0 || txs[len(txs)-1].local {
+				continue
+			}
+			cheapest := txs[0].tx.BlobGasFeeCap()
+			for _, meta := range txs[1:] {
+				if meta.tx.BlobGasFeeCap().Cmp(cheapest) < 0 {
+					cheapest = meta.tx.BlobGasFeeCap()
+				}
+			}
+			if worst == nil || cheapest.Cmp(worst) < 0 {
+				victim, worst = txs[len(txs)-1], cheapest
+			}
+		}
+		if victim == nil || (!local && worst.Cmp(price) >= 0) {
+			return nil, false
+		}
+		addr, _ := types.Sender(pool.signer, victim.tx)
+		evicted[addr]++
+
+		victims = append(victims, victim)
+		if victim.size >= need {
+			break
+		}
+		need -= victim.size
+	}
+	return victims, true
+}
+
+// flatten returns the transactions of an account sequence.
+func flatten(txs []*blobTxMeta) types.Transactions {
+	list := make(types.Transactions, len(txs))
+	for i, meta := range txs {
+		list[i] = meta.tx
+	}
+	return list
+}
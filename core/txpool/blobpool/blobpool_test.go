@@ -0,0 +1,417 @@
+// Copyright 2023 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package blobpool
+
+import (
+	"crypto/ecdsa"
+	"errors"
+	"math/big"
+	"reflect"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core"
+	"github.com/ethereum/go-ethereum/core/rawdb"
+	"github.com/ethereum/go-ethereum/core/state"
+	"github.com/ethereum/go-ethereum/core/txpool"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/crypto"
+	"github.com/ethereum/go-ethereum/crypto/kzg4844"
+	"github.com/ethereum/go-ethereum/event"
+	"github.com/ethereum/go-ethereum/params"
+	"github.com/holiman/uint256"
+)
+
+var testChainConfig = func() *params.ChainConfig {
+	config := *params.AllEthashProtocolChanges
+	config.ShanghaiTime = new(uint64)
+	config.CancunTime = new(uint64)
+	return &config
+}()
+
+var (
+	testBlobOnce   sync.Once
+	testBlob       kzg4844.Blob
+	testBlobCommit kzg4844.Commitment
+	testBlobProof  kzg4844.Proof
+)
+
+// testBlockChain is a mock of the live chain for testing the pool.
+type testBlockChain struct {
+	statedb       *state.StateDB
+	chainHeadFeed event.Feed
+}
+
+func newTestBlockChain() *testBlockChain {
+	statedb, _ := state.New(types.EmptyRootHash, state.NewDatabase(rawdb.NewMemoryDatabase()), nil)
+	return &testBlockChain{statedb: statedb}
+}
+
+func (bc *testBlockChain) CurrentBlock() *types.Header {
+	excess, used := uint64(0), uint64(0)
+	return &types.Header{
+		Number:        big.NewInt(1),
+		GasLimit:      30_000_000,
+		BaseFee:       big.NewInt(params.InitialBaseFee),
+		ExcessDataGas: &excess,
+		DataGasUsed:   &used,
+	}
+}
+
+func (bc *testBlockChain) GetBlock(hash common.Hash, number uint64) *types.Block {
+	return types.NewBlock(bc.CurrentBlock(), nil, nil, nil, nil)
+}
+
+func (bc *testBlockChain) StateAt(common.Hash) (*state.StateDB, error) {
+	return bc.statedb, nil
+}
+
+func (bc *testBlockChain) SubscribeChainHeadEvent(ch chan<- core.ChainHeadEvent) event.Subscription {
+	return bc.chainHeadFeed.Subscribe(ch)
+}
+
+// makeTx creates a signed blob transaction with the given number of blobs, along
+// with its sidecar.
+func makeTx(nonce uint64, tip, fee, blobFee uint64, blobs int, key *ecdsa.PrivateKey) (*types.Transaction, *types.BlobTxSidecar) {
+	testBlobOnce.Do(func() {
+		for i := 0; i < len(testBlob); i += 32 {
+			testBlob[i+16] = byte(i / 32)
+		}
+		testBlobCommit, _ = kzg4844.BlobToCommitment(testBlob)
+		testBlobProof, _ = kzg4844.ComputeBlobProof(testBlob, testBlobCommit)
+	})
+	sidecar := new(types.BlobTxSidecar)
+	for i := 0; i < blobs; i++ {
+		sidecar.Blobs = append(sidecar.Blobs, testBlob)
+		sidecar.Commitments = append(sidecar.Commitments, testBlobCommit)
+		sidecar.Proofs = append(sidecar.Proofs, testBlobProof)
+	}
+	tx := types.MustSignNewTx(key, types.LatestSigner(testChainConfig), &types.BlobTx{
+		ChainID:    uint256.MustFromBig(testChainConfig.ChainID),
+		Nonce:      nonce,
+		GasTipCap:  uint256.NewInt(tip),
+		GasFeeCap:  uint256.NewInt(fee),
+		Gas:        params.TxGas,
+		Value:      uint256.NewInt(100),
+		BlobFeeCap: uint256.NewInt(blobFee),
+		BlobHashes: sidecar.BlobHashes(),
+	})
+	return tx, sidecar
+}
+
+// newFundedKey creates a new account and funds it in the test chain's state.
+func newFundedKey(chain *testBlockChain) *ecdsa.PrivateKey {
+	key, _ := crypto.GenerateKey()
+	chain.statedb.AddBalance(crypto.PubkeyToAddress(key.PublicKey), big.NewInt(params.Ether))
+	return key
+}
+
+// Tests that blob transactions are accepted along with their sidecars, exposed
+// as pending and announced via the event feed.
+func TestAdd(t *testing.T) {
+	chain := newTestBlockChain()
+	pool, err := New(Config{Datadir: ""}, testChainConfig, chain)
+	if err != nil {
+		t.Fatalf("failed to create blob pool: %v", err)
+	}
+	defer pool.Stop()
+
+	events := make(chan core.NewTxsEvent, 1)
+	sub := pool.SubscribeNewTxsEvent(events)
+	defer sub.Unsubscribe()
+
+	key := newFundedKey(chain)
+	addr := crypto.PubkeyToAddress(key.PublicKey)
+
+	tx, sidecar := makeTx(0, 1, 2*params.InitialBaseFee, 1, 2, key)
+	if errs := pool.Add([]*types.Transaction{tx}, nil, false); !errors.Is(errs[0], ErrSidecarMissing) {
+		t.Fatalf("sidecarless transaction error mismatch: have %v, want %v", errs[0], ErrSidecarMissing)
+	}
+	if errs := pool.Add([]*types.Transaction{tx}, []*types.BlobTxSidecar{sidecar}, false); errs[0] != nil {
+		t.Fatalf("failed to add blob transaction: %v", errs[0])
+	}
+	if errs := pool.Add([]*types.Transaction{tx}, []*types.BlobTxSidecar{sidecar}, false); !errors.Is(errs[0], txpool.ErrAlreadyKnown) {
+		t.Fatalf("duplicate transaction error mismatch: have %v, want %v", errs[0], txpool.ErrAlreadyKnown)
+	}
+	gapped, gappedSidecar := makeTx(2, 1, 1000, 1, 1, key)
+	if errs := pool.Add([]*types.Transaction{gapped}, []*types.BlobTxSidecar{gappedSidecar}, false); !errors.Is(errs[0], core.ErrNonceTooHigh) {
+		t.Fatalf("gapped transaction error mismatch: have %v, want %v", errs[0], core.ErrNonceTooHigh)
+	}
+	select {
+	case ev := <-events:
+		if len(ev.Txs) != 1 || ev.Txs[0].Hash() != tx.Hash() {
+			t.Fatalf("event mismatch: have %v, want %v", ev.Txs, tx.Hash())
+		}
+	case <-time.After(time.Second):
+		t.Fatal("new transaction event not fired")
+	}
+	if !pool.Has(tx.Hash()) || pool.Get(tx.Hash()) == nil {
+		t.Fatal("pooled transaction not found")
+	}
+	if have := pool.Sidecar(tx.Hash()); !reflect.DeepEqual(have, sidecar) {
+		t.Fatal("pooled sidecar mismatch")
+	}
+	if pending := pool.Pending(true); len(pending[addr]) != 1 {
+		t.Fatalf("pending transactions mismatch: have %d, want 1", len(pending[addr]))
+	}
+	if nonce := pool.Nonce(addr); nonce != 1 {
+		t.Fatalf("pool nonce mismatch: have %d, want 1", nonce)
+	}
+}
+
+// Tests that pooled blob transactions can only be replaced by bumping all the
+// fee caps, and that the number of blobs per account is capped.
+func TestReplaceAndAccountLimit(t *testing.T) {
+	chain := newTestBlockChain()
+	pool, err := New(Config{AccountBlobs: 3, PriceBump: 100}, testChainConfig, chain)
+	if err != nil {
+		t.Fatalf("failed to create blob pool: %v", err)
+	}
+	defer pool.Stop()
+
+	key := newFundedKey(chain)
+	add := func(tx *types.Transaction, sidecar *types.BlobTxSidecar) error {
+		return pool.Add([]*types.Transaction{tx}, []*types.BlobTxSidecar{sidecar}, false)[0]
+	}
+	orig, origSidecar := makeTx(0, 1, 1000, 10, 1, key)
+	if err := add(orig, origSidecar); err != nil {
+		t.Fatalf("failed to add blob transaction: %v", err)
+	}
+	// Bumping only the execution fees is not enough
+	tx, sidecar := makeTx(0, 2, 2000, 10, 1, key)
+	if err := add(tx, sidecar); !errors.Is(err, txpool.ErrReplaceUnderpriced) {
+		t.Fatalf("replacement error mismatch: have %v, want %v", err, txpool.ErrReplaceUnderpriced)
+	}
+	tx, sidecar = makeTx(0, 2, 2000, 20, 2, key)
+	if err := add(tx, sidecar); err != nil {
+		t.Fatalf("failed to replace blob transaction: %v", err)
+	}
+	if pool.Has(orig.Hash()) || !pool.Has(tx.Hash()) {
+		t.Fatal("transaction not replaced")
+	}
+	// The account holds 2 blobs, the limit is 3
+	tx, sidecar = makeTx(1, 1, 1000, 10, 2, key)
+	if err := add(tx, sidecar); !errors.Is(err, ErrAccountLimitExceeded) {
+		t.Fatalf("account limit error mismatch: have %v, want %v", err, ErrAccountLimitExceeded)
+	}
+	tx, sidecar = makeTx(1, 1, 1000, 10, 1, key)
+	if err := add(tx, sidecar); err != nil {
+		t.Fatalf("failed to add blob transaction: %v", err)
+	}
+}
+
+// Tests that when the pool is full, the transactions with the worst blob fee
+// caps are evicted in favour of better ones.
+func TestEviction(t *testing.T) {
+	chain := newTestBlockChain()
+	pool, err := New(Config{Datacap: 2 * blobSize}, testChainConfig, chain)
+	if err != nil {
+		t.Fatalf("failed to create blob pool: %v", err)
+	}
+	defer pool.Stop()
+
+	var (
+		cheap, cheapSidecar = makeTx(0, 1, 1000, 10, 1, newFundedKey(chain))
+		dear, dearSidecar   = makeTx(0, 1, 1000, 30, 1, newFundedKey(chain))
+		low, lowSidecar     = makeTx(0, 1, 1000, 5, 1, newFundedKey(chain))
+		high, highSidecar   = makeTx(0, 1, 1000, 20, 1, newFundedKey(chain))
+	)
+	errs := pool.Add([]*types.Transaction{cheap, dear}, []*types.BlobTxSidecar{cheapSidecar, dearSidecar}, false)
+	if errs[0] != nil || errs[1] != nil {
+		t.Fatalf("failed to add blob transactions: %v", errs)
+	}
+	if err := pool.Add([]*types.Transaction{low}, []*types.BlobTxSidecar{lowSidecar}, false)[0]; !errors.Is(err, txpool.ErrUnderpriced) {
+		t.Fatalf("underpriced error mismatch: have %v, want %v", err, txpool.ErrUnderpriced)
+	}
+	if err := pool.Add([]*types.Transaction{high}, []*types.BlobTxSidecar{highSidecar}, false)[0]; err != nil {
+		t.Fatalf("failed to add blob transaction: %v", err)
+	}
+	if pool.Has(cheap.Hash()) || !pool.Has(dear.Hash()) || !pool.Has(high.Hash()) {
+		t.Fatal("cheapest transaction not evicted")
+	}
+	if pool.datasize != 2*blobSize {
+		t.Fatalf("datasize mismatch: have %d, want %d", pool.datasize, 2*blobSize)
+	}
+}
+
+// Tests that the additions, replacements and drops of blob transactions are
+// reported as transaction pool events, along with the reasons of the drops.
+func TestEvents(t *testing.T) {
+	chain := newTestBlockChain()
+	pool, err := New(Config{Datacap: 2 * blobSize, PriceBump: 100}, testChainConfig, chain)
+	if err != nil {
+		t.Fatalf("failed to create blob pool: %v", err)
+	}
+	defer pool.Stop()
+
+	events := make(chan txpool.TxPoolEvent, 16)
+	sub := pool.SubscribeTxPoolEvent(events)
+	defer sub.Unsubscribe()
+
+	var (
+		key, included = newFundedKey(chain), newFundedKey(chain)
+
+		orig, origSidecar = makeTx(0, 1, 1000, 10, 1, key)
+		bump, bumpSidecar = makeTx(0, 2, 2000, 20, 1, key)
+		dear, dearSidecar = makeTx(0, 1, 1000, 30, 1, included)
+		best, bestSidecar = makeTx(0, 1, 1000, 40, 1, newFundedKey(chain))
+	)
+	// Add a transaction, replace it, then evict the replacement by filling the pool
+	for i, tx := range []*types.Transaction{orig, bump, dear, best} {
+		sidecar := []*types.BlobTxSidecar{origSidecar, bumpSidecar, dearSidecar, bestSidecar}[i]
+		if err := pool.Add([]*types.Transaction{tx}, []*types.BlobTxSidecar{sidecar}, false)[0]; err != nil {
+			t.Fatalf("failed to add blob transaction %d: %v", i, err)
+		}
+	}
+	// Include a transaction and move the pool onto the new head
+	chain.statedb.SetNonce(crypto.PubkeyToAddress(included.PublicKey), 1)
+	chain.chainHeadFeed.Send(core.ChainHeadEvent{Block: types.NewBlock(chain.CurrentBlock(), nil, nil, nil, nil)})
+
+	want := []txpool.TxPoolEvent{
+		{Kind: txpool.TxAdded, Tx: orig},
+		{Kind: txpool.TxReplaced, Tx: orig, Replacement: bump},
+		{Kind: txpool.TxAdded, Tx: bump},
+		{Kind: txpool.TxAdded, Tx: dear},
+		{Kind: txpool.TxDropped, Tx: bump, Reason: txpool.DropUnderpriced},
+		{Kind: txpool.TxAdded, Tx: best},
+		{Kind: txpool.TxDropped, Tx: dear, Reason: txpool.DropStale},
+	}
+	for i, want := range want {
+		select {
+		case have := <-events:
+			if have.Kind != want.Kind || have.Tx.Hash() != want.Tx.Hash() || have.Reason != want.Reason || (want.Replacement != nil && (have.Replacement == nil || have.Replacement.Hash() != want.Replacement.Hash())) {
+				t.Fatalf("event %d mismatch: have %v %x (%s), want %v %x (%s)", i, have.Kind, have.Tx.Hash(), have.Reason, want.Kind, want.Tx.Hash(), want.Reason)
+			}
+			if from, _ := types.Sender(pool.signer, want.Tx); have.From != from {
+				t.Errorf("event %d sender mismatch: have %x, want %x", i, have.From, from)
+			}
+		case <-time.After(time.Second):
+			t.Fatalf("event %d missing", i)
+		}
+	}
+}
+
+// Tests that blob transactions survive restarts, and that the ones included in
+// the meantime are dropped on startup.
+func TestPersistence(t *testing.T) {
+	var (
+		chain  = newTestBlockChain()
+		config = Config{Datadir: t.TempDir()}
+		key    = newFundedKey(chain)
+		addr   = crypto.PubkeyToAddress(key.PublicKey)
+	)
+	pool, err := New(config, testChainConfig, chain)
+	if err != nil {
+		t.Fatalf("failed to create blob pool: %v", err)
+	}
+	tx0, sidecar0 := makeTx(0, 1, 1000, 10, 1, key)
+	tx1, sidecar1 := makeTx(1, 1, 1000, 10, 1, key)
+	for _, err := range pool.Add([]*types.Transaction{tx0, tx1}, []*types.BlobTxSidecar{sidecar0, sidecar1}, false) {
+		if err != nil {
+			t.Fatalf("failed to add blob transaction: %v", err)
+		}
+	}
+	pool.Stop()
+
+	// Reopen the pool with the first transaction included
+	chain.statedb.SetNonce(addr, 1)
+	if pool, err = New(config, testChainConfig, chain); err != nil {
+		t.Fatalf("failed to reopen blob pool: %v", err)
+	}
+	defer pool.Stop()
+
+	if pool.Has(tx0.Hash()) {
+		t.Fatal("included transaction not dropped")
+	}
+	if !pool.Has(tx1.Hash()) {
+		t.Fatal("pending transaction not reloaded")
+	}
+	if have := pool.Sidecar(tx1.Hash()); !reflect.DeepEqual(have, sidecar1) {
+		t.Fatal("reloaded sidecar mismatch")
+	}
+	// Include the second one too and ensure it's dropped on the next head
+	chain.statedb.SetNonce(addr, 2)
+	pool.mu.Lock()
+	pool.reset(chain.CurrentBlock())
+	pool.mu.Unlock()
+
+	if pool.Has(tx1.Hash()) || pool.datasize != 0 {
+		t.Fatal("included transaction not dropped")
+	}
+}
+
+// Tests that the blob pool plugs into the main transaction pool, sharing its
+// pending set and event feed, while keeping the accounts of the two disjoint.
+func TestTxPoolIntegration(t *testing.T) {
+	chain := newTestBlockChain()
+	blobs, err := New(Config{}, testChainConfig, chain)
+	if err != nil {
+		t.Fatalf("failed to create blob pool: %v", err)
+	}
+	pool := txpool.New(txpool.Config{}, testChainConfig, chain, blobs)
+	defer pool.Stop()
+
+	events := make(chan core.NewTxsEvent, 1)
+	sub := pool.SubscribeNewTxsEvent(events)
+	defer sub.Unsubscribe()
+
+	key := newFundedKey(chain)
+	addr := crypto.PubkeyToAddress(key.PublicKey)
+
+	tx, sidecar := makeTx(0, 1, params.InitialBaseFee*2, 10, 1, key)
+	if err := pool.AddLocal(tx); !errors.Is(err, ErrSidecarMissing) {
+		t.Fatalf("blob transaction without sidecar error mismatch: have %v, want %v", err, ErrSidecarMissing)
+	}
+	if err := pool.AddPrivate(tx.WithBlobTxSidecar(sidecar)); !errors.Is(err, core.ErrTxTypeNotSupported) {
+		t.Fatalf("private blob transaction error mismatch: have %v, want %v", err, core.ErrTxTypeNotSupported)
+	}
+	// Blob transactions carrying their sidecars are routed to the blob pool
+	if errs := pool.AddRemotes([]*types.Transaction{tx.WithBlobTxSidecar(sidecar)}); errs[0] != nil {
+		t.Fatalf("failed to add blob transaction: %v", errs[0])
+	}
+	select {
+	case ev := <-events:
+		if len(ev.Txs) != 1 || ev.Txs[0].Hash() != tx.Hash() {
+			t.Fatalf("event mismatch: have %v, want %v", ev.Txs, tx.Hash())
+		}
+	case <-time.After(time.Second):
+		t.Fatal("new transaction event not fired")
+	}
+	if pending := pool.Pending(false); len(pending[addr]) != 1 || pending[addr][0].Hash() != tx.Hash() {
+		t.Fatalf("pending transactions mismatch: have %v", pending[addr])
+	}
+	if !pool.Has(tx.Hash()) || pool.Status([]common.Hash{tx.Hash()})[0] != txpool.TxStatusPending {
+		t.Fatal("blob transaction not found via the main pool")
+	}
+	if nonce := pool.Nonce(addr); nonce != 1 {
+		t.Fatalf("pool nonce mismatch: have %d, want 1", nonce)
+	}
+	// The account is reserved by the blob pool
+	legacy := types.MustSignNewTx(key, types.LatestSigner(testChainConfig), &types.DynamicFeeTx{
+		ChainID:   testChainConfig.ChainID,
+		Nonce:     1,
+		GasTipCap: big.NewInt(1),
+		GasFeeCap: big.NewInt(params.InitialBaseFee * 2),
+		Gas:       params.TxGas,
+		To:        &common.Address{},
+	})
+	if err := pool.AddLocal(legacy); !errors.Is(err, txpool.ErrAlreadyReserved) {
+		t.Fatalf("reserved account error mismatch: have %v, want %v", err, txpool.ErrAlreadyReserved)
+	}
+}
@@ -0,0 +1,210 @@
+// Copyright 2023 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package txpool
+
+import (
+	"errors"
+	"fmt"
+	"sync"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/crypto"
+)
+
+const (
+	// maxBundleTxs is the maximum number of transactions a single bundle may
+	// consist of.
+	maxBundleTxs = 32
+
+	// maxBundleFutureBlocks is the maximum number of blocks ahead of the current
+	// head a bundle may target.
+	maxBundleFutureBlocks = 128
+)
+
+var (
+	// ErrEmptyBundle is returned if a bundle without transactions is submitted.
+	ErrEmptyBundle = errors.New("empty bundle")
+
+	// ErrBundleTooLarge is returned if a bundle contains more transactions than
+	// permitted.
+	ErrBundleTooLarge = errors.New("bundle too large")
+
+	// ErrBundleTarget is returned if a bundle targets an already mined block or
+	// one too far in the future.
+	ErrBundleTarget = errors.New("invalid bundle target block")
+
+	// ErrBundleTimestamps is returned if a bundle's timestamp range is empty.
+	ErrBundleTimestamps = errors.New("invalid bundle timestamp range")
+
+	// ErrBundlePoolFull is returned if the maximum number of bundles is already
+	// tracked.
+	ErrBundlePoolFull = errors.New("bundle pool is full")
+)
+
+// Bundle is a group of transactions to be included in a specific block, in the
+// given order and atomically, or not at all.
+type Bundle struct {
+	Txs          types.Transactions // Transactions to include, in order
+	BlockNumber  uint64             // Number of the block the bundle targets
+	MinTimestamp uint64             // Earliest block timestamp to include the bundle at, 0 if unbounded
+	MaxTimestamp uint64             // Latest block timestamp to include the bundle at, 0 if unbounded
+}
+
+// Hash returns the identifier of the bundle, the hash of its transaction hashes.
+func (b *Bundle) Hash() common.Hash {
+	hashes := make([]byte, 0, len(b.Txs)*common.HashLength)
+	for _, tx := range b.Txs {
+		hashes = append(hashes, tx.Hash().Bytes()...)
+	}
+	return crypto.Keccak256Hash(hashes)
+}
+
+// validAt returns whether the bundle may be included in a block with the given
+// timestamp.
+func (b *Bundle) validAt(timestamp uint64) bool {
+	if b.MinTimestamp != 0 && timestamp < b.MinTimestamp {
+		return false
+	}
+	if b.MaxTimestamp != 0 && timestamp > b.MaxTimestamp {
+		return false
+	}
+	return true
+}
+
+// bundlePool tracks the bundles submitted for upcoming blocks, indexed by the
+// block number they target.
+type bundlePool struct {
+	bundles map[uint64]map[common.Hash]*Bundle // Bundles grouped by target block
+	count   int                                // Number of bundles tracked
+	limit   int                                // Maximum number of bundles to track
+	lock    sync.RWMutex
+}
+
+// newBundlePool creates a bundle pool tracking at most the given number of
+// bundles.
+func newBundlePool(limit int) *bundlePool {
+	return &bundlePool{
+		bundles: make(map[uint64]map[common.Hash]*Bundle),
+		limit:   limit,
+	}
+}
+
+// add inserts a bundle into the pool, or returns ErrAlreadyKnown if it's already
+// tracked. Once the pool has room for the bundle, it's only inserted if the given
+// admission check passes.
+func (p *bundlePool) add(bundle *Bundle, admit func() error) error {
+	p.lock.Lock()
+	defer p.lock.Unlock()
+
+	hash := bundle.Hash()
+	if _, ok := p.bundles[bundle.BlockNumber][hash]; ok {
+		return ErrAlreadyKnown
+	}
+	if p.count >= p.limit {
+		return ErrBundlePoolFull
+	}
+	if err := admit(); err != nil {
+		return err
+	}
+	if p.bundles[bundle.BlockNumber] == nil {
+		p.bundles[bundle.BlockNumber] = make(map[common.Hash]*Bundle)
+	}
+	p.bundles[bundle.BlockNumber][hash] = bundle
+	p.count++
+	bundleGauge.Update(int64(p.count))
+	return nil
+}
+
+// get retrieves the bundles targeting the given block which may be included at
+// the given timestamp.
+func (p *bundlePool) get(number uint64, timestamp uint64) []*Bundle {
+	p.lock.RLock()
+	defer p.lock.RUnlock()
+
+	var bundles []*Bundle
+	for _, bundle := range p.bundles[number] {
+		if bundle.validAt(timestamp) {
+			bundles = append(bundles, bundle)
+		}
+	}
+	return bundles
+}
+
+// prune drops all the bundles which target the given block or an earlier one.
+func (p *bundlePool) prune(number uint64) {
+	p.lock.Lock()
+	defer p.lock.Unlock()
+
+	for target, bundles := range p.bundles {
+		if target <= number {
+			delete(p.bundles, target)
+			p.count -= len(bundles)
+		}
+	}
+	bundleGauge.Update(int64(p.count))
+}
+
+// AddBundle validates a bundle of transactions and schedules it for inclusion
+// into its target block. Bundles are not subject to the pricing constraints of
+// the pool, since they are ranked by their total payment to the block producer
+// when building the block.
+func (pool *TxPool) AddBundle(bundle *Bundle) error {
+	switch {
+	case len(bundle.Txs) == 0:
+		return ErrEmptyBundle
+	case len(bundle.Txs) > maxBundleTxs:
+		return fmt.Errorf("%w: %d transactions, limit %d", ErrBundleTooLarge, len(bundle.Txs), maxBundleTxs)
+	case bundle.MaxTimestamp != 0 && bundle.MaxTimestamp < bundle.MinTimestamp:
+		return fmt.Errorf("%w: min %d, max %d", ErrBundleTimestamps, bundle.MinTimestamp, bundle.MaxTimestamp)
+	}
+	head := pool.currentHead.Load().Number.Uint64()
+	if bundle.BlockNumber <= head || bundle.BlockNumber > head+maxBundleFutureBlocks {
+		return fmt.Errorf("%w: %d, head %d", ErrBundleTarget, bundle.BlockNumber, head)
+	}
+	for i, tx := range bundle.Txs {
+		if err := pool.validateTxBasics(tx, true); err != nil {
+			return fmt.Errorf("transaction %d: %w", i, err)
+		}
+	}
+	// Check the admission policies only if the bundle is new and fits, so that
+	// they don't account for bundles refused anyway
+	admit := func() error {
+		for i, tx := range bundle.Txs {
+			from, _ := types.Sender(pool.signer, tx) // already validated above
+			if err := pool.admit(tx, from, false); err != nil {
+				return fmt.Errorf("transaction %d: %w", i, err)
+			}
+		}
+		return nil
+	}
+	if err := pool.bundles.add(bundle, admit); err != nil {
+		return err
+	}
+	for _, tx := range bundle.Txs {
+		from, _ := types.Sender(pool.signer, tx) // already validated above
+		pool.admitted(tx, from, false)
+	}
+	bundleMeter.Mark(1)
+	return nil
+}
+
+// Bundles retrieves the bundles targeting the given block number which may be
+// included in a block with the given timestamp.
+func (pool *TxPool) Bundles(number uint64, timestamp uint64) []*Bundle {
+	return pool.bundles.get(number, timestamp)
+}
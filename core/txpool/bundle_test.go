@@ -0,0 +1,107 @@
+// Copyright 2023 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package txpool
+
+import (
+	"errors"
+	"math/big"
+	"testing"
+	"time"
+
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/crypto"
+)
+
+// Tests that bundles are validated on submission, retrieved according to their
+// target block and timestamp range, and dropped once their block is mined.
+func TestBundles(t *testing.T) {
+	t.Parallel()
+
+	pool, key := setupPool()
+	defer pool.Stop()
+
+	// Bundles are not subject to the pool's price limit
+	cheap := pricedTransaction(0, 100000, big.NewInt(0), key)
+	other, _ := crypto.GenerateKey()
+
+	tests := []struct {
+		bundle *Bundle
+		err    error
+	}{
+		{bundle: &Bundle{BlockNumber: 1}, err: ErrEmptyBundle},
+		{bundle: &Bundle{Txs: make(types.Transactions, maxBundleTxs+1), BlockNumber: 1}, err: ErrBundleTooLarge},
+		{bundle: &Bundle{Txs: types.Transactions{cheap}, BlockNumber: 0}, err: ErrBundleTarget},
+		{bundle: &Bundle{Txs: types.Transactions{cheap}, BlockNumber: maxBundleFutureBlocks + 1}, err: ErrBundleTarget},
+		{bundle: &Bundle{Txs: types.Transactions{cheap}, BlockNumber: 1, MinTimestamp: 20, MaxTimestamp: 10}, err: ErrBundleTimestamps},
+		{bundle: &Bundle{Txs: types.Transactions{cheap}, BlockNumber: 1}},
+		{bundle: &Bundle{Txs: types.Transactions{cheap}, BlockNumber: 1}, err: ErrAlreadyKnown},
+		{bundle: &Bundle{Txs: types.Transactions{cheap, transaction(0, 100000, other)}, BlockNumber: 1, MinTimestamp: 10, MaxTimestamp: 20}},
+		{bundle: &Bundle{Txs: types.Transactions{cheap}, BlockNumber: 2}},
+	}
+	for i, tt := range tests {
+		if err := pool.AddBundle(tt.bundle); !errors.Is(err, tt.err) {
+			t.Errorf("test %d: error mismatch: have %v, want %v", i, err, tt.err)
+		}
+	}
+	if bundles := pool.Bundles(1, 5); len(bundles) != 1 {
+		t.Errorf("bundle count mismatch before range: have %d, want %d", len(bundles), 1)
+	}
+	if bundles := pool.Bundles(1, 15); len(bundles) != 2 {
+		t.Errorf("bundle count mismatch within range: have %d, want %d", len(bundles), 2)
+	}
+	if bundles := pool.Bundles(2, 15); len(bundles) != 1 {
+		t.Errorf("bundle count mismatch for later block: have %d, want %d", len(bundles), 1)
+	}
+	// Mine the first targeted block and ensure its bundles are dropped
+	<-pool.requestReset(nil, &types.Header{Number: big.NewInt(1), GasLimit: 1000000, BaseFee: big.NewInt(1)})
+
+	if bundles := pool.Bundles(1, 15); len(bundles) != 0 {
+		t.Errorf("bundle count mismatch for mined block: have %d, want %d", len(bundles), 0)
+	}
+	if bundles := pool.Bundles(2, 15); len(bundles) != 1 {
+		t.Errorf("bundle count mismatch for upcoming block: have %d, want %d", len(bundles), 1)
+	}
+}
+
+// Tests that bundles refused by the pool, e.g. already known ones, are not
+// accounted for by the admission policies.
+func TestBundleAdmission(t *testing.T) {
+	t.Parallel()
+
+	pool, key := setupPool()
+	defer pool.Stop()
+
+	policies, err := NewAdmissionPolicies(AdmissionConfig{SenderRate: 2, RateWindow: time.Hour})
+	if err != nil {
+		t.Fatalf("failed to create policies: %v", err)
+	}
+	pool.SetAdmissionPolicies(policies...)
+
+	bundle := &Bundle{Txs: types.Transactions{transaction(0, 100000, key)}, BlockNumber: 1}
+	if err := pool.AddBundle(bundle); err != nil {
+		t.Fatalf("failed to add bundle: %v", err)
+	}
+	if err := pool.AddBundle(bundle); !errors.Is(err, ErrAlreadyKnown) {
+		t.Fatalf("known bundle error mismatch: have %v, want %v", err, ErrAlreadyKnown)
+	}
+	if err := pool.AddBundle(&Bundle{Txs: bundle.Txs, BlockNumber: 2}); err != nil {
+		t.Fatalf("failed to add bundle: %v", err)
+	}
+	if err := pool.AddBundle(&Bundle{Txs: bundle.Txs, BlockNumber: 3}); !errors.Is(err, ErrTxRejected) {
+		t.Fatalf("rate limited bundle error mismatch: have %v, want %v", err, ErrTxRejected)
+	}
+}
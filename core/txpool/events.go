@@ -0,0 +1,133 @@
+// Copyright 2023 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package txpool
+
+import (
+	"fmt"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/event"
+)
+
+// TxEventKind is the type of change a transaction underwent in the pool.
+type TxEventKind uint8
+
+const (
+	TxAdded    TxEventKind = iota // Transaction accepted into the pool
+	TxPromoted                    // Transaction became executable and moved to the pending set
+	TxDemoted                     // Transaction became non-executable and moved back to the queue
+	TxReplaced                    // Transaction replaced by another with the same nonce
+	TxDropped                     // Transaction removed from the pool for the given reason
+)
+
+// String implements fmt.Stringer.
+func (k TxEventKind) String() string {
+	switch k {
+	case TxAdded:
+		return "added"
+	case TxPromoted:
+		return "promoted"
+	case TxDemoted:
+		return "demoted"
+	case TxReplaced:
+		return "replaced"
+	case TxDropped:
+		return "dropped"
+	default:
+		return fmt.Sprintf("unknown(%d)", uint8(k))
+	}
+}
+
+// DropReason describes why a transaction was dropped from the pool.
+type DropReason string
+
+const (
+	DropUnderpriced DropReason = "underpriced"        // Evicted by better paying transactions, or below the minimum tip
+	DropStale       DropReason = "nonce too low"      // Nonce already used on chain, typically included in a block
+	DropUnpayable   DropReason = "insufficient funds" // Cost exceeds the sender's balance or gas the block gas limit
+	DropOverflow    DropReason = "pool overflow"      // Exceeded the per account or global pool limits
+	DropExpired     DropReason = "lifetime expired"   // Queued for longer than the configured lifetime
+	DropNonceGap    DropReason = "nonce gap"          // Not executable after a gap, for pools that don't queue such transactions
+)
+
+// TxPoolEvent is posted whenever a transaction in the pool changes state,
+// allowing subscribers to track its lifecycle from admission to departure.
+//
+// Events are not posted for private transactions. Inclusion into a block isn't
+// reported explicitly either, included transactions are dropped as stale. Blob
+// transactions, held by the blob pool, are never queued: they are only added,
+// replaced or dropped.
+type TxPoolEvent struct {
+	Kind        TxEventKind
+	Tx          *types.Transaction
+	From        common.Address
+	Replacement *types.Transaction // Transaction replacing Tx, set for TxReplaced
+	Reason      DropReason         // Reason for removing Tx, set for TxDropped
+}
+
+// SubscribeTxPoolEvent registers a subscription of TxPoolEvent and starts
+// sending the state changes of the pooled transactions to the given channel.
+func (pool *TxPool) SubscribeTxPoolEvent(ch chan<- TxPoolEvent) event.Subscription {
+	sub := pool.eventScope.Track(pool.eventFeed.Subscribe(ch))
+	if pool.blobs == nil {
+		return sub
+	}
+	return joinSubscriptions(sub, pool.blobs.SubscribeTxPoolEvent(ch))
+}
+
+// recordEvent queues a transaction event to be sent to subscribers once the pool
+// lock is released. Events are only tracked if anyone is subscribed to them.
+//
+// Note, this method assumes the pool lock is held!
+func (pool *TxPool) recordEvent(ev TxPoolEvent) {
+	if pool.eventScope.Count() == 0 {
+		return
+	}
+	ev.From, _ = types.Sender(pool.signer, ev.Tx) // already validated during insertion
+	pool.events = append(pool.events, ev)
+}
+
+// recordDrops queues a drop event for each of the given transactions.
+//
+// Note, this method assumes the pool lock is held!
+func (pool *TxPool) recordDrops(txs types.Transactions, reason DropReason) {
+	for _, tx := range txs {
+		pool.recordEvent(TxPoolEvent{Kind: TxDropped, Tx: tx, Reason: reason})
+	}
+}
+
+// takeEvents retrieves and clears the queued transaction events.
+//
+// Note, this method assumes the pool lock is held!
+func (pool *TxPool) takeEvents() []TxPoolEvent {
+	events := pool.events
+	pool.events = nil
+	return events
+}
+
+// sendEvents posts the given transaction events to the subscribers, omitting
+// those of private transactions. It must not be called with the pool lock held,
+// since subscribers might call back into the pool.
+func (pool *TxPool) sendEvents(events []TxPoolEvent) {
+	for _, ev := range events {
+		if pool.IsPrivate(ev.Tx.Hash()) {
+			continue
+		}
+		pool.eventFeed.Send(ev)
+	}
+}
@@ -0,0 +1,168 @@
+// Copyright 2023 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package txpool
+
+import (
+	"fmt"
+	"math/big"
+	"sort"
+	"testing"
+	"time"
+
+	"github.com/ethereum/go-ethereum/crypto"
+)
+
+// describeEvent flattens a transaction event into a comparable string.
+func describeEvent(ev TxPoolEvent) string {
+	desc := fmt.Sprintf("%v %x from %x", ev.Kind, ev.Tx.Hash(), ev.From)
+	if ev.Replacement != nil {
+		desc += fmt.Sprintf(" by %x", ev.Replacement.Hash())
+	}
+	if ev.Reason != "" {
+		desc += fmt.Sprintf(" (%s)", ev.Reason)
+	}
+	return desc
+}
+
+// validateTxPoolEvents checks that the given events, in any order, were posted and
+// nothing else.
+func validateTxPoolEvents(events chan TxPoolEvent, want []TxPoolEvent) error {
+	var have, expect []string
+	for _, ev := range want {
+		expect = append(expect, describeEvent(ev))
+	}
+	for len(have) < len(want) {
+		select {
+		case ev := <-events:
+			have = append(have, describeEvent(ev))
+		case <-time.After(time.Second):
+			return fmt.Errorf("event #%d not fired", len(have))
+		}
+	}
+	select {
+	case ev := <-events:
+		return fmt.Errorf("more than %d events fired: %v", len(want), describeEvent(ev))
+	case <-time.After(50 * time.Millisecond):
+	}
+	sort.Strings(have)
+	sort.Strings(expect)
+	for i := range have {
+		if have[i] != expect[i] {
+			return fmt.Errorf("event mismatch: have %v, want %v", have, expect)
+		}
+	}
+	return nil
+}
+
+// Tests that the state changes of pooled transactions are reported through the
+// transaction event stream, along with the reasons for dropping them.
+func TestTxPoolEvents(t *testing.T) {
+	t.Parallel()
+
+	pool, key := setupPool()
+	defer pool.Stop()
+
+	events := make(chan TxPoolEvent, 32)
+	sub := pool.SubscribeTxPoolEvent(events)
+	defer sub.Unsubscribe()
+
+	from := crypto.PubkeyToAddress(key.PublicKey)
+	testAddBalance(pool, from, big.NewInt(1000000))
+
+	// Add an executable and a gapped transaction
+	tx0, tx2 := pricedTransaction(0, 100000, big.NewInt(1), key), pricedTransaction(2, 100000, big.NewInt(1), key)
+	if err := pool.addRemoteSync(tx0); err != nil {
+		t.Fatalf("failed to add transaction: %v", err)
+	}
+	if err := pool.addRemoteSync(tx2); err != nil {
+		t.Fatalf("failed to add transaction: %v", err)
+	}
+	if err := validateTxPoolEvents(events, []TxPoolEvent{
+		{Kind: TxAdded, Tx: tx0, From: from},
+		{Kind: TxPromoted, Tx: tx0, From: from},
+		{Kind: TxAdded, Tx: tx2, From: from},
+	}); err != nil {
+		t.Fatalf("addition events invalid: %v", err)
+	}
+	// Replace the executable transaction and fill the nonce gap
+	tx0b, tx1 := pricedTransaction(0, 100000, big.NewInt(2), key), pricedTransaction(1, 100000, big.NewInt(1), key)
+	if err := pool.addRemoteSync(tx0b); err != nil {
+		t.Fatalf("failed to replace transaction: %v", err)
+	}
+	if err := pool.addRemoteSync(tx1); err != nil {
+		t.Fatalf("failed to add transaction: %v", err)
+	}
+	if err := validateTxPoolEvents(events, []TxPoolEvent{
+		{Kind: TxReplaced, Tx: tx0, From: from, Replacement: tx0b},
+		{Kind: TxAdded, Tx: tx0b, From: from},
+		{Kind: TxAdded, Tx: tx1, From: from},
+		{Kind: TxPromoted, Tx: tx1, From: from},
+		{Kind: TxPromoted, Tx: tx2, From: from},
+	}); err != nil {
+		t.Fatalf("replacement events invalid: %v", err)
+	}
+	// Reduce the balance to make the first transaction unpayable, demoting the rest
+	pool.mu.Lock()
+	pool.currentState.SetBalance(from, big.NewInt(150000))
+	pool.mu.Unlock()
+	<-pool.requestReset(nil, nil)
+
+	if err := validateTxPoolEvents(events, []TxPoolEvent{
+		{Kind: TxDropped, Tx: tx0b, From: from, Reason: DropUnpayable},
+		{Kind: TxDemoted, Tx: tx1, From: from},
+		{Kind: TxDemoted, Tx: tx2, From: from},
+	}); err != nil {
+		t.Fatalf("demotion events invalid: %v", err)
+	}
+	// Include the first transaction, making the demoted ones executable again
+	testSetNonce(pool, from, 1)
+	<-pool.requestReset(nil, nil)
+
+	if err := validateTxPoolEvents(events, []TxPoolEvent{
+		{Kind: TxPromoted, Tx: tx1, From: from},
+		{Kind: TxPromoted, Tx: tx2, From: from},
+	}); err != nil {
+		t.Fatalf("promotion events invalid: %v", err)
+	}
+	testSetNonce(pool, from, 2)
+	<-pool.requestReset(nil, nil)
+
+	if err := validateTxPoolEvents(events, []TxPoolEvent{
+		{Kind: TxDropped, Tx: tx1, From: from, Reason: DropStale},
+	}); err != nil {
+		t.Fatalf("inclusion events invalid: %v", err)
+	}
+	// Raise the minimum tip, dropping the remaining transaction
+	pool.SetGasTip(big.NewInt(2))
+
+	if err := validateTxPoolEvents(events, []TxPoolEvent{
+		{Kind: TxDropped, Tx: tx2, From: from, Reason: DropUnderpriced},
+	}); err != nil {
+		t.Fatalf("underpricing events invalid: %v", err)
+	}
+	if err := validatePoolInternals(pool); err != nil {
+		t.Fatalf("pool internal state corrupted: %v", err)
+	}
+	// Private transactions are never reported
+	private := pricedTransaction(2, 21000, big.NewInt(2), key)
+	if err := pool.AddPrivate(private); err != nil {
+		t.Fatalf("failed to add private transaction: %v", err)
+	}
+	if err := validateTxPoolEvents(events, nil); err != nil {
+		t.Fatalf("private events invalid: %v", err)
+	}
+}
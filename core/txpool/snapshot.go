@@ -0,0 +1,184 @@
+// Copyright 2023 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package txpool
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"time"
+
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/log"
+	"github.com/ethereum/go-ethereum/rlp"
+)
+
+// snapshotVersion is the version of the pool snapshot format, bumped whenever
+// the format changes in an incompatible way.
+const snapshotVersion = 1
+
+// snapshotBatch is the number of snapshotted transactions injected into the
+// pool at once while loading.
+const snapshotBatch = 1024
+
+// snapshotHeader is the first item of a pool snapshot.
+type snapshotHeader struct {
+	Version uint64
+}
+
+// snapshotEntry is a single snapshotted transaction along with its local flag
+// and the time it arrived in the pool in unix nanoseconds, zero if unknown.
+type snapshotEntry struct {
+	Tx      *types.Transaction
+	Local   bool
+	Arrival uint64 `rlp:"optional"`
+}
+
+// snapshot is a periodically regenerated dump of the entire transaction pool,
+// remote transactions included, allowing all of them to survive node restarts.
+// Contrary to the local journal, the snapshot is never appended to but always
+// written out anew.
+type snapshot struct {
+	path string // Filesystem path to store the snapshot at
+}
+
+// newSnapshot creates a new pool snapshot stored at the given path.
+func newSnapshot(path string) *snapshot {
+	return &snapshot{path: path}
+}
+
+// load parses the pool snapshot from disk and injects its transactions into
+// the pool in batches, revalidating them in the process. Loading stops once
+// the given number of transaction slots have been read, keeping the memory
+// use bounded by the pool capacity.
+//
+// The arrival times of the transactions are passed along with them, the zero
+// time denoting an unknown one.
+//
+// If the snapshot turns out to be corrupted, the transactions parsed up to the
+// corruption are kept and the snapshot is moved aside for inspection.
+func (snap *snapshot) load(slots int, add func(txs []*types.Transaction, arrivals []time.Time, local bool) []error) error {
+	input, err := os.Open(snap.path)
+	if errors.Is(err, fs.ErrNotExist) {
+		// Skip the parsing if the snapshot doesn't exist at all
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	defer input.Close()
+
+	// Limit the stream to the size of the file, so corrupted size prefixes
+	// cannot make the decoder allocate arbitrary amounts of memory
+	info, err := input.Stat()
+	if err != nil {
+		return err
+	}
+	stream := rlp.NewStream(bufio.NewReader(input), uint64(info.Size()))
+
+	var (
+		total, dropped, loaded int
+
+		batch      []*types.Transaction
+		arrivals   []time.Time
+		batchLocal bool
+	)
+	flush := func() {
+		for _, err := range add(batch, arrivals, batchLocal) {
+			if err != nil && !errors.Is(err, ErrAlreadyKnown) {
+				log.Debug("Failed to add snapshotted transaction", "err", err)
+				dropped++
+			}
+		}
+		batch, arrivals = batch[:0], arrivals[:0]
+	}
+	var header snapshotHeader
+	if err = stream.Decode(&header); err == nil && header.Version != snapshotVersion {
+		err = fmt.Errorf("unsupported snapshot version %d", header.Version)
+	}
+	for err == nil && loaded < slots {
+		var entry snapshotEntry
+		if err = stream.Decode(&entry); err != nil {
+			break
+		}
+		if len(batch) > 0 && (entry.Local != batchLocal || len(batch) >= snapshotBatch) {
+			flush()
+		}
+		var arrival time.Time
+		if entry.Arrival != 0 {
+			arrival = time.Unix(0, int64(entry.Arrival))
+		}
+		batch, arrivals, batchLocal = append(batch, entry.Tx), append(arrivals, arrival), entry.Local
+		total++
+		loaded += numSlots(entry.Tx)
+	}
+	if len(batch) > 0 {
+		flush()
+	}
+	log.Info("Loaded transaction pool snapshot", "transactions", total, "dropped", dropped)
+
+	if err != nil && err != io.EOF {
+		input.Close()
+		if rerr := os.Rename(snap.path, snap.path+".corrupt"); rerr != nil {
+			log.Error("Failed to move corrupted pool snapshot aside", "err", rerr)
+		}
+		return fmt.Errorf("corrupted pool snapshot after %d transactions: %w", total, err)
+	}
+	if loaded >= slots {
+		log.Warn("Pool snapshot exceeds pool capacity, truncated", "slots", slots)
+	}
+	return nil
+}
+
+// write regenerates the pool snapshot with the given transactions, replacing
+// the previous one atomically.
+func (snap *snapshot) write(entries []snapshotEntry) error {
+	replacement, err := os.OpenFile(snap.path+".new", os.O_WRONLY|os.O_CREATE|os.O_TRUNC, 0644)
+	if err != nil {
+		return err
+	}
+	output := bufio.NewWriter(replacement)
+	if err = rlp.Encode(output, &snapshotHeader{Version: snapshotVersion}); err == nil {
+		for i := range entries {
+			if err = rlp.Encode(output, &entries[i]); err != nil {
+				break
+			}
+		}
+	}
+	if err == nil {
+		err = output.Flush()
+	}
+	if err == nil {
+		err = replacement.Sync()
+	}
+	if cerr := replacement.Close(); err == nil {
+		err = cerr
+	}
+	if err != nil {
+		os.Remove(snap.path + ".new")
+		return err
+	}
+	// Replace the live snapshot with the newly generated one
+	if err = os.Rename(snap.path+".new", snap.path); err != nil {
+		return err
+	}
+	log.Info("Regenerated transaction pool snapshot", "transactions", len(entries))
+	return nil
+}
@@ -0,0 +1,199 @@
+// Copyright 2023 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package txpool
+
+import (
+	"crypto/ecdsa"
+	"math/big"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core/rawdb"
+	"github.com/ethereum/go-ethereum/core/state"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/crypto"
+	"github.com/ethereum/go-ethereum/event"
+	"github.com/ethereum/go-ethereum/params"
+)
+
+// Tests that the whole pool, remote and queued transactions included, survives
+// restarts if snapshotting is enabled, revalidated against the new head.
+func TestSnapshotting(t *testing.T) {
+	t.Parallel()
+
+	statedb, _ := state.New(types.EmptyRootHash, state.NewDatabase(rawdb.NewMemoryDatabase()), nil)
+	blockchain := newTestBlockChain(1000000, statedb, new(event.Feed))
+
+	config := testTxPoolConfig
+	config.Journal = ""
+	config.Snapshot = filepath.Join(t.TempDir(), "txpool.rlp")
+
+	pool := New(config, params.TestChainConfig, blockchain, nil)
+
+	local, _ := crypto.GenerateKey()
+	remote, _ := crypto.GenerateKey()
+
+	testAddBalance(pool, crypto.PubkeyToAddress(local.PublicKey), big.NewInt(1000000000))
+	testAddBalance(pool, crypto.PubkeyToAddress(remote.PublicKey), big.NewInt(1000000000))
+
+	// Add two pending and a queued transaction for both a local and a remote account
+	for _, nonce := range []uint64{0, 1, 3} {
+		if err := pool.AddLocal(pricedTransaction(nonce, 100000, big.NewInt(1), local)); err != nil {
+			t.Fatalf("failed to add local transaction: %v", err)
+		}
+		if err := pool.addRemoteSync(pricedTransaction(nonce, 100000, big.NewInt(1), remote)); err != nil {
+			t.Fatalf("failed to add remote transaction: %v", err)
+		}
+	}
+	if pending, queued := pool.Stats(); pending != 4 || queued != 2 {
+		t.Fatalf("pool stats mismatch: have %d/%d, want %d/%d", pending, queued, 4, 2)
+	}
+	// Terminate the old pool, bump the remote nonce, create a new pool and ensure
+	// all the still valid transactions survive along with their arrival times
+	arrivals := make(map[common.Hash]time.Time)
+	pool.all.Range(func(hash common.Hash, tx *types.Transaction, local bool) bool {
+		arrivals[hash] = pool.Arrival(hash)
+		return true
+	}, true, true)
+	pool.Stop()
+	statedb.SetNonce(crypto.PubkeyToAddress(remote.PublicKey), 1)
+	blockchain = newTestBlockChain(1000000, statedb, new(event.Feed))
+
+	pool = New(config, params.TestChainConfig, blockchain, nil)
+
+	if pending, queued := pool.Stats(); pending != 3 || queued != 2 {
+		t.Fatalf("pool stats mismatch: have %d/%d, want %d/%d", pending, queued, 3, 2)
+	}
+	if !pool.locals.contains(crypto.PubkeyToAddress(local.PublicKey)) {
+		t.Errorf("local account not restored as local")
+	}
+	if pool.locals.contains(crypto.PubkeyToAddress(remote.PublicKey)) {
+		t.Errorf("remote account restored as local")
+	}
+	pool.all.Range(func(hash common.Hash, tx *types.Transaction, local bool) bool {
+		if have, want := pool.Arrival(hash), arrivals[hash]; !have.Equal(want) {
+			t.Errorf("transaction %x arrival mismatch: have %v, want %v", hash, have, want)
+		}
+		return true
+	}, true, true)
+	if err := validatePoolInternals(pool); err != nil {
+		t.Fatalf("pool internal state corrupted: %v", err)
+	}
+	pool.Stop()
+}
+
+// Tests that the transactions loaded from the snapshot are subject to the
+// admission policies the pool is created with.
+func TestSnapshotAdmission(t *testing.T) {
+	t.Parallel()
+
+	statedb, _ := state.New(types.EmptyRootHash, state.NewDatabase(rawdb.NewMemoryDatabase()), nil)
+	blockchain := newTestBlockChain(1000000, statedb, new(event.Feed))
+
+	config := testTxPoolConfig
+	config.Journal = ""
+	config.Snapshot = filepath.Join(t.TempDir(), "txpool.rlp")
+
+	pool := New(config, params.TestChainConfig, blockchain, nil)
+
+	allowed, _ := crypto.GenerateKey()
+	denied, _ := crypto.GenerateKey()
+	for _, key := range []*ecdsa.PrivateKey{allowed, denied} {
+		testAddBalance(pool, crypto.PubkeyToAddress(key.PublicKey), big.NewInt(1000000000))
+		if err := pool.addRemoteSync(pricedTransaction(0, 100000, big.NewInt(1), key)); err != nil {
+			t.Fatalf("failed to add transaction: %v", err)
+		}
+	}
+	pool.Stop()
+
+	policies, err := NewAdmissionPolicies(AdmissionConfig{SenderDeny: []common.Address{crypto.PubkeyToAddress(denied.PublicKey)}})
+	if err != nil {
+		t.Fatalf("failed to create policies: %v", err)
+	}
+	pool = New(config, params.TestChainConfig, blockchain, nil, policies...)
+	defer pool.Stop()
+
+	if pending, _ := pool.Stats(); pending != 1 {
+		t.Fatalf("pending transactions mismatch: have %d, want 1", pending)
+	}
+	if pending, queued := pool.ContentFrom(crypto.PubkeyToAddress(denied.PublicKey)); len(pending)+len(queued) != 0 {
+		t.Fatalf("denied sender's transactions loaded from snapshot")
+	}
+}
+
+// Tests that a corrupted snapshot is recovered up to the point of corruption and
+// moved aside.
+func TestSnapshotCorruption(t *testing.T) {
+	t.Parallel()
+
+	var (
+		path  = filepath.Join(t.TempDir(), "txpool.rlp")
+		snap  = newSnapshot(path)
+		newTx = func() *types.Transaction { k, _ := crypto.GenerateKey(); return transaction(0, 100000, k) }
+		valid = []snapshotEntry{{Tx: newTx(), Local: true}, {Tx: newTx()}, {Tx: newTx()}}
+	)
+	if err := snap.write(valid); err != nil {
+		t.Fatalf("failed to write snapshot: %v", err)
+	}
+	// Append a huge size prefix to the snapshot, which must not be allocated
+	file, err := os.OpenFile(path, os.O_WRONLY|os.O_APPEND, 0644)
+	if err != nil {
+		t.Fatalf("failed to open snapshot: %v", err)
+	}
+	file.Write([]byte{0xfb, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff})
+	file.Close()
+
+	var locals, remotes int
+	err = snap.load(1024, func(txs []*types.Transaction, arrivals []time.Time, local bool) []error {
+		if local {
+			locals += len(txs)
+		} else {
+			remotes += len(txs)
+		}
+		return make([]error, len(txs))
+	})
+	if err == nil {
+		t.Fatalf("corrupted snapshot loaded without error")
+	}
+	if locals != 1 || remotes != 2 {
+		t.Fatalf("recovered transactions mismatch: have %d/%d, want %d/%d", locals, remotes, 1, 2)
+	}
+	if _, err := os.Stat(path); !os.IsNotExist(err) {
+		t.Errorf("corrupted snapshot not moved aside: %v", err)
+	}
+	if _, err := os.Stat(path + ".corrupt"); err != nil {
+		t.Errorf("corrupted snapshot missing: %v", err)
+	}
+	// Ensure loading is bounded by the given slot count
+	if err := snap.write(valid); err != nil {
+		t.Fatalf("failed to write snapshot: %v", err)
+	}
+	var loaded int
+	err = snap.load(2, func(txs []*types.Transaction, arrivals []time.Time, local bool) []error {
+		loaded += len(txs)
+		return make([]error, len(txs))
+	})
+	if err != nil {
+		t.Fatalf("failed to load snapshot: %v", err)
+	}
+	if loaded != 2 {
+		t.Fatalf("loaded transactions mismatch: have %d, want %d", loaded, 2)
+	}
+}
@@ -18,6 +18,7 @@ package txpool
 
 import (
 	"errors"
+	"fmt"
 	"math"
 	"math/big"
 	"sort"
@@ -26,6 +27,7 @@ import (
 	"time"
 
 	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/common/lru"
 	"github.com/ethereum/go-ethereum/common/prque"
 	"github.com/ethereum/go-ethereum/consensus/misc"
 	"github.com/ethereum/go-ethereum/core"
@@ -52,6 +54,10 @@ const (
 	// more expensive to propagate; larger transactions also take more resources
 	// to validate whether they fit into the pool or not.
 	txMaxSize = 4 * txSlotSize // 128KB
+
+	// retiredArrivals is the number of arrival times of removed transactions to
+	// remember, covering the transactions of the blocks dropped by a reorg.
+	retiredArrivals = 16384
 )
 
 var (
@@ -90,6 +96,15 @@ var (
 	// ErrFutureReplacePending is returned if a future transaction replaces a pending
 	// transaction. Future transactions should only be able to replace other future transactions.
 	ErrFutureReplacePending = errors.New("future transaction tries to replace pending")
+
+	// ErrAlreadyReserved is returned if the sender of a transaction already has
+	// transactions pooled in the blob pool, or vice versa. The nonces of an
+	// account are only ever tracked by a single pool.
+	ErrAlreadyReserved = errors.New("address already reserved")
+
+	// ErrBlobPoolDisabled is returned if a blob transaction is added to a pool
+	// running without a blob pool.
+	ErrBlobPoolDisabled = errors.New("blob pool disabled")
 )
 
 var (
@@ -117,6 +132,15 @@ var (
 	invalidTxMeter     = metrics.NewRegisteredMeter("txpool/invalid", nil)
 	underpricedTxMeter = metrics.NewRegisteredMeter("txpool/underpriced", nil)
 	overflowedTxMeter  = metrics.NewRegisteredMeter("txpool/overflowed", nil)
+	rejectedTxMeter    = metrics.NewRegisteredMeter("txpool/rejected", nil) // Refused by an admission policy
+
+	// Metrics for private transactions
+	privateTxMeter      = metrics.NewRegisteredMeter("txpool/private/added", nil)
+	privateExpiredMeter = metrics.NewRegisteredMeter("txpool/private/expired", nil) // Dropped due to expiry
+
+	// Metrics for transaction bundles
+	bundleMeter = metrics.NewRegisteredMeter("txpool/bundles/added", nil)
+	bundleGauge = metrics.NewRegisteredGauge("txpool/bundles", nil)
 
 	// throttleTxMeter counts how many transactions are rejected due to too-many-changes between
 	// txpool reorgs.
@@ -155,6 +179,57 @@ type blockChain interface {
 	SubscribeChainHeadEvent(ch chan<- core.ChainHeadEvent) event.Subscription
 }
 
+// BlobPool is the pool of blob transactions, holding their sidecars out of the
+// main pool. Its transactions are exposed through the main pool's Pending and
+// SubscribeNewTxsEvent, stripped of their sidecars.
+type BlobPool interface {
+	// Add inserts a batch of blob transactions along with their sidecars.
+	Add(txs []*types.Transaction, sidecars []*types.BlobTxSidecar, local bool) []error
+
+	// Get retrieves a pooled transaction, stripped of its sidecar.
+	Get(hash common.Hash) *types.Transaction
+
+	// Has returns whether a transaction is pooled.
+	Has(hash common.Hash) bool
+
+	// Sidecar retrieves the sidecar of a pooled transaction.
+	Sidecar(hash common.Hash) *types.BlobTxSidecar
+
+	// HasAccount returns whether any transaction is pooled for an account.
+	HasAccount(addr common.Address) bool
+
+	// Nonce returns the next nonce of an account, with all its pooled
+	// transactions applied on top.
+	Nonce(addr common.Address) uint64
+
+	// Pending retrieves all currently processable transactions, grouped by
+	// origin account and sorted by nonce.
+	Pending(enforceTips bool) map[common.Address]types.Transactions
+
+	// Content retrieves all pooled transactions, grouped by origin account and
+	// sorted by nonce.
+	Content() map[common.Address]types.Transactions
+
+	// ContentFrom retrieves the pooled transactions of an account.
+	ContentFrom(addr common.Address) types.Transactions
+
+	// Stats retrieves the number of pending and queued transactions.
+	Stats() (int, int)
+
+	// SetGasTip updates the minimum gas tip required for a new transaction.
+	SetGasTip(tip *big.Int)
+
+	// SubscribeNewTxsEvent subscribes to the transactions added to the pool.
+	SubscribeNewTxsEvent(ch chan<- core.NewTxsEvent) event.Subscription
+
+	// SubscribeTxPoolEvent subscribes to the state changes of the pooled
+	// transactions.
+	SubscribeTxPoolEvent(ch chan<- TxPoolEvent) event.Subscription
+
+	// Stop terminates the blob pool.
+	Stop()
+}
+
 // Config are the configuration parameters of the transaction pool.
 type Config struct {
 	Locals    []common.Address // Addresses that should be treated by default as local
@@ -162,6 +237,9 @@ type Config struct {
 	Journal   string           // Journal of local transactions to survive node restarts
 	Rejournal time.Duration    // Time interval to regenerate the local transaction journal
 
+	Snapshot   string        // Snapshot of the entire pool to survive node restarts, disabled if empty
+	Resnapshot time.Duration // Time interval to regenerate the pool snapshot
+
 	PriceLimit uint64 // Minimum gas price to enforce for acceptance into the pool
 	PriceBump  uint64 // Minimum price bump percentage to replace an already existing transaction (nonce)
 
@@ -171,6 +249,11 @@ type Config struct {
 	GlobalQueue  uint64 // Maximum number of non-executable transaction slots for all accounts
 
 	Lifetime time.Duration // Maximum amount of time non-executable transaction are queued
+
+	PrivateExpiry uint64 // Number of blocks after which unmined private transactions are dropped
+	GlobalBundles uint64 // Maximum number of transaction bundles tracked for upcoming blocks
+
+	Admission AdmissionConfig // Admission policies new transactions are subject to
 }
 
 // DefaultConfig contains the default configurations for the transaction pool.
@@ -178,6 +261,8 @@ var DefaultConfig = Config{
 	Journal:   "transactions.rlp",
 	Rejournal: time.Hour,
 
+	Resnapshot: 5 * time.Minute,
+
 	PriceLimit: 1,
 	PriceBump:  10,
 
@@ -187,6 +272,9 @@ var DefaultConfig = Config{
 	GlobalQueue:  1024,
 
 	Lifetime: 3 * time.Hour,
+
+	PrivateExpiry: 25,
+	GlobalBundles: 1024,
 }
 
 // sanitize checks the provided user configurations and changes anything that's
@@ -197,6 +285,10 @@ func (config *Config) sanitize() Config {
 		log.Warn("Sanitizing invalid txpool journal time", "provided", conf.Rejournal, "updated", time.Second)
 		conf.Rejournal = time.Second
 	}
+	if conf.Resnapshot < time.Second {
+		log.Warn("Sanitizing invalid txpool snapshot time", "provided", conf.Resnapshot, "updated", time.Second)
+		conf.Resnapshot = time.Second
+	}
 	if conf.PriceLimit < 1 {
 		log.Warn("Sanitizing invalid txpool price limit", "provided", conf.PriceLimit, "updated", DefaultConfig.PriceLimit)
 		conf.PriceLimit = DefaultConfig.PriceLimit
@@ -225,6 +317,14 @@ func (config *Config) sanitize() Config {
 		log.Warn("Sanitizing invalid txpool lifetime", "provided", conf.Lifetime, "updated", DefaultConfig.Lifetime)
 		conf.Lifetime = DefaultConfig.Lifetime
 	}
+	if conf.PrivateExpiry < 1 {
+		log.Warn("Sanitizing invalid txpool private expiry", "provided", conf.PrivateExpiry, "updated", DefaultConfig.PrivateExpiry)
+		conf.PrivateExpiry = DefaultConfig.PrivateExpiry
+	}
+	if conf.GlobalBundles < 1 {
+		log.Warn("Sanitizing invalid txpool global bundles", "provided", conf.GlobalBundles, "updated", DefaultConfig.GlobalBundles)
+		conf.GlobalBundles = DefaultConfig.GlobalBundles
+	}
 	return conf
 }
 
@@ -242,6 +342,8 @@ type TxPool struct {
 	gasTip      atomic.Pointer[big.Int]
 	txFeed      event.Feed
 	scope       event.SubscriptionScope
+	eventFeed   event.Feed
+	eventScope  event.SubscriptionScope
 	signer      types.Signer
 	mu          sync.RWMutex
 
@@ -249,8 +351,21 @@ type TxPool struct {
 	currentState  *state.StateDB               // Current state in the blockchain head
 	pendingNonces *noncer                      // Pending state tracking virtual nonces
 
-	locals  *accountSet // Set of local transaction to exempt from eviction rules
-	journal *journal    // Journal of local transaction to back up to disk
+	locals   *accountSet // Set of local transaction to exempt from eviction rules
+	journal  *journal    // Journal of local transaction to back up to disk
+	snapshot *snapshot   // Snapshot of all transactions to back up to disk
+	blobs    BlobPool    // Pool of blob transactions, nil if blob transactions are not accepted
+
+	blobReserved map[common.Address]int // Accounts with blob transactions being handed over to the blob pool
+
+	admission atomic.Pointer[AdmissionChain] // Admission policies new transactions are subject to
+
+	private     map[common.Hash]uint64 // Private transactions never announced to peers, mapped to their expiry block
+	privateLock sync.RWMutex           // Lock protecting the private transaction set
+
+	bundles *bundlePool // Transaction bundles submitted for upcoming blocks
+
+	events []TxPoolEvent // Transaction events pending to be sent once the lock is released
 
 	pending map[common.Address]*list     // All currently processable transactions
 	queue   map[common.Address]*list     // Queued but non-processable transactions
@@ -276,8 +391,13 @@ type txpoolResetRequest struct {
 }
 
 // New creates a new transaction pool to gather, sort and filter inbound
-// transactions from the network.
-func New(config Config, chainconfig *params.ChainConfig, chain blockChain) *TxPool {
+// transactions from the network. Blob transactions are handed over to the given
+// blob pool, whose lifecycle the transaction pool takes over; if it's nil, blob
+// transactions are rejected.
+//
+// The admission policies are in force from the start, so the transactions loaded
+// from the journal and the snapshot are subject to them too.
+func New(config Config, chainconfig *params.ChainConfig, chain blockChain, blobs BlobPool, policies ...AdmissionPolicy) *TxPool {
 	// Sanitize the input to ensure no vulnerable gas prices are set
 	config = (&config).sanitize()
 
@@ -291,6 +411,10 @@ func New(config Config, chainconfig *params.ChainConfig, chain blockChain) *TxPo
 		queue:           make(map[common.Address]*list),
 		beats:           make(map[common.Address]time.Time),
 		all:             newLookup(),
+		blobs:           blobs,
+		blobReserved:    make(map[common.Address]int),
+		private:         make(map[common.Hash]uint64),
+		bundles:         newBundlePool(int(config.GlobalBundles)),
 		chainHeadCh:     make(chan core.ChainHeadEvent, chainHeadChanSize),
 		reqResetCh:      make(chan *txpoolResetRequest),
 		reqPromoteCh:    make(chan *accountSet),
@@ -306,6 +430,7 @@ func New(config Config, chainconfig *params.ChainConfig, chain blockChain) *TxPo
 		pool.locals.add(addr)
 	}
 	pool.priced = newPricedList(pool.all)
+	pool.SetAdmissionPolicies(policies...)
 	pool.reset(nil, chain.CurrentBlock())
 
 	// Start the reorg loop early so it can handle requests generated during journal loading.
@@ -323,6 +448,23 @@ func New(config Config, chainconfig *params.ChainConfig, chain blockChain) *TxPo
 			log.Warn("Failed to rotate transaction journal", "err", err)
 		}
 	}
+	// If the whole pool is snapshotted, load it from disk revalidating every
+	// transaction against the current head
+	if config.Snapshot != "" {
+		pool.snapshot = newSnapshot(config.Snapshot)
+
+		add := func(txs []*types.Transaction, arrivals []time.Time, local bool) []error {
+			for i, tx := range txs {
+				if !arrivals[i].IsZero() {
+					pool.all.RestoreArrival(tx.Hash(), arrivals[i])
+				}
+			}
+			return pool.addTxs(txs, local && !config.NoLocals, true)
+		}
+		if err := pool.snapshot.load(int(config.GlobalSlots+config.GlobalQueue), add); err != nil {
+			log.Warn("Failed to load transaction pool snapshot", "err", err)
+		}
+	}
 
 	// Subscribe events from blockchain and start the main event loop.
 	pool.chainHeadSub = pool.chain.SubscribeChainHeadEvent(pool.chainHeadCh)
@@ -344,12 +486,15 @@ func (pool *TxPool) loop() {
 		report  = time.NewTicker(statsReportInterval)
 		evict   = time.NewTicker(evictionInterval)
 		journal = time.NewTicker(pool.config.Rejournal)
+		// Start the snapshot ticker, never firing if snapshots are disabled
+		snapshot = time.NewTicker(pool.config.Resnapshot)
 		// Track the previous head headers for transaction reorgs
 		head = pool.chain.CurrentBlock()
 	)
 	defer report.Stop()
 	defer evict.Stop()
 	defer journal.Stop()
+	defer snapshot.Stop()
 
 	// Notify tests that the init phase is done
 	close(pool.initDoneCh)
@@ -393,11 +538,15 @@ func (pool *TxPool) loop() {
 					for _, tx := range list {
 						pool.removeTx(tx.Hash(), true)
 					}
+					pool.recordDrops(list, DropExpired)
 					queuedEvictionMeter.Mark(int64(len(list)))
 				}
 			}
+			events := pool.takeEvents()
 			pool.mu.Unlock()
 
+			pool.sendEvents(events)
+
 		// Handle local transaction journal rotation
 		case <-journal.C:
 			if pool.journal != nil {
@@ -407,6 +556,12 @@ func (pool *TxPool) loop() {
 				}
 				pool.mu.Unlock()
 			}
+
+		// Handle pool snapshot regeneration
+		case <-snapshot.C:
+			if pool.snapshot != nil {
+				pool.writeSnapshot()
+			}
 		}
 	}
 }
@@ -415,6 +570,7 @@ func (pool *TxPool) loop() {
 func (pool *TxPool) Stop() {
 	// Unsubscribe all subscriptions registered from txpool
 	pool.scope.Close()
+	pool.eventScope.Close()
 
 	// Unsubscribe subscriptions registered from blockchain
 	pool.chainHeadSub.Unsubscribe()
@@ -423,21 +579,96 @@ func (pool *TxPool) Stop() {
 	if pool.journal != nil {
 		pool.journal.close()
 	}
+	if pool.snapshot != nil {
+		pool.writeSnapshot()
+	}
+	if pool.blobs != nil {
+		pool.blobs.Stop()
+	}
 	log.Info("Transaction pool stopped")
 }
 
+// BlobPool returns the blob pool attached to the transaction pool, if any.
+func (pool *TxPool) BlobPool() BlobPool {
+	return pool.blobs
+}
+
+// Sidecar retrieves the sidecar of a pooled blob transaction, or nil if the
+// transaction is not known to the blob pool.
+func (pool *TxPool) Sidecar(hash common.Hash) *types.BlobTxSidecar {
+	if pool.blobs == nil {
+		return nil
+	}
+	return pool.blobs.Sidecar(hash)
+}
+
 // SubscribeNewTxsEvent registers a subscription of NewTxsEvent and
 // starts sending event to the given channel.
 func (pool *TxPool) SubscribeNewTxsEvent(ch chan<- core.NewTxsEvent) event.Subscription {
-	return pool.scope.Track(pool.txFeed.Subscribe(ch))
+	sub := pool.scope.Track(pool.txFeed.Subscribe(ch))
+	if pool.blobs == nil {
+		return sub
+	}
+	return joinSubscriptions(sub, pool.blobs.SubscribeNewTxsEvent(ch))
+}
+
+// joinSubscriptions merges the subscriptions of the pool and the blob pool into
+// one, ending both once either fails or the merged one is unsubscribed.
+func joinSubscriptions(sub, blobSub event.Subscription) event.Subscription {
+	return event.NewSubscription(func(quit <-chan struct{}) error {
+		defer sub.Unsubscribe()
+		defer blobSub.Unsubscribe()
+
+		select {
+		case <-quit:
+			return nil
+		case err := <-sub.Err():
+			return err
+		case err := <-blobSub.Err():
+			return err
+		}
+	})
+}
+
+// SubscribePublicTxsEvent registers a subscription of NewTxsEvent like
+// SubscribeNewTxsEvent, with the private transactions filtered out of the events.
+func (pool *TxPool) SubscribePublicTxsEvent(ch chan<- core.NewTxsEvent) event.Subscription {
+	return event.NewSubscription(func(quit <-chan struct{}) error {
+		events := make(chan core.NewTxsEvent, cap(ch))
+		sub := pool.SubscribeNewTxsEvent(events)
+		defer sub.Unsubscribe()
+
+		for {
+			select {
+			case ev := <-events:
+				// The event is shared with other subscribers, filter into a copy
+				var txs []*types.Transaction
+				for _, tx := range ev.Txs {
+					if !pool.IsPrivate(tx.Hash()) {
+						txs = append(txs, tx)
+					}
+				}
+				if len(txs) == 0 {
+					continue
+				}
+				select {
+				case ch <- core.NewTxsEvent{Txs: txs}:
+				case <-quit:
+					return nil
+				}
+			case err := <-sub.Err():
+				return err
+			case <-quit:
+				return nil
+			}
+		}
+	})
 }
 
 // SetGasTip updates the minimum gas tip required by the transaction pool for a
 // new transaction, and drops all transactions below this threshold.
 func (pool *TxPool) SetGasTip(tip *big.Int) {
 	pool.mu.Lock()
-	defer pool.mu.Unlock()
-
 	old := pool.gasTip.Load()
 	pool.gasTip.Store(new(big.Int).Set(tip))
 
@@ -449,6 +680,14 @@ func (pool *TxPool) SetGasTip(tip *big.Int) {
 			pool.removeTx(tx.Hash(), false)
 		}
 		pool.priced.Removed(len(drop))
+		pool.recordDrops(drop, DropUnderpriced)
+	}
+	events := pool.takeEvents()
+	pool.mu.Unlock()
+
+	pool.sendEvents(events)
+	if pool.blobs != nil {
+		pool.blobs.SetGasTip(tip)
 	}
 	log.Info("Transaction pool tip threshold updated", "tip", tip)
 }
@@ -456,6 +695,9 @@ func (pool *TxPool) SetGasTip(tip *big.Int) {
 // Nonce returns the next nonce of an account, with all transactions executable
 // by the pool already applied on top.
 func (pool *TxPool) Nonce(addr common.Address) uint64 {
+	if pool.blobs != nil && pool.blobs.HasAccount(addr) {
+		return pool.blobs.Nonce(addr)
+	}
 	pool.mu.RLock()
 	defer pool.mu.RUnlock()
 
@@ -466,9 +708,14 @@ func (pool *TxPool) Nonce(addr common.Address) uint64 {
 // number of queued (non-executable) transactions.
 func (pool *TxPool) Stats() (int, int) {
 	pool.mu.RLock()
-	defer pool.mu.RUnlock()
+	pending, queued := pool.stats()
+	pool.mu.RUnlock()
 
-	return pool.stats()
+	if pool.blobs != nil {
+		blobPending, blobQueued := pool.blobs.Stats()
+		pending, queued = pending+blobPending, queued+blobQueued
+	}
+	return pending, queued
 }
 
 // stats retrieves the current pool stats, namely the number of pending and the
@@ -487,34 +734,50 @@ func (pool *TxPool) stats() (int, int) {
 
 // Content retrieves the data content of the transaction pool, returning all the
 // pending as well as queued transactions, grouped by account and sorted by nonce.
+// Private transactions are omitted.
 func (pool *TxPool) Content() (map[common.Address]types.Transactions, map[common.Address]types.Transactions) {
 	pool.mu.Lock()
 	defer pool.mu.Unlock()
 
 	pending := make(map[common.Address]types.Transactions, len(pool.pending))
 	for addr, list := range pool.pending {
-		pending[addr] = list.Flatten()
+		if txs := pool.public(list.Flatten()); len(txs) > 0 {
+			pending[addr] = txs
+		}
 	}
 	queued := make(map[common.Address]types.Transactions, len(pool.queue))
 	for addr, list := range pool.queue {
-		queued[addr] = list.Flatten()
+		if txs := pool.public(list.Flatten()); len(txs) > 0 {
+			queued[addr] = txs
+		}
+	}
+	if pool.blobs != nil {
+		for addr, txs := range pool.blobs.Content() {
+			if _, ok := pending[addr]; !ok {
+				pending[addr] = txs
+			}
+		}
 	}
 	return pending, queued
 }
 
 // ContentFrom retrieves the data content of the transaction pool, returning the
 // pending as well as queued transactions of this address, grouped by nonce.
+// Private transactions are omitted.
 func (pool *TxPool) ContentFrom(addr common.Address) (types.Transactions, types.Transactions) {
 	pool.mu.RLock()
 	defer pool.mu.RUnlock()
 
 	var pending types.Transactions
 	if list, ok := pool.pending[addr]; ok {
-		pending = list.Flatten()
+		pending = pool.public(list.Flatten())
 	}
 	var queued types.Transactions
 	if list, ok := pool.queue[addr]; ok {
-		queued = list.Flatten()
+		queued = pool.public(list.Flatten())
+	}
+	if pending == nil && pool.blobs != nil {
+		pending = pool.blobs.ContentFrom(addr)
 	}
 	return pending, queued
 }
@@ -547,6 +810,13 @@ func (pool *TxPool) Pending(enforceTips bool) map[common.Address]types.Transacti
 			pending[addr] = txs
 		}
 	}
+	if pool.blobs != nil {
+		for addr, txs := range pool.blobs.Pending(enforceTips) {
+			if _, ok := pending[addr]; !ok {
+				pending[addr] = txs
+			}
+		}
+	}
 	return pending
 }
 
@@ -561,19 +831,46 @@ func (pool *TxPool) Locals() []common.Address {
 // local retrieves all currently known local transactions, grouped by origin
 // account and sorted by nonce. The returned transaction set is a copy and can be
 // freely modified by calling code.
+//
+// Private transactions are omitted, as they would be announced to the network
+// if reloaded from the journal after a restart.
 func (pool *TxPool) local() map[common.Address]types.Transactions {
 	txs := make(map[common.Address]types.Transactions)
 	for addr := range pool.locals.accounts {
 		if pending := pool.pending[addr]; pending != nil {
-			txs[addr] = append(txs[addr], pending.Flatten()...)
+			txs[addr] = append(txs[addr], pool.public(pending.Flatten())...)
 		}
 		if queued := pool.queue[addr]; queued != nil {
-			txs[addr] = append(txs[addr], queued.Flatten()...)
+			txs[addr] = append(txs[addr], pool.public(queued.Flatten())...)
 		}
 	}
 	return txs
 }
 
+// writeSnapshot regenerates the pool snapshot with all the pending and queued
+// transactions, ordered by account and nonce. Private transactions are omitted.
+func (pool *TxPool) writeSnapshot() {
+	pool.mu.RLock()
+	var entries []snapshotEntry
+	for _, txs := range []map[common.Address]*list{pool.pending, pool.queue} {
+		for addr, list := range txs {
+			local := pool.locals.contains(addr)
+			for _, tx := range pool.public(list.Flatten()) {
+				entry := snapshotEntry{Tx: tx, Local: local}
+				if arrival := pool.all.Arrival(tx.Hash()); !arrival.IsZero() {
+					entry.Arrival = uint64(arrival.UnixNano())
+				}
+				entries = append(entries, entry)
+			}
+		}
+	}
+	pool.mu.RUnlock()
+
+	if err := pool.snapshot.write(entries); err != nil {
+		log.Warn("Failed to write transaction pool snapshot", "err", err)
+	}
+}
+
 // validateTxBasics checks whether a transaction is valid according to the consensus
 // rules, but does not check state-dependent validation such as sufficient balance.
 // This check is meant as an early check which only needs to be performed once,
@@ -632,7 +929,10 @@ func (pool *TxPool) validateTx(tx *types.Transaction, local bool) error {
 // If a newly added transaction is marked as local, its sending account will be
 // be added to the allowlist, preventing any associated transaction from being dropped
 // out of the pool due to pricing constraints.
-func (pool *TxPool) add(tx *types.Transaction, local bool) (replaced bool, err error) {
+//
+// New transactions are checked against the admission policies once validated,
+// whereas reinjected ones are not.
+func (pool *TxPool) add(tx *types.Transaction, local, admit bool) (replaced bool, err error) {
 	// If the transaction is already known, discard it
 	hash := tx.Hash()
 	if pool.all.Get(hash) != nil {
@@ -654,6 +954,16 @@ func (pool *TxPool) add(tx *types.Transaction, local bool) (replaced bool, err e
 	// already validated by this point
 	from, _ := types.Sender(pool.signer, tx)
 
+	// The account's nonces might be tracked by the blob pool
+	if pool.blobReserved[from] > 0 || (pool.blobs != nil && pool.blobs.HasAccount(from)) {
+		return false, ErrAlreadyReserved
+	}
+	// Refuse new transactions not allowed in by the admission policies
+	if admit {
+		if err := pool.admit(tx, from, isLocal); err != nil {
+			return false, err
+		}
+	}
 	// If the transaction pool is full, discard underpriced transactions
 	if uint64(pool.all.Slots()+numSlots(tx)) > pool.config.GlobalSlots+pool.config.GlobalQueue {
 		// If the new transaction is underpriced, don't accept it
@@ -711,6 +1021,7 @@ func (pool *TxPool) add(tx *types.Transaction, local bool) (replaced bool, err e
 			dropped := pool.removeTx(tx.Hash(), false)
 			pool.changesSinceReorg += dropped
 		}
+		pool.recordDrops(drop, DropUnderpriced)
 	}
 
 	// Try to replace an existing transaction in the pending pool
@@ -726,7 +1037,9 @@ func (pool *TxPool) add(tx *types.Transaction, local bool) (replaced bool, err e
 			pool.all.Remove(old.Hash())
 			pool.priced.Removed(1)
 			pendingReplaceMeter.Mark(1)
+			pool.recordEvent(TxPoolEvent{Kind: TxReplaced, Tx: old, Replacement: tx})
 		}
+		pool.recordEvent(TxPoolEvent{Kind: TxAdded, Tx: tx})
 		pool.all.Add(tx, isLocal)
 		pool.priced.Put(tx, isLocal)
 		pool.journalTx(from, tx)
@@ -735,6 +1048,9 @@ func (pool *TxPool) add(tx *types.Transaction, local bool) (replaced bool, err e
 
 		// Successful promotion, bump the heartbeat
 		pool.beats[from] = time.Now()
+		if admit {
+			pool.admitted(tx, from, isLocal)
+		}
 		return old != nil, nil
 	}
 	// New transaction isn't replacing a pending one, push into queue
@@ -742,6 +1058,7 @@ func (pool *TxPool) add(tx *types.Transaction, local bool) (replaced bool, err e
 	if err != nil {
 		return false, err
 	}
+	pool.recordEvent(TxPoolEvent{Kind: TxAdded, Tx: tx})
 	// Mark local addresses and journal local transactions
 	if local && !pool.locals.contains(from) {
 		log.Info("Setting new local account", "address", from)
@@ -752,7 +1069,9 @@ func (pool *TxPool) add(tx *types.Transaction, local bool) (replaced bool, err e
 		localGauge.Inc(1)
 	}
 	pool.journalTx(from, tx)
-
+	if admit {
+		pool.admitted(tx, from, isLocal)
+	}
 	log.Trace("Pooled new future transaction", "hash", hash, "from", from, "to", tx.To())
 	return replaced, nil
 }
@@ -801,6 +1120,7 @@ func (pool *TxPool) enqueueTx(hash common.Hash, tx *types.Transaction, local boo
 		pool.all.Remove(old.Hash())
 		pool.priced.Removed(1)
 		queuedReplaceMeter.Mark(1)
+		pool.recordEvent(TxPoolEvent{Kind: TxReplaced, Tx: old, Replacement: tx})
 	} else {
 		// Nothing was replaced, bump the queued counter
 		queuedGauge.Inc(1)
@@ -828,6 +1148,10 @@ func (pool *TxPool) journalTx(from common.Address, tx *types.Transaction) {
 	if pool.journal == nil || !pool.locals.contains(from) {
 		return
 	}
+	// Never journal private transactions, they would be announced on reload
+	if pool.IsPrivate(tx.Hash()) {
+		return
+	}
 	if err := pool.journal.insert(tx); err != nil {
 		log.Warn("Failed to journal local transaction", "err", err)
 	}
@@ -850,6 +1174,7 @@ func (pool *TxPool) promoteTx(addr common.Address, hash common.Hash, tx *types.T
 		pool.all.Remove(hash)
 		pool.priced.Removed(1)
 		pendingDiscardMeter.Mark(1)
+		pool.recordEvent(TxPoolEvent{Kind: TxReplaced, Tx: tx, Replacement: list.txs.Get(tx.Nonce())})
 		return false
 	}
 	// Otherwise discard any previous transaction and mark this
@@ -857,10 +1182,13 @@ func (pool *TxPool) promoteTx(addr common.Address, hash common.Hash, tx *types.T
 		pool.all.Remove(old.Hash())
 		pool.priced.Removed(1)
 		pendingReplaceMeter.Mark(1)
+		pool.recordEvent(TxPoolEvent{Kind: TxReplaced, Tx: old, Replacement: tx})
 	} else {
 		// Nothing was replaced, bump the pending counter
 		pendingGauge.Inc(1)
 	}
+	pool.recordEvent(TxPoolEvent{Kind: TxPromoted, Tx: tx})
+
 	// Set the potentially new pending nonce and notify any subsystems of the new tx
 	pool.pendingNonces.set(addr, tx.Nonce()+1)
 
@@ -914,14 +1242,233 @@ func (pool *TxPool) AddRemote(tx *types.Transaction) error {
 	return errs[0]
 }
 
+// AddPrivate enqueues a single private transaction into the pool if it is valid.
+// Private transactions are included by the local miner like any other, but are
+// never announced to the network, nor reported via the pool content. Unless
+// mined, they are dropped after the configured number of blocks.
+//
+// Private transactions are subject to the remote pricing constraints, since
+// marking their senders local would persist them in the journal.
+func (pool *TxPool) AddPrivate(tx *types.Transaction) error {
+	// Blob transactions are announced by the blob pool, they can't be private
+	if tx.Type() == types.BlobTxType {
+		return fmt.Errorf("%w: private blob transaction", core.ErrTxTypeNotSupported)
+	}
+	// Mark the transaction private before insertion, otherwise it might get
+	// announced in between
+	hash := tx.Hash()
+
+	pool.privateLock.Lock()
+	if _, ok := pool.private[hash]; ok && pool.Has(hash) {
+		pool.privateLock.Unlock()
+		knownTxMeter.Mark(1)
+		return ErrAlreadyKnown
+	}
+	pool.private[hash] = pool.currentHead.Load().Number.Uint64() + pool.config.PrivateExpiry
+	pool.privateLock.Unlock()
+
+	if err := pool.addTxs([]*types.Transaction{tx}, false, true)[0]; err != nil {
+		pool.privateLock.Lock()
+		delete(pool.private, hash)
+		pool.privateLock.Unlock()
+		return err
+	}
+	privateTxMeter.Mark(1)
+	return nil
+}
+
+// IsPrivate returns whether a transaction was submitted privately and must not
+// be announced to the network.
+func (pool *TxPool) IsPrivate(hash common.Hash) bool {
+	pool.privateLock.RLock()
+	defer pool.privateLock.RUnlock()
+
+	_, ok := pool.private[hash]
+	return ok
+}
+
+// PrivateContent retrieves all the private transactions currently in the pool,
+// pending and queued alike, grouped by account and sorted by nonce.
+func (pool *TxPool) PrivateContent() map[common.Address]types.Transactions {
+	pool.mu.RLock()
+	defer pool.mu.RUnlock()
+
+	pool.privateLock.RLock()
+	defer pool.privateLock.RUnlock()
+
+	content := make(map[common.Address]types.Transactions)
+	for hash := range pool.private {
+		if tx := pool.all.Get(hash); tx != nil {
+			addr, _ := types.Sender(pool.signer, tx) // already validated during insertion
+			content[addr] = append(content[addr], tx)
+		}
+	}
+	for _, txs := range content {
+		sort.Sort(types.TxByNonce(txs))
+	}
+	return content
+}
+
+// public filters the private transactions out of the given list.
+func (pool *TxPool) public(txs types.Transactions) types.Transactions {
+	pool.privateLock.RLock()
+	defer pool.privateLock.RUnlock()
+
+	if len(pool.private) == 0 {
+		return txs
+	}
+	public := txs[:0]
+	for _, tx := range txs {
+		if _, ok := pool.private[tx.Hash()]; !ok {
+			public = append(public, tx)
+		}
+	}
+	return public
+}
+
+// expirePrivate drops all private transactions which expired by the given head.
+// Expired transactions still in the pool are removed from it, whereas included
+// ones are only forgotten once expired, keeping them private if reorged out.
+//
+// Note, this method assumes the pool lock is held!
+func (pool *TxPool) expirePrivate(head *types.Header) {
+	pool.privateLock.Lock()
+	defer pool.privateLock.Unlock()
+
+	number := head.Number.Uint64()
+	for hash, expiry := range pool.private {
+		if number < expiry {
+			continue
+		}
+		delete(pool.private, hash)
+		if pool.all.Get(hash) != nil {
+			log.Debug("Dropping expired private transaction", "hash", hash, "expiry", expiry)
+			pool.removeTx(hash, true)
+			privateExpiredMeter.Mark(1)
+		}
+	}
+}
+
+// AddBlobs enqueues a batch of blob transactions into the blob pool along with
+// their sidecars, if they are valid. Blob transactions are rejected if their
+// senders have transactions in the main pool.
+//
+// Blob transactions submitted through the other Add methods are routed here, with
+// the sidecars they carry.
+func (pool *TxPool) AddBlobs(txs []*types.Transaction, sidecars []*types.BlobTxSidecar, local bool) []error {
+	errs := make([]error, len(txs))
+	if pool.blobs == nil {
+		for i := range errs {
+			errs[i] = ErrBlobPoolDisabled
+		}
+		return errs
+	}
+	var (
+		accepted []*types.Transaction
+		cars     []*types.BlobTxSidecar
+		index    []int
+	)
+	// Reserve the senders while the transactions are handed over, so the main
+	// pool can't accept transactions of theirs in between. The lock can't be
+	// held across the hand-over, as the blob pool announces the transactions.
+	var reserved []common.Address
+
+	pool.mu.Lock()
+	for i, tx := range txs {
+		from, err := types.Sender(pool.signer, tx)
+		if err != nil {
+			errs[i] = ErrInvalidSender
+			continue
+		}
+		if pool.pending[from] != nil || pool.queue[from] != nil {
+			errs[i] = ErrAlreadyReserved
+			continue
+		}
+		if err := pool.admit(tx, from, local); err != nil {
+			errs[i] = err
+			continue
+		}
+		var sidecar *types.BlobTxSidecar
+		if i < len(sidecars) {
+			sidecar = sidecars[i]
+		}
+		accepted, cars, index = append(accepted, tx), append(cars, sidecar), append(index, i)
+
+		pool.blobReserved[from]++
+		reserved = append(reserved, from)
+	}
+	pool.mu.Unlock()
+
+	added := pool.blobs.Add(accepted, cars, local)
+
+	pool.mu.Lock()
+	for i, err := range added {
+		if errs[index[i]] = err; err == nil {
+			pool.admitted(accepted[i], reserved[i], local)
+		}
+	}
+	for _, from := range reserved {
+		if pool.blobReserved[from]--; pool.blobReserved[from] == 0 {
+			delete(pool.blobReserved, from)
+		}
+	}
+	pool.mu.Unlock()
+	return errs
+}
+
+// SetAdmissionPolicies replaces the admission policies new transactions have to
+// pass before entering the pool, blob transactions included. Transactions that
+// are already pooled or reinjected after a reorg are not subject to them.
+func (pool *TxPool) SetAdmissionPolicies(policies ...AdmissionPolicy) {
+	if len(policies) == 0 {
+		pool.admission.Store(nil)
+		return
+	}
+	chain := AdmissionChain(policies)
+	pool.admission.Store(&chain)
+}
+
+// admit checks a new transaction against the admission policies.
+func (pool *TxPool) admit(tx *types.Transaction, from common.Address, local bool) error {
+	chain := pool.admission.Load()
+	if chain == nil {
+		return nil
+	}
+	if err := chain.Admit(tx, from, local); err != nil {
+		log.Trace("Rejecting transaction by admission policy", "hash", tx.Hash(), "err", err)
+		rejectedTxMeter.Mark(1)
+		return err
+	}
+	return nil
+}
+
+// admitted notifies the admission policies of a new transaction accepted into
+// the pool after passing them.
+func (pool *TxPool) admitted(tx *types.Transaction, from common.Address, local bool) {
+	if chain := pool.admission.Load(); chain != nil {
+		chain.Record(tx, from, local)
+	}
+}
+
 // addTxs attempts to queue a batch of transactions if they are valid.
 func (pool *TxPool) addTxs(txs []*types.Transaction, local, sync bool) []error {
 	// Filter out known ones without obtaining the pool lock or recovering signatures
 	var (
-		errs = make([]error, len(txs))
-		news = make([]*types.Transaction, 0, len(txs))
+		errs  = make([]error, len(txs))
+		news  = make([]*types.Transaction, 0, len(txs))
+		index = make([]int, 0, len(txs))
+
+		blobs    []*types.Transaction
+		sidecars []*types.BlobTxSidecar
+		blobIdx  []int
 	)
 	for i, tx := range txs {
+		// Blob transactions are handed over to the blob pool with their sidecars
+		if tx.Type() == types.BlobTxType {
+			blobs, sidecars = append(blobs, tx.WithBlobTxSidecar(nil)), append(sidecars, tx.BlobTxSidecar())
+			blobIdx = append(blobIdx, i)
+			continue
+		}
 		// If the transaction is known, pre-set the error slot
 		if pool.all.Get(tx.Hash()) != nil {
 			errs[i] = ErrAlreadyKnown
@@ -937,7 +1484,12 @@ func (pool *TxPool) addTxs(txs []*types.Transaction, local, sync bool) []error {
 			continue
 		}
 		// Accumulate all unknown transactions for deeper processing
-		news = append(news, tx)
+		news, index = append(news, tx), append(index, i)
+	}
+	if len(blobs) > 0 {
+		for i, err := range pool.AddBlobs(blobs, sidecars, local) {
+			errs[blobIdx[i]] = err
+		}
 	}
 	if len(news) == 0 {
 		return errs
@@ -945,16 +1497,11 @@ func (pool *TxPool) addTxs(txs []*types.Transaction, local, sync bool) []error {
 
 	// Process all the new transaction and merge any errors into the original slice
 	pool.mu.Lock()
-	newErrs, dirtyAddrs := pool.addTxsLocked(news, local)
+	newErrs, dirtyAddrs := pool.addTxsLocked(news, local, true)
 	pool.mu.Unlock()
 
-	var nilSlot = 0
-	for _, err := range newErrs {
-		for errs[nilSlot] != nil {
-			nilSlot++
-		}
-		errs[nilSlot] = err
-		nilSlot++
+	for i, err := range newErrs {
+		errs[index[i]] = err
 	}
 	// Reorg the pool internals if needed and return
 	done := pool.requestPromoteExecutables(dirtyAddrs)
@@ -964,13 +1511,14 @@ func (pool *TxPool) addTxs(txs []*types.Transaction, local, sync bool) []error {
 	return errs
 }
 
-// addTxsLocked attempts to queue a batch of transactions if they are valid.
+// addTxsLocked attempts to queue a batch of transactions if they are valid,
+// checking them against the admission policies if requested.
 // The transaction pool lock must be held.
-func (pool *TxPool) addTxsLocked(txs []*types.Transaction, local bool) ([]error, *accountSet) {
+func (pool *TxPool) addTxsLocked(txs []*types.Transaction, local, admit bool) ([]error, *accountSet) {
 	dirty := newAccountSet(pool.signer)
 	errs := make([]error, len(txs))
 	for i, tx := range txs {
-		replaced, err := pool.add(tx, local)
+		replaced, err := pool.add(tx, local, admit)
 		errs[i] = err
 		if err == nil && !replaced {
 			dirty.addTx(tx)
@@ -985,7 +1533,11 @@ func (pool *TxPool) addTxsLocked(txs []*types.Transaction, local bool) ([]error,
 func (pool *TxPool) Status(hashes []common.Hash) []TxStatus {
 	status := make([]TxStatus, len(hashes))
 	for i, hash := range hashes {
-		tx := pool.Get(hash)
+		if pool.blobs != nil && pool.blobs.Has(hash) {
+			status[i] = TxStatusPending
+			continue
+		}
+		tx := pool.all.Get(hash)
 		if tx == nil {
 			continue
 		}
@@ -1005,13 +1557,28 @@ func (pool *TxPool) Status(hashes []common.Hash) []TxStatus {
 
 // Get returns a transaction if it is contained in the pool and nil otherwise.
 func (pool *TxPool) Get(hash common.Hash) *types.Transaction {
-	return pool.all.Get(hash)
+	if tx := pool.all.Get(hash); tx != nil {
+		return tx
+	}
+	if pool.blobs != nil {
+		return pool.blobs.Get(hash)
+	}
+	return nil
+}
+
+// Arrival returns the time a transaction was added to the pool, or the zero time
+// if it's not tracked by the pool or is a blob transaction.
+func (pool *TxPool) Arrival(hash common.Hash) time.Time {
+	return pool.all.Arrival(hash)
 }
 
 // Has returns an indicator whether txpool has a transaction cached with the
 // given hash.
 func (pool *TxPool) Has(hash common.Hash) bool {
-	return pool.all.Get(hash) != nil
+	if pool.all.Get(hash) != nil {
+		return true
+	}
+	return pool.blobs != nil && pool.blobs.Has(hash)
 }
 
 // removeTx removes a single transaction from the queue, moving all subsequent
@@ -1044,6 +1611,7 @@ func (pool *TxPool) removeTx(hash common.Hash, outofbound bool) int {
 			for _, tx := range invalids {
 				// Internal shuffle shouldn't touch the lookup set.
 				pool.enqueueTx(tx.Hash(), tx, false, false)
+				pool.recordEvent(TxPoolEvent{Kind: TxDemoted, Tx: tx})
 			}
 			// Update the account nonce if needed
 			pool.pendingNonces.setIfLower(addr, tx.Nonce())
@@ -1208,6 +1776,8 @@ func (pool *TxPool) runReorg(done chan struct{}, reset *txpoolResetRequest, dirt
 	// because of another transaction (e.g. higher gas price).
 	if reset != nil {
 		pool.demoteUnexecutables()
+		pool.expirePrivate(pool.currentHead.Load())
+		pool.bundles.prune(pool.currentHead.Load().Number.Uint64())
 		if reset.newHead != nil && pool.chainconfig.IsLondon(new(big.Int).Add(reset.newHead.Number, big.NewInt(1))) {
 			pendingBaseFee := misc.CalcBaseFee(pool.chainconfig, reset.newHead)
 			pool.priced.SetBaseFee(pendingBaseFee)
@@ -1226,8 +1796,12 @@ func (pool *TxPool) runReorg(done chan struct{}, reset *txpoolResetRequest, dirt
 
 	dropBetweenReorgHistogram.Update(int64(pool.changesSinceReorg))
 	pool.changesSinceReorg = 0 // Reset change counter
+	txEvents := pool.takeEvents()
 	pool.mu.Unlock()
 
+	// Notify subscribers of the state changes of pooled transactions
+	pool.sendEvents(txEvents)
+
 	// Notify subsystems for newly added transactions
 	for _, tx := range promoted {
 		addr, _ := types.Sender(pool.signer, tx)
@@ -1327,7 +1901,7 @@ func (pool *TxPool) reset(oldHead, newHead *types.Header) {
 	// Inject any transactions discarded due to reorgs
 	log.Debug("Reinjecting stale transactions", "count", len(reinject))
 	core.SenderCacher.Recover(pool.signer, reinject)
-	pool.addTxsLocked(reinject, false)
+	pool.addTxsLocked(reinject, false, false)
 }
 
 // promoteExecutables moves transactions that have become processable from the
@@ -1350,6 +1924,7 @@ func (pool *TxPool) promoteExecutables(accounts []common.Address) []*types.Trans
 			hash := tx.Hash()
 			pool.all.Remove(hash)
 		}
+		pool.recordDrops(forwards, DropStale)
 		log.Trace("Removed old queued transactions", "count", len(forwards))
 		// Drop all transactions that are too costly (low balance or out of gas)
 		drops, _ := list.Filter(pool.currentState.GetBalance(addr), gasLimit)
@@ -1357,6 +1932,7 @@ func (pool *TxPool) promoteExecutables(accounts []common.Address) []*types.Trans
 			hash := tx.Hash()
 			pool.all.Remove(hash)
 		}
+		pool.recordDrops(drops, DropUnpayable)
 		log.Trace("Removed unpayable queued transactions", "count", len(drops))
 		queuedNofundsMeter.Mark(int64(len(drops)))
 
@@ -1380,6 +1956,7 @@ func (pool *TxPool) promoteExecutables(accounts []common.Address) []*types.Trans
 				pool.all.Remove(hash)
 				log.Trace("Removed cap-exceeding queued transaction", "hash", hash)
 			}
+			pool.recordDrops(caps, DropOverflow)
 			queuedRateLimitMeter.Mark(int64(len(caps)))
 		}
 		// Mark all the items dropped as removed
@@ -1446,6 +2023,7 @@ func (pool *TxPool) truncatePending() {
 						log.Trace("Removed fairness-exceeding pending transaction", "hash", hash)
 					}
 					pool.priced.Removed(len(caps))
+					pool.recordDrops(caps, DropOverflow)
 					pendingGauge.Dec(int64(len(caps)))
 					if pool.locals.contains(offenders[i]) {
 						localGauge.Dec(int64(len(caps)))
@@ -1473,6 +2051,7 @@ func (pool *TxPool) truncatePending() {
 					log.Trace("Removed fairness-exceeding pending transaction", "hash", hash)
 				}
 				pool.priced.Removed(len(caps))
+				pool.recordDrops(caps, DropOverflow)
 				pendingGauge.Dec(int64(len(caps)))
 				if pool.locals.contains(addr) {
 					localGauge.Dec(int64(len(caps)))
@@ -1512,9 +2091,11 @@ func (pool *TxPool) truncateQueue() {
 
 		// Drop all transactions if they are less than the overflow
 		if size := uint64(list.Len()); size <= drop {
-			for _, tx := range list.Flatten() {
+			txs := list.Flatten()
+			for _, tx := range txs {
 				pool.removeTx(tx.Hash(), true)
 			}
+			pool.recordDrops(txs, DropOverflow)
 			drop -= size
 			queuedRateLimitMeter.Mark(int64(size))
 			continue
@@ -1523,6 +2104,7 @@ func (pool *TxPool) truncateQueue() {
 		txs := list.Flatten()
 		for i := len(txs) - 1; i >= 0 && drop > 0; i-- {
 			pool.removeTx(txs[i].Hash(), true)
+			pool.recordDrops(txs[i:i+1], DropOverflow)
 			drop--
 			queuedRateLimitMeter.Mark(1)
 		}
@@ -1549,6 +2131,7 @@ func (pool *TxPool) demoteUnexecutables() {
 			pool.all.Remove(hash)
 			log.Trace("Removed old pending transaction", "hash", hash)
 		}
+		pool.recordDrops(olds, DropStale)
 		// Drop all transactions that are too costly (low balance or out of gas), and queue any invalids back for later
 		drops, invalids := list.Filter(pool.currentState.GetBalance(addr), gasLimit)
 		for _, tx := range drops {
@@ -1556,6 +2139,7 @@ func (pool *TxPool) demoteUnexecutables() {
 			log.Trace("Removed unpayable pending transaction", "hash", hash)
 			pool.all.Remove(hash)
 		}
+		pool.recordDrops(drops, DropUnpayable)
 		pendingNofundsMeter.Mark(int64(len(drops)))
 
 		for _, tx := range invalids {
@@ -1564,6 +2148,7 @@ func (pool *TxPool) demoteUnexecutables() {
 
 			// Internal shuffle shouldn't touch the lookup set.
 			pool.enqueueTx(hash, tx, false, false)
+			pool.recordEvent(TxPoolEvent{Kind: TxDemoted, Tx: tx})
 		}
 		pendingGauge.Dec(int64(len(olds) + len(drops) + len(invalids)))
 		if pool.locals.contains(addr) {
@@ -1578,6 +2163,7 @@ func (pool *TxPool) demoteUnexecutables() {
 
 				// Internal shuffle shouldn't touch the lookup set.
 				pool.enqueueTx(hash, tx, false, false)
+				pool.recordEvent(TxPoolEvent{Kind: TxDemoted, Tx: tx})
 			}
 			pendingGauge.Dec(int64(len(gapped)))
 		}
@@ -1683,17 +2269,25 @@ func (as *accountSet) merge(other *accountSet) {
 // This lookup set combines the notion of "local transactions", which is useful
 // to build upper-level structure.
 type lookup struct {
-	slots   int
-	lock    sync.RWMutex
-	locals  map[common.Hash]*types.Transaction
-	remotes map[common.Hash]*types.Transaction
+	slots    int
+	lock     sync.RWMutex
+	locals   map[common.Hash]*types.Transaction
+	remotes  map[common.Hash]*types.Transaction
+	arrivals map[common.Hash]time.Time
+
+	// retired tracks the arrival times of transactions recently removed from
+	// (or about to be restored into) the lookup, so transactions reinjected on
+	// a reorg or loaded from a snapshot keep their original arrival time.
+	retired lru.BasicLRU[common.Hash, time.Time]
 }
 
 // newLookup returns a new lookup structure.
 func newLookup() *lookup {
 	return &lookup{
-		locals:  make(map[common.Hash]*types.Transaction),
-		remotes: make(map[common.Hash]*types.Transaction),
+		locals:   make(map[common.Hash]*types.Transaction),
+		remotes:  make(map[common.Hash]*types.Transaction),
+		arrivals: make(map[common.Hash]time.Time),
+		retired:  lru.NewBasicLRU[common.Hash, time.Time](retiredArrivals),
 	}
 }
 
@@ -1731,6 +2325,28 @@ func (t *lookup) Get(hash common.Hash) *types.Transaction {
 	return t.remotes[hash]
 }
 
+// Arrival returns the time a transaction was added to the lookup, or the zero
+// time if not found.
+func (t *lookup) Arrival(hash common.Hash) time.Time {
+	t.lock.RLock()
+	defer t.lock.RUnlock()
+
+	return t.arrivals[hash]
+}
+
+// RestoreArrival records the time a transaction originally arrived at, to be
+// used instead of the current time once it's added to the lookup.
+func (t *lookup) RestoreArrival(hash common.Hash, arrival time.Time) {
+	t.lock.Lock()
+	defer t.lock.Unlock()
+
+	if _, ok := t.arrivals[hash]; ok {
+		t.arrivals[hash] = arrival
+		return
+	}
+	t.retired.Add(hash, arrival)
+}
+
 // GetLocal returns a transaction if it exists in the lookup, or nil if not found.
 func (t *lookup) GetLocal(hash common.Hash) *types.Transaction {
 	t.lock.RLock()
@@ -1792,6 +2408,12 @@ func (t *lookup) Add(tx *types.Transaction, local bool) {
 	} else {
 		t.remotes[tx.Hash()] = tx
 	}
+	if arrival, ok := t.retired.Get(tx.Hash()); ok {
+		t.retired.Remove(tx.Hash())
+		t.arrivals[tx.Hash()] = arrival
+	} else {
+		t.arrivals[tx.Hash()] = time.Now()
+	}
 }
 
 // Remove removes a transaction from the lookup.
@@ -1812,6 +2434,9 @@ func (t *lookup) Remove(hash common.Hash) {
 
 	delete(t.locals, hash)
 	delete(t.remotes, hash)
+
+	t.retired.Add(hash, t.arrivals[hash])
+	delete(t.arrivals, hash)
 }
 
 // RemoteToLocals migrates the transactions belongs to the given locals to locals
@@ -24,6 +24,7 @@ import (
 	"math/big"
 	"math/rand"
 	"os"
+	"path/filepath"
 	"sync/atomic"
 	"testing"
 	"time"
@@ -130,7 +131,7 @@ func setupPoolWithConfig(config *params.ChainConfig) (*TxPool, *ecdsa.PrivateKey
 	blockchain := newTestBlockChain(10000000, statedb, new(event.Feed))
 
 	key, _ := crypto.GenerateKey()
-	pool := New(testTxPoolConfig, config, blockchain)
+	pool := New(testTxPoolConfig, config, blockchain, nil)
 
 	// wait for the pool to initialize
 	<-pool.initDoneCh
@@ -247,7 +248,7 @@ func TestStateChangeDuringReset(t *testing.T) {
 	tx0 := transaction(0, 100000, key)
 	tx1 := transaction(1, 100000, key)
 
-	pool := New(testTxPoolConfig, params.TestChainConfig, blockchain)
+	pool := New(testTxPoolConfig, params.TestChainConfig, blockchain, nil)
 	defer pool.Stop()
 
 	nonce := pool.Nonce(address)
@@ -443,14 +444,14 @@ func TestChainFork(t *testing.T) {
 	resetState()
 
 	tx := transaction(0, 100000, key)
-	if _, err := pool.add(tx, false); err != nil {
+	if _, err := pool.add(tx, false, true); err != nil {
 		t.Error("didn't expect error", err)
 	}
 	pool.removeTx(tx.Hash(), true)
 
 	// reset the pool's internal state
 	resetState()
-	if _, err := pool.add(tx, false); err != nil {
+	if _, err := pool.add(tx, false, true); err != nil {
 		t.Error("didn't expect error", err)
 	}
 }
@@ -477,10 +478,10 @@ func TestDoubleNonce(t *testing.T) {
 	tx3, _ := types.SignTx(types.NewTransaction(0, common.Address{}, big.NewInt(100), 1000000, big.NewInt(1), nil), signer, key)
 
 	// Add the first two transaction, ensure higher priced stays only
-	if replace, err := pool.add(tx1, false); err != nil || replace {
+	if replace, err := pool.add(tx1, false, true); err != nil || replace {
 		t.Errorf("first transaction insert failed (%v) or reported replacement (%v)", err, replace)
 	}
-	if replace, err := pool.add(tx2, false); err != nil || !replace {
+	if replace, err := pool.add(tx2, false, true); err != nil || !replace {
 		t.Errorf("second transaction insert failed (%v) or not reported replacement (%v)", err, replace)
 	}
 	<-pool.requestPromoteExecutables(newAccountSet(signer, addr))
@@ -492,7 +493,7 @@ func TestDoubleNonce(t *testing.T) {
 	}
 
 	// Add the third transaction and ensure it's not saved (smaller price)
-	pool.add(tx3, false)
+	pool.add(tx3, false, true)
 	<-pool.requestPromoteExecutables(newAccountSet(signer, addr))
 	if pool.pending[addr].Len() != 1 {
 		t.Error("expected 1 pending transactions, got", pool.pending[addr].Len())
@@ -515,7 +516,7 @@ func TestMissingNonce(t *testing.T) {
 	addr := crypto.PubkeyToAddress(key.PublicKey)
 	testAddBalance(pool, addr, big.NewInt(100000000000000))
 	tx := transaction(1, 100000, key)
-	if _, err := pool.add(tx, false); err != nil {
+	if _, err := pool.add(tx, false, true); err != nil {
 		t.Error("didn't expect error", err)
 	}
 	if len(pool.pending) != 0 {
@@ -666,7 +667,7 @@ func TestPostponing(t *testing.T) {
 	statedb, _ := state.New(types.EmptyRootHash, state.NewDatabase(rawdb.NewMemoryDatabase()), nil)
 	blockchain := newTestBlockChain(1000000, statedb, new(event.Feed))
 
-	pool := New(testTxPoolConfig, params.TestChainConfig, blockchain)
+	pool := New(testTxPoolConfig, params.TestChainConfig, blockchain, nil)
 	defer pool.Stop()
 
 	// Create two test accounts to produce different gap profiles with
@@ -882,7 +883,7 @@ func testQueueGlobalLimiting(t *testing.T, nolocals bool) {
 	config.NoLocals = nolocals
 	config.GlobalQueue = config.AccountQueue*3 - 1 // reduce the queue limits to shorten test time (-1 to make it non divisible)
 
-	pool := New(config, params.TestChainConfig, blockchain)
+	pool := New(config, params.TestChainConfig, blockchain, nil)
 	defer pool.Stop()
 
 	// Create a number of test accounts and fund them (last one will be the local)
@@ -974,7 +975,7 @@ func testQueueTimeLimiting(t *testing.T, nolocals bool) {
 	config.Lifetime = time.Second
 	config.NoLocals = nolocals
 
-	pool := New(config, params.TestChainConfig, blockchain)
+	pool := New(config, params.TestChainConfig, blockchain, nil)
 	defer pool.Stop()
 
 	// Create two test accounts to ensure remotes expire but locals do not
@@ -1158,7 +1159,7 @@ func TestPendingGlobalLimiting(t *testing.T) {
 	config := testTxPoolConfig
 	config.GlobalSlots = config.AccountSlots * 10
 
-	pool := New(config, params.TestChainConfig, blockchain)
+	pool := New(config, params.TestChainConfig, blockchain, nil)
 	defer pool.Stop()
 
 	// Create a number of test accounts and fund them
@@ -1261,7 +1262,7 @@ func TestCapClearsFromAll(t *testing.T) {
 	config.AccountQueue = 2
 	config.GlobalSlots = 8
 
-	pool := New(config, params.TestChainConfig, blockchain)
+	pool := New(config, params.TestChainConfig, blockchain, nil)
 	defer pool.Stop()
 
 	// Create a number of test accounts and fund them
@@ -1293,7 +1294,7 @@ func TestPendingMinimumAllowance(t *testing.T) {
 	config := testTxPoolConfig
 	config.GlobalSlots = 1
 
-	pool := New(config, params.TestChainConfig, blockchain)
+	pool := New(config, params.TestChainConfig, blockchain, nil)
 	defer pool.Stop()
 
 	// Create a number of test accounts and fund them
@@ -1338,7 +1339,7 @@ func TestRepricing(t *testing.T) {
 	statedb, _ := state.New(types.EmptyRootHash, state.NewDatabase(rawdb.NewMemoryDatabase()), nil)
 	blockchain := newTestBlockChain(1000000, statedb, new(event.Feed))
 
-	pool := New(testTxPoolConfig, params.TestChainConfig, blockchain)
+	pool := New(testTxPoolConfig, params.TestChainConfig, blockchain, nil)
 	defer pool.Stop()
 
 	// Keep track of transaction events to ensure all executables get announced
@@ -1586,7 +1587,7 @@ func TestRepricingKeepsLocals(t *testing.T) {
 	statedb, _ := state.New(types.EmptyRootHash, state.NewDatabase(rawdb.NewMemoryDatabase()), nil)
 	blockchain := newTestBlockChain(1000000, statedb, new(event.Feed))
 
-	pool := New(testTxPoolConfig, eip1559Config, blockchain)
+	pool := New(testTxPoolConfig, eip1559Config, blockchain, nil)
 	defer pool.Stop()
 
 	// Create a number of test accounts and fund them
@@ -1663,7 +1664,7 @@ func TestUnderpricing(t *testing.T) {
 	config.GlobalSlots = 2
 	config.GlobalQueue = 2
 
-	pool := New(config, params.TestChainConfig, blockchain)
+	pool := New(config, params.TestChainConfig, blockchain, nil)
 	defer pool.Stop()
 
 	// Keep track of transaction events to ensure all executables get announced
@@ -1777,7 +1778,7 @@ func TestStableUnderpricing(t *testing.T) {
 	config.GlobalSlots = 128
 	config.GlobalQueue = 0
 
-	pool := New(config, params.TestChainConfig, blockchain)
+	pool := New(config, params.TestChainConfig, blockchain, nil)
 	defer pool.Stop()
 
 	// Keep track of transaction events to ensure all executables get announced
@@ -2005,7 +2006,7 @@ func TestDeduplication(t *testing.T) {
 	statedb, _ := state.New(types.EmptyRootHash, state.NewDatabase(rawdb.NewMemoryDatabase()), nil)
 	blockchain := newTestBlockChain(1000000, statedb, new(event.Feed))
 
-	pool := New(testTxPoolConfig, params.TestChainConfig, blockchain)
+	pool := New(testTxPoolConfig, params.TestChainConfig, blockchain, nil)
 	defer pool.Stop()
 
 	// Create a test account to add transactions with
@@ -2071,7 +2072,7 @@ func TestReplacement(t *testing.T) {
 	statedb, _ := state.New(types.EmptyRootHash, state.NewDatabase(rawdb.NewMemoryDatabase()), nil)
 	blockchain := newTestBlockChain(1000000, statedb, new(event.Feed))
 
-	pool := New(testTxPoolConfig, params.TestChainConfig, blockchain)
+	pool := New(testTxPoolConfig, params.TestChainConfig, blockchain, nil)
 	defer pool.Stop()
 
 	// Keep track of transaction events to ensure all executables get announced
@@ -2281,7 +2282,7 @@ func testJournaling(t *testing.T, nolocals bool) {
 	config.Journal = journal
 	config.Rejournal = time.Second
 
-	pool := New(config, params.TestChainConfig, blockchain)
+	pool := New(config, params.TestChainConfig, blockchain, nil)
 
 	// Create two test accounts to ensure remotes expire but locals do not
 	local, _ := crypto.GenerateKey()
@@ -2318,7 +2319,7 @@ func testJournaling(t *testing.T, nolocals bool) {
 	statedb.SetNonce(crypto.PubkeyToAddress(local.PublicKey), 1)
 	blockchain = newTestBlockChain(1000000, statedb, new(event.Feed))
 
-	pool = New(config, params.TestChainConfig, blockchain)
+	pool = New(config, params.TestChainConfig, blockchain, nil)
 
 	pending, queued = pool.Stats()
 	if queued != 0 {
@@ -2344,7 +2345,7 @@ func testJournaling(t *testing.T, nolocals bool) {
 
 	statedb.SetNonce(crypto.PubkeyToAddress(local.PublicKey), 1)
 	blockchain = newTestBlockChain(1000000, statedb, new(event.Feed))
-	pool = New(config, params.TestChainConfig, blockchain)
+	pool = New(config, params.TestChainConfig, blockchain, nil)
 
 	pending, queued = pool.Stats()
 	if pending != 0 {
@@ -2365,6 +2366,115 @@ func testJournaling(t *testing.T, nolocals bool) {
 	pool.Stop()
 }
 
+// Tests that the pool tracks the arrival time of transactions until they are
+// removed, and restores it if they are added back, e.g. on a reorg.
+func TestTransactionArrival(t *testing.T) {
+	t.Parallel()
+
+	pool, key := setupPool()
+	defer pool.Stop()
+
+	testAddBalance(pool, crypto.PubkeyToAddress(key.PublicKey), big.NewInt(1000000))
+
+	first, second := transaction(0, 100000, key), transaction(1, 100000, key)
+	if err := pool.addRemoteSync(first); err != nil {
+		t.Fatalf("failed to add first transaction: %v", err)
+	}
+	if err := pool.addRemoteSync(second); err != nil {
+		t.Fatalf("failed to add second transaction: %v", err)
+	}
+	arrival1, arrival2 := pool.Arrival(first.Hash()), pool.Arrival(second.Hash())
+	if arrival1.IsZero() || arrival2.IsZero() {
+		t.Fatalf("arrival times not tracked: %v, %v", arrival1, arrival2)
+	}
+	if arrival2.Before(arrival1) {
+		t.Errorf("arrival order mismatch: second %v before first %v", arrival2, arrival1)
+	}
+	pool.removeTx(first.Hash(), true)
+	if arrival := pool.Arrival(first.Hash()); !arrival.IsZero() {
+		t.Errorf("arrival time retained after removal: %v", arrival)
+	}
+	pool.mu.Lock()
+	pool.addTxsLocked([]*types.Transaction{first}, false, false)
+	pool.mu.Unlock()
+
+	if arrival := pool.Arrival(first.Hash()); !arrival.Equal(arrival1) {
+		t.Errorf("arrival time not restored on reinjection: have %v, want %v", arrival, arrival1)
+	}
+}
+
+// Tests that private transactions are hidden from the pool content and the
+// journal, and that they are dropped once expired.
+func TestPrivateTransactions(t *testing.T) {
+	t.Parallel()
+
+	// Create a journaled pool with a local account sending private transactions
+	statedb, _ := state.New(types.EmptyRootHash, state.NewDatabase(rawdb.NewMemoryDatabase()), nil)
+	blockchain := newTestBlockChain(1000000, statedb, new(event.Feed))
+
+	config := testTxPoolConfig
+	config.Journal = filepath.Join(t.TempDir(), "journal.rlp")
+	config.PrivateExpiry = 10
+
+	pool := New(config, params.TestChainConfig, blockchain, nil)
+	defer pool.Stop()
+
+	key, _ := crypto.GenerateKey()
+	addr := crypto.PubkeyToAddress(key.PublicKey)
+	testAddBalance(pool, addr, big.NewInt(1000000000))
+
+	if err := pool.AddLocal(pricedTransaction(0, 100000, big.NewInt(1), key)); err != nil {
+		t.Fatalf("failed to add local transaction: %v", err)
+	}
+	private := []*types.Transaction{
+		pricedTransaction(1, 100000, big.NewInt(1), key),
+		pricedTransaction(3, 100000, big.NewInt(1), key),
+	}
+	for i, tx := range private {
+		if err := pool.AddPrivate(tx); err != nil {
+			t.Fatalf("failed to add private transaction %d: %v", i, err)
+		}
+		if !pool.IsPrivate(tx.Hash()) {
+			t.Errorf("transaction %d not marked private", i)
+		}
+	}
+	if err := pool.AddPrivate(private[0]); !errors.Is(err, ErrAlreadyKnown) {
+		t.Errorf("duplicate private transaction error mismatch: have %v, want %v", err, ErrAlreadyKnown)
+	}
+	if pending, queued := pool.Stats(); pending != 2 || queued != 1 {
+		t.Fatalf("pool stats mismatch: have %d/%d, want %d/%d", pending, queued, 2, 1)
+	}
+	// Ensure the private transactions are hidden from the content and journal
+	pending, queued := pool.Content()
+	if len(pending[addr]) != 1 || len(queued[addr]) != 0 {
+		t.Errorf("public content mismatch: have %d/%d, want %d/%d", len(pending[addr]), len(queued[addr]), 1, 0)
+	}
+	if content := pool.PrivateContent(); len(content[addr]) != 2 || content[addr][0] != private[0] || content[addr][1] != private[1] {
+		t.Errorf("private content mismatch: have %v, want %v", content[addr], private)
+	}
+	if local := pool.local(); len(local[addr]) != 1 {
+		t.Errorf("journaled transactions mismatch: have %d, want %d", len(local[addr]), 1)
+	}
+	// Move the head right before the expiry and ensure nothing's dropped yet
+	<-pool.requestReset(nil, &types.Header{Number: big.NewInt(9), GasLimit: 1000000, BaseFee: big.NewInt(1)})
+	if pending, queued := pool.Stats(); pending != 2 || queued != 1 {
+		t.Fatalf("pool stats mismatch: have %d/%d, want %d/%d", pending, queued, 2, 1)
+	}
+	// Reach the expiry and ensure the private transactions are dropped
+	<-pool.requestReset(nil, &types.Header{Number: big.NewInt(10), GasLimit: 1000000, BaseFee: big.NewInt(1)})
+	if pending, queued := pool.Stats(); pending != 1 || queued != 0 {
+		t.Fatalf("pool stats mismatch: have %d/%d, want %d/%d", pending, queued, 1, 0)
+	}
+	for i, tx := range private {
+		if pool.IsPrivate(tx.Hash()) {
+			t.Errorf("expired transaction %d still tracked", i)
+		}
+	}
+	if err := validatePoolInternals(pool); err != nil {
+		t.Fatalf("pool internal state corrupted: %v", err)
+	}
+}
+
 // TestStatusCheck tests that the pool can correctly retrieve the
 // pending status of individual transactions.
 func TestStatusCheck(t *testing.T) {
@@ -2374,7 +2484,7 @@ func TestStatusCheck(t *testing.T) {
 	statedb, _ := state.New(types.EmptyRootHash, state.NewDatabase(rawdb.NewMemoryDatabase()), nil)
 	blockchain := newTestBlockChain(1000000, statedb, new(event.Feed))
 
-	pool := New(testTxPoolConfig, params.TestChainConfig, blockchain)
+	pool := New(testTxPoolConfig, params.TestChainConfig, blockchain, nil)
 	defer pool.Stop()
 
 	// Create the test accounts to check various transaction statuses with
@@ -90,6 +90,9 @@ type Header struct {
 
 	// DataGasUsed was added by EIP-4844 and is ignored in legacy headers.
 	DataGasUsed *uint64 `json:"dataGasUsed" rlp:"optional"`
+
+	// ParentBeaconRoot was added by EIP-4788 and is ignored in legacy headers.
+	ParentBeaconRoot *common.Hash `json:"parentBeaconBlockRoot" rlp:"optional"`
 }
 
 // field type overrides for gencodec
@@ -284,6 +287,18 @@ func CopyHeader(h *Header) *Header {
 		cpy.WithdrawalsHash = new(common.Hash)
 		*cpy.WithdrawalsHash = *h.WithdrawalsHash
 	}
+	if h.ExcessDataGas != nil {
+		cpy.ExcessDataGas = new(uint64)
+		*cpy.ExcessDataGas = *h.ExcessDataGas
+	}
+	if h.DataGasUsed != nil {
+		cpy.DataGasUsed = new(uint64)
+		*cpy.DataGasUsed = *h.DataGasUsed
+	}
+	if h.ParentBeaconRoot != nil {
+		cpy.ParentBeaconRoot = new(common.Hash)
+		*cpy.ParentBeaconRoot = *h.ParentBeaconRoot
+	}
 	return &cpy
 }
 
@@ -370,6 +385,15 @@ func (b *Block) DataGasUsed() *uint64 {
 	return dataGasUsed
 }
 
+func (b *Block) BeaconRoot() *common.Hash {
+	var beaconRoot *common.Hash
+	if b.header.ParentBeaconRoot != nil {
+		beaconRoot = new(common.Hash)
+		*beaconRoot = *b.header.ParentBeaconRoot
+	}
+	return beaconRoot
+}
+
 func (b *Block) Header() *Header { return CopyHeader(b.header) }
 
 // Body returns the non-header content of the block.
@@ -484,6 +484,33 @@ func TestReceiptUnmarshalBinary(t *testing.T) {
 	}
 }
 
+func TestBlobReceiptEncoding(t *testing.T) {
+	receipt := &Receipt{
+		Status:            ReceiptStatusSuccessful,
+		CumulativeGasUsed: 21000,
+		Logs:              []*Log{},
+		Type:              BlobTxType,
+	}
+	receipt.Bloom = CreateBloom(Receipts{receipt})
+
+	have, err := receipt.MarshalBinary()
+	if err != nil {
+		t.Fatalf("marshal binary error: %v", err)
+	}
+	buf := new(bytes.Buffer)
+	Receipts{receipt}.EncodeIndex(0, buf)
+	if !bytes.Equal(have, buf.Bytes()) {
+		t.Errorf("BinaryMarshal and EncodeIndex mismatch, got %x want %x", have, buf.Bytes())
+	}
+	got := new(Receipt)
+	if err := got.UnmarshalBinary(have); err != nil {
+		t.Fatalf("unmarshal binary error: %v", err)
+	}
+	if !reflect.DeepEqual(got, receipt) {
+		t.Errorf("receipt unmarshalled from binary mismatch, got %v want %v", got, receipt)
+	}
+}
+
 func clearComputedFieldsOnReceipts(receipts []*Receipt) []*Receipt {
 	r := make([]*Receipt, len(receipts))
 	for i, receipt := range receipts {
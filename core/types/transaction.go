@@ -50,8 +50,9 @@ const (
 
 // Transaction is an Ethereum transaction.
 type Transaction struct {
-	inner TxData    // Consensus contents of a transaction
-	time  time.Time // Time first seen locally (spam avoidance)
+	inner   TxData         // Consensus contents of a transaction
+	time    time.Time      // Time first seen locally (spam avoidance)
+	sidecar *BlobTxSidecar // Blob sidecar relayed along a blob transaction, not part of the consensus encoding
 
 	// caches
 	hash atomic.Value
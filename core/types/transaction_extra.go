@@ -1,24 +1,56 @@
 package types
 
 import (
-	"bufio"
-	"encoding/base64"
-	"github.com/ethereum/go-ethereum/common"
-	"github.com/ethereum/go-ethereum/log"
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
 	"math/big"
-	"os"
+	"sort"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/common/hexutil"
+	"github.com/ethereum/go-ethereum/rlp"
 )
 
+// TxExtraVersion is the version of the TxExtra encoding produced by this package.
+const TxExtraVersion = 1
+
+// ErrTxExtraVersion is returned when decoding a TxExtra of an unknown version.
+var ErrTxExtraVersion = errors.New("unsupported tx witness version")
+
+// TxExtra is the state witness of a single transaction: the state roots before
+// and after its execution, together with the pre- and post-state of every
+// account, storage slot and contract code it touched. A nil account marks an
+// address that is absent from the state.
+//
+// Every witnessed account and storage slot carries a Merkle proof against the
+// pre- and post-state root respectively, so the witness can be authenticated
+// by anyone knowing the two roots. Deleting keys might collapse trie nodes into
+// siblings that no witnessed key leads to, those siblings are carried along as
+// additional pre-state trie nodes.
 type TxExtra struct {
 	TxHash        common.Hash
 	PreStateRoot  common.Hash
 	PostStateRoot common.Hash
 
-	PreState map[common.Address]*StateAccount
+	PreState  map[common.Address]*StateAccount
+	PostState map[common.Address]*StateAccount
+
+	PreStorage  map[common.Address]map[common.Hash]common.Hash
+	PostStorage map[common.Address]map[common.Hash]common.Hash
+
+	PreCode  map[common.Address][]byte
+	PostCode map[common.Address][]byte
+
+	PreStateProof  map[common.Address][][]byte
+	PostStateProof map[common.Address][][]byte
 
-	PreStorage map[common.Address]map[common.Hash]common.Hash
+	PreStorageProof  map[common.Address]map[common.Hash][][]byte
+	PostStorageProof map[common.Address]map[common.Hash][][]byte
 
-	PreCode map[common.Address][]byte
+	PreStateNodes [][]byte
 }
 
 func NewTxExtra(hash common.Hash) *TxExtra {
@@ -27,8 +59,16 @@ func NewTxExtra(hash common.Hash) *TxExtra {
 		PreStateRoot:  common.Hash{},
 		PostStateRoot: common.Hash{},
 		PreState:      map[common.Address]*StateAccount{},
+		PostState:     map[common.Address]*StateAccount{},
 		PreStorage:    map[common.Address]map[common.Hash]common.Hash{},
+		PostStorage:   map[common.Address]map[common.Hash]common.Hash{},
 		PreCode:       map[common.Address][]byte{},
+		PostCode:      map[common.Address][]byte{},
+
+		PreStateProof:    map[common.Address][][]byte{},
+		PostStateProof:   map[common.Address][][]byte{},
+		PreStorageProof:  map[common.Address]map[common.Hash][][]byte{},
+		PostStorageProof: map[common.Address]map[common.Hash][][]byte{},
 	}
 }
 
@@ -47,13 +87,33 @@ func (t *TxExtra) AddPreState(address common.Address, stateAccount *StateAccount
 	t.PreState[address] = stateAccount
 }
 
+func (t *TxExtra) AddPostState(address common.Address, stateAccount *StateAccount) {
+	if t.PostState == nil {
+		t.PostState = map[common.Address]*StateAccount{}
+	}
+	t.PostState[address] = stateAccount
+}
+
 func (t *TxExtra) AddPreStorage(address common.Address, key, value common.Hash) {
+	if t.PreStorage == nil {
+		t.PreStorage = map[common.Address]map[common.Hash]common.Hash{}
+	}
 	if t.PreStorage[address] == nil {
 		t.PreStorage[address] = map[common.Hash]common.Hash{}
 	}
 	t.PreStorage[address][key] = value
 }
 
+func (t *TxExtra) AddPostStorage(address common.Address, key, value common.Hash) {
+	if t.PostStorage == nil {
+		t.PostStorage = map[common.Address]map[common.Hash]common.Hash{}
+	}
+	if t.PostStorage[address] == nil {
+		t.PostStorage[address] = map[common.Hash]common.Hash{}
+	}
+	t.PostStorage[address][key] = value
+}
+
 func (t *TxExtra) AddPreCode(address common.Address, enc []byte) {
 	if t.PreCode == nil {
 		t.PreCode = map[common.Address][]byte{}
@@ -61,218 +121,441 @@ func (t *TxExtra) AddPreCode(address common.Address, enc []byte) {
 	t.PreCode[address] = enc
 }
 
-// MinerExtra File
-
-func InitFile(blockNumber *big.Int) {
-	var f *os.File
-	var err error
-	left := new(big.Int).Quo(blockNumber, new(big.Int).SetUint64(1000))
-	os.MkdirAll("./minerExtra/"+left.String(), 0755)
-	fileName := "./minerExtra/" + left.String() + "/" + blockNumber.String() + ".txt"
-	if CheckFileExist(fileName) { //文件存在
-		os.Remove(fileName)
+func (t *TxExtra) AddPostCode(address common.Address, enc []byte) {
+	if t.PostCode == nil {
+		t.PostCode = map[common.Address][]byte{}
 	}
-	f, err = os.Create(fileName) //创建文件
-	if err != nil {
-		log.Info("创建文件出错", "file create fail", err)
-		return
-	}
-	defer f.Close()
-
+	t.PostCode[address] = enc
 }
 
-func InitTxFile(blockNumber *big.Int) {
-	var f *os.File
-	var err error
-	left := new(big.Int).Quo(blockNumber, new(big.Int).SetUint64(1000))
-	os.MkdirAll("./minerExtra/temp/"+left.String(), 0755)
-	os.MkdirAll("./minerExtra/temp/C"+left.String(), 0755)
-	fileName := "./minerExtra/temp/" + left.String() + "/" + blockNumber.String() + ".txt"
-	fileName2 := "./minerExtra/temp/C" + left.String() + "/" + blockNumber.String() + ".txt"
-	if CheckFileExist(fileName) { //文件存在
-		os.Remove(fileName)
+func (t *TxExtra) AddPreStateProof(address common.Address, proof [][]byte) {
+	if t.PreStateProof == nil {
+		t.PreStateProof = map[common.Address][][]byte{}
 	}
-	if CheckFileExist(fileName2) { //文件存在
-		os.Remove(fileName2)
-	}
-	f, err = os.Create(fileName) //创建文件
-	if err != nil {
-		log.Info("InitTxFile出错", "file create fail", err)
-		return
-	}
-	defer f.Close()
+	t.PreStateProof[address] = proof
 }
 
-func ReNameTxFile(blockNumber *big.Int) {
-	left := new(big.Int).Quo(blockNumber, new(big.Int).SetUint64(1000))
-	oldName := "./minerExtra/temp/" + left.String() + "/" + blockNumber.String() + ".txt"
-	newName := "./minerExtra/temp/C" + left.String() + "/" + blockNumber.String() + ".txt"
-	err := os.Rename(oldName, newName)
-	if err != nil {
-		log.Info("ReNameTxFileErr", "error", err)
+func (t *TxExtra) AddPostStateProof(address common.Address, proof [][]byte) {
+	if t.PostStateProof == nil {
+		t.PostStateProof = map[common.Address][][]byte{}
 	}
+	t.PostStateProof[address] = proof
 }
 
-func DelTxFile(blockNumber *big.Int) {
-	left := new(big.Int).Quo(blockNumber, new(big.Int).SetUint64(1000))
-	fileName := "./minerExtra/temp/" + left.String() + "/" + blockNumber.String() + ".txt"
-	fileName2 := "./minerExtra/temp/C" + left.String() + "/" + blockNumber.String() + ".txt"
-	if CheckFileExist(fileName) { //文件存在
-		os.Remove(fileName)
+func (t *TxExtra) AddPreStorageProof(address common.Address, key common.Hash, proof [][]byte) {
+	if t.PreStorageProof == nil {
+		t.PreStorageProof = map[common.Address]map[common.Hash][][]byte{}
 	}
-	if CheckFileExist(fileName2) { //文件存在
-		os.Remove(fileName2)
+	if t.PreStorageProof[address] == nil {
+		t.PreStorageProof[address] = map[common.Hash][][]byte{}
 	}
+	t.PreStorageProof[address][key] = proof
 }
 
-func WriteHash(blockNumber *big.Int, hash common.Hash) {
-	WriteTxFile(blockNumber, hash.String())
+// AddPreStateNodes adds pre-state trie nodes needed to delete keys from the state.
+func (t *TxExtra) AddPreStateNodes(nodes [][]byte) {
+	t.PreStateNodes = append(t.PreStateNodes, nodes...)
 }
 
-func WriteStateKey(blockNumber *big.Int, address common.Address) {
-	WriteTxFile(blockNumber, "0\t"+address.String())
+func (t *TxExtra) AddPostStorageProof(address common.Address, key common.Hash, proof [][]byte) {
+	if t.PostStorageProof == nil {
+		t.PostStorageProof = map[common.Address]map[common.Hash][][]byte{}
+	}
+	if t.PostStorageProof[address] == nil {
+		t.PostStorageProof[address] = map[common.Hash][][]byte{}
+	}
+	t.PostStorageProof[address][key] = proof
 }
 
-func WriteStorageKey(blockNumber *big.Int, address common.Address, key common.Hash) {
-	WriteTxFile(blockNumber, "1\t"+address.String()+"\t"+key.String())
+// txExtraRLP is the versioned envelope of an RLP encoded TxExtra. The payload
+// is interpreted according to the version.
+type txExtraRLP struct {
+	Version uint64
+	Payload rlp.RawValue
 }
-func WriteTxCode(blockNumber *big.Int, address common.Address) {
-	WriteTxFile(blockNumber, "2\t"+address.String())
+
+// txExtraV1 is the version 1 payload of a TxExtra, with the witness maps
+// flattened into lists sorted by key so the encoding is deterministic.
+type txExtraV1 struct {
+	TxHash        common.Hash
+	PreStateRoot  common.Hash
+	PostStateRoot common.Hash
+	PreState      []extraAccountRLP
+	PostState     []extraAccountRLP
+	PreStorage    []extraStorageRLP
+	PostStorage   []extraStorageRLP
+	PreCode       []extraCodeRLP
+	PostCode      []extraCodeRLP
+
+	PreStateProof    []extraAccountProofRLP `rlp:"optional"`
+	PostStateProof   []extraAccountProofRLP `rlp:"optional"`
+	PreStorageProof  []extraStorageProofRLP `rlp:"optional"`
+	PostStorageProof []extraStorageProofRLP `rlp:"optional"`
+	PreStateNodes    [][]byte               `rlp:"optional"`
 }
 
-func WriteTxFile(blockNumber *big.Int, text string) {
-	left := new(big.Int).Quo(blockNumber, new(big.Int).SetUint64(1000))
-	filePath := "./minerExtra/temp/" + left.String() + "/" + blockNumber.String() + ".txt"
-	file, err := os.OpenFile(filePath, os.O_WRONLY|os.O_APPEND, 0666)
-	if err != nil {
-		//log.Info("文件打开失败", "文件打开失败", err, "string", text)
-	}
-	//及时关闭file句柄
-	defer file.Close()
-	//写入文件时，使用带缓存的 *Writer
-	write := bufio.NewWriter(file)
-	write.WriteString(text + "\n")
-	//Flush将缓存的文件真正写入到文件中
-	write.Flush()
+type extraAccountRLP struct {
+	Address common.Address
+	Account *StateAccount `rlp:"nil"`
 }
 
-func ReadTxFile(blockNumber *big.Int, hash common.Hash) []string {
-	var lines []string
-	left := new(big.Int).Quo(blockNumber, new(big.Int).SetUint64(1000))
-	myfile, err := os.Open("./minerExtra/temp/C" + left.String() + "/" + blockNumber.String() + ".txt") //open the file
-	if err != nil {
-		log.Info("ReadTxFile", "Error opening file:", err)
-		return lines
-	}
-	defer myfile.Close()
-	scanner := bufio.NewScanner(myfile)
-	start := false
-	for scanner.Scan() {
-		if start {
-			if scanner.Text() == hash.String() {
-				break
-			}
-			lines = append(lines, scanner.Text())
-		}
-		if scanner.Text() == hash.String() {
-			start = true
-		}
-	}
-	return lines
+type extraStorageRLP struct {
+	Address common.Address
+	Key     common.Hash
+	Value   common.Hash
 }
 
-func WriteTxHash(blockNumber *big.Int, hash common.Hash) {
-	WriteFile(blockNumber, "txHash\t"+hash.String())
+type extraCodeRLP struct {
+	Address common.Address
+	Code    []byte
 }
 
-func WritePreRoot(blockNumber *big.Int, hash common.Hash) {
-	WriteFile(blockNumber, "preStateRoot\t"+hash.String())
+type extraAccountProofRLP struct {
+	Address common.Address
+	Proof   [][]byte
 }
 
-func WritePostRoot(blockNumber *big.Int, hash common.Hash) {
-	WriteFile(blockNumber, "postStateRoot\t"+hash.String())
+type extraStorageProofRLP struct {
+	Address common.Address
+	Key     common.Hash
+	Proof   [][]byte
 }
 
-func WritePreState(blockNumber *big.Int, address common.Address, enc []byte) {
-	WriteFile(blockNumber, "preState\t"+address.String()+"\t"+base64.StdEncoding.EncodeToString(enc))
+func flattenAccounts(accounts map[common.Address]*StateAccount) []extraAccountRLP {
+	list := make([]extraAccountRLP, 0, len(accounts))
+	for addr, account := range accounts {
+		list = append(list, extraAccountRLP{addr, account})
+	}
+	sort.Slice(list, func(i, j int) bool {
+		return bytes.Compare(list[i].Address[:], list[j].Address[:]) < 0
+	})
+	return list
 }
 
-func WritePostState(blockNumber *big.Int, address common.Address, enc []byte) {
-	WriteFile(blockNumber, "postState\t"+address.String()+"\t"+base64.StdEncoding.EncodeToString(enc))
+func flattenStorage(storage map[common.Address]map[common.Hash]common.Hash) []extraStorageRLP {
+	var list []extraStorageRLP
+	for addr, slots := range storage {
+		for key, val := range slots {
+			list = append(list, extraStorageRLP{addr, key, val})
+		}
+	}
+	sort.Slice(list, func(i, j int) bool {
+		if c := bytes.Compare(list[i].Address[:], list[j].Address[:]); c != 0 {
+			return c < 0
+		}
+		return bytes.Compare(list[i].Key[:], list[j].Key[:]) < 0
+	})
+	return list
 }
 
-func WritePreStateProof(blockNumber *big.Int, address common.Address, path [][]byte) {
-	text := ""
-	for _, bytes := range path {
-		text += "\t" + base64.StdEncoding.EncodeToString(bytes)
+func flattenCode(codes map[common.Address][]byte) []extraCodeRLP {
+	list := make([]extraCodeRLP, 0, len(codes))
+	for addr, code := range codes {
+		list = append(list, extraCodeRLP{addr, code})
 	}
+	sort.Slice(list, func(i, j int) bool {
+		return bytes.Compare(list[i].Address[:], list[j].Address[:]) < 0
+	})
+	return list
+}
 
-	WriteFile(blockNumber, "preStateProof\t"+address.String()+text)
+func flattenAccountProofs(proofs map[common.Address][][]byte) []extraAccountProofRLP {
+	var list []extraAccountProofRLP
+	for addr, proof := range proofs {
+		list = append(list, extraAccountProofRLP{addr, proof})
+	}
+	sort.Slice(list, func(i, j int) bool {
+		return bytes.Compare(list[i].Address[:], list[j].Address[:]) < 0
+	})
+	return list
 }
 
-func WritePostStateProof(blockNumber *big.Int, address common.Address, path [][]byte) {
-	text := ""
-	for _, bytes := range path {
-		text += "\t" + base64.StdEncoding.EncodeToString(bytes)
+func flattenStorageProofs(proofs map[common.Address]map[common.Hash][][]byte) []extraStorageProofRLP {
+	var list []extraStorageProofRLP
+	for addr, slots := range proofs {
+		for key, proof := range slots {
+			list = append(list, extraStorageProofRLP{addr, key, proof})
+		}
+	}
+	sort.Slice(list, func(i, j int) bool {
+		if c := bytes.Compare(list[i].Address[:], list[j].Address[:]); c != 0 {
+			return c < 0
+		}
+		return bytes.Compare(list[i].Key[:], list[j].Key[:]) < 0
+	})
+	return list
+}
+
+// EncodeRLP implements rlp.Encoder, always producing the latest version.
+func (t *TxExtra) EncodeRLP(w io.Writer) error {
+	payload, err := rlp.EncodeToBytes(&txExtraV1{
+		TxHash:        t.TxHash,
+		PreStateRoot:  t.PreStateRoot,
+		PostStateRoot: t.PostStateRoot,
+		PreState:      flattenAccounts(t.PreState),
+		PostState:     flattenAccounts(t.PostState),
+		PreStorage:    flattenStorage(t.PreStorage),
+		PostStorage:   flattenStorage(t.PostStorage),
+		PreCode:       flattenCode(t.PreCode),
+		PostCode:      flattenCode(t.PostCode),
+
+		PreStateProof:    flattenAccountProofs(t.PreStateProof),
+		PostStateProof:   flattenAccountProofs(t.PostStateProof),
+		PreStorageProof:  flattenStorageProofs(t.PreStorageProof),
+		PostStorageProof: flattenStorageProofs(t.PostStorageProof),
+		PreStateNodes:    t.PreStateNodes,
+	})
+	if err != nil {
+		return err
 	}
+	return rlp.Encode(w, &txExtraRLP{Version: TxExtraVersion, Payload: payload})
+}
 
-	WriteFile(blockNumber, "postStateProof\t"+address.String()+text)
+// DecodeRLP implements rlp.Decoder.
+func (t *TxExtra) DecodeRLP(s *rlp.Stream) error {
+	var env txExtraRLP
+	if err := s.Decode(&env); err != nil {
+		return err
+	}
+	if env.Version != TxExtraVersion {
+		return fmt.Errorf("%w: %d", ErrTxExtraVersion, env.Version)
+	}
+	var dec txExtraV1
+	if err := rlp.DecodeBytes(env.Payload, &dec); err != nil {
+		return err
+	}
+	*t = *NewTxExtra(dec.TxHash)
+	t.PreStateRoot, t.PostStateRoot = dec.PreStateRoot, dec.PostStateRoot
+	for _, entry := range dec.PreState {
+		t.AddPreState(entry.Address, entry.Account)
+	}
+	for _, entry := range dec.PostState {
+		t.AddPostState(entry.Address, entry.Account)
+	}
+	for _, entry := range dec.PreStorage {
+		t.AddPreStorage(entry.Address, entry.Key, entry.Value)
+	}
+	for _, entry := range dec.PostStorage {
+		t.AddPostStorage(entry.Address, entry.Key, entry.Value)
+	}
+	for _, entry := range dec.PreCode {
+		t.AddPreCode(entry.Address, entry.Code)
+	}
+	for _, entry := range dec.PostCode {
+		t.AddPostCode(entry.Address, entry.Code)
+	}
+	for _, entry := range dec.PreStateProof {
+		t.AddPreStateProof(entry.Address, entry.Proof)
+	}
+	for _, entry := range dec.PostStateProof {
+		t.AddPostStateProof(entry.Address, entry.Proof)
+	}
+	for _, entry := range dec.PreStorageProof {
+		t.AddPreStorageProof(entry.Address, entry.Key, entry.Proof)
+	}
+	for _, entry := range dec.PostStorageProof {
+		t.AddPostStorageProof(entry.Address, entry.Key, entry.Proof)
+	}
+	t.AddPreStateNodes(dec.PreStateNodes)
+	return nil
+}
+
+// extraAccountJSON is the JSON representation of a witnessed account.
+type extraAccountJSON struct {
+	Nonce       hexutil.Uint64 `json:"nonce"`
+	Balance     *hexutil.Big   `json:"balance"`
+	StorageRoot common.Hash    `json:"storageRoot"`
+	CodeHash    hexutil.Bytes  `json:"codeHash"`
+}
+
+// txExtraJSON is the JSON representation of a TxExtra. Maps are keyed by their
+// hex encoded keys, which encoding/json emits in sorted order.
+type txExtraJSON struct {
+	Version       hexutil.Uint64                                 `json:"version"`
+	TxHash        common.Hash                                    `json:"txHash"`
+	PreStateRoot  common.Hash                                    `json:"preStateRoot"`
+	PostStateRoot common.Hash                                    `json:"postStateRoot"`
+	PreState      map[common.Address]*extraAccountJSON           `json:"preState"`
+	PostState     map[common.Address]*extraAccountJSON           `json:"postState"`
+	PreStorage    map[common.Address]map[common.Hash]common.Hash `json:"preStorage"`
+	PostStorage   map[common.Address]map[common.Hash]common.Hash `json:"postStorage"`
+	PreCode       map[common.Address]hexutil.Bytes               `json:"preCode"`
+	PostCode      map[common.Address]hexutil.Bytes               `json:"postCode"`
+
+	PreStateProof    map[common.Address][]hexutil.Bytes                 `json:"preStateProof"`
+	PostStateProof   map[common.Address][]hexutil.Bytes                 `json:"postStateProof"`
+	PreStorageProof  map[common.Address]map[common.Hash][]hexutil.Bytes `json:"preStorageProof"`
+	PostStorageProof map[common.Address]map[common.Hash][]hexutil.Bytes `json:"postStorageProof"`
+	PreStateNodes    []hexutil.Bytes                                    `json:"preStateNodes,omitempty"`
+}
+
+func accountsToJSON(accounts map[common.Address]*StateAccount) map[common.Address]*extraAccountJSON {
+	enc := make(map[common.Address]*extraAccountJSON, len(accounts))
+	for addr, account := range accounts {
+		if account == nil {
+			enc[addr] = nil
+			continue
+		}
+		enc[addr] = &extraAccountJSON{
+			Nonce:       hexutil.Uint64(account.Nonce),
+			Balance:     (*hexutil.Big)(account.Balance),
+			StorageRoot: account.Root,
+			CodeHash:    account.CodeHash,
+		}
+	}
+	return enc
 }
 
-func WritePreStorage(blockNumber *big.Int, address common.Address, hash, val common.Hash) {
-	WriteFile(blockNumber, "preStorage\t"+address.String()+"\t"+hash.String()+"\t"+val.String())
+func accountsFromJSON(accounts map[common.Address]*extraAccountJSON) map[common.Address]*StateAccount {
+	dec := make(map[common.Address]*StateAccount, len(accounts))
+	for addr, account := range accounts {
+		if account == nil {
+			dec[addr] = nil
+			continue
+		}
+		dec[addr] = &StateAccount{
+			Nonce:    uint64(account.Nonce),
+			Balance:  (*big.Int)(account.Balance),
+			Root:     account.StorageRoot,
+			CodeHash: account.CodeHash,
+		}
+		if dec[addr].Balance == nil {
+			dec[addr].Balance = new(big.Int)
+		}
+	}
+	return dec
 }
 
-func WritePostStorage(blockNumber *big.Int, address common.Address, hash, val common.Hash) {
-	WriteFile(blockNumber, "postStorage\t"+address.String()+"\t"+hash.String()+"\t"+val.String())
+func codesToJSON(codes map[common.Address][]byte) map[common.Address]hexutil.Bytes {
+	enc := make(map[common.Address]hexutil.Bytes, len(codes))
+	for addr, code := range codes {
+		enc[addr] = code
+	}
+	return enc
 }
 
-func WritePreStorageProof(blockNumber *big.Int, address common.Address, hash common.Hash, path [][]byte) {
-	text := ""
-	for _, bytes := range path {
-		text += "\t" + base64.StdEncoding.EncodeToString(bytes)
+func codesFromJSON(codes map[common.Address]hexutil.Bytes) map[common.Address][]byte {
+	dec := make(map[common.Address][]byte, len(codes))
+	for addr, code := range codes {
+		dec[addr] = code
 	}
+	return dec
+}
 
-	WriteFile(blockNumber, "preStorageProof\t"+address.String()+"\t"+hash.String()+text)
+func proofToJSON(proof [][]byte) []hexutil.Bytes {
+	enc := make([]hexutil.Bytes, len(proof))
+	for i, node := range proof {
+		enc[i] = node
+	}
+	return enc
 }
 
-func WritePostStorageProof(blockNumber *big.Int, address common.Address, hash common.Hash, path [][]byte) {
-	text := ""
-	for _, bytes := range path {
-		text += "\t" + base64.StdEncoding.EncodeToString(bytes)
+func proofFromJSON(proof []hexutil.Bytes) [][]byte {
+	dec := make([][]byte, len(proof))
+	for i, node := range proof {
+		dec[i] = node
 	}
+	return dec
+}
 
-	WriteFile(blockNumber, "postStorageProof\t"+address.String()+"\t"+hash.String()+text)
+func accountProofsToJSON(proofs map[common.Address][][]byte) map[common.Address][]hexutil.Bytes {
+	enc := make(map[common.Address][]hexutil.Bytes, len(proofs))
+	for addr, proof := range proofs {
+		enc[addr] = proofToJSON(proof)
+	}
+	return enc
 }
 
-func WritePreCode(blockNumber *big.Int, address common.Address, code []byte) {
-	WriteFile(blockNumber, "preCode\t"+address.String()+"\t"+base64.StdEncoding.EncodeToString(code))
+func accountProofsFromJSON(proofs map[common.Address][]hexutil.Bytes) map[common.Address][][]byte {
+	dec := make(map[common.Address][][]byte, len(proofs))
+	for addr, proof := range proofs {
+		dec[addr] = proofFromJSON(proof)
+	}
+	return dec
 }
 
-func WritePostCode(blockNumber *big.Int, address common.Address, code []byte) {
-	WriteFile(blockNumber, "postCode\t"+address.String()+"\t"+base64.StdEncoding.EncodeToString(code))
+func storageProofsToJSON(proofs map[common.Address]map[common.Hash][][]byte) map[common.Address]map[common.Hash][]hexutil.Bytes {
+	enc := make(map[common.Address]map[common.Hash][]hexutil.Bytes, len(proofs))
+	for addr, slots := range proofs {
+		enc[addr] = make(map[common.Hash][]hexutil.Bytes, len(slots))
+		for key, proof := range slots {
+			enc[addr][key] = proofToJSON(proof)
+		}
+	}
+	return enc
 }
 
-func WriteFile(blockNumber *big.Int, text string) {
-	left := new(big.Int).Quo(blockNumber, new(big.Int).SetUint64(1000))
-	filePath := "./minerExtra/" + left.String() + "/" + blockNumber.String() + ".txt"
-	file, err := os.OpenFile(filePath, os.O_WRONLY|os.O_APPEND, 0666)
-	if err != nil {
-		//log.Info("文件打开失败", "文件打开失败", err, "string", text)
+func storageProofsFromJSON(proofs map[common.Address]map[common.Hash][]hexutil.Bytes) map[common.Address]map[common.Hash][][]byte {
+	dec := make(map[common.Address]map[common.Hash][][]byte, len(proofs))
+	for addr, slots := range proofs {
+		dec[addr] = make(map[common.Hash][][]byte, len(slots))
+		for key, proof := range slots {
+			dec[addr][key] = proofFromJSON(proof)
+		}
 	}
-	//及时关闭file句柄
-	defer file.Close()
-	//写入文件时，使用带缓存的 *Writer
-	write := bufio.NewWriter(file)
-	write.WriteString(text + "\n")
-	//Flush将缓存的文件真正写入到文件中
-	write.Flush()
+	return dec
 }
 
-func CheckFileExist(fileName string) bool {
-	_, err := os.Stat(fileName)
-	if os.IsNotExist(err) {
-		return false
+func copyStorage(storage map[common.Address]map[common.Hash]common.Hash) map[common.Address]map[common.Hash]common.Hash {
+	cpy := make(map[common.Address]map[common.Hash]common.Hash, len(storage))
+	for addr, slots := range storage {
+		cpy[addr] = make(map[common.Hash]common.Hash, len(slots))
+		for key, val := range slots {
+			cpy[addr][key] = val
+		}
+	}
+	return cpy
+}
+
+// MarshalJSON implements json.Marshaler.
+func (t *TxExtra) MarshalJSON() ([]byte, error) {
+	return json.Marshal(&txExtraJSON{
+		Version:       TxExtraVersion,
+		TxHash:        t.TxHash,
+		PreStateRoot:  t.PreStateRoot,
+		PostStateRoot: t.PostStateRoot,
+		PreState:      accountsToJSON(t.PreState),
+		PostState:     accountsToJSON(t.PostState),
+		PreStorage:    copyStorage(t.PreStorage),
+		PostStorage:   copyStorage(t.PostStorage),
+		PreCode:       codesToJSON(t.PreCode),
+		PostCode:      codesToJSON(t.PostCode),
+
+		PreStateProof:    accountProofsToJSON(t.PreStateProof),
+		PostStateProof:   accountProofsToJSON(t.PostStateProof),
+		PreStorageProof:  storageProofsToJSON(t.PreStorageProof),
+		PostStorageProof: storageProofsToJSON(t.PostStorageProof),
+		PreStateNodes:    proofToJSON(t.PreStateNodes),
+	})
+}
+
+// UnmarshalJSON implements json.Unmarshaler.
+func (t *TxExtra) UnmarshalJSON(input []byte) error {
+	var dec txExtraJSON
+	if err := json.Unmarshal(input, &dec); err != nil {
+		return err
+	}
+	if dec.Version != TxExtraVersion {
+		return fmt.Errorf("%w: %d", ErrTxExtraVersion, dec.Version)
+	}
+	*t = TxExtra{
+		TxHash:        dec.TxHash,
+		PreStateRoot:  dec.PreStateRoot,
+		PostStateRoot: dec.PostStateRoot,
+		PreState:      accountsFromJSON(dec.PreState),
+		PostState:     accountsFromJSON(dec.PostState),
+		PreStorage:    copyStorage(dec.PreStorage),
+		PostStorage:   copyStorage(dec.PostStorage),
+		PreCode:       codesFromJSON(dec.PreCode),
+		PostCode:      codesFromJSON(dec.PostCode),
+
+		PreStateProof:    accountProofsFromJSON(dec.PreStateProof),
+		PostStateProof:   accountProofsFromJSON(dec.PostStateProof),
+		PreStorageProof:  storageProofsFromJSON(dec.PreStorageProof),
+		PostStorageProof: storageProofsFromJSON(dec.PostStorageProof),
+	}
+	if len(dec.PreStateNodes) > 0 {
+		t.PreStateNodes = proofFromJSON(dec.PreStateNodes)
 	}
-	return true
+	return nil
 }
@@ -0,0 +1,101 @@
+package types
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"math/big"
+	"reflect"
+	"testing"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/rlp"
+)
+
+func newTestTxExtra() *TxExtra {
+	var (
+		sender   = common.HexToAddress("0x1000000000000000000000000000000000000001")
+		contract = common.HexToAddress("0x2000000000000000000000000000000000000002")
+		created  = common.HexToAddress("0x3000000000000000000000000000000000000003")
+	)
+	extra := NewTxExtra(common.HexToHash("0x01"))
+	extra.SetPreStateRoot(common.HexToHash("0x02"))
+	extra.SetPostStateRoot(common.HexToHash("0x03"))
+
+	extra.AddPreState(sender, &StateAccount{Nonce: 1, Balance: big.NewInt(1000), Root: EmptyRootHash, CodeHash: EmptyCodeHash.Bytes()})
+	extra.AddPreState(contract, &StateAccount{Nonce: 1, Balance: big.NewInt(0), Root: common.HexToHash("0xaa"), CodeHash: common.HexToHash("0xbb").Bytes()})
+	extra.AddPreState(created, nil)
+	extra.AddPostState(sender, &StateAccount{Nonce: 2, Balance: big.NewInt(900), Root: EmptyRootHash, CodeHash: EmptyCodeHash.Bytes()})
+	extra.AddPostState(contract, &StateAccount{Nonce: 1, Balance: big.NewInt(0), Root: common.HexToHash("0xcc"), CodeHash: common.HexToHash("0xbb").Bytes()})
+	extra.AddPostState(created, &StateAccount{Nonce: 1, Balance: big.NewInt(100), Root: EmptyRootHash, CodeHash: EmptyCodeHash.Bytes()})
+
+	extra.AddPreStorage(contract, common.HexToHash("0x02"), common.HexToHash("0x20"))
+	extra.AddPreStorage(contract, common.HexToHash("0x01"), common.Hash{})
+	extra.AddPostStorage(contract, common.HexToHash("0x02"), common.HexToHash("0x21"))
+	extra.AddPostStorage(contract, common.HexToHash("0x01"), common.HexToHash("0x10"))
+
+	extra.AddPreCode(contract, []byte{0x60, 0x01, 0x60, 0x00, 0x55})
+	extra.AddPostCode(contract, []byte{0x60, 0x01, 0x60, 0x00, 0x55})
+
+	extra.AddPreStateProof(sender, [][]byte{{0xf8, 0x01}, {0xf8, 0x02}})
+	extra.AddPostStateProof(sender, [][]byte{{0xf8, 0x03}})
+	extra.AddPreStorageProof(contract, common.HexToHash("0x02"), [][]byte{{0xe2, 0x01}})
+	extra.AddPostStorageProof(contract, common.HexToHash("0x02"), [][]byte{{0xe2, 0x02}})
+	extra.AddPreStateNodes([][]byte{{0xf8, 0x04}})
+	return extra
+}
+
+func TestTxExtraRLP(t *testing.T) {
+	extra := newTestTxExtra()
+
+	enc, err := rlp.EncodeToBytes(extra)
+	if err != nil {
+		t.Fatalf("failed to encode witness: %v", err)
+	}
+	dec := new(TxExtra)
+	if err := rlp.DecodeBytes(enc, dec); err != nil {
+		t.Fatalf("failed to decode witness: %v", err)
+	}
+	if !reflect.DeepEqual(dec, extra) {
+		t.Fatalf("witness mismatch: have %+v, want %+v", dec, extra)
+	}
+	// Re-encoding the decoded witness must yield the exact same bytes
+	reenc, err := rlp.EncodeToBytes(dec)
+	if err != nil {
+		t.Fatalf("failed to re-encode witness: %v", err)
+	}
+	if !bytes.Equal(enc, reenc) {
+		t.Fatalf("encoding not deterministic: %x != %x", enc, reenc)
+	}
+}
+
+func TestTxExtraRLPVersion(t *testing.T) {
+	payload, _ := rlp.EncodeToBytes(&txExtraV1{})
+	enc, _ := rlp.EncodeToBytes(&txExtraRLP{Version: TxExtraVersion + 1, Payload: payload})
+
+	if err := rlp.DecodeBytes(enc, new(TxExtra)); !errors.Is(err, ErrTxExtraVersion) {
+		t.Fatalf("unexpected error: have %v, want %v", err, ErrTxExtraVersion)
+	}
+}
+
+func TestTxExtraJSON(t *testing.T) {
+	extra := newTestTxExtra()
+
+	enc, err := json.Marshal(extra)
+	if err != nil {
+		t.Fatalf("failed to marshal witness: %v", err)
+	}
+	dec := new(TxExtra)
+	if err := json.Unmarshal(enc, dec); err != nil {
+		t.Fatalf("failed to unmarshal witness: %v", err)
+	}
+	// Compare the canonical encodings, big.Int internals differ after JSON decoding
+	have, _ := rlp.EncodeToBytes(dec)
+	want, _ := rlp.EncodeToBytes(extra)
+	if !bytes.Equal(have, want) {
+		t.Fatalf("witness mismatch: have %+v, want %+v", dec, extra)
+	}
+	if err := json.Unmarshal([]byte(`{"version":"0x2"}`), new(TxExtra)); !errors.Is(err, ErrTxExtraVersion) {
+		t.Fatalf("unexpected error: have %v, want %v", err, ErrTxExtraVersion)
+	}
+}
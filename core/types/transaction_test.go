@@ -30,7 +30,9 @@ import (
 
 	"github.com/ethereum/go-ethereum/common"
 	"github.com/ethereum/go-ethereum/crypto"
+	"github.com/ethereum/go-ethereum/crypto/kzg4844"
 	"github.com/ethereum/go-ethereum/rlp"
+	"github.com/holiman/uint256"
 )
 
 // The values in those tests are from the Transaction Tests
@@ -599,3 +601,68 @@ func TestTransactionSizes(t *testing.T) {
 		}
 	}
 }
+
+// Tests that blob transactions carrying a sidecar round trip through their
+// network encoding, and that the sidecar does not leak into the canonical one.
+func TestBlobTxNetworkEncoding(t *testing.T) {
+	key, _ := crypto.HexToECDSA("b71c71a67e1177ad4e901695e1b4b9ee17ae16c6668d313eac2f96dbcda3f291")
+	sidecar := &BlobTxSidecar{
+		Blobs:       []kzg4844.Blob{{0x01}},
+		Commitments: []kzg4844.Commitment{{0x02}},
+		Proofs:      []kzg4844.Proof{{0x03}},
+	}
+	tx, err := SignNewTx(key, NewCancunSigner(big.NewInt(1)), &BlobTx{
+		ChainID:    uint256.NewInt(1),
+		Nonce:      1,
+		Gas:        21000,
+		To:         common.HexToAddress("0x01"),
+		Value:      uint256.NewInt(1),
+		GasTipCap:  uint256.NewInt(1),
+		GasFeeCap:  uint256.NewInt(1),
+		BlobFeeCap: uint256.NewInt(1),
+		BlobHashes: sidecar.BlobHashes(),
+	})
+	if err != nil {
+		t.Fatalf("failed to sign transaction: %v", err)
+	}
+	canon, _ := tx.MarshalBinary()
+
+	withSidecar := tx.WithBlobTxSidecar(sidecar)
+	if withSidecar.Hash() != tx.Hash() {
+		t.Fatalf("sidecar changed transaction hash")
+	}
+	if bin, _ := withSidecar.MarshalBinary(); !bytes.Equal(bin, canon) {
+		t.Fatalf("sidecar leaked into canonical encoding")
+	}
+	network, err := withSidecar.MarshalNetwork()
+	if err != nil {
+		t.Fatalf("failed to encode network form: %v", err)
+	}
+	if bytes.Equal(network, canon) {
+		t.Fatalf("network form misses the sidecar")
+	}
+	// Decode both forms and check that only the network one carries the sidecar
+	dec := new(Transaction)
+	if err := dec.UnmarshalNetwork(network); err != nil {
+		t.Fatalf("failed to decode network form: %v", err)
+	}
+	if dec.Hash() != tx.Hash() {
+		t.Fatalf("hash mismatch: have %x, want %x", dec.Hash(), tx.Hash())
+	}
+	if !reflect.DeepEqual(dec.BlobTxSidecar(), sidecar) {
+		t.Fatalf("sidecar mismatch")
+	}
+	if dec.Size() != tx.Size() {
+		t.Fatalf("size mismatch: have %d, want %d", dec.Size(), tx.Size())
+	}
+	dec = new(Transaction)
+	if err := dec.UnmarshalNetwork(canon); err != nil {
+		t.Fatalf("failed to decode canonical form: %v", err)
+	}
+	if dec.Hash() != tx.Hash() || dec.BlobTxSidecar() != nil {
+		t.Fatalf("canonical form decoded incorrectly")
+	}
+	if stripped := withSidecar.WithBlobTxSidecar(nil); stripped.BlobTxSidecar() != nil {
+		t.Fatalf("sidecar not stripped")
+	}
+}
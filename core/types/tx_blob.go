@@ -17,10 +17,14 @@
 package types
 
 import (
+	"bytes"
+	"crypto/sha256"
 	"math/big"
 
 	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/crypto/kzg4844"
 	"github.com/ethereum/go-ethereum/params"
+	"github.com/ethereum/go-ethereum/rlp"
 	"github.com/holiman/uint256"
 )
 
@@ -44,6 +48,109 @@ type BlobTx struct {
 	S *uint256.Int `json:"s" gencodec:"required"`
 }
 
+// BlobTxSidecar contains the blobs of a blob transaction, along with their KZG
+// commitments and proofs. The sidecar is not part of the signed transaction, it
+// is only relayed alongside it until the transaction is included in a block.
+type BlobTxSidecar struct {
+	Blobs       []kzg4844.Blob       // Blobs needed by the blob pool
+	Commitments []kzg4844.Commitment // Commitments needed by the blob pool
+	Proofs      []kzg4844.Proof      // Proofs needed by the blob pool
+}
+
+// BlobHashes computes the versioned hashes of the blob commitments, which the
+// transaction is expected to reference.
+func (sc *BlobTxSidecar) BlobHashes() []common.Hash {
+	var (
+		hasher = sha256.New()
+		hashes = make([]common.Hash, len(sc.Commitments))
+	)
+	for i, commit := range sc.Commitments {
+		hasher.Write(commit[:])
+		hasher.Sum(hashes[i][:0])
+		hasher.Reset()
+
+		hashes[i][0] = params.BlobTxHashVersion
+	}
+	return hashes
+}
+
+// blobTxWithSidecar is the network representation of a blob transaction, which
+// wraps the transaction payload along with its sidecar into an outer list.
+type blobTxWithSidecar struct {
+	BlobTx      *BlobTx
+	Blobs       []kzg4844.Blob
+	Commitments []kzg4844.Commitment
+	Proofs      []kzg4844.Proof
+}
+
+// BlobTxSidecar returns the sidecar relayed along with a blob transaction, or
+// nil if the transaction carries none.
+func (tx *Transaction) BlobTxSidecar() *BlobTxSidecar {
+	return tx.sidecar
+}
+
+// WithBlobTxSidecar returns a copy of the transaction carrying the given sidecar,
+// or stripped of it if the sidecar is nil. The sidecar does not change the hash
+// of the transaction, it is only included in its network encoding.
+func (tx *Transaction) WithBlobTxSidecar(sidecar *BlobTxSidecar) *Transaction {
+	cpy := &Transaction{inner: tx.inner, time: tx.time, sidecar: sidecar}
+	if hash := tx.hash.Load(); hash != nil {
+		cpy.hash.Store(hash)
+	}
+	if from := tx.from.Load(); from != nil {
+		cpy.from.Store(from)
+	}
+	return cpy
+}
+
+// MarshalNetwork returns the encoding of the transaction used when relaying it
+// over the network. It is the canonical encoding, except for blob transactions
+// carrying a sidecar, which are encoded as type || rlp([tx_payload_body, blobs,
+// commitments, proofs]).
+func (tx *Transaction) MarshalNetwork() ([]byte, error) {
+	inner, ok := tx.inner.(*BlobTx)
+	if !ok || tx.sidecar == nil {
+		return tx.MarshalBinary()
+	}
+	var buf bytes.Buffer
+	buf.WriteByte(BlobTxType)
+	err := rlp.Encode(&buf, &blobTxWithSidecar{
+		BlobTx:      inner,
+		Blobs:       tx.sidecar.Blobs,
+		Commitments: tx.sidecar.Commitments,
+		Proofs:      tx.sidecar.Proofs,
+	})
+	return buf.Bytes(), err
+}
+
+// UnmarshalNetwork decodes the network encoding of a transaction, attaching the
+// sidecar of a blob transaction if one is included. Canonical encodings are
+// accepted as well.
+func (tx *Transaction) UnmarshalNetwork(b []byte) error {
+	if len(b) > 1 && b[0] == BlobTxType {
+		// The payload of a plain blob transaction starts with its chain id,
+		// whereas the network form starts with the payload list itself
+		content, _, err := rlp.SplitList(b[1:])
+		if err != nil {
+			return err
+		}
+		if kind, _, _, err := rlp.Split(content); err == nil && kind == rlp.List {
+			var inner blobTxWithSidecar
+			if err := rlp.DecodeBytes(b[1:], &inner); err != nil {
+				return err
+			}
+			tx.setDecoded(inner.BlobTx, 0)
+			tx.sidecar = &BlobTxSidecar{
+				Blobs:       inner.Blobs,
+				Commitments: inner.Commitments,
+				Proofs:      inner.Proofs,
+			}
+			return nil
+		}
+	}
+	return tx.UnmarshalBinary(b)
+}
+
 // copy creates a deep copy of the transaction data and initializes all fields.
 func (tx *BlobTx) copy() TxData {
 	cpy := &BlobTx{
@@ -17,7 +17,6 @@
 package vm
 
 import (
-	"github.com/ethereum/go-ethereum/core/types"
 	"math/big"
 	"sync/atomic"
 
@@ -176,9 +175,10 @@ func (evm *EVM) SetBlockContext(blockCtx BlockContext) {
 // the necessary steps to create accounts and reverses the state in case of an
 // execution error or failed value transfer.
 func (evm *EVM) Call(caller ContractRef, addr common.Address, input []byte, gas uint64, value *big.Int) (ret []byte, leftOverGas uint64, err error) {
-	blockNumber := evm.Context.BlockNumber
-	types.WriteStateKey(blockNumber, caller.Address())
-	types.WriteStateKey(blockNumber, addr)
+	if witness := evm.Config.Witness; witness != nil {
+		witness.RecordAccount(caller.Address())
+		witness.RecordAccount(addr)
+	}
 	// Fail if we're trying to execute above the call depth limit
 	if evm.depth > int(params.CallCreateDepth) {
 		return nil, gas, ErrDepth
@@ -230,12 +230,13 @@ func (evm *EVM) Call(caller ContractRef, addr common.Address, input []byte, gas
 	} else {
 		// Initialise a new contract and set the code that is to be used by the EVM.
 		// The contract is a scoped environment for this execution context only.
+		if witness := evm.Config.Witness; witness != nil {
+			witness.RecordCode(addr)
+		}
 		code := evm.StateDB.GetCode(addr)
 		if len(code) == 0 {
 			ret, err = nil, nil // gas is unchanged
 		} else {
-			types.WriteTxCode(evm.Context.BlockNumber, addr)
-			types.WriteStateKey(evm.Context.BlockNumber, addr)
 			addrCopy := addr
 			// If the account has no code, we can abort here
 			// The depth-check is already done, and precompiles handled above
@@ -268,6 +269,10 @@ func (evm *EVM) Call(caller ContractRef, addr common.Address, input []byte, gas
 // CallCode differs from Call in the sense that it executes the given address'
 // code with the caller as context.
 func (evm *EVM) CallCode(caller ContractRef, addr common.Address, input []byte, gas uint64, value *big.Int) (ret []byte, leftOverGas uint64, err error) {
+	if witness := evm.Config.Witness; witness != nil {
+		witness.RecordAccount(caller.Address())
+		witness.RecordAccount(addr)
+	}
 	// Fail if we're trying to execute above the call depth limit
 	if evm.depth > int(params.CallCreateDepth) {
 		return nil, gas, ErrDepth
@@ -297,6 +302,9 @@ func (evm *EVM) CallCode(caller ContractRef, addr common.Address, input []byte,
 		// Initialise a new contract and set the code that is to be used by the EVM.
 		// The contract is a scoped environment for this execution context only.
 		contract := NewContract(caller, AccountRef(caller.Address()), value, gas)
+		if witness := evm.Config.Witness; witness != nil {
+			witness.RecordCode(addrCopy)
+		}
 		contract.SetCallCode(&addrCopy, evm.StateDB.GetCodeHash(addrCopy), evm.StateDB.GetCode(addrCopy))
 		ret, err = evm.interpreter.Run(contract, input, false)
 		gas = contract.Gas
@@ -341,8 +349,9 @@ func (evm *EVM) DelegateCall(caller ContractRef, addr common.Address, input []by
 		addrCopy := addr
 		// Initialise a new contract and make initialise the delegate values
 		contract := NewContract(caller, AccountRef(caller.Address()), nil, gas).AsDelegate()
-		types.WriteTxCode(evm.Context.BlockNumber, addrCopy)
-		types.WriteStateKey(evm.Context.BlockNumber, addrCopy)
+		if witness := evm.Config.Witness; witness != nil {
+			witness.RecordCode(addrCopy)
+		}
 		contract.SetCallCode(&addrCopy, evm.StateDB.GetCodeHash(addrCopy), evm.StateDB.GetCode(addrCopy))
 		ret, err = evm.interpreter.Run(contract, input, false)
 		gas = contract.Gas
@@ -361,9 +370,10 @@ func (evm *EVM) DelegateCall(caller ContractRef, addr common.Address, input []by
 // Opcodes that attempt to perform such modifications will result in exceptions
 // instead of performing the modifications.
 func (evm *EVM) StaticCall(caller ContractRef, addr common.Address, input []byte, gas uint64) (ret []byte, leftOverGas uint64, err error) {
-	blockNumber := evm.Context.BlockNumber
-	types.WriteStateKey(blockNumber, caller.Address())
-	types.WriteStateKey(blockNumber, addr)
+	if witness := evm.Config.Witness; witness != nil {
+		witness.RecordAccount(caller.Address())
+		witness.RecordAccount(addr)
+	}
 	// Fail if we're trying to execute above the call depth limit
 	if evm.depth > int(params.CallCreateDepth) {
 		return nil, gas, ErrDepth
@@ -399,9 +409,10 @@ func (evm *EVM) StaticCall(caller ContractRef, addr common.Address, input []byte
 		// Initialise a new contract and set the code that is to be used by the EVM.
 		// The contract is a scoped environment for this execution context only.
 		contract := NewContract(caller, AccountRef(addrCopy), new(big.Int), gas)
+		if witness := evm.Config.Witness; witness != nil {
+			witness.RecordCode(addrCopy)
+		}
 		contract.SetCallCode(&addrCopy, evm.StateDB.GetCodeHash(addrCopy), evm.StateDB.GetCode(addrCopy))
-		types.WriteStateKey(evm.Context.BlockNumber, addr)
-		types.WriteTxCode(blockNumber, addrCopy)
 		// When an error was returned by the EVM or when setting the creation code
 		// above we revert to the snapshot and consume any gas remaining. Additionally
 		// when we're in Homestead this also counts for code storage gas errors.
@@ -431,6 +442,10 @@ func (c *codeAndHash) Hash() common.Hash {
 
 // create creates a new contract using code as deployment code.
 func (evm *EVM) create(caller ContractRef, codeAndHash *codeAndHash, gas uint64, value *big.Int, address common.Address, typ OpCode) ([]byte, common.Address, uint64, error) {
+	if witness := evm.Config.Witness; witness != nil {
+		witness.RecordAccount(caller.Address())
+		witness.RecordAccount(address)
+	}
 	// Depth check execution. Fail if we're trying to execute above the
 	// limit.
 	if evm.depth > int(params.CallCreateDepth) {
@@ -517,10 +532,6 @@ func (evm *EVM) create(caller ContractRef, codeAndHash *codeAndHash, gas uint64,
 			evm.Config.Tracer.CaptureExit(ret, gas-contract.Gas, err)
 		}
 	}
-
-	types.WriteStateKey(evm.Context.BlockNumber, caller.Address())
-	types.WriteStateKey(evm.Context.BlockNumber, address)
-	types.WriteTxCode(evm.Context.BlockNumber, address)
 	return ret, address, contract.Gas, err
 }
 
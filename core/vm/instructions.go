@@ -259,7 +259,9 @@ func opAddress(pc *uint64, interpreter *EVMInterpreter, scope *ScopeContext) ([]
 func opBalance(pc *uint64, interpreter *EVMInterpreter, scope *ScopeContext) ([]byte, error) {
 	slot := scope.Stack.peek()
 	address := common.Address(slot.Bytes20())
-	types.WriteStateKey(interpreter.evm.Context.BlockNumber, address)
+	if witness := interpreter.evm.Config.Witness; witness != nil {
+		witness.RecordAccount(address)
+	}
 	slot.SetFromBig(interpreter.evm.StateDB.GetBalance(address))
 	return nil, nil
 }
@@ -342,7 +344,9 @@ func opReturnDataCopy(pc *uint64, interpreter *EVMInterpreter, scope *ScopeConte
 
 func opExtCodeSize(pc *uint64, interpreter *EVMInterpreter, scope *ScopeContext) ([]byte, error) {
 	slot := scope.Stack.peek()
-	types.WriteTxCode(interpreter.evm.Context.BlockNumber, slot.Bytes20())
+	if witness := interpreter.evm.Config.Witness; witness != nil {
+		witness.RecordCode(slot.Bytes20())
+	}
 	slot.SetUint64(uint64(interpreter.evm.StateDB.GetCodeSize(slot.Bytes20())))
 	return nil, nil
 }
@@ -383,6 +387,9 @@ func opExtCodeCopy(pc *uint64, interpreter *EVMInterpreter, scope *ScopeContext)
 		uint64CodeOffset = 0xffffffffffffffff
 	}
 	addr := common.Address(a.Bytes20())
+	if witness := interpreter.evm.Config.Witness; witness != nil {
+		witness.RecordCode(addr)
+	}
 	codeCopy := getData(interpreter.evm.StateDB.GetCode(addr), uint64CodeOffset, length.Uint64())
 	scope.Memory.Set(memOffset.Uint64(), length.Uint64(), codeCopy)
 
@@ -418,6 +425,9 @@ func opExtCodeCopy(pc *uint64, interpreter *EVMInterpreter, scope *ScopeContext)
 func opExtCodeHash(pc *uint64, interpreter *EVMInterpreter, scope *ScopeContext) ([]byte, error) {
 	slot := scope.Stack.peek()
 	address := common.Address(slot.Bytes20())
+	if witness := interpreter.evm.Config.Witness; witness != nil {
+		witness.RecordAccount(address)
+	}
 	if interpreter.evm.StateDB.Empty(address) {
 		slot.Clear()
 	} else {
@@ -515,9 +525,11 @@ func opMstore8(pc *uint64, interpreter *EVMInterpreter, scope *ScopeContext) ([]
 func opSload(pc *uint64, interpreter *EVMInterpreter, scope *ScopeContext) ([]byte, error) {
 	loc := scope.Stack.peek()
 	hash := common.Hash(loc.Bytes32())
+	if witness := interpreter.evm.Config.Witness; witness != nil {
+		witness.RecordStorage(scope.Contract.Address(), hash)
+	}
 	val := interpreter.evm.StateDB.GetState(scope.Contract.Address(), hash)
 	loc.SetBytes(val.Bytes())
-	types.WriteStorageKey(interpreter.evm.Context.BlockNumber, scope.Contract.Address(), hash)
 	return nil, nil
 }
 
@@ -527,9 +539,10 @@ func opSstore(pc *uint64, interpreter *EVMInterpreter, scope *ScopeContext) ([]b
 	}
 	loc := scope.Stack.pop()
 	val := scope.Stack.pop()
-	//log.Info("opSstore", "blockNumber", interpreter.evm.Context.BlockNumber, "addr", scope.Contract.Address().Hex(), "key", loc.Hex(), "val", val.Hex())
+	if witness := interpreter.evm.Config.Witness; witness != nil {
+		witness.RecordStorage(scope.Contract.Address(), loc.Bytes32())
+	}
 	interpreter.evm.StateDB.SetState(scope.Contract.Address(), loc.Bytes32(), val.Bytes32())
-	types.WriteStorageKey(interpreter.evm.Context.BlockNumber, scope.Contract.Address(), loc.Bytes32())
 	return nil, nil
 }
 
@@ -723,8 +736,6 @@ func opCallCode(pc *uint64, interpreter *EVMInterpreter, scope *ScopeContext) ([
 		gas += params.CallStipend
 		bigVal = value.ToBig()
 	}
-	types.WriteStateKey(interpreter.evm.Context.BlockNumber, toAddr)
-	types.WriteTxCode(interpreter.evm.Context.BlockNumber, toAddr)
 	ret, returnGas, err := interpreter.evm.CallCode(scope.Contract, toAddr, args, gas, bigVal)
 	if err != nil {
 		temp.Clear()
@@ -826,8 +837,10 @@ func opSelfdestruct(pc *uint64, interpreter *EVMInterpreter, scope *ScopeContext
 	}
 	beneficiary := scope.Stack.pop()
 	balance := interpreter.evm.StateDB.GetBalance(scope.Contract.Address())
+	if witness := interpreter.evm.Config.Witness; witness != nil {
+		witness.RecordAccount(beneficiary.Bytes20())
+	}
 	interpreter.evm.StateDB.AddBalance(beneficiary.Bytes20(), balance)
-	types.WriteStateKey(interpreter.evm.Context.BlockNumber, beneficiary.Bytes20())
 	interpreter.evm.StateDB.Suicide(scope.Contract.Address())
 	if tracer := interpreter.evm.Config.Tracer; tracer != nil {
 		tracer.CaptureEnter(SELFDESTRUCT, scope.Contract.Address(), beneficiary.Bytes20(), []byte{}, 0, balance)
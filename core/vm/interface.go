@@ -90,3 +90,16 @@ type CallContext interface {
 	// Create creates a new contract
 	Create(env *EVM, me ContractRef, data []byte, gas, value *big.Int) ([]byte, common.Address, error)
 }
+
+// StateWitnessRecorder is notified of the accounts, storage slots and contract
+// codes accessed by the EVM, so that they can be recorded as the state witness
+// of a transaction. Notifications are delivered before the state is accessed,
+// and the same item may be reported multiple times.
+type StateWitnessRecorder interface {
+	// RecordAccount records the access of an account.
+	RecordAccount(addr common.Address)
+	// RecordStorage records the access of a storage slot of an account.
+	RecordStorage(addr common.Address, key common.Hash)
+	// RecordCode records the access of the code of an account.
+	RecordCode(addr common.Address)
+}
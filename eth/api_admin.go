@@ -24,8 +24,10 @@ import (
 	"os"
 	"strings"
 
+	"github.com/ethereum/go-ethereum/common"
 	"github.com/ethereum/go-ethereum/core"
 	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/internal/ethapi"
 	"github.com/ethereum/go-ethereum/rlp"
 )
 
@@ -137,3 +139,22 @@ func (api *AdminAPI) ImportChain(file string) (bool, error) {
 	}
 	return true, nil
 }
+
+// PrivateTransactions returns the privately submitted transactions currently in
+// the transaction pool, grouped by account and nonce. These are never reported
+// by the public txpool namespace.
+func (api *AdminAPI) PrivateTransactions() map[common.Address]map[string]*ethapi.RPCTransaction {
+	var (
+		content = make(map[common.Address]map[string]*ethapi.RPCTransaction)
+		head    = api.eth.BlockChain().CurrentHeader()
+		config  = api.eth.BlockChain().Config()
+	)
+	for account, txs := range api.eth.TxPool().PrivateContent() {
+		dump := make(map[string]*ethapi.RPCTransaction, len(txs))
+		for _, tx := range txs {
+			dump[fmt.Sprintf("%d", tx.Nonce())] = ethapi.NewRPCPendingTransaction(tx, head, config)
+		}
+		content[account] = dump
+	}
+	return content
+}
@@ -297,16 +297,34 @@ func (b *EthAPIBackend) SendTx(ctx context.Context, signedTx *types.Transaction)
 	return b.eth.txPool.AddLocal(signedTx)
 }
 
+func (b *EthAPIBackend) SendPrivateTx(ctx context.Context, signedTx *types.Transaction) error {
+	return b.eth.txPool.AddPrivate(signedTx)
+}
+
+func (b *EthAPIBackend) SendBundle(ctx context.Context, bundle *txpool.Bundle) error {
+	return b.eth.txPool.AddBundle(bundle)
+}
+
+// GetPoolTransactions retrieves the pending transactions of the pool, apart from
+// the private ones, which are only exposed through the admin API.
 func (b *EthAPIBackend) GetPoolTransactions() (types.Transactions, error) {
 	pending := b.eth.txPool.Pending(false)
 	var txs types.Transactions
 	for _, batch := range pending {
-		txs = append(txs, batch...)
+		for _, tx := range batch {
+			if !b.eth.txPool.IsPrivate(tx.Hash()) {
+				txs = append(txs, tx)
+			}
+		}
 	}
 	return txs, nil
 }
 
+// GetPoolTransaction retrieves a pooled transaction, unless it's private.
 func (b *EthAPIBackend) GetPoolTransaction(hash common.Hash) *types.Transaction {
+	if b.eth.txPool.IsPrivate(hash) {
+		return nil
+	}
 	return b.eth.txPool.Get(hash)
 }
 
@@ -335,8 +353,14 @@ func (b *EthAPIBackend) TxPool() *txpool.TxPool {
 	return b.eth.TxPool()
 }
 
+// SubscribeNewTxsEvent subscribes to the transactions entering the pool, apart
+// from the private ones.
 func (b *EthAPIBackend) SubscribeNewTxsEvent(ch chan<- core.NewTxsEvent) event.Subscription {
-	return b.eth.TxPool().SubscribeNewTxsEvent(ch)
+	return b.eth.TxPool().SubscribePublicTxsEvent(ch)
+}
+
+func (b *EthAPIBackend) SubscribeTxPoolEvent(ch chan<- txpool.TxPoolEvent) event.Subscription {
+	return b.eth.TxPool().SubscribeTxPoolEvent(ch)
 }
 
 func (b *EthAPIBackend) SyncProgress() ethereum.SyncProgress {
@@ -414,6 +438,16 @@ func (b *EthAPIBackend) StateAtBlock(ctx context.Context, block *types.Block, re
 	return b.eth.StateAtBlock(ctx, block, reexec, base, readOnly, preferDisk)
 }
 
+func (b *EthAPIBackend) TraceIndexer() *tracers.TraceIndexer {
+	return b.eth.traceIndexer
+}
+
+// TraceDir returns the directory chain traces are written into, or the empty
+// string if the node has no data directory.
+func (b *EthAPIBackend) TraceDir() string {
+	return b.eth.traceDir
+}
+
 func (b *EthAPIBackend) StateAtTransaction(ctx context.Context, block *types.Block, txIndex int, reexec uint64) (*core.Message, vm.BlockContext, *state.StateDB, tracers.StateReleaseFunc, error) {
 	return b.eth.stateAtTransaction(ctx, block, txIndex, reexec)
 }
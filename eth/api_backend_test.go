@@ -0,0 +1,122 @@
+// Copyright 2023 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package eth
+
+import (
+	"context"
+	"math/big"
+	"testing"
+	"time"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/crypto"
+	"github.com/ethereum/go-ethereum/eth/ethconfig"
+	"github.com/ethereum/go-ethereum/eth/filters"
+	"github.com/ethereum/go-ethereum/node"
+	"github.com/ethereum/go-ethereum/params"
+)
+
+// Tests that private transactions are not leaked through the pending transaction
+// filters and subscriptions, nor through the pool lookups of the RPC backend.
+func TestPrivateTxsHidden(t *testing.T) {
+	var (
+		key, _ = crypto.GenerateKey()
+		sender = crypto.PubkeyToAddress(key.PublicKey)
+	)
+	genesis := &core.Genesis{
+		Config:   params.AllEthashProtocolChanges,
+		GasLimit: 30_000_000,
+		BaseFee:  big.NewInt(params.InitialBaseFee),
+		Alloc:    core.GenesisAlloc{sender: {Balance: big.NewInt(params.Ether)}},
+	}
+	stack, err := node.New(&node.Config{})
+	if err != nil {
+		t.Fatalf("failed to create node: %v", err)
+	}
+	defer stack.Close()
+
+	config := ethconfig.Defaults
+	config.Genesis = genesis
+	ethservice, err := New(stack, &config)
+	if err != nil {
+		t.Fatalf("failed to create ethereum service: %v", err)
+	}
+	if err := stack.Start(); err != nil {
+		t.Fatalf("failed to start node: %v", err)
+	}
+	var (
+		backend = ethservice.APIBackend
+		api     = filters.NewFilterAPI(filters.NewFilterSystem(backend, filters.Config{}), false)
+		filter  = api.NewPendingTransactionFilter(nil)
+		events  = make(chan core.NewTxsEvent, 16)
+	)
+	sub := backend.SubscribeNewTxsEvent(events)
+	defer sub.Unsubscribe()
+
+	var (
+		signer = types.LatestSigner(genesis.Config)
+		to     = common.HexToAddress("0xdead")
+		fee    = big.NewInt(2 * params.InitialBaseFee)
+		tip    = big.NewInt(params.GWei)
+	)
+	private := types.MustSignNewTx(key, signer, &types.DynamicFeeTx{Nonce: 0, To: &to, Gas: params.TxGas, GasFeeCap: fee, GasTipCap: tip})
+	public := types.MustSignNewTx(key, signer, &types.DynamicFeeTx{Nonce: 1, To: &to, Gas: params.TxGas, GasFeeCap: fee, GasTipCap: tip})
+
+	if err := backend.SendPrivateTx(context.Background(), private); err != nil {
+		t.Fatalf("failed to send private transaction: %v", err)
+	}
+	if err := backend.SendTx(context.Background(), public); err != nil {
+		t.Fatalf("failed to send public transaction: %v", err)
+	}
+	// The public transaction is announced, the private one never
+	select {
+	case ev := <-events:
+		if len(ev.Txs) != 1 || ev.Txs[0].Hash() != public.Hash() {
+			t.Fatalf("announced transactions mismatch: have %v, want %x", ev.Txs, public.Hash())
+		}
+	case <-time.After(time.Second):
+		t.Fatalf("public transaction not announced")
+	}
+	// The filter system processes the events asynchronously, wait for them
+	var hashes []common.Hash
+	for i := 0; i < 50 && len(hashes) == 0; i++ {
+		time.Sleep(20 * time.Millisecond)
+		changes, err := api.GetFilterChanges(filter)
+		if err != nil {
+			t.Fatalf("failed to get filter changes: %v", err)
+		}
+		hashes = append(hashes, changes.([]common.Hash)...)
+	}
+	if len(hashes) != 1 || hashes[0] != public.Hash() {
+		t.Fatalf("filtered transactions mismatch: have %x, want %x", hashes, public.Hash())
+	}
+	// Pool lookups must not expose the private transaction either
+	if backend.GetPoolTransaction(private.Hash()) != nil {
+		t.Errorf("private transaction exposed by hash")
+	}
+	if backend.GetPoolTransaction(public.Hash()) == nil {
+		t.Errorf("public transaction not found by hash")
+	}
+	txs, _ := backend.GetPoolTransactions()
+	for _, tx := range txs {
+		if tx.Hash() == private.Hash() {
+			t.Errorf("private transaction exposed in pending transactions")
+		}
+	}
+}
@@ -404,6 +404,50 @@ func (api *DebugAPI) GetAccessibleState(from, to rpc.BlockNumber) (uint64, error
 	return 0, errors.New("no state found")
 }
 
+// defaultWitnessReexec is the number of blocks to reprocess at most to obtain
+// the parent state of a block whose transaction witnesses are regenerated.
+const defaultWitnessReexec = uint64(128)
+
+// GetTransactionWitness returns the state witness of a transaction. If no witness
+// was recorded when the transaction was processed, it is regenerated by
+// re-executing the transaction on top of its parent state.
+func (api *DebugAPI) GetTransactionWitness(ctx context.Context, hash common.Hash) (*types.TxExtra, error) {
+	tx, blockHash, blockNumber, index := rawdb.ReadTransaction(api.eth.ChainDb(), hash)
+	if tx == nil {
+		return nil, fmt.Errorf("transaction %#x not found", hash)
+	}
+	if witness := rawdb.ReadTxWitness(api.eth.ChainDb(), blockHash, hash); witness != nil {
+		return witness, nil
+	}
+	block := api.eth.blockchain.GetBlock(blockHash, blockNumber)
+	if block == nil {
+		return nil, fmt.Errorf("block %#x not found", blockHash)
+	}
+	return api.eth.witnessAtTransaction(ctx, block, int(index), defaultWitnessReexec)
+}
+
+// GetBlockWitness returns the state witnesses of all the transactions in a block.
+// If any of them was not recorded when the block was processed, they are all
+// regenerated by re-executing the block on top of its parent state.
+func (api *DebugAPI) GetBlockWitness(ctx context.Context, blockNrOrHash rpc.BlockNumberOrHash) ([]*types.TxExtra, error) {
+	block, err := api.eth.APIBackend.BlockByNumberOrHash(ctx, blockNrOrHash)
+	if err != nil {
+		return nil, err
+	}
+	if block == nil {
+		return nil, fmt.Errorf("block %v not found", blockNrOrHash)
+	}
+	witnesses := make([]*types.TxExtra, 0, len(block.Transactions()))
+	for _, tx := range block.Transactions() {
+		witness := rawdb.ReadTxWitness(api.eth.ChainDb(), block.Hash(), tx.Hash())
+		if witness == nil {
+			return api.eth.witnessesAtBlock(ctx, block, defaultWitnessReexec)
+		}
+		witnesses = append(witnesses, witness)
+	}
+	return witnesses, nil
+}
+
 // SetTrieFlushInterval configures how often in-memory tries are persisted
 // to disk. The value is in terms of block processing time, not wall clock.
 // If the value is shorter than the block generation time, or even 0 or negative,
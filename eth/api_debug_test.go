@@ -18,6 +18,7 @@ package eth
 
 import (
 	"bytes"
+	"context"
 	"fmt"
 	"math/big"
 	"reflect"
@@ -26,10 +27,16 @@ import (
 
 	"github.com/davecgh/go-spew/spew"
 	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/consensus/ethash"
+	"github.com/ethereum/go-ethereum/core"
 	"github.com/ethereum/go-ethereum/core/rawdb"
 	"github.com/ethereum/go-ethereum/core/state"
 	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/core/vm"
 	"github.com/ethereum/go-ethereum/crypto"
+	"github.com/ethereum/go-ethereum/params"
+	"github.com/ethereum/go-ethereum/rlp"
+	"github.com/ethereum/go-ethereum/rpc"
 	"github.com/ethereum/go-ethereum/trie"
 )
 
@@ -224,3 +231,86 @@ func TestStorageRangeAt(t *testing.T) {
 		}
 	}
 }
+
+func TestGetTransactionWitness(t *testing.T) {
+	t.Parallel()
+
+	var (
+		key, _   = crypto.HexToECDSA("b71c71a67e1177ad4e901695e1b4b9ee17ae16c6668d313eac2f96dbcda3f291")
+		sender   = crypto.PubkeyToAddress(key.PublicKey)
+		contract = common.HexToAddress("0xc0de")
+		signer   = types.LatestSigner(params.TestChainConfig)
+		gspec    = &core.Genesis{
+			Config: params.TestChainConfig,
+			Alloc: core.GenesisAlloc{
+				sender: {Balance: big.NewInt(1000000000000000000)},
+				// SSTORE(NUMBER, CALLVALUE)
+				contract: {Balance: big.NewInt(0), Code: common.FromHex("0x344355")},
+			},
+		}
+		engine = ethash.NewFaker()
+	)
+	_, blocks, _ := core.GenerateChainWithGenesis(gspec, engine, 2, func(i int, b *core.BlockGen) {
+		for j := 0; j < 2; j++ {
+			tx, _ := types.SignTx(types.NewTransaction(b.TxNonce(sender), contract, big.NewInt(int64(j+1)), 100000, b.BaseFee(), nil), signer, key)
+			b.AddTx(tx)
+		}
+	})
+	db := rawdb.NewMemoryDatabase()
+	chain, err := core.NewBlockChain(db, &core.CacheConfig{TrieDirtyDisabled: true, WitnessNoLimit: true}, gspec, nil, engine, vm.Config{}, nil, nil)
+	if err != nil {
+		t.Fatalf("failed to create chain: %v", err)
+	}
+	defer chain.Stop()
+	if _, err := chain.InsertChain(blocks); err != nil {
+		t.Fatalf("failed to insert chain: %v", err)
+	}
+	eth := &Ethereum{blockchain: chain, chainDb: db}
+	eth.APIBackend = &EthAPIBackend{eth: eth}
+	api := NewDebugAPI(eth)
+
+	// Collect the recorded witnesses and drop them to force their regeneration
+	block := blocks[1]
+	recorded := make([][]byte, len(block.Transactions()))
+	for i, tx := range block.Transactions() {
+		if recorded[i] = rawdb.ReadTxWitnessRLP(db, block.Hash(), tx.Hash()); len(recorded[i]) == 0 {
+			t.Fatalf("witness of transaction %d not recorded", i)
+		}
+	}
+	check := func(name string, witnesses []*types.TxExtra) {
+		if len(witnesses) != len(recorded) {
+			t.Fatalf("%s: witness count mismatch: have %d, want %d", name, len(witnesses), len(recorded))
+		}
+		for i, witness := range witnesses {
+			if enc, _ := rlp.EncodeToBytes(witness); !bytes.Equal(enc, recorded[i]) {
+				t.Errorf("%s: witness %d mismatch: have %x, want %x", name, i, enc, recorded[i])
+			}
+		}
+	}
+	ctx := context.Background()
+	for _, regen := range []bool{false, true} {
+		if regen {
+			for _, tx := range block.Transactions() {
+				rawdb.DeleteTxWitness(db, block.Hash(), tx.Hash())
+			}
+		}
+		var witnesses []*types.TxExtra
+		for _, tx := range block.Transactions() {
+			witness, err := api.GetTransactionWitness(ctx, tx.Hash())
+			if err != nil {
+				t.Fatalf("failed to retrieve transaction witness (regen: %v): %v", regen, err)
+			}
+			witnesses = append(witnesses, witness)
+		}
+		check(fmt.Sprintf("transaction (regen: %v)", regen), witnesses)
+
+		witnesses, err := api.GetBlockWitness(ctx, rpc.BlockNumberOrHashWithHash(block.Hash(), false))
+		if err != nil {
+			t.Fatalf("failed to retrieve block witness (regen: %v): %v", regen, err)
+		}
+		check(fmt.Sprintf("block (regen: %v)", regen), witnesses)
+	}
+	if _, err := api.GetTransactionWitness(ctx, common.Hash{0x01}); err == nil {
+		t.Fatalf("witness returned for unknown transaction")
+	}
+}
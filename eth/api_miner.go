@@ -17,11 +17,26 @@
 package eth
 
 import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
 	"math/big"
 	"time"
 
+	"github.com/ethereum/go-ethereum/accounts/abi"
 	"github.com/ethereum/go-ethereum/common"
 	"github.com/ethereum/go-ethereum/common/hexutil"
+	"github.com/ethereum/go-ethereum/core"
+	"github.com/ethereum/go-ethereum/core/state"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/core/vm"
+	"github.com/ethereum/go-ethereum/eth/tracers"
+	"github.com/ethereum/go-ethereum/internal/ethapi"
+	"github.com/ethereum/go-ethereum/rpc"
+
+	// Register the prestate tracer used to diff the state of simulations
+	_ "github.com/ethereum/go-ethereum/eth/tracers/native"
 )
 
 // MinerAPI provides an API to control the miner.
@@ -83,3 +98,209 @@ func (api *MinerAPI) SetEtherbase(etherbase common.Address) bool {
 func (api *MinerAPI) SetRecommitInterval(interval int) {
 	api.e.Miner().SetRecommitInterval(time.Duration(interval) * time.Millisecond)
 }
+
+// SimulationTx is a transaction to simulate on top of the pending block, either
+// a signed one in its binary encoding, or the fields of a call to execute on
+// behalf of an arbitrary sender, as accepted by eth_call.
+type SimulationTx struct {
+	Signed *types.Transaction
+	Call   *ethapi.TransactionArgs
+}
+
+// UnmarshalJSON parses a signed transaction from a hex string, or the fields of
+// an unsigned one from an object.
+func (tx *SimulationTx) UnmarshalJSON(input []byte) error {
+	if len(input) > 0 && input[0] == '"' {
+		var raw hexutil.Bytes
+		if err := json.Unmarshal(input, &raw); err != nil {
+			return err
+		}
+		tx.Signed = new(types.Transaction)
+		return tx.Signed.UnmarshalBinary(raw)
+	}
+	tx.Call = new(ethapi.TransactionArgs)
+	return json.Unmarshal(input, tx.Call)
+}
+
+// SimulationTxResult is the outcome of simulating a single transaction.
+type SimulationTxResult struct {
+	TxHash       *common.Hash    `json:"txHash,omitempty"` // Only set for signed transactions
+	From         common.Address  `json:"from"`
+	GasUsed      hexutil.Uint64  `json:"gasUsed"`
+	ReturnData   hexutil.Bytes   `json:"returnData"`
+	Logs         []*types.Log    `json:"logs"`
+	Error        string          `json:"error,omitempty"`
+	RevertReason string          `json:"revertReason,omitempty"`
+	StateDiff    json.RawMessage `json:"stateDiff,omitempty"`
+	CoinbaseDiff *hexutil.Big    `json:"coinbaseDiff"`
+}
+
+// SimulationResult is the outcome of simulating a list of transactions.
+type SimulationResult struct {
+	BlockNumber  hexutil.Uint64        `json:"blockNumber"`
+	Coinbase     common.Address        `json:"coinbase"`
+	GasUsed      hexutil.Uint64        `json:"gasUsed"`
+	CoinbaseDiff *hexutil.Big          `json:"coinbaseDiff"`
+	Results      []*SimulationTxResult `json:"results"`
+}
+
+// SimulatePending executes a list of transactions in order on top of the current
+// pending block, with the given state and block overrides applied, and returns
+// the gas used, logs, revert reason, state changes and coinbase payment of each
+// of them. Nothing is retained after the simulation.
+//
+// Transactions failing to execute, e.g. due to an invalid nonce, don't abort the
+// simulation: their error is reported and they don't affect the subsequent ones.
+func (api *MinerAPI) SimulatePending(ctx context.Context, txs []SimulationTx, overrides *ethapi.StateOverride, blockOverrides *ethapi.BlockOverrides) (*SimulationResult, error) {
+	if len(txs) == 0 {
+		return nil, errors.New("no transactions to simulate")
+	}
+	backend := api.e.APIBackend
+	statedb, header, err := backend.StateAndHeaderByNumber(ctx, rpc.PendingBlockNumber)
+	if err != nil {
+		return nil, err
+	}
+	if err := overrides.Apply(statedb); err != nil {
+		return nil, err
+	}
+	blockCtx := core.NewEVMBlockContext(header, api.e.BlockChain(), nil)
+	blockOverrides.Apply(&blockCtx)
+
+	// Limit the simulation the same way as calls, cancelling the EVM on timeout
+	var cancel context.CancelFunc
+	if timeout := backend.RPCEVMTimeout(); timeout > 0 {
+		ctx, cancel = context.WithTimeout(ctx, timeout)
+	} else {
+		ctx, cancel = context.WithCancel(ctx)
+	}
+	defer cancel()
+
+	var (
+		chainConfig = backend.ChainConfig()
+		signer      = types.MakeSigner(chainConfig, blockCtx.BlockNumber, blockCtx.Time)
+		gasPool     = new(core.GasPool)
+		coinbase    = new(big.Int).Set(statedb.GetBalance(blockCtx.Coinbase))
+		results     = make([]*SimulationTxResult, len(txs))
+		gasUsed     uint64
+	)
+	// Only the gas left over by the pending transactions is available
+	if blockCtx.GasLimit > header.GasUsed {
+		gasPool.AddGas(blockCtx.GasLimit - header.GasUsed)
+	}
+	for i, tx := range txs {
+		var (
+			msg  *core.Message
+			hash common.Hash
+		)
+		switch {
+		case tx.Signed != nil:
+			hash = tx.Signed.Hash()
+			msg, err = core.TransactionToMessage(tx.Signed, signer, blockCtx.BaseFee)
+		case tx.Call != nil:
+			// Unsigned transactions default to the gas left in the block
+			args := *tx.Call
+			if args.Gas == nil {
+				gas := hexutil.Uint64(gasPool.Gas())
+				args.Gas = &gas
+			}
+			// Unsigned transactions have no hash, key their logs by position
+			hash = common.BigToHash(big.NewInt(int64(i)))
+			msg, err = args.ToMessage(backend.RPCGasCap(), blockCtx.BaseFee)
+		default:
+			err = errors.New("missing transaction")
+		}
+		if err != nil {
+			return nil, fmt.Errorf("transaction %d: %w", i, err)
+		}
+		res, err := api.simulateTx(ctx, statedb, &blockCtx, gasPool, msg, hash, i, tx.Signed == nil)
+		if err != nil {
+			return nil, fmt.Errorf("transaction %d: %w", i, err)
+		}
+		if tx.Signed != nil {
+			res.TxHash = &hash
+		}
+		gasUsed += uint64(res.GasUsed)
+		results[i] = res
+	}
+	return &SimulationResult{
+		BlockNumber:  hexutil.Uint64(blockCtx.BlockNumber.Uint64()),
+		Coinbase:     blockCtx.Coinbase,
+		GasUsed:      hexutil.Uint64(gasUsed),
+		CoinbaseDiff: (*hexutil.Big)(new(big.Int).Sub(statedb.GetBalance(blockCtx.Coinbase), coinbase)),
+		Results:      results,
+	}, nil
+}
+
+// simulateTx executes a single message on top of the given state, tracing the
+// state changes it makes. Errors preventing the message from being executed are
+// reported in the result, only internal failures are returned.
+//
+// The base fee is only waived for unsigned calls, signed transactions have to
+// pay it like they would in a block.
+func (api *MinerAPI) simulateTx(ctx context.Context, statedb *state.StateDB, blockCtx *vm.BlockContext, gasPool *core.GasPool, msg *core.Message, hash common.Hash, index int, noBaseFee bool) (*SimulationTxResult, error) {
+	tracer, err := tracers.DefaultDirectory.New("prestateTracer", &tracers.Context{
+		BlockNumber: blockCtx.BlockNumber,
+		TxIndex:     index,
+		TxHash:      hash,
+	}, json.RawMessage(`{"diffMode": true}`))
+	if err != nil {
+		return nil, err
+	}
+	var (
+		coinbase = new(big.Int).Set(statedb.GetBalance(blockCtx.Coinbase))
+		evm      = vm.NewEVM(*blockCtx, core.NewEVMTxContext(msg), statedb, api.e.APIBackend.ChainConfig(), vm.Config{Tracer: tracer, NoBaseFee: noBaseFee})
+		done     = make(chan struct{})
+	)
+	defer close(done)
+	go func() {
+		select {
+		case <-ctx.Done():
+			evm.Cancel()
+		case <-done:
+		}
+	}()
+	statedb.SetTxContext(hash, index)
+
+	var (
+		result = &SimulationTxResult{From: msg.From, Logs: []*types.Log{}}
+		snap   = statedb.Snapshot()
+		gas    = gasPool.Gas()
+	)
+	res, err := core.ApplyMessage(evm, msg, gasPool)
+	if evm.Cancelled() {
+		return nil, fmt.Errorf("execution aborted (timeout = %v)", api.e.APIBackend.RPCEVMTimeout())
+	}
+	if err != nil {
+		// The message was rejected before execution, but possibly only after its
+		// gas was bought (e.g. insufficient intrinsic gas), undo the purchase
+		statedb.RevertToSnapshot(snap)
+		gasPool.SetGas(gas)
+
+		result.Error = err.Error()
+		result.CoinbaseDiff = new(hexutil.Big)
+		return result, nil
+	}
+	// Finalise the state to attribute self-destructs and touched accounts to
+	// the transaction, as block processing would
+	statedb.Finalise(api.e.APIBackend.ChainConfig().IsEIP158(blockCtx.BlockNumber))
+
+	result.GasUsed = hexutil.Uint64(res.UsedGas)
+	result.ReturnData = res.Return()
+	if logs := statedb.GetLogs(hash, blockCtx.BlockNumber.Uint64(), common.Hash{}); logs != nil {
+		result.Logs = logs
+	}
+	if res.Err != nil {
+		result.Error = res.Err.Error()
+		if errors.Is(res.Err, vm.ErrExecutionReverted) {
+			result.ReturnData = res.Revert()
+			if reason, err := abi.UnpackRevert(res.Revert()); err == nil {
+				result.RevertReason = reason
+			}
+		}
+	}
+	if result.StateDiff, err = tracer.GetResult(); err != nil {
+		return nil, err
+	}
+	result.CoinbaseDiff = (*hexutil.Big)(new(big.Int).Sub(statedb.GetBalance(blockCtx.Coinbase), coinbase))
+	return result, nil
+}
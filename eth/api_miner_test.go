@@ -0,0 +1,241 @@
+// Copyright 2023 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package eth
+
+import (
+	"context"
+	"encoding/json"
+	"math/big"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/common/hexutil"
+	"github.com/ethereum/go-ethereum/core"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/crypto"
+	"github.com/ethereum/go-ethereum/eth/ethconfig"
+	"github.com/ethereum/go-ethereum/internal/ethapi"
+	"github.com/ethereum/go-ethereum/node"
+	"github.com/ethereum/go-ethereum/params"
+)
+
+// Tests that transactions are simulated on top of the pending block, reporting
+// the outcome of each of them.
+func TestSimulatePending(t *testing.T) {
+	var (
+		key, _   = crypto.GenerateKey()
+		sender   = crypto.PubkeyToAddress(key.PublicKey)
+		coinbase = common.HexToAddress("0xc0ffee")
+		funded   = big.NewInt(params.Ether)
+
+		// Contract logging the 0x2a word
+		logger     = common.HexToAddress("0x1000")
+		loggerCode = common.FromHex("602a60005260206000a000")
+
+		// Contract reverting with Error("boom")
+		reverter     = common.HexToAddress("0x2000")
+		revertReason = append(append(crypto.Keccak256([]byte("Error(string)"))[:4], common.LeftPadBytes([]byte{0x20}, 32)...), append(common.LeftPadBytes([]byte{4}, 32), common.RightPadBytes([]byte("boom"), 32)...)...)
+		reverterCode = append(common.FromHex("6064600c60003960646000fd"), revertReason...)
+	)
+	genesis := &core.Genesis{
+		Config:   params.AllEthashProtocolChanges,
+		GasLimit: 30_000_000,
+		BaseFee:  big.NewInt(params.InitialBaseFee),
+		Alloc: core.GenesisAlloc{
+			sender:   {Balance: funded},
+			logger:   {Code: loggerCode, Balance: new(big.Int)},
+			reverter: {Code: reverterCode, Balance: new(big.Int)},
+		},
+	}
+	stack, err := node.New(&node.Config{})
+	if err != nil {
+		t.Fatalf("failed to create node: %v", err)
+	}
+	defer stack.Close()
+
+	config := ethconfig.Defaults
+	config.Genesis = genesis
+	ethservice, err := New(stack, &config)
+	if err != nil {
+		t.Fatalf("failed to create ethereum service: %v", err)
+	}
+	if err := stack.Start(); err != nil {
+		t.Fatalf("failed to start node: %v", err)
+	}
+	// Wait for the miner to assemble the pending block
+	for i := 0; ; i++ {
+		if block, _ := ethservice.Miner().Pending(); block != nil {
+			break
+		}
+		if i == 100 {
+			t.Fatalf("pending block not assembled")
+		}
+		time.Sleep(20 * time.Millisecond)
+	}
+	var (
+		signer   = types.LatestSigner(genesis.Config)
+		tip      = big.NewInt(params.GWei)
+		feeCap   = new(big.Int).Add(big.NewInt(2*params.InitialBaseFee), tip)
+		to       = common.HexToAddress("0xdead")
+		transfer = types.MustSignNewTx(key, signer, &types.DynamicFeeTx{Nonce: 0, To: &to, Value: big.NewInt(1), Gas: params.TxGas, GasFeeCap: feeCap, GasTipCap: tip})
+		stale    = types.MustSignNewTx(key, signer, &types.DynamicFeeTx{Nonce: 0, To: &to, Value: big.NewInt(2), Gas: params.TxGas, GasFeeCap: feeCap, GasTipCap: tip})
+
+		// The unsigned calls bump the sender nonce too, these follow them
+		lowGas = types.MustSignNewTx(key, signer, &types.DynamicFeeTx{Nonce: 3, To: &to, Gas: params.TxGas - 1, GasFeeCap: feeCap, GasTipCap: tip})
+		cheap  = types.MustSignNewTx(key, signer, &types.DynamicFeeTx{Nonce: 3, To: &to, Gas: params.TxGas, GasFeeCap: big.NewInt(1), GasTipCap: big.NewInt(1)})
+
+		unfunded = common.HexToAddress("0xbeef")
+		value    = hexutil.Big(*big.NewInt(params.Ether))
+		balance  = (*hexutil.Big)(new(big.Int).Mul(big.NewInt(2), big.NewInt(params.Ether)))
+	)
+	// Pass the transactions through JSON, the way they are submitted over RPC
+	rawTransfer, _ := transfer.MarshalBinary()
+	rawStale, _ := stale.MarshalBinary()
+	rawLowGas, _ := lowGas.MarshalBinary()
+	rawCheap, _ := cheap.MarshalBinary()
+	input, _ := json.Marshal([]interface{}{
+		hexutil.Bytes(rawTransfer),
+		&ethapi.TransactionArgs{From: &sender, To: &logger},
+		&ethapi.TransactionArgs{From: &sender, To: &reverter},
+		hexutil.Bytes(rawStale),
+		&ethapi.TransactionArgs{From: &unfunded, To: &to, Value: &value},
+		hexutil.Bytes(rawLowGas),
+		hexutil.Bytes(rawCheap),
+		&ethapi.TransactionArgs{From: &sender, To: &to, Value: (*hexutil.Big)(big.NewInt(1))},
+	})
+	var txs []SimulationTx
+	if err := json.Unmarshal(input, &txs); err != nil {
+		t.Fatalf("failed to decode transactions: %v", err)
+	}
+	overrides := &ethapi.StateOverride{unfunded: {Balance: &balance}}
+
+	api := NewMinerAPI(ethservice)
+	result, err := api.SimulatePending(context.Background(), txs, overrides, &ethapi.BlockOverrides{Coinbase: &coinbase})
+	if err != nil {
+		t.Fatalf("failed to simulate: %v", err)
+	}
+	if have, want := uint64(result.BlockNumber), uint64(1); have != want {
+		t.Errorf("block number mismatch: have %d, want %d", have, want)
+	}
+	if result.Coinbase != coinbase {
+		t.Errorf("coinbase mismatch: have %x, want %x", result.Coinbase, coinbase)
+	}
+	if len(result.Results) != len(txs) {
+		t.Fatalf("result count mismatch: have %d, want %d", len(result.Results), len(txs))
+	}
+	// The signed transfer pays its tip to the coinbase and changes balances
+	res := result.Results[0]
+	if res.TxHash == nil || *res.TxHash != transfer.Hash() {
+		t.Errorf("transfer: hash mismatch: have %v, want %x", res.TxHash, transfer.Hash())
+	}
+	if res.Error != "" || uint64(res.GasUsed) != params.TxGas {
+		t.Errorf("transfer: outcome mismatch: error %q, gas %d", res.Error, res.GasUsed)
+	}
+	if have, want := res.CoinbaseDiff.ToInt(), new(big.Int).Mul(tip, big.NewInt(int64(params.TxGas))); have.Cmp(want) != 0 {
+		t.Errorf("transfer: coinbase diff mismatch: have %v, want %v", have, want)
+	}
+	type stateDiff struct {
+		Pre map[common.Address]struct {
+			Balance *hexutil.Big `json:"balance"`
+		} `json:"pre"`
+		Post map[common.Address]struct {
+			Balance *hexutil.Big `json:"balance"`
+		} `json:"post"`
+	}
+	var diff stateDiff
+	if err := json.Unmarshal(res.StateDiff, &diff); err != nil {
+		t.Fatalf("transfer: failed to decode state diff: %v", err)
+	}
+	if post, ok := diff.Post[to]; !ok || post.Balance.ToInt().Cmp(big.NewInt(1)) != 0 {
+		t.Errorf("transfer: recipient missing from state diff: %s", res.StateDiff)
+	}
+	// The unsigned calls emit logs and report revert reasons
+	if res := result.Results[1]; res.TxHash != nil || res.Error != "" || len(res.Logs) != 1 || res.Logs[0].Address != logger {
+		t.Errorf("logger: outcome mismatch: hash %v, error %q, logs %v", res.TxHash, res.Error, res.Logs)
+	}
+	if res := result.Results[2]; res.RevertReason != "boom" || !strings.Contains(res.Error, "reverted") || res.GasUsed == 0 {
+		t.Errorf("reverter: outcome mismatch: error %q, reason %q, gas %d", res.Error, res.RevertReason, res.GasUsed)
+	}
+	// The stale transaction is rejected without affecting the state
+	if res := result.Results[3]; !strings.Contains(res.Error, "nonce too low") || res.GasUsed != 0 || res.CoinbaseDiff.ToInt().Sign() != 0 {
+		t.Errorf("stale: outcome mismatch: error %q, gas %d, coinbase diff %v", res.Error, res.GasUsed, res.CoinbaseDiff)
+	}
+	// The overridden balance funds the unsigned transfer
+	if res := result.Results[4]; res.Error != "" {
+		t.Errorf("override: unexpected error: %v", res.Error)
+	}
+	// Transactions rejected after buying their gas don't leave the sender charged,
+	// and signed ones can't skip the base fee
+	if res := result.Results[5]; !strings.Contains(res.Error, core.ErrIntrinsicGas.Error()) || res.GasUsed != 0 {
+		t.Errorf("low gas: outcome mismatch: error %q, gas %d", res.Error, res.GasUsed)
+	}
+	if res := result.Results[6]; !strings.Contains(res.Error, core.ErrFeeCapTooLow.Error()) {
+		t.Errorf("cheap: outcome mismatch: error %q", res.Error)
+	}
+	var after stateDiff
+	if err := json.Unmarshal(result.Results[7].StateDiff, &after); err != nil {
+		t.Fatalf("follow-up: failed to decode state diff: %v", err)
+	}
+	pre, post := after.Pre[sender].Balance, diff.Post[sender].Balance
+	if pre == nil || post == nil || pre.ToInt().Cmp(post.ToInt()) != 0 {
+		t.Errorf("follow-up: sender balance mismatch: have %v, want %v", pre, post)
+	}
+	var total uint64
+	for _, res := range result.Results {
+		total += uint64(res.GasUsed)
+	}
+	if uint64(result.GasUsed) != total {
+		t.Errorf("total gas mismatch: have %d, want %d", result.GasUsed, total)
+	}
+	if result.CoinbaseDiff.ToInt().Cmp(result.Results[0].CoinbaseDiff.ToInt()) != 0 {
+		t.Errorf("total coinbase diff mismatch: have %v, want %v", result.CoinbaseDiff, result.Results[0].CoinbaseDiff)
+	}
+	// Nothing must be retained after the simulation
+	if _, state := ethservice.Miner().Pending(); state.GetNonce(sender) != 0 || state.GetBalance(to).Sign() != 0 {
+		t.Errorf("simulation leaked into the pending state")
+	}
+	// Transactions only get the gas left over by the pending block
+	if err := ethservice.TxPool().AddLocal(transfer); err != nil {
+		t.Fatalf("failed to add pending transaction: %v", err)
+	}
+	for i := 0; ; i++ {
+		if block, _ := ethservice.Miner().Pending(); block != nil && block.GasUsed() > 0 {
+			break
+		}
+		if i == 100 {
+			t.Fatalf("pending transaction not included")
+		}
+		time.Sleep(20 * time.Millisecond)
+	}
+	var (
+		left      = genesis.GasLimit - params.TxGas
+		fits      = types.MustSignNewTx(key, signer, &types.DynamicFeeTx{Nonce: 1, To: &to, Gas: left, GasFeeCap: feeCap, GasTipCap: tip})
+		overflows = types.MustSignNewTx(key, signer, &types.DynamicFeeTx{Nonce: 1, To: &to, Gas: left + 1, GasFeeCap: feeCap, GasTipCap: tip})
+	)
+	result, err = api.SimulatePending(context.Background(), []SimulationTx{{Signed: overflows}, {Signed: fits}}, nil, nil)
+	if err != nil {
+		t.Fatalf("failed to simulate on non-empty pending block: %v", err)
+	}
+	if res := result.Results[0]; !strings.Contains(res.Error, core.ErrGasLimitReached.Error()) {
+		t.Errorf("overflowing: outcome mismatch: error %q", res.Error)
+	}
+	if res := result.Results[1]; res.Error != "" {
+		t.Errorf("fitting: unexpected error: %v", res.Error)
+	}
+}
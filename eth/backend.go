@@ -35,6 +35,7 @@ import (
 	"github.com/ethereum/go-ethereum/core/rawdb"
 	"github.com/ethereum/go-ethereum/core/state/pruner"
 	"github.com/ethereum/go-ethereum/core/txpool"
+	"github.com/ethereum/go-ethereum/core/txpool/blobpool"
 	"github.com/ethereum/go-ethereum/core/types"
 	"github.com/ethereum/go-ethereum/core/vm"
 	"github.com/ethereum/go-ethereum/eth/downloader"
@@ -42,6 +43,7 @@ import (
 	"github.com/ethereum/go-ethereum/eth/gasprice"
 	"github.com/ethereum/go-ethereum/eth/protocols/eth"
 	"github.com/ethereum/go-ethereum/eth/protocols/snap"
+	"github.com/ethereum/go-ethereum/eth/tracers"
 	"github.com/ethereum/go-ethereum/ethdb"
 	"github.com/ethereum/go-ethereum/event"
 	"github.com/ethereum/go-ethereum/internal/ethapi"
@@ -84,6 +86,10 @@ type Ethereum struct {
 	bloomIndexer      *core.ChainIndexer             // Bloom indexer operating during block imports
 	closeBloomHandler chan struct{}
 
+	traceDb      ethdb.Database        // Database of the indexed call traces, if enabled
+	traceIndexer *tracers.TraceIndexer // Call trace indexer operating on chain head updates
+	traceDir     string                // Directory chain traces are written into, empty if unavailable
+
 	APIBackend *EthAPIBackend
 
 	miner     *miner.Miner
@@ -190,6 +196,9 @@ func New(stack *node.Node, config *ethconfig.Config) (*Ethereum, error) {
 			TrieTimeLimit:       config.TrieTimeout,
 			SnapshotLimit:       config.SnapshotCache,
 			Preimages:           config.Preimages,
+			WitnessStart:        config.WitnessStart,
+			WitnessEnd:          config.WitnessEnd,
+			WitnessNoLimit:      config.WitnessNoLimit,
 		}
 	)
 	// Override the chain config with provided settings.
@@ -206,7 +215,21 @@ func New(stack *node.Node, config *ethconfig.Config) (*Ethereum, error) {
 	if config.TxPool.Journal != "" {
 		config.TxPool.Journal = stack.ResolvePath(config.TxPool.Journal)
 	}
-	eth.txPool = txpool.New(config.TxPool, eth.blockchain.Config(), eth.blockchain)
+	if config.TxPool.Snapshot != "" {
+		config.TxPool.Snapshot = stack.ResolvePath(config.TxPool.Snapshot)
+	}
+	if config.BlobPool.Datadir != "" {
+		config.BlobPool.Datadir = stack.ResolvePath(config.BlobPool.Datadir)
+	}
+	policies, err := txpool.NewAdmissionPolicies(config.TxPool.Admission)
+	if err != nil {
+		return nil, fmt.Errorf("invalid txpool admission policies: %w", err)
+	}
+	blobPool, err := blobpool.New(config.BlobPool, eth.blockchain.Config(), eth.blockchain)
+	if err != nil {
+		return nil, err
+	}
+	eth.txPool = txpool.New(config.TxPool, eth.blockchain.Config(), eth.blockchain, blobPool, policies...)
 
 	// Permit the downloader to use the trie cache allowance during fast sync
 	cacheLimit := cacheConfig.TrieCleanLimit + cacheConfig.TrieDirtyLimit + cacheConfig.SnapshotLimit
@@ -224,6 +247,9 @@ func New(stack *node.Node, config *ethconfig.Config) (*Ethereum, error) {
 		return nil, err
 	}
 
+	if err := miner.ValidateOrdering(config.Miner.Ordering); err != nil {
+		return nil, fmt.Errorf("invalid miner ordering: %w", err)
+	}
 	eth.miner = miner.New(eth, &config.Miner, eth.blockchain.Config(), eth.EventMux(), eth.engine, eth.isLocalBlock)
 	eth.miner.SetExtra(makeExtraData(config.Miner.ExtraData))
 
@@ -237,6 +263,25 @@ func New(stack *node.Node, config *ethconfig.Config) (*Ethereum, error) {
 	}
 	eth.APIBackend.gpo = gasprice.NewOracle(eth.APIBackend, gpoParams)
 
+	// Confine the chain traces written to files to a dedicated directory
+	traceDir := config.TraceDir
+	if traceDir == "" {
+		traceDir = "chaintraces"
+	}
+	eth.traceDir = stack.ResolvePath(traceDir)
+
+	// Index the call traces of the chain if requested. The traces are kept in a
+	// key-value store of their own rather than an append-only freezer, since the
+	// backfill extends the index backwards and pruning cuts it from below.
+	if config.TraceIndex {
+		eth.traceDb, err = stack.OpenDatabase("traces", 0, 0, "eth/db/traces/", false)
+		if err != nil {
+			return nil, err
+		}
+		eth.traceIndexer = tracers.NewTraceIndexer(eth.APIBackend, eth.traceDb, config.TraceIndexHistory)
+		log.Info("Enabled call trace indexing", "history", config.TraceIndexHistory)
+	}
+
 	// Setup DNS discovery iterators.
 	dnsclient := dnsdisc.NewClient(dnsdisc.Config{})
 	eth.ethDialCandidates, err = dnsclient.NewIterator(eth.config.EthDiscoveryURLs...)
@@ -485,6 +530,11 @@ func (s *Ethereum) Start() error {
 	// Start the bloom bits servicing goroutines
 	s.startBloomHandlers(params.BloomBitsBlocks)
 
+	// Start indexing the call traces if enabled
+	if s.traceIndexer != nil {
+		s.traceIndexer.Start()
+	}
+
 	// Regularly update shutdown marker
 	s.shutdownTracker.Start()
 
@@ -510,6 +560,10 @@ func (s *Ethereum) Stop() error {
 	s.handler.Stop()
 
 	// Then stop everything else.
+	if s.traceIndexer != nil {
+		s.traceIndexer.Stop()
+		s.traceDb.Close()
+	}
 	s.bloomIndexer.Close()
 	close(s.closeBloomHandler)
 	s.txPool.Stop()
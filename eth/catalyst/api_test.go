@@ -37,6 +37,7 @@ import (
 	"github.com/ethereum/go-ethereum/core"
 	"github.com/ethereum/go-ethereum/core/types"
 	"github.com/ethereum/go-ethereum/crypto"
+	"github.com/ethereum/go-ethereum/crypto/kzg4844"
 	"github.com/ethereum/go-ethereum/eth"
 	"github.com/ethereum/go-ethereum/eth/downloader"
 	"github.com/ethereum/go-ethereum/eth/ethconfig"
@@ -46,6 +47,7 @@ import (
 	"github.com/ethereum/go-ethereum/params"
 	"github.com/ethereum/go-ethereum/rpc"
 	"github.com/ethereum/go-ethereum/trie"
+	"github.com/holiman/uint256"
 )
 
 var (
@@ -311,7 +313,7 @@ func TestEth2NewBlock(t *testing.T) {
 		if err != nil {
 			t.Fatalf("Failed to create the executable data %v", err)
 		}
-		block, err := engine.ExecutableDataToBlock(*execData)
+		block, err := engine.ExecutableDataToBlock(*execData, nil, nil)
 		if err != nil {
 			t.Fatalf("Failed to convert executable data to block %v", err)
 		}
@@ -353,7 +355,7 @@ func TestEth2NewBlock(t *testing.T) {
 		if err != nil {
 			t.Fatalf("Failed to create the executable data %v", err)
 		}
-		block, err := engine.ExecutableDataToBlock(*execData)
+		block, err := engine.ExecutableDataToBlock(*execData, nil, nil)
 		if err != nil {
 			t.Fatalf("Failed to convert executable data to block %v", err)
 		}
@@ -452,6 +454,20 @@ func startEthService(t *testing.T, genesis *core.Genesis, blocks []*types.Block)
 		n.Close()
 		t.Fatal("can't import test blocks:", err)
 	}
+	// Wait for the pool to reset to the imported head, otherwise transactions
+	// continuing the imported ones are considered gapped and queued
+	statedb, err := ethservice.BlockChain().State()
+	if err != nil {
+		n.Close()
+		t.Fatal("can't retrieve head state:", err)
+	}
+	for i := 0; ethservice.TxPool().Nonce(testAddr) != statedb.GetNonce(testAddr); i++ {
+		if i == 250 {
+			n.Close()
+			t.Fatal("transaction pool not reset to the imported head")
+		}
+		time.Sleep(20 * time.Millisecond)
+	}
 
 	ethservice.SetEtherbase(testAddr)
 	ethservice.SetSynced()
@@ -985,7 +1001,7 @@ func TestSimultaneousNewBlock(t *testing.T) {
 				t.Fatal(testErr)
 			}
 		}
-		block, err := engine.ExecutableDataToBlock(*execData)
+		block, err := engine.ExecutableDataToBlock(*execData, nil, nil)
 		if err != nil {
 			t.Fatalf("Failed to convert executable data to block %v", err)
 		}
@@ -1073,6 +1089,9 @@ func TestWithdrawals(t *testing.T) {
 	if execData.ExecutionPayload.StateRoot != parent.Root {
 		t.Fatalf("mismatch state roots (got: %s, want: %s)", execData.ExecutionPayload.StateRoot, blocks[8].Root())
 	}
+	if execData.BlobsBundle != nil {
+		t.Fatalf("blobs bundle attached to pre-cancun payload")
+	}
 
 	// 10: verify locally built block
 	if status, err := api.NewPayloadV2(*execData.ExecutionPayload); err != nil {
@@ -1255,6 +1274,159 @@ func TestNilWithdrawals(t *testing.T) {
 	}
 }
 
+// TestCancunPayload tests building and importing Cancun payloads carrying blob
+// transactions through the V3 engine methods.
+func TestCancunPayload(t *testing.T) {
+	genesis, blocks := generateMergeChain(10, true)
+	// Set shanghai and cancun time to last block + 5 seconds (first post-merge block)
+	time := blocks[len(blocks)-1].Time() + 5
+	genesis.Config.ShanghaiTime = &time
+	genesis.Config.CancunTime = &time
+
+	n, ethservice := startEthService(t, genesis, blocks)
+	ethservice.Merger().ReachTTD()
+	defer n.Close()
+
+	api := NewConsensusAPI(ethservice)
+
+	// 10: Build an empty Cancun block, the pool only accepts blob transactions
+	// on top of a Cancun head.
+	parent := ethservice.BlockChain().CurrentHeader()
+	blockParams := engine.PayloadAttributes{
+		Timestamp:   parent.Time + 5,
+		Withdrawals: make([]*types.Withdrawal, 0),
+		BeaconRoot:  &common.Hash{0x42},
+	}
+	fcState := engine.ForkchoiceStateV1{
+		HeadBlockHash: parent.Hash(),
+	}
+	if _, err := api.ForkchoiceUpdatedV2(fcState, &blockParams); err == nil {
+		t.Fatalf("expected error preparing cancun payload with V2")
+	}
+	resp, err := api.ForkchoiceUpdatedV3(fcState, &blockParams)
+	if err != nil {
+		t.Fatalf("error preparing payload, err=%v", err)
+	}
+	if resp.PayloadStatus.Status != engine.VALID {
+		t.Fatalf("unexpected status (got: %s, want: %s)", resp.PayloadStatus.Status, engine.VALID)
+	}
+	envelope, err := api.GetPayloadV3(*resp.PayloadID)
+	if err != nil {
+		t.Fatalf("error getting payload, err=%v", err)
+	}
+	if envelope.ExecutionPayload.ExcessBlobGas == nil || envelope.ExecutionPayload.BlobGasUsed == nil {
+		t.Fatalf("missing blob gas fields in cancun payload")
+	}
+	if envelope.BlobsBundle == nil {
+		t.Fatalf("missing blobs bundle in cancun payload")
+	}
+	if _, err := api.GetPayloadV2(*resp.PayloadID); err == nil {
+		t.Fatalf("cancun payload served through V2")
+	}
+	if status, err := api.NewPayloadV3(*envelope.ExecutionPayload, []common.Hash{}, blockParams.BeaconRoot); err != nil {
+		t.Fatalf("error validating payload: %v", err)
+	} else if status.Status != engine.VALID {
+		t.Fatalf("invalid payload: %v", *status.ValidationError)
+	}
+	fcState.HeadBlockHash = envelope.ExecutionPayload.BlockHash
+	if _, err := api.ForkchoiceUpdatedV3(fcState, nil); err != nil {
+		t.Fatalf("error setting head, err=%v", err)
+	}
+
+	// 11: Pool a blob transaction and build a block including it
+	// Pool a blob transaction to be included in the next payload
+	var (
+		blob      kzg4844.Blob
+		commit, _ = kzg4844.BlobToCommitment(blob)
+		proof, _  = kzg4844.ComputeBlobProof(blob, commit)
+		sidecar   = &types.BlobTxSidecar{
+			Blobs:       []kzg4844.Blob{blob},
+			Commitments: []kzg4844.Commitment{commit},
+			Proofs:      []kzg4844.Proof{proof},
+		}
+	)
+	tx := types.MustSignNewTx(testKey, types.LatestSigner(genesis.Config), &types.BlobTx{
+		ChainID:    uint256.MustFromBig(genesis.Config.ChainID),
+		Nonce:      ethservice.TxPool().Nonce(testAddr),
+		GasTipCap:  uint256.NewInt(params.GWei),
+		GasFeeCap:  uint256.NewInt(2 * params.InitialBaseFee),
+		Gas:        params.TxGas,
+		To:         common.Address{0x01},
+		BlobFeeCap: uint256.NewInt(params.GWei),
+		BlobHashes: sidecar.BlobHashes(),
+	})
+	if errs := ethservice.TxPool().AddBlobs([]*types.Transaction{tx}, []*types.BlobTxSidecar{sidecar}, true); errs[0] != nil {
+		t.Fatalf("failed to add blob transaction: %v", errs[0])
+	}
+	blockParams = engine.PayloadAttributes{
+		Timestamp:   envelope.ExecutionPayload.Timestamp + 5,
+		Withdrawals: make([]*types.Withdrawal, 0),
+		BeaconRoot:  &common.Hash{0x43},
+	}
+	payload, err := api.eth.Miner().BuildPayload(&miner.BuildPayloadArgs{
+		Parent:      fcState.HeadBlockHash,
+		Timestamp:   blockParams.Timestamp,
+		Withdrawals: blockParams.Withdrawals,
+		BeaconRoot:  blockParams.BeaconRoot,
+	})
+	if err != nil {
+		t.Fatalf("error building payload, err=%v", err)
+	}
+	envelope = payload.ResolveFull()
+	execData := envelope.ExecutionPayload
+	if len(execData.Transactions) != 1 {
+		t.Fatalf("invalid number of transactions, have %d want 1", len(execData.Transactions))
+	}
+	if execData.BlobGasUsed == nil || *execData.BlobGasUsed != params.BlobTxDataGasPerBlob {
+		t.Fatalf("invalid blob gas used, have %v want %d", execData.BlobGasUsed, params.BlobTxDataGasPerBlob)
+	}
+	if execData.ExcessBlobGas == nil {
+		t.Fatalf("missing excess blob gas")
+	}
+	bundle := envelope.BlobsBundle
+	if bundle == nil || len(bundle.Blobs) != 1 || len(bundle.Commitments) != 1 || len(bundle.Proofs) != 1 {
+		t.Fatalf("invalid blobs bundle: %v", bundle)
+	}
+	if !bytes.Equal(bundle.Commitments[0], commit[:]) || !bytes.Equal(bundle.Proofs[0], proof[:]) {
+		t.Fatalf("blobs bundle mismatch")
+	}
+	// Ensure the payload is rejected with mismatching versioned hashes or
+	// through the pre-cancun methods
+	if status, err := api.NewPayloadV3(*execData, []common.Hash{}, blockParams.BeaconRoot); err != nil {
+		t.Fatalf("error validating payload: %v", err)
+	} else if status.Status != engine.INVALID {
+		t.Fatalf("payload with missing versioned hashes accepted")
+	}
+	if status, err := api.NewPayloadV3(*execData, []common.Hash{{0x01}}, blockParams.BeaconRoot); err != nil {
+		t.Fatalf("error validating payload: %v", err)
+	} else if status.Status != engine.INVALID {
+		t.Fatalf("payload with wrong versioned hashes accepted")
+	}
+	if _, err := api.NewPayloadV3(*execData, tx.BlobHashes(), nil); err == nil {
+		t.Fatalf("payload without parent beacon block root accepted")
+	}
+	if _, err := api.NewPayloadV2(*execData); err == nil {
+		t.Fatalf("cancun payload accepted through V2")
+	}
+	// Ensure the payload is accepted with the right versioned hashes
+	if status, err := api.NewPayloadV3(*execData, tx.BlobHashes(), blockParams.BeaconRoot); err != nil {
+		t.Fatalf("error validating payload: %v", err)
+	} else if status.Status != engine.VALID {
+		t.Fatalf("invalid payload: %v", *status.ValidationError)
+	}
+	fcState.HeadBlockHash = execData.BlockHash
+	if _, err := api.ForkchoiceUpdatedV3(fcState, nil); err != nil {
+		t.Fatalf("error setting head, err=%v", err)
+	}
+	head := ethservice.BlockChain().CurrentBlock()
+	if head.Hash() != execData.BlockHash {
+		t.Fatalf("head not updated, have %x want %x", head.Hash(), execData.BlockHash)
+	}
+	if head.ParentBeaconRoot == nil || *head.ParentBeaconRoot != *blockParams.BeaconRoot {
+		t.Fatalf("parent beacon block root mismatch, have %v want %x", head.ParentBeaconRoot, *blockParams.BeaconRoot)
+	}
+}
+
 func setupBodies(t *testing.T) (*node.Node, *eth.Ethereum, []*types.Block) {
 	genesis, blocks := generateMergeChain(10, true)
 	// enable shanghai on the last block
@@ -28,6 +28,7 @@ import (
 	"github.com/ethereum/go-ethereum/consensus/ethash"
 	"github.com/ethereum/go-ethereum/core"
 	"github.com/ethereum/go-ethereum/core/txpool"
+	"github.com/ethereum/go-ethereum/core/txpool/blobpool"
 	"github.com/ethereum/go-ethereum/eth/downloader"
 	"github.com/ethereum/go-ethereum/eth/gasprice"
 	"github.com/ethereum/go-ethereum/ethdb"
@@ -72,6 +73,7 @@ var Defaults = Config{
 	FilterLogCacheSize:      32,
 	Miner:                   miner.DefaultConfig,
 	TxPool:                  txpool.DefaultConfig,
+	BlobPool:                blobpool.DefaultConfig,
 	RPCGasCap:               50000000,
 	RPCEVMTimeout:           5 * time.Second,
 	GPO:                     FullNodeGPO,
@@ -135,11 +137,25 @@ type Config struct {
 	// This is the number of blocks for which logs will be cached in the filter system.
 	FilterLogCacheSize int
 
+	// Transaction witness options
+	WitnessStart   uint64 // First block number to record transaction witnesses for
+	WitnessEnd     uint64 // Last block number to record transaction witnesses for (0 = disabled)
+	WitnessNoLimit bool   // Whether to record transaction witnesses for every block
+
+	// Call trace index options
+	TraceIndex        bool   // Whether to index the call traces of the chain
+	TraceIndexHistory uint64 // Number of recent blocks to keep the call trace index for, backfilled as far as historical state is available
+
+	// TraceDir is the directory debug_traceChainToFile writes into, relative to
+	// the data directory unless absolute. Defaults to "chaintraces".
+	TraceDir string `toml:",omitempty"`
+
 	// Mining options
 	Miner miner.Config
 
 	// Transaction pool options
-	TxPool txpool.Config
+	TxPool   txpool.Config
+	BlobPool blobpool.Config
 
 	// Gas Price Oracle options
 	GPO gasprice.Config
@@ -8,6 +8,7 @@ import (
 	"github.com/ethereum/go-ethereum/common"
 	"github.com/ethereum/go-ethereum/core"
 	"github.com/ethereum/go-ethereum/core/txpool"
+	"github.com/ethereum/go-ethereum/core/txpool/blobpool"
 	"github.com/ethereum/go-ethereum/eth/downloader"
 	"github.com/ethereum/go-ethereum/eth/gasprice"
 	"github.com/ethereum/go-ethereum/miner"
@@ -46,8 +47,15 @@ func (c Config) MarshalTOML() (interface{}, error) {
 		SnapshotCache           int
 		Preimages               bool
 		FilterLogCacheSize      int
+		WitnessStart            uint64
+		WitnessEnd              uint64
+		WitnessNoLimit          bool
+		TraceIndex              bool
+		TraceIndexHistory       uint64
+		TraceDir                string `toml:",omitempty"`
 		Miner                   miner.Config
 		TxPool                  txpool.Config
+		BlobPool                blobpool.Config
 		GPO                     gasprice.Config
 		EnablePreimageRecording bool
 		DocRoot                 string `toml:"-"`
@@ -87,8 +95,15 @@ func (c Config) MarshalTOML() (interface{}, error) {
 	enc.SnapshotCache = c.SnapshotCache
 	enc.Preimages = c.Preimages
 	enc.FilterLogCacheSize = c.FilterLogCacheSize
+	enc.WitnessStart = c.WitnessStart
+	enc.WitnessEnd = c.WitnessEnd
+	enc.WitnessNoLimit = c.WitnessNoLimit
+	enc.TraceIndex = c.TraceIndex
+	enc.TraceIndexHistory = c.TraceIndexHistory
+	enc.TraceDir = c.TraceDir
 	enc.Miner = c.Miner
 	enc.TxPool = c.TxPool
+	enc.BlobPool = c.BlobPool
 	enc.GPO = c.GPO
 	enc.EnablePreimageRecording = c.EnablePreimageRecording
 	enc.DocRoot = c.DocRoot
@@ -132,8 +147,15 @@ func (c *Config) UnmarshalTOML(unmarshal func(interface{}) error) error {
 		SnapshotCache           *int
 		Preimages               *bool
 		FilterLogCacheSize      *int
+		WitnessStart            *uint64
+		WitnessEnd              *uint64
+		WitnessNoLimit          *bool
+		TraceIndex              *bool
+		TraceIndexHistory       *uint64
+		TraceDir                *string `toml:",omitempty"`
 		Miner                   *miner.Config
 		TxPool                  *txpool.Config
+		BlobPool                *blobpool.Config
 		GPO                     *gasprice.Config
 		EnablePreimageRecording *bool
 		DocRoot                 *string `toml:"-"`
@@ -236,12 +258,33 @@ func (c *Config) UnmarshalTOML(unmarshal func(interface{}) error) error {
 	if dec.FilterLogCacheSize != nil {
 		c.FilterLogCacheSize = *dec.FilterLogCacheSize
 	}
+	if dec.WitnessStart != nil {
+		c.WitnessStart = *dec.WitnessStart
+	}
+	if dec.WitnessEnd != nil {
+		c.WitnessEnd = *dec.WitnessEnd
+	}
+	if dec.WitnessNoLimit != nil {
+		c.WitnessNoLimit = *dec.WitnessNoLimit
+	}
+	if dec.TraceIndex != nil {
+		c.TraceIndex = *dec.TraceIndex
+	}
+	if dec.TraceIndexHistory != nil {
+		c.TraceIndexHistory = *dec.TraceIndexHistory
+	}
+	if dec.TraceDir != nil {
+		c.TraceDir = *dec.TraceDir
+	}
 	if dec.Miner != nil {
 		c.Miner = *dec.Miner
 	}
 	if dec.TxPool != nil {
 		c.TxPool = *dec.TxPool
 	}
+	if dec.BlobPool != nil {
+		c.BlobPool = *dec.BlobPool
+	}
 	if dec.GPO != nil {
 		c.GPO = *dec.GPO
 	}
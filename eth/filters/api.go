@@ -28,6 +28,7 @@ import (
 	"github.com/ethereum/go-ethereum"
 	"github.com/ethereum/go-ethereum/common"
 	"github.com/ethereum/go-ethereum/common/hexutil"
+	"github.com/ethereum/go-ethereum/core/txpool"
 	"github.com/ethereum/go-ethereum/core/types"
 	"github.com/ethereum/go-ethereum/internal/ethapi"
 	"github.com/ethereum/go-ethereum/rpc"
@@ -183,6 +184,79 @@ func (api *FilterAPI) NewPendingTransactions(ctx context.Context, fullTx *bool)
 	return rpcSub, nil
 }
 
+// TxPoolEventCriteria selects the transaction pool events to subscribe to.
+type TxPoolEventCriteria struct {
+	Senders []common.Address `json:"senders"` // Senders to report the transactions of, all if empty
+}
+
+// RPCTxPoolEvent is the notification sent about a state change of a pooled
+// transaction.
+type RPCTxPoolEvent struct {
+	Type       string         `json:"type"`
+	Hash       common.Hash    `json:"hash"`
+	From       common.Address `json:"from"`
+	Nonce      hexutil.Uint64 `json:"nonce"`
+	ReplacedBy *common.Hash   `json:"replacedBy,omitempty"`
+	Reason     string         `json:"reason,omitempty"`
+}
+
+// newRPCTxPoolEvent converts a transaction pool event into its RPC representation.
+func newRPCTxPoolEvent(ev txpool.TxPoolEvent) *RPCTxPoolEvent {
+	result := &RPCTxPoolEvent{
+		Type:   ev.Kind.String(),
+		Hash:   ev.Tx.Hash(),
+		From:   ev.From,
+		Nonce:  hexutil.Uint64(ev.Tx.Nonce()),
+		Reason: string(ev.Reason),
+	}
+	if ev.Replacement != nil {
+		hash := ev.Replacement.Hash()
+		result.ReplacedBy = &hash
+	}
+	return result
+}
+
+// TxpoolEvents creates a subscription that is triggered each time a transaction
+// in the pool changes state: it's added, promoted to the pending set, demoted
+// back to the queue, replaced, or dropped along with the reason. If senders are
+// specified, only the events of their transactions are sent.
+func (api *FilterAPI) TxpoolEvents(ctx context.Context, crit *TxPoolEventCriteria) (*rpc.Subscription, error) {
+	notifier, supported := rpc.NotifierFromContext(ctx)
+	if !supported {
+		return &rpc.Subscription{}, rpc.ErrNotificationsUnsupported
+	}
+	senders := make(map[common.Address]struct{})
+	if crit != nil {
+		for _, sender := range crit.Senders {
+			senders[sender] = struct{}{}
+		}
+	}
+	var (
+		rpcSub    = notifier.CreateSubscription()
+		events    = make(chan txpool.TxPoolEvent, 128)
+		eventsSub = api.sys.backend.SubscribeTxPoolEvent(events)
+	)
+	go func() {
+		defer eventsSub.Unsubscribe()
+
+		for {
+			select {
+			case ev := <-events:
+				if _, ok := senders[ev.From]; len(senders) > 0 && !ok {
+					continue
+				}
+				notifier.Notify(rpcSub.ID, newRPCTxPoolEvent(ev))
+			case <-rpcSub.Err():
+				return
+			case <-notifier.Closed():
+				return
+			}
+		}
+	}()
+
+	return rpcSub, nil
+}
+
 // NewBlockFilter creates a filter that fetches blocks that are imported into the chain.
 // It is part of the filter package since polling goes with eth_getFilterChanges.
 func (api *FilterAPI) NewBlockFilter() rpc.ID {
@@ -33,6 +33,7 @@ import (
 	"github.com/ethereum/go-ethereum/core"
 	"github.com/ethereum/go-ethereum/core/bloombits"
 	"github.com/ethereum/go-ethereum/core/rawdb"
+	"github.com/ethereum/go-ethereum/core/txpool"
 	"github.com/ethereum/go-ethereum/core/types"
 	"github.com/ethereum/go-ethereum/crypto"
 	"github.com/ethereum/go-ethereum/ethdb"
@@ -46,6 +47,7 @@ type testBackend struct {
 	db              ethdb.Database
 	sections        uint64
 	txFeed          event.Feed
+	txPoolFeed      event.Feed
 	logsFeed        event.Feed
 	rmLogsFeed      event.Feed
 	pendingLogsFeed event.Feed
@@ -133,6 +135,10 @@ func (b *testBackend) SubscribeNewTxsEvent(ch chan<- core.NewTxsEvent) event.Sub
 	return b.txFeed.Subscribe(ch)
 }
 
+func (b *testBackend) SubscribeTxPoolEvent(ch chan<- txpool.TxPoolEvent) event.Subscription {
+	return b.txPoolFeed.Subscribe(ch)
+}
+
 func (b *testBackend) SubscribeRemovedLogsEvent(ch chan<- core.RemovedLogsEvent) event.Subscription {
 	return b.rmLogsFeed.Subscribe(ch)
 }
@@ -358,6 +364,62 @@ func TestPendingTxFilterFullTx(t *testing.T) {
 	}
 }
 
+// TestTxPoolEventSubscription tests whether transaction pool event subscriptions
+// deliver the events of the requested senders only.
+func TestTxPoolEventSubscription(t *testing.T) {
+	t.Parallel()
+
+	var (
+		db           = rawdb.NewMemoryDatabase()
+		backend, sys = newTestFilterSystem(t, db, Config{})
+		api          = NewFilterAPI(sys, false)
+
+		alice = common.HexToAddress("0xa11ce")
+		bob   = common.HexToAddress("0xb0b")
+
+		tx0  = types.NewTransaction(0, common.Address{}, new(big.Int), 0, new(big.Int), nil)
+		tx0b = types.NewTransaction(0, common.Address{}, big.NewInt(1), 0, new(big.Int), nil)
+		tx1  = types.NewTransaction(1, common.Address{}, new(big.Int), 0, new(big.Int), nil)
+	)
+	server := rpc.NewServer()
+	defer server.Stop()
+	if err := server.RegisterName("eth", api); err != nil {
+		t.Fatalf("failed to register filter API: %v", err)
+	}
+	client := rpc.DialInProc(server)
+	defer client.Close()
+
+	notifications := make(chan *RPCTxPoolEvent)
+	sub, err := client.EthSubscribe(context.Background(), notifications, "txpoolEvents", &TxPoolEventCriteria{Senders: []common.Address{alice}})
+	if err != nil {
+		t.Fatalf("failed to subscribe: %v", err)
+	}
+	defer sub.Unsubscribe()
+
+	backend.txPoolFeed.Send(txpool.TxPoolEvent{Kind: txpool.TxAdded, Tx: tx0, From: bob})
+	backend.txPoolFeed.Send(txpool.TxPoolEvent{Kind: txpool.TxReplaced, Tx: tx0, From: alice, Replacement: tx0b})
+	backend.txPoolFeed.Send(txpool.TxPoolEvent{Kind: txpool.TxDropped, Tx: tx1, From: bob, Reason: txpool.DropExpired})
+	backend.txPoolFeed.Send(txpool.TxPoolEvent{Kind: txpool.TxDropped, Tx: tx1, From: alice, Reason: txpool.DropStale})
+
+	replacement := tx0b.Hash()
+	want := []*RPCTxPoolEvent{
+		{Type: "replaced", Hash: tx0.Hash(), From: alice, Nonce: 0, ReplacedBy: &replacement},
+		{Type: "dropped", Hash: tx1.Hash(), From: alice, Nonce: 1, Reason: "nonce too low"},
+	}
+	for i, want := range want {
+		select {
+		case have := <-notifications:
+			if !reflect.DeepEqual(have, want) {
+				t.Errorf("notification %d mismatch: have %+v, want %+v", i, have, want)
+			}
+		case err := <-sub.Err():
+			t.Fatalf("subscription failed: %v", err)
+		case <-time.After(time.Second):
+			t.Fatalf("notification %d not delivered", i)
+		}
+	}
+}
+
 // TestLogFilterCreation test whether a given filter criteria makes sense.
 // If not it must return an error.
 func TestLogFilterCreation(t *testing.T) {
@@ -61,13 +61,22 @@ type txPool interface {
 	// tx hash.
 	Get(hash common.Hash) *types.Transaction
 
-	// AddRemotes should add the given transactions to the pool.
+	// Sidecar retrieves the sidecar of a blob transaction from local txpool
+	// with given tx hash.
+	Sidecar(hash common.Hash) *types.BlobTxSidecar
+
+	// AddRemotes should add the given transactions to the pool. Blob
+	// transactions carry their sidecars.
 	AddRemotes([]*types.Transaction) []error
 
 	// Pending should return pending transactions.
 	// The slice should be modifiable by the caller.
 	Pending(enforceTips bool) map[common.Address]types.Transactions
 
+	// IsPrivate returns whether a transaction was submitted privately and must
+	// not be announced to the network.
+	IsPrivate(hash common.Hash) bool
+
 	// SubscribeNewTxsEvent should return an event subscription of
 	// NewTxsEvent and send events to the given channel.
 	SubscribeNewTxsEvent(chan<- core.NewTxsEvent) event.Subscription
@@ -559,9 +568,17 @@ func (h *handler) BroadcastTransactions(txs types.Transactions) {
 	)
 	// Broadcast transactions to a batch of peers not knowing about it
 	for _, tx := range txs {
+		// Private transactions are only ever included by the local miner
+		if h.txpool.IsPrivate(tx.Hash()) {
+			continue
+		}
 		peers := h.peers.peersWithoutTransaction(tx.Hash())
-		// Send the tx unconditionally to a subset of our peers
+		// Send the tx unconditionally to a subset of our peers. Blob transactions
+		// are only announced, peers need to fetch their sidecars anyway.
 		numDirect := int(math.Sqrt(float64(len(peers))))
+		if tx.Type() == types.BlobTxType {
+			numDirect = 0
+		}
 		for _, peer := range peers[:numDirect] {
 			txset[peer] = append(txset[peer], tx.Hash())
 		}
@@ -33,7 +33,31 @@ import (
 type ethHandler handler
 
 func (h *ethHandler) Chain() *core.BlockChain { return h.chain }
-func (h *ethHandler) TxPool() eth.TxPool      { return h.txpool }
+func (h *ethHandler) TxPool() eth.TxPool      { return &publicTxPool{h.txpool} }
+
+// publicTxPool is a view of the transaction pool served to remote peers, hiding
+// all the privately submitted transactions.
+type publicTxPool struct {
+	txPool
+}
+
+// Get retrieves the transaction from the local txpool with the given hash, if
+// it's not private.
+func (p *publicTxPool) Get(hash common.Hash) *types.Transaction {
+	if p.IsPrivate(hash) {
+		return nil
+	}
+	return p.txPool.Get(hash)
+}
+
+// Sidecar retrieves the sidecar of a blob transaction from the local txpool
+// with the given hash, if it's not private.
+func (p *publicTxPool) Sidecar(hash common.Hash) *types.BlobTxSidecar {
+	if p.IsPrivate(hash) {
+		return nil
+	}
+	return p.txPool.Sidecar(hash)
+}
 
 // RunPeer is invoked when a peer joins on the `eth` protocol.
 func (h *ethHandler) RunPeer(peer *eth.Peer, hand eth.Handler) error {
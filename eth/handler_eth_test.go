@@ -30,6 +30,7 @@ import (
 	"github.com/ethereum/go-ethereum/core/rawdb"
 	"github.com/ethereum/go-ethereum/core/types"
 	"github.com/ethereum/go-ethereum/core/vm"
+	"github.com/ethereum/go-ethereum/crypto"
 	"github.com/ethereum/go-ethereum/eth/downloader"
 	"github.com/ethereum/go-ethereum/eth/protocols/eth"
 	"github.com/ethereum/go-ethereum/event"
@@ -456,6 +457,86 @@ func testTransactionPropagation(t *testing.T, protocol uint) {
 	}
 }
 
+// Tests that privately submitted transactions are neither announced to newly
+// connecting peers, nor broadcast to existing ones.
+func TestPrivateTxPropagation66(t *testing.T) { testPrivateTxPropagation(t, eth.ETH66) }
+func TestPrivateTxPropagation67(t *testing.T) { testPrivateTxPropagation(t, eth.ETH67) }
+func TestPrivateTxPropagation68(t *testing.T) { testPrivateTxPropagation(t, eth.ETH68) }
+
+func testPrivateTxPropagation(t *testing.T, protocol uint) {
+	t.Parallel()
+
+	source := newTestHandler()
+	source.handler.snapSync.Store(false) // Avoid requiring snap, otherwise some will be dropped below
+	defer source.close()
+
+	sink := newTestHandler()
+	defer sink.close()
+	sink.handler.acceptTxs.Store(true) // mark synced to accept transactions
+
+	// Create a batch of public and private transactions for both the initial sync
+	// and the live broadcast
+	privateKey, _ := crypto.GenerateKey()
+
+	var public, private []*types.Transaction
+	for nonce := uint64(0); nonce < 8; nonce++ {
+		tx, _ := types.SignTx(types.NewTransaction(nonce, common.Address{}, big.NewInt(0), 100000, big.NewInt(0), nil), types.HomesteadSigner{}, testKey)
+		public = append(public, tx)
+
+		tx, _ = types.SignTx(types.NewTransaction(nonce, common.Address{}, big.NewInt(0), 100000, big.NewInt(0), nil), types.HomesteadSigner{}, privateKey)
+		private = append(private, tx)
+	}
+	source.txpool.AddRemotes(public[:4])
+	source.txpool.addPrivate(private[:4])
+
+	txCh := make(chan core.NewTxsEvent, 1024)
+	sub := sink.txpool.SubscribeNewTxsEvent(txCh)
+	defer sub.Unsubscribe()
+
+	// Connect the two handlers and inject the rest of the transactions
+	sourcePipe, sinkPipe := p2p.MsgPipe()
+	defer sourcePipe.Close()
+	defer sinkPipe.Close()
+
+	sourcePeer := eth.NewPeer(protocol, p2p.NewPeerPipe(enode.ID{1}, "", nil, sourcePipe), sourcePipe, source.txpool)
+	sinkPeer := eth.NewPeer(protocol, p2p.NewPeerPipe(enode.ID{0}, "", nil, sinkPipe), sinkPipe, sink.txpool)
+	defer sourcePeer.Close()
+	defer sinkPeer.Close()
+
+	go source.handler.runEthPeer(sourcePeer, func(peer *eth.Peer) error {
+		return eth.Handle((*ethHandler)(source.handler), peer)
+	})
+	go sink.handler.runEthPeer(sinkPeer, func(peer *eth.Peer) error {
+		return eth.Handle((*ethHandler)(sink.handler), peer)
+	})
+	time.Sleep(100 * time.Millisecond) // Wait for the handshake to complete
+
+	source.txpool.addPrivate(private[4:])
+	source.txpool.AddRemotes(public[4:])
+
+	// Ensure all the public transactions arrive, but none of the private ones
+	arrived := make(map[common.Hash]bool)
+	for timeout := time.After(2 * time.Second); len(arrived) < len(public); {
+		select {
+		case event := <-txCh:
+			for _, tx := range event.Txs {
+				arrived[tx.Hash()] = true
+			}
+		case <-timeout:
+			t.Fatalf("transaction propagation timed out: have %d, want %d", len(arrived), len(public))
+		}
+	}
+	for _, tx := range private {
+		if arrived[tx.Hash()] || sink.txpool.Has(tx.Hash()) {
+			t.Errorf("private transaction %x propagated", tx.Hash())
+		}
+	}
+	// Ensure the private transactions can't be requested explicitly either
+	if tx := (*ethHandler)(source.handler).TxPool().Get(private[0].Hash()); tx != nil {
+		t.Errorf("private transaction served to peers")
+	}
+}
+
 // Tests that blocks are broadcast to a sqrt number of peers only.
 func TestBroadcastBlock1Peer(t *testing.T)    { testBroadcastBlock(t, 1, 1) }
 func TestBroadcastBlock2Peers(t *testing.T)   { testBroadcastBlock(t, 2, 1) }
@@ -47,7 +47,8 @@ var (
 // Its goal is to get around setting up a valid statedb for the balance and nonce
 // checks.
 type testTxPool struct {
-	pool map[common.Hash]*types.Transaction // Hash map of collected transactions
+	pool    map[common.Hash]*types.Transaction // Hash map of collected transactions
+	private map[common.Hash]bool               // Set of transactions not to announce
 
 	txFeed event.Feed   // Notification feed to allow waiting for inclusion
 	lock   sync.RWMutex // Protects the transaction pool
@@ -56,7 +57,8 @@ type testTxPool struct {
 // newTestTxPool creates a mock transaction pool.
 func newTestTxPool() *testTxPool {
 	return &testTxPool{
-		pool: make(map[common.Hash]*types.Transaction),
+		pool:    make(map[common.Hash]*types.Transaction),
+		private: make(map[common.Hash]bool),
 	}
 }
 
@@ -78,6 +80,17 @@ func (p *testTxPool) Get(hash common.Hash) *types.Transaction {
 	return p.pool[hash]
 }
 
+// Sidecar retrieves the sidecar a blob transaction was added to the pool with.
+func (p *testTxPool) Sidecar(hash common.Hash) *types.BlobTxSidecar {
+	p.lock.Lock()
+	defer p.lock.Unlock()
+
+	if tx := p.pool[hash]; tx != nil {
+		return tx.BlobTxSidecar()
+	}
+	return nil
+}
+
 // AddRemotes appends a batch of transactions to the pool, and notifies any
 // listeners if the addition channel is non nil
 func (p *testTxPool) AddRemotes(txs []*types.Transaction) []error {
@@ -107,6 +120,25 @@ func (p *testTxPool) Pending(enforceTips bool) map[common.Address]types.Transact
 	return batches
 }
 
+// addPrivate adds the given transactions to the pool, marking them private.
+func (p *testTxPool) addPrivate(txs []*types.Transaction) {
+	p.lock.Lock()
+	for _, tx := range txs {
+		p.private[tx.Hash()] = true
+	}
+	p.lock.Unlock()
+
+	p.AddRemotes(txs)
+}
+
+// IsPrivate returns whether a transaction was added privately.
+func (p *testTxPool) IsPrivate(hash common.Hash) bool {
+	p.lock.RLock()
+	defer p.lock.RUnlock()
+
+	return p.private[hash]
+}
+
 // SubscribeNewTxsEvent should return an event subscription of NewTxsEvent and
 // send events to the given channel.
 func (p *testTxPool) SubscribeNewTxsEvent(ch chan<- core.NewTxsEvent) event.Subscription {
@@ -91,6 +91,10 @@ type Backend interface {
 type TxPool interface {
 	// Get retrieves the transaction from the local txpool with the given hash.
 	Get(hash common.Hash) *types.Transaction
+
+	// Sidecar retrieves the sidecar of a blob transaction from the local txpool
+	// with the given hash.
+	Sidecar(hash common.Hash) *types.BlobTxSidecar
 }
 
 // MakeProtocols constructs the P2P protocol definitions for `eth`.
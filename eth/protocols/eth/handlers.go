@@ -497,8 +497,17 @@ func answerGetPooledTransactions(backend Backend, query GetPooledTransactionsPac
 		if tx == nil {
 			continue
 		}
+		// Blob transactions are useless without their sidecars, relay them in
+		// their network form
+		if tx.Type() == types.BlobTxType {
+			sidecar := backend.TxPool().Sidecar(hash)
+			if sidecar == nil {
+				continue
+			}
+			tx = tx.WithBlobTxSidecar(sidecar)
+		}
 		// If known, encode and queue for response packet
-		if encoded, err := rlp.EncodeToBytes(tx); err != nil {
+		if encoded, err := encodePooledTransaction(tx); err != nil {
 			log.Error("Failed to encode transaction", "err", err)
 		} else {
 			hashes = append(hashes, hash)
@@ -321,14 +321,71 @@ type GetPooledTransactionsPacket66 struct {
 }
 
 // PooledTransactionsPacket is the network packet for transaction distribution.
+// Blob transactions are relayed in their network form, along with their sidecars.
 type PooledTransactionsPacket []*types.Transaction
 
+// DecodeRLP implements rlp.Decoder, decoding blob transactions from their network
+// form with the sidecars attached.
+func (p *PooledTransactionsPacket) DecodeRLP(s *rlp.Stream) error {
+	var raws []rlp.RawValue
+	if err := s.Decode(&raws); err != nil {
+		return err
+	}
+	txs := make([]*types.Transaction, len(raws))
+	for i, raw := range raws {
+		kind, content, _, err := rlp.Split(raw)
+		if err != nil {
+			return err
+		}
+		tx := new(types.Transaction)
+		if kind == rlp.List {
+			err = rlp.DecodeBytes(raw, tx)
+		} else {
+			err = tx.UnmarshalNetwork(content)
+		}
+		if err != nil {
+			return err
+		}
+		txs[i] = tx
+	}
+	*p = txs
+	return nil
+}
+
+// encodePooledTransaction encodes a transaction to be relayed in a pooled
+// transactions packet, using the network form for blob transactions.
+func encodePooledTransaction(tx *types.Transaction) (rlp.RawValue, error) {
+	if tx.Type() == types.LegacyTxType {
+		return rlp.EncodeToBytes(tx)
+	}
+	enc, err := tx.MarshalNetwork()
+	if err != nil {
+		return nil, err
+	}
+	return rlp.EncodeToBytes(enc)
+}
+
 // PooledTransactionsPacket66 is the network packet for transaction distribution over eth/66.
 type PooledTransactionsPacket66 struct {
 	RequestId uint64
 	PooledTransactionsPacket
 }
 
+// DecodeRLP implements rlp.Decoder. It shadows the decoder promoted from the
+// embedded packet, which would otherwise try to decode the whole message.
+func (p *PooledTransactionsPacket66) DecodeRLP(s *rlp.Stream) error {
+	if _, err := s.List(); err != nil {
+		return err
+	}
+	if err := s.Decode(&p.RequestId); err != nil {
+		return err
+	}
+	if err := s.Decode(&p.PooledTransactionsPacket); err != nil {
+		return err
+	}
+	return s.ListEnd()
+}
+
 // PooledTransactionsRLPPacket is the network packet for transaction distribution, used
 // in the cases we already have them in rlp-encoded form
 type PooledTransactionsRLPPacket []rlp.RawValue
@@ -23,7 +23,10 @@ import (
 
 	"github.com/ethereum/go-ethereum/common"
 	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/crypto"
+	"github.com/ethereum/go-ethereum/crypto/kzg4844"
 	"github.com/ethereum/go-ethereum/rlp"
+	"github.com/holiman/uint256"
 )
 
 // Tests that the custom union field encoder and decoder works correctly.
@@ -264,3 +267,53 @@ func TestEth66Messages(t *testing.T) {
 		}
 	}
 }
+
+// Tests that blob transactions are relayed in pooled transaction packets along
+// with their sidecars.
+func TestPooledBlobTransactions(t *testing.T) {
+	key, _ := crypto.GenerateKey()
+	sidecar := &types.BlobTxSidecar{
+		Blobs:       []kzg4844.Blob{{0x01}},
+		Commitments: []kzg4844.Commitment{{0x02}},
+		Proofs:      []kzg4844.Proof{{0x03}},
+	}
+	blobtx := types.MustSignNewTx(key, types.NewCancunSigner(big.NewInt(1)), &types.BlobTx{
+		ChainID:    uint256.NewInt(1),
+		Gas:        21000,
+		GasTipCap:  uint256.NewInt(1),
+		GasFeeCap:  uint256.NewInt(1),
+		BlobFeeCap: uint256.NewInt(1),
+		BlobHashes: sidecar.BlobHashes(),
+	})
+	legacy := types.MustSignNewTx(key, types.HomesteadSigner{}, &types.LegacyTx{Gas: 21000, GasPrice: big.NewInt(1)})
+
+	var raws []rlp.RawValue
+	for _, tx := range []*types.Transaction{legacy, blobtx.WithBlobTxSidecar(sidecar)} {
+		raw, err := encodePooledTransaction(tx)
+		if err != nil {
+			t.Fatalf("failed to encode transaction: %v", err)
+		}
+		raws = append(raws, raw)
+	}
+	enc, err := rlp.EncodeToBytes(PooledTransactionsRLPPacket66{1111, raws})
+	if err != nil {
+		t.Fatalf("failed to encode packet: %v", err)
+	}
+	var packet PooledTransactionsPacket66
+	if err := rlp.DecodeBytes(enc, &packet); err != nil {
+		t.Fatalf("failed to decode packet: %v", err)
+	}
+	if len(packet.PooledTransactionsPacket) != 2 {
+		t.Fatalf("transaction count mismatch: have %d, want 2", len(packet.PooledTransactionsPacket))
+	}
+	if have := packet.PooledTransactionsPacket[0]; have.Hash() != legacy.Hash() || have.BlobTxSidecar() != nil {
+		t.Fatalf("legacy transaction mismatch")
+	}
+	have := packet.PooledTransactionsPacket[1]
+	if have.Hash() != blobtx.Hash() {
+		t.Fatalf("blob transaction hash mismatch: have %x, want %x", have.Hash(), blobtx.Hash())
+	}
+	if have.BlobTxSidecar() == nil || have.BlobTxSidecar().Commitments[0] != sidecar.Commitments[0] {
+		t.Fatalf("blob transaction sidecar not relayed")
+	}
+}
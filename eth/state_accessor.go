@@ -230,3 +230,61 @@ func (eth *Ethereum) stateAtTransaction(ctx context.Context, block *types.Block,
 	}
 	return nil, vm.BlockContext{}, nil, nil, fmt.Errorf("transaction index %d out of range for block %#x", txIndex, block.Hash())
 }
+
+// witnessAtTransaction regenerates the state witness of a certain transaction by
+// re-executing it on top of the state it was originally applied to.
+func (eth *Ethereum) witnessAtTransaction(ctx context.Context, block *types.Block, txIndex int, reexec uint64) (*types.TxExtra, error) {
+	if txIndex < 0 || txIndex >= len(block.Transactions()) {
+		return nil, fmt.Errorf("transaction index %d out of range for block %#x", txIndex, block.Hash())
+	}
+	_, _, statedb, release, err := eth.stateAtTransaction(ctx, block, txIndex, reexec)
+	if err != nil {
+		return nil, err
+	}
+	defer release()
+
+	tx := block.Transactions()[txIndex]
+	statedb.SetTxContext(tx.Hash(), txIndex)
+	_, witness, err := core.ApplyTransactionWithWitness(eth.blockchain.Config(), eth.blockchain, nil, new(core.GasPool).AddGas(tx.Gas()), statedb, block.Header(), tx, new(uint64), vm.Config{})
+	if err != nil {
+		return nil, fmt.Errorf("transaction %#x failed: %v", tx.Hash(), err)
+	}
+	return witness, nil
+}
+
+// witnessesAtBlock regenerates the state witnesses of all the transactions in a
+// certain block by re-executing them on top of the parent state.
+func (eth *Ethereum) witnessesAtBlock(ctx context.Context, block *types.Block, reexec uint64) ([]*types.TxExtra, error) {
+	if block.NumberU64() == 0 {
+		return nil, errors.New("no transaction in genesis")
+	}
+	parent := eth.blockchain.GetBlock(block.ParentHash(), block.NumberU64()-1)
+	if parent == nil {
+		return nil, fmt.Errorf("parent %#x not found", block.ParentHash())
+	}
+	statedb, release, err := eth.StateAtBlock(ctx, parent, reexec, nil, true, false)
+	if err != nil {
+		return nil, err
+	}
+	defer release()
+
+	var (
+		config    = eth.blockchain.Config()
+		header    = block.Header()
+		gp        = new(core.GasPool).AddGas(block.GasLimit())
+		usedGas   = new(uint64)
+		witnesses = make([]*types.TxExtra, 0, len(block.Transactions()))
+	)
+	for i, tx := range block.Transactions() {
+		if err := ctx.Err(); err != nil {
+			return nil, err
+		}
+		statedb.SetTxContext(tx.Hash(), i)
+		_, witness, err := core.ApplyTransactionWithWitness(config, eth.blockchain, nil, gp, statedb, header, tx, usedGas, vm.Config{})
+		if err != nil {
+			return nil, fmt.Errorf("transaction %#x failed: %v", tx.Hash(), err)
+		}
+		witnesses = append(witnesses, witness)
+	}
+	return witnesses, nil
+}
@@ -0,0 +1,144 @@
+// Copyright 2023 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package eth
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"math/big"
+	"testing"
+	"time"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/consensus/ethash"
+	"github.com/ethereum/go-ethereum/core"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/crypto"
+	"github.com/ethereum/go-ethereum/eth/ethconfig"
+	"github.com/ethereum/go-ethereum/eth/tracers"
+	"github.com/ethereum/go-ethereum/node"
+	"github.com/ethereum/go-ethereum/params"
+)
+
+// Tests that the call traces of newly imported blocks are indexed, that the
+// configured history is backfilled and pruned, and that the trace RPCs serve the
+// indexed traces.
+func TestTraceIndexer(t *testing.T) {
+	var (
+		key, _  = crypto.GenerateKey()
+		sender  = crypto.PubkeyToAddress(key.PublicKey)
+		genesis = &core.Genesis{
+			Config:   params.AllEthashProtocolChanges,
+			GasLimit: 30_000_000,
+			BaseFee:  big.NewInt(params.InitialBaseFee),
+			Alloc:    core.GenesisAlloc{sender: {Balance: big.NewInt(params.Ether)}},
+		}
+		signer = types.LatestSigner(genesis.Config)
+		to     = common.HexToAddress("0xdead")
+	)
+	_, blocks, _ := core.GenerateChainWithGenesis(genesis, ethash.NewFaker(), 8, func(i int, b *core.BlockGen) {
+		tx := types.MustSignNewTx(key, signer, &types.LegacyTx{Nonce: uint64(i), To: &to, Value: big.NewInt(1), Gas: params.TxGas, GasPrice: b.BaseFee()})
+		b.AddTx(tx)
+	})
+	stack, err := node.New(&node.Config{})
+	if err != nil {
+		t.Fatalf("failed to create node: %v", err)
+	}
+	defer stack.Close()
+
+	config := ethconfig.Defaults
+	config.Genesis = genesis
+	config.TraceIndex = true
+	config.TraceIndexHistory = 3
+	ethservice, err := New(stack, &config)
+	if err != nil {
+		t.Fatalf("failed to create ethereum service: %v", err)
+	}
+	// Import a part of the chain before the indexer starts, to be backfilled
+	if _, err := ethservice.BlockChain().InsertChain(blocks[:6]); err != nil {
+		t.Fatalf("failed to import blocks: %v", err)
+	}
+	if err := stack.Start(); err != nil {
+		t.Fatalf("failed to start node: %v", err)
+	}
+	waitTraceIndex := func(head common.Hash, tail uint64) {
+		t.Helper()
+		for i := 0; ; i++ {
+			have := tracers.ReadTraceIndexTail(ethservice.traceDb)
+			if have != nil && *have == tail && tracers.ReadTraceIndexHead(ethservice.traceDb) == head {
+				return
+			}
+			if i == 250 {
+				t.Fatalf("trace index not completed: tail %v, head %x", have, tracers.ReadTraceIndexHead(ethservice.traceDb))
+			}
+			time.Sleep(20 * time.Millisecond)
+		}
+	}
+	waitTraceIndex(blocks[5].Hash(), 4)
+
+	// Import the rest of the chain, indexed as it arrives and pruned behind
+	if _, err := ethservice.BlockChain().InsertChain(blocks[6:]); err != nil {
+		t.Fatalf("failed to import blocks: %v", err)
+	}
+	waitTraceIndex(blocks[7].Hash(), 6)
+
+	for i, block := range blocks {
+		hash := block.Transactions()[0].Hash()
+		if have, want := tracers.HasTxTrace(ethservice.traceDb, hash), i >= 5; have != want {
+			t.Errorf("block #%d: trace presence mismatch: have %v, want %v", block.NumberU64(), have, want)
+		}
+	}
+	// The indexed traces must match the ones produced by re-execution
+	var (
+		api      = tracers.NewAPI(ethservice.APIBackend)
+		tracer   = "flatCallTracer"
+		indexed  = &tracers.TraceConfig{Tracer: &tracer, TracerConfig: json.RawMessage(`{"convertParityErrors": true}`)}
+		executed = &tracers.TraceConfig{Tracer: &tracer, TracerConfig: json.RawMessage(`{"convertParityErrors":true,"includePrecompiles":false}`)}
+		txhash   = blocks[6].Transactions()[0].Hash()
+	)
+	_, stored := tracers.ReadTxTrace(ethservice.traceDb, txhash)
+	result, err := api.TraceTransaction(context.Background(), txhash, executed)
+	if err != nil {
+		t.Fatalf("failed to trace transaction: %v", err)
+	}
+	if !bytes.Equal(stored, result.(json.RawMessage)) {
+		t.Fatalf("indexed trace mismatch: have %s, want %s", stored, result)
+	}
+	// Replace an indexed trace to check it's served instead of re-executing
+	marker := []byte(`[{"marker":true}]`)
+	tracers.WriteTxTrace(ethservice.traceDb, txhash, blocks[6].Hash(), marker)
+
+	if result, err := api.TraceTransaction(context.Background(), txhash, indexed); err != nil || !bytes.Equal(result.(json.RawMessage), marker) {
+		t.Errorf("debug trace not served from the index: %s, %v", result, err)
+	}
+	if result, err := api.TraceTransaction(context.Background(), txhash, executed); err != nil || bytes.Equal(result.(json.RawMessage), marker) {
+		t.Errorf("debug trace with different config served from the index: %s, %v", result, err)
+	}
+	traceAPI := tracers.NewTraceAPI(ethservice.APIBackend)
+	if frames, err := traceAPI.Transaction(context.Background(), txhash); err != nil || len(frames) != 1 || string(frames[0]) != `{"marker":true}` {
+		t.Errorf("transaction trace not served from the index: %s, %v", frames, err)
+	}
+	if frames, err := traceAPI.Block(context.Background(), 7); err != nil || len(frames) != 1 || string(frames[0]) != `{"marker":true}` {
+		t.Errorf("block trace not served from the index: %s, %v", frames, err)
+	}
+	// Transactions outside of the index are re-executed
+	frames, err := traceAPI.Transaction(context.Background(), blocks[0].Transactions()[0].Hash())
+	if err != nil || len(frames) != 1 {
+		t.Fatalf("failed to trace unindexed transaction: %s, %v", frames, err)
+	}
+}
@@ -92,12 +92,21 @@ type Backend interface {
 
 // API is the collection of tracing APIs exposed over the private debugging endpoint.
 type API struct {
-	backend Backend
+	backend  Backend
+	index    *TraceIndexer // Index of call traces served without re-execution, if any
+	traceDir string        // Directory chain traces are written into, if any
 }
 
 // NewAPI creates a new API definition for the tracing methods of the Ethereum service.
 func NewAPI(backend Backend) *API {
-	return &API{backend: backend}
+	api := &API{backend: backend}
+	if b, ok := backend.(indexedBackend); ok {
+		api.index = b.TraceIndexer()
+	}
+	if b, ok := backend.(traceDirBackend); ok {
+		api.traceDir = b.TraceDir()
+	}
+	return api
 }
 
 // chainContext constructs the context reader which is used by the evm for reading
@@ -571,6 +580,12 @@ func (api *API) traceBlock(ctx context.Context, block *types.Block, config *Trac
 	if block.NumberU64() == 0 {
 		return nil, errors.New("genesis is not traceable")
 	}
+	// Serve the call traces from the index if available
+	if isIndexedConfig(config) {
+		if results := api.index.blockTraces(block); results != nil {
+			return results, nil
+		}
+	}
 	// Prepare base state
 	parent, err := api.blockByNumberAndHash(ctx, rpc.BlockNumber(block.NumberU64()-1), block.ParentHash())
 	if err != nil {
@@ -838,6 +853,12 @@ func (api *API) TraceTransaction(ctx context.Context, hash common.Hash, config *
 	if blockNumber == 0 {
 		return nil, errors.New("genesis is not traceable")
 	}
+	// Serve the call trace from the index if available
+	if isIndexedConfig(config) {
+		if trace := api.index.txTrace(hash, blockHash); trace != nil {
+			return trace, nil
+		}
+	}
 	reexec := defaultTraceReexec
 	if config != nil && config.Reexec != nil {
 		reexec = *config.Reexec
@@ -976,6 +997,10 @@ func APIs(backend Backend) []rpc.API {
 			Namespace: "debug",
 			Service:   NewAPI(backend),
 		},
+		{
+			Namespace: "trace",
+			Service:   NewTraceAPI(backend),
+		},
 	}
 }
 
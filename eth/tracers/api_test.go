@@ -369,6 +369,100 @@ func TestTraceTransaction(t *testing.T) {
 	}
 }
 
+func TestBisectTransaction(t *testing.T) {
+	t.Parallel()
+
+	// Initialize test accounts, the contract stores 0x2a in slot 0 and reads it back
+	accounts := newAccounts(1)
+	contract := common.HexToAddress("0x00000000000000000000000000000000deadbeef")
+	genesis := &core.Genesis{
+		Config: params.TestChainConfig,
+		Alloc: core.GenesisAlloc{
+			accounts[0].addr: {Balance: big.NewInt(params.Ether)},
+			contract: {
+				Balance: common.Big0,
+				Code:    []byte{byte(vm.PUSH1), 0x2a, byte(vm.PUSH1), 0x00, byte(vm.SSTORE), byte(vm.PUSH1), 0x00, byte(vm.SLOAD), byte(vm.STOP)},
+				Storage: map[common.Hash]common.Hash{{}: common.HexToHash("0x07")},
+			},
+		},
+	}
+	target := common.Hash{}
+	signer := types.HomesteadSigner{}
+	backend := newTestBackend(t, 1, genesis, func(i int, b *core.BlockGen) {
+		tx, _ := types.SignTx(types.NewTransaction(uint64(i), contract, big.NewInt(0), 100000, b.BaseFee(), nil), signer, accounts[0].key)
+		b.AddTx(tx)
+		target = tx.Hash()
+	})
+	defer backend.chain.Stop()
+	api := NewAPI(backend)
+
+	// Right before the SSTORE, nothing is written yet and the old value is witnessed
+	store, err := api.BisectTransaction(context.Background(), target, 2, nil)
+	if err != nil {
+		t.Fatalf("failed to bisect transaction: %v", err)
+	}
+	if store.Op != "SSTORE" || store.Pc != 4 || store.Steps != 6 || store.Address != contract {
+		t.Fatalf("unexpected step: %+v", store)
+	}
+	if want := []common.Hash{common.HexToHash("0x2a"), {}}; !reflect.DeepEqual(store.Stack, want) {
+		t.Fatalf("stack mismatch: have %v, want %v", store.Stack, want)
+	}
+	if store.StorageRoot != types.EmptyRootHash {
+		t.Fatalf("storage root mismatch: have %x, want %x", store.StorageRoot, types.EmptyRootHash)
+	}
+	if have := store.Witness.Storage[contract][common.Hash{}]; have != common.HexToHash("0x07") {
+		t.Fatalf("witnessed slot mismatch: have %x, want %x", have, common.HexToHash("0x07"))
+	}
+	// Right before the SLOAD, the written slot is committed to and witnessed
+	load, err := api.BisectTransaction(context.Background(), target, 4, nil)
+	if err != nil {
+		t.Fatalf("failed to bisect transaction: %v", err)
+	}
+	if load.Op != "SLOAD" || load.StorageRoot == types.EmptyRootHash {
+		t.Fatalf("unexpected step: %+v", load)
+	}
+	if have := load.Witness.Storage[contract][common.Hash{}]; have != common.HexToHash("0x2a") {
+		t.Fatalf("witnessed slot mismatch: have %x, want %x", have, common.HexToHash("0x2a"))
+	}
+	if len(load.Witness.Accounts) != 1 {
+		t.Fatalf("witnessed accounts mismatch: have %d, want 1", len(load.Witness.Accounts))
+	}
+	// Commitments must be reproducible
+	again, err := api.BisectTransaction(context.Background(), target, 4, nil)
+	if err != nil {
+		t.Fatalf("failed to bisect transaction: %v", err)
+	}
+	if again.Commitment != load.Commitment || load.Commitment == store.Commitment {
+		t.Fatalf("commitment mismatch: %x, %x, %x", store.Commitment, load.Commitment, again.Commitment)
+	}
+	// The transaction roots are reported even without a recorded witness
+	block := backend.chain.GetBlockByNumber(1)
+	statedb, err := backend.chain.StateAt(backend.chain.Genesis().Root())
+	if err != nil {
+		t.Fatalf("failed to open genesis state: %v", err)
+	}
+	var (
+		gp      = new(core.GasPool).AddGas(block.GasLimit())
+		usedGas = new(uint64)
+	)
+	if _, err := core.ApplyTransaction(backend.chainConfig, backend.chain, nil, gp, statedb, block.Header(), block.Transactions()[0], usedGas, vm.Config{}); err != nil {
+		t.Fatalf("failed to apply transaction: %v", err)
+	}
+	if load.PreState != backend.chain.Genesis().Root() {
+		t.Fatalf("pre-state root mismatch: have %x, want %x", load.PreState, backend.chain.Genesis().Root())
+	}
+	if want := statedb.IntermediateRoot(true); load.PostState != want {
+		t.Fatalf("post-state root mismatch: have %x, want %x", load.PostState, want)
+	}
+	// Steps beyond the execution are rejected
+	if _, err := api.BisectTransaction(context.Background(), target, 6, nil); err == nil {
+		t.Fatal("expected error for out of range step")
+	}
+	if _, err := api.BisectTransaction(context.Background(), common.Hash{42}, 0, nil); !errors.Is(err, errTxNotFound) {
+		t.Fatalf("want %v, have %v", errTxNotFound, err)
+	}
+}
+
 func TestTraceBlock(t *testing.T) {
 	t.Parallel()
 
@@ -0,0 +1,375 @@
+// Copyright 2023 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package tracers
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/common/hexutil"
+	"github.com/ethereum/go-ethereum/rpc"
+)
+
+const (
+	// flatCallTracerName is the native tracer producing the traces of the trace
+	// namespace.
+	flatCallTracerName = "flatCallTracer"
+
+	// traceFilterMaxBlocks is the maximum number of blocks a trace_filter
+	// request may span.
+	traceFilterMaxBlocks = 10000
+)
+
+// flatCallTracerConfig makes the flat call tracer report errors the way
+// OpenEthereum does.
+var flatCallTracerConfig = json.RawMessage(`{"convertParityErrors":true}`)
+
+// replayTracers maps the trace types accepted by the replay methods to the
+// native tracers producing them.
+var replayTracers = map[string]string{
+	"trace":     flatCallTracerName,
+	"stateDiff": "stateDiffTracer",
+	"vmTrace":   "vmTracer",
+}
+
+// TraceAPI is the collection of tracing APIs compatible with the trace namespace
+// of OpenEthereum, built on top of the native tracers.
+type TraceAPI struct {
+	api *API
+}
+
+// NewTraceAPI creates a new API definition for the OpenEthereum style tracing
+// methods of the Ethereum service.
+func NewTraceAPI(backend Backend) *TraceAPI {
+	return &TraceAPI{api: NewAPI(backend)}
+}
+
+// TraceFilterArgs represents the arguments of a trace_filter request.
+type TraceFilterArgs struct {
+	FromBlock   *rpc.BlockNumber `json:"fromBlock"`   // First block to search, defaults to latest
+	ToBlock     *rpc.BlockNumber `json:"toBlock"`     // Last block to search, defaults to latest
+	FromAddress []common.Address `json:"fromAddress"` // Senders to match, any if empty
+	ToAddress   []common.Address `json:"toAddress"`   // Recipients to match, any if empty
+	After       *uint64          `json:"after"`       // Number of matching traces to skip
+	Count       *uint64          `json:"count"`       // Maximum number of traces to return
+}
+
+// TraceResults is the outcome of replaying a transaction with the requested
+// trace types.
+type TraceResults struct {
+	Output          hexutil.Bytes     `json:"output"`
+	StateDiff       json.RawMessage   `json:"stateDiff"`
+	Trace           []json.RawMessage `json:"trace"`
+	VmTrace         json.RawMessage   `json:"vmTrace"`
+	TransactionHash *common.Hash      `json:"transactionHash,omitempty"`
+}
+
+// flatTrace contains the fields of a flat call trace needed for filtering and
+// replaying, the trace itself being forwarded as is.
+type flatTrace struct {
+	Action struct {
+		From          *common.Address `json:"from"`
+		To            *common.Address `json:"to"`
+		Address       *common.Address `json:"address"`
+		RefundAddress *common.Address `json:"refundAddress"`
+	} `json:"action"`
+	Result *struct {
+		Address *common.Address `json:"address"`
+		Code    hexutil.Bytes   `json:"code"`
+		Output  hexutil.Bytes   `json:"output"`
+	} `json:"result"`
+	Type string `json:"type"`
+}
+
+// endpoints returns the sender and the recipient of a trace. Contract creations
+// are sent to the created contract, and self destructs from the destructed one
+// to the beneficiary.
+func (t *flatTrace) endpoints() (from, to *common.Address) {
+	switch t.Type {
+	case "create":
+		if t.Result != nil {
+			to = t.Result.Address
+		}
+		return t.Action.From, to
+	case "suicide":
+		return t.Action.Address, t.Action.RefundAddress
+	default:
+		return t.Action.From, t.Action.To
+	}
+}
+
+// Block returns the traces of all the transactions in a block.
+func (api *TraceAPI) Block(ctx context.Context, number rpc.BlockNumber) ([]json.RawMessage, error) {
+	results, err := api.api.TraceBlockByNumber(ctx, number, flatTraceConfig())
+	if err != nil {
+		return nil, err
+	}
+	traces := []json.RawMessage{}
+	for _, res := range results {
+		frames, err := decodeTxTraceResult(res)
+		if err != nil {
+			return nil, err
+		}
+		traces = append(traces, frames...)
+	}
+	return traces, nil
+}
+
+// Transaction returns the traces of a transaction.
+func (api *TraceAPI) Transaction(ctx context.Context, hash common.Hash) ([]json.RawMessage, error) {
+	result, err := api.api.TraceTransaction(ctx, hash, flatTraceConfig())
+	if err != nil {
+		return nil, err
+	}
+	var frames []json.RawMessage
+	if err := json.Unmarshal(result.(json.RawMessage), &frames); err != nil {
+		return nil, err
+	}
+	return frames, nil
+}
+
+// Filter returns the traces within a range of blocks matching the given sender
+// and recipient addresses. The matching traces are paginated by skipping the
+// first `after` ones and returning at most `count`.
+func (api *TraceAPI) Filter(ctx context.Context, args TraceFilterArgs) ([]json.RawMessage, error) {
+	head, err := api.api.backend.HeaderByNumber(ctx, rpc.LatestBlockNumber)
+	if err != nil {
+		return nil, err
+	}
+	resolve := func(number *rpc.BlockNumber) uint64 {
+		if number == nil || *number < 0 {
+			return head.Number.Uint64()
+		}
+		return uint64(*number)
+	}
+	from, to := resolve(args.FromBlock), resolve(args.ToBlock)
+	if from > to {
+		return nil, fmt.Errorf("invalid block range %d to %d", from, to)
+	}
+	if to-from >= traceFilterMaxBlocks {
+		return nil, fmt.Errorf("block range %d to %d exceeds the limit of %d blocks", from, to, traceFilterMaxBlocks)
+	}
+	var (
+		traces = []json.RawMessage{}
+		skip   uint64
+	)
+	if args.After != nil {
+		skip = *args.After
+	}
+	for number := from; number <= to; number++ {
+		if args.Count != nil && uint64(len(traces)) >= *args.Count {
+			break
+		}
+		if err := ctx.Err(); err != nil {
+			return nil, err
+		}
+		block, err := api.api.blockByNumber(ctx, rpc.BlockNumber(number))
+		if err != nil {
+			return nil, err
+		}
+		// Blocks without transactions, such as the genesis, have no traces
+		if len(block.Transactions()) == 0 {
+			continue
+		}
+		results, err := api.api.traceBlock(ctx, block, flatTraceConfig())
+		if err != nil {
+			return nil, err
+		}
+		for _, res := range results {
+			frames, err := decodeTxTraceResult(res)
+			if err != nil {
+				return nil, err
+			}
+			matched, err := filterTraces(frames, args.FromAddress, args.ToAddress)
+			if err != nil {
+				return nil, err
+			}
+			traces, skip = paginateTraces(traces, matched, skip, args.Count)
+		}
+	}
+	return traces, nil
+}
+
+// ReplayTransaction re-executes a transaction, returning the requested types of
+// traces out of trace, stateDiff and vmTrace.
+func (api *TraceAPI) ReplayTransaction(ctx context.Context, hash common.Hash, traceTypes []string) (*TraceResults, error) {
+	config, err := replayTraceConfig(traceTypes)
+	if err != nil {
+		return nil, err
+	}
+	result, err := api.api.TraceTransaction(ctx, hash, config)
+	if err != nil {
+		return nil, err
+	}
+	return newTraceResults(result.(json.RawMessage), traceTypes)
+}
+
+// ReplayBlockTransactions re-executes all the transactions in a block, returning
+// the requested types of traces of each of them.
+func (api *TraceAPI) ReplayBlockTransactions(ctx context.Context, number rpc.BlockNumber, traceTypes []string) ([]*TraceResults, error) {
+	config, err := replayTraceConfig(traceTypes)
+	if err != nil {
+		return nil, err
+	}
+	results, err := api.api.TraceBlockByNumber(ctx, number, config)
+	if err != nil {
+		return nil, err
+	}
+	replays := make([]*TraceResults, 0, len(results))
+	for _, res := range results {
+		if res.Error != "" {
+			return nil, fmt.Errorf("tracing failed for transaction %x: %s", res.TxHash, res.Error)
+		}
+		replay, err := newTraceResults(res.Result.(json.RawMessage), traceTypes)
+		if err != nil {
+			return nil, err
+		}
+		hash := res.TxHash
+		replay.TransactionHash = &hash
+		replays = append(replays, replay)
+	}
+	return replays, nil
+}
+
+// flatTraceConfig returns the tracing configuration running the flat call tracer.
+func flatTraceConfig() *TraceConfig {
+	tracer := flatCallTracerName
+	return &TraceConfig{Tracer: &tracer, TracerConfig: flatCallTracerConfig}
+}
+
+// replayTraceConfig returns the tracing configuration running the tracers of the
+// requested trace types at once. The flat call tracer always runs, providing
+// the output of the transaction.
+func replayTraceConfig(traceTypes []string) (*TraceConfig, error) {
+	config := map[string]json.RawMessage{flatCallTracerName: flatCallTracerConfig}
+	for _, typ := range traceTypes {
+		name, ok := replayTracers[typ]
+		if !ok {
+			return nil, fmt.Errorf("unknown trace type %q", typ)
+		}
+		if _, ok := config[name]; !ok {
+			config[name] = nil
+		}
+	}
+	blob, err := json.Marshal(config)
+	if err != nil {
+		return nil, err
+	}
+	tracer := "muxTracer"
+	return &TraceConfig{Tracer: &tracer, TracerConfig: blob}, nil
+}
+
+// newTraceResults assembles the replay result of a transaction from the outputs
+// of the tracers run by the configuration of replayTraceConfig.
+func newTraceResults(result json.RawMessage, traceTypes []string) (*TraceResults, error) {
+	var outputs map[string]json.RawMessage
+	if err := json.Unmarshal(result, &outputs); err != nil {
+		return nil, err
+	}
+	var frames []json.RawMessage
+	if err := json.Unmarshal(outputs[flatCallTracerName], &frames); err != nil {
+		return nil, err
+	}
+	if len(frames) == 0 {
+		return nil, errors.New("missing top level call trace")
+	}
+	var top flatTrace
+	if err := json.Unmarshal(frames[0], &top); err != nil {
+		return nil, err
+	}
+	res := &TraceResults{Output: hexutil.Bytes{}, Trace: []json.RawMessage{}}
+	if top.Result != nil {
+		if top.Type == "create" {
+			res.Output = top.Result.Code
+		} else {
+			res.Output = top.Result.Output
+		}
+	}
+	for _, typ := range traceTypes {
+		switch typ {
+		case "trace":
+			res.Trace = frames
+		case "stateDiff":
+			res.StateDiff = outputs[replayTracers[typ]]
+		case "vmTrace":
+			res.VmTrace = outputs[replayTracers[typ]]
+		}
+	}
+	return res, nil
+}
+
+// decodeTxTraceResult splits the flat call trace of a transaction into its frames.
+func decodeTxTraceResult(res *txTraceResult) ([]json.RawMessage, error) {
+	if res.Error != "" {
+		return nil, fmt.Errorf("tracing failed for transaction %x: %s", res.TxHash, res.Error)
+	}
+	var frames []json.RawMessage
+	if err := json.Unmarshal(res.Result.(json.RawMessage), &frames); err != nil {
+		return nil, err
+	}
+	return frames, nil
+}
+
+// filterTraces returns the traces sent by any of the senders to any of the
+// recipients. An empty address list matches any address.
+func filterTraces(frames []json.RawMessage, senders, recipients []common.Address) ([]json.RawMessage, error) {
+	contains := func(addrs []common.Address, addr *common.Address) bool {
+		if len(addrs) == 0 {
+			return true
+		}
+		if addr == nil {
+			return false
+		}
+		for _, a := range addrs {
+			if a == *addr {
+				return true
+			}
+		}
+		return false
+	}
+	var matched []json.RawMessage
+	for _, frame := range frames {
+		var trace flatTrace
+		if err := json.Unmarshal(frame, &trace); err != nil {
+			return nil, err
+		}
+		if from, to := trace.endpoints(); contains(senders, from) && contains(recipients, to) {
+			matched = append(matched, frame)
+		}
+	}
+	return matched, nil
+}
+
+// paginateTraces appends the matched traces to the collected ones, skipping the
+// given number of them first and stopping once count traces are collected. The
+// number of traces still to be skipped is returned along the collected ones.
+func paginateTraces(traces, matched []json.RawMessage, skip uint64, count *uint64) ([]json.RawMessage, uint64) {
+	for _, trace := range matched {
+		if skip > 0 {
+			skip--
+			continue
+		}
+		if count != nil && uint64(len(traces)) >= *count {
+			break
+		}
+		traces = append(traces, trace)
+	}
+	return traces, skip
+}
@@ -0,0 +1,134 @@
+// Copyright 2023 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package tracers
+
+import (
+	"encoding/json"
+	"reflect"
+	"testing"
+
+	"github.com/ethereum/go-ethereum/common"
+)
+
+// Tests that flat call traces are matched by their sender and recipient, taking
+// contract creations and self destructs into account, and paginated across
+// transactions.
+func TestFilterTraces(t *testing.T) {
+	var (
+		alice   = common.HexToAddress("0xa11ce")
+		bob     = common.HexToAddress("0xb0b")
+		created = common.HexToAddress("0xc0ffee")
+
+		call    = json.RawMessage(`{"action":{"from":"0x00000000000000000000000000000000000a11ce","to":"0x0000000000000000000000000000000000000b0b"},"type":"call"}`)
+		create  = json.RawMessage(`{"action":{"from":"0x0000000000000000000000000000000000000b0b"},"result":{"address":"0x0000000000000000000000000000000000c0ffee"},"type":"create"}`)
+		failed  = json.RawMessage(`{"action":{"from":"0x0000000000000000000000000000000000000b0b"},"error":"Reverted","type":"create"}`)
+		suicide = json.RawMessage(`{"action":{"address":"0x0000000000000000000000000000000000c0ffee","refundAddress":"0x00000000000000000000000000000000000a11ce"},"type":"suicide"}`)
+		frames  = []json.RawMessage{call, create, failed, suicide}
+		tests   = []struct {
+			senders    []common.Address
+			recipients []common.Address
+			want       []json.RawMessage
+		}{
+			{nil, nil, frames},
+			{[]common.Address{alice}, nil, []json.RawMessage{call}},
+			{[]common.Address{bob}, nil, []json.RawMessage{create, failed}},
+			{nil, []common.Address{created}, []json.RawMessage{create}},
+			{[]common.Address{created}, []common.Address{alice}, []json.RawMessage{suicide}},
+			{[]common.Address{alice, bob}, []common.Address{bob, created}, []json.RawMessage{call, create}},
+			{[]common.Address{alice}, []common.Address{alice}, nil},
+		}
+	)
+	for i, tt := range tests {
+		have, err := filterTraces(frames, tt.senders, tt.recipients)
+		if err != nil {
+			t.Fatalf("test %d: failed to filter traces: %v", i, err)
+		}
+		if !reflect.DeepEqual(have, tt.want) {
+			t.Errorf("test %d: filtered traces mismatch: have %s, want %s", i, have, tt.want)
+		}
+	}
+	// Paginate over the traces of two transactions
+	var (
+		count  = uint64(3)
+		traces []json.RawMessage
+		skip   = uint64(2)
+	)
+	traces, skip = paginateTraces(traces, []json.RawMessage{call, create, failed}, skip, &count)
+	if skip != 0 || !reflect.DeepEqual(traces, []json.RawMessage{failed}) {
+		t.Fatalf("first page mismatch: have %s, %d to skip", traces, skip)
+	}
+	traces, _ = paginateTraces(traces, []json.RawMessage{suicide, call, create}, skip, &count)
+	if !reflect.DeepEqual(traces, []json.RawMessage{failed, suicide, call}) {
+		t.Fatalf("second page mismatch: have %s", traces)
+	}
+}
+
+// Tests that the replay results are assembled from the outputs of the tracers
+// run for the requested trace types.
+func TestReplayTraceResults(t *testing.T) {
+	if _, err := replayTraceConfig([]string{"trace", "bogus"}); err == nil {
+		t.Fatalf("unknown trace type accepted")
+	}
+	config, err := replayTraceConfig([]string{"stateDiff", "vmTrace"})
+	if err != nil {
+		t.Fatalf("failed to create replay config: %v", err)
+	}
+	if *config.Tracer != "muxTracer" {
+		t.Fatalf("tracer mismatch: have %s, want muxTracer", *config.Tracer)
+	}
+	var tracers map[string]json.RawMessage
+	if err := json.Unmarshal(config.TracerConfig, &tracers); err != nil {
+		t.Fatalf("failed to decode tracer config: %v", err)
+	}
+	if len(tracers) != 3 || string(tracers[flatCallTracerName]) != string(flatCallTracerConfig) {
+		t.Fatalf("tracer config mismatch: have %s", config.TracerConfig)
+	}
+	var (
+		call      = `{"action":{"callType":"call"},"result":{"gasUsed":"0x0","output":"0x2a"},"type":"call"}`
+		create    = `{"action":{},"result":{"address":"0x0000000000000000000000000000000000c0ffee","code":"0x6000"},"type":"create"}`
+		stateDiff = `{"0x0000000000000000000000000000000000c0ffee":{"balance":"="}}`
+		vmTrace   = `{"code":"0x","ops":[]}`
+	)
+	result := json.RawMessage(`{"flatCallTracer":[` + call + `],"stateDiffTracer":` + stateDiff + `,"vmTracer":` + vmTrace + `}`)
+
+	res, err := newTraceResults(result, []string{"stateDiff"})
+	if err != nil {
+		t.Fatalf("failed to assemble replay results: %v", err)
+	}
+	if res.Output.String() != "0x2a" || len(res.Trace) != 0 || string(res.StateDiff) != stateDiff || res.VmTrace != nil {
+		t.Errorf("stateDiff replay mismatch: %+v", res)
+	}
+	res, err = newTraceResults(result, []string{"trace", "vmTrace"})
+	if err != nil {
+		t.Fatalf("failed to assemble replay results: %v", err)
+	}
+	if len(res.Trace) != 1 || string(res.Trace[0]) != call || res.StateDiff != nil || string(res.VmTrace) != vmTrace {
+		t.Errorf("trace replay mismatch: %+v", res)
+	}
+	// Contract creations output the deployed code
+	res, err = newTraceResults(json.RawMessage(`{"flatCallTracer":[`+create+`]}`), nil)
+	if err != nil {
+		t.Fatalf("failed to assemble replay results: %v", err)
+	}
+	if res.Output.String() != "0x6000" {
+		t.Errorf("create output mismatch: have %v, want 0x6000", res.Output)
+	}
+	blob, _ := json.Marshal(res)
+	if want := `{"output":"0x6000","stateDiff":null,"trace":[],"vmTrace":null}`; string(blob) != want {
+		t.Errorf("encoding mismatch: have %s, want %s", blob, want)
+	}
+}
@@ -0,0 +1,294 @@
+// Copyright 2023 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package tracers
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"math/big"
+	"sort"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/common/hexutil"
+	"github.com/ethereum/go-ethereum/core"
+	"github.com/ethereum/go-ethereum/core/vm"
+	"github.com/ethereum/go-ethereum/crypto"
+	"github.com/ethereum/go-ethereum/rlp"
+	"github.com/ethereum/go-ethereum/rpc"
+	"github.com/ethereum/go-ethereum/trie"
+)
+
+// BisectConfig holds extra parameters to the bisection functions.
+type BisectConfig struct {
+	Reexec *uint64
+}
+
+// StepCommitment is the state commitment of a transaction execution right before
+// a given step, i.e. a single EVM opcode, is executed. Two parties disagreeing on
+// the outcome of a transaction can bisect over the step index, comparing their
+// commitments, until they find the single step they disagree on.
+type StepCommitment struct {
+	Step        uint64         `json:"step"`          // Index of the step within the transaction
+	Steps       uint64         `json:"steps"`         // Number of steps executed by the transaction
+	Address     common.Address `json:"address"`       // Contract executing the step
+	Pc          uint64         `json:"pc"`            // Program counter of the step
+	Op          string         `json:"op"`            // Opcode executed by the step
+	Depth       int            `json:"depth"`         // Call depth of the step
+	Gas         uint64         `json:"gas"`           // Gas available before the step
+	Stack       []common.Hash  `json:"stack"`         // Stack words, bottom first
+	MemoryHash  common.Hash    `json:"memoryHash"`    // Hash of the whole memory
+	StorageRoot common.Hash    `json:"storageRoot"`   // Root of the slots written by the preceding steps
+	Commitment  common.Hash    `json:"commitment"`    // Hash committing to all the above
+	PreState    common.Hash    `json:"preStateRoot"`  // State root before the transaction
+	PostState   common.Hash    `json:"postStateRoot"` // State root after the transaction
+	Witness     *StepWitness   `json:"witness"`       // State needed to execute the step
+}
+
+// StepWitness holds everything needed to execute exactly one step on its own:
+// the code of the executing contract, its memory and the state the step reads,
+// as of right before the step is executed.
+type StepWitness struct {
+	Code     hexutil.Bytes                                  `json:"code"`
+	Memory   hexutil.Bytes                                  `json:"memory"`
+	Accounts map[common.Address]*StepAccount                `json:"accounts"`
+	Storage  map[common.Address]map[common.Hash]common.Hash `json:"storage"`
+	Codes    map[common.Address]hexutil.Bytes               `json:"codes"`
+}
+
+// StepAccount is an account accessed by a step, nil if it does not exist.
+type StepAccount struct {
+	Nonce    hexutil.Uint64 `json:"nonce"`
+	Balance  *hexutil.Big   `json:"balance"`
+	CodeHash common.Hash    `json:"codeHash"`
+}
+
+// BisectTransaction re-executes a transaction and returns its state commitment
+// right before the step with the given index, along with the witness needed to
+// execute that step. The pre- and post-state roots of the transaction, used to
+// narrow a dispute down to a single transaction, are included as well.
+func (api *API) BisectTransaction(ctx context.Context, hash common.Hash, step uint64, config *BisectConfig) (*StepCommitment, error) {
+	tx, blockHash, blockNumber, index, err := api.backend.GetTransaction(ctx, hash)
+	if err != nil {
+		return nil, err
+	}
+	// Only mined txes are supported
+	if tx == nil {
+		return nil, errTxNotFound
+	}
+	// It shouldn't happen in practice.
+	if blockNumber == 0 {
+		return nil, errors.New("genesis is not traceable")
+	}
+	reexec := defaultTraceReexec
+	if config != nil && config.Reexec != nil {
+		reexec = *config.Reexec
+	}
+	block, err := api.blockByNumberAndHash(ctx, rpc.BlockNumber(blockNumber), blockHash)
+	if err != nil {
+		return nil, err
+	}
+	msg, vmctx, statedb, release, err := api.backend.StateAtTransaction(ctx, block, int(index), reexec)
+	if err != nil {
+		return nil, err
+	}
+	defer release()
+
+	var (
+		tracer = newStepTracer(step)
+		vmenv  = vm.NewEVM(vmctx, core.NewEVMTxContext(msg), statedb, api.backend.ChainConfig(), vm.Config{Tracer: tracer, Witness: tracer})
+		eip158 = api.backend.ChainConfig().IsEIP158(block.Number())
+	)
+	// Hash the state around the transaction, the same way its witness roots are
+	preState := statedb.IntermediateRoot(eip158)
+	statedb.SetTxContext(hash, int(index))
+	if _, err := core.ApplyMessage(vmenv, msg, new(core.GasPool).AddGas(msg.GasLimit)); err != nil {
+		return nil, fmt.Errorf("tracing failed: %w", err)
+	}
+	if tracer.result == nil {
+		return nil, fmt.Errorf("step %d out of range, transaction executed %d steps", step, tracer.steps)
+	}
+	tracer.result.Steps = tracer.steps
+	tracer.result.PreState, tracer.result.PostState = preState, statedb.IntermediateRoot(eip158)
+	return tracer.result, nil
+}
+
+// stepTracer is an EVM logger capturing the state commitment right before a
+// given step. It also acts as a state witness recorder, collecting the state
+// accessed during that step.
+type stepTracer struct {
+	env       *vm.EVM
+	target    uint64                                      // Index of the step to capture
+	steps     uint64                                      // Number of steps executed so far
+	written   map[common.Address]map[common.Hash]struct{} // Slots written by the steps so far
+	recording bool                                        // Whether the target step is executing
+	result    *StepCommitment
+}
+
+func newStepTracer(target uint64) *stepTracer {
+	return &stepTracer{
+		target:  target,
+		written: make(map[common.Address]map[common.Hash]struct{}),
+	}
+}
+
+func (t *stepTracer) CaptureTxStart(gasLimit uint64) {}
+
+func (t *stepTracer) CaptureTxEnd(restGas uint64) {}
+
+func (t *stepTracer) CaptureStart(env *vm.EVM, from common.Address, to common.Address, create bool, input []byte, gas uint64, value *big.Int) {
+	t.env = env
+}
+
+func (t *stepTracer) CaptureEnd(output []byte, gasUsed uint64, err error) {
+	t.recording = false
+}
+
+func (t *stepTracer) CaptureEnter(typ vm.OpCode, from common.Address, to common.Address, input []byte, gas uint64, value *big.Int) {
+}
+
+func (t *stepTracer) CaptureExit(output []byte, gasUsed uint64, err error) {}
+
+func (t *stepTracer) CaptureState(pc uint64, op vm.OpCode, gas, cost uint64, scope *vm.ScopeContext, rData []byte, depth int, err error) {
+	t.recording = false
+	if t.steps == t.target {
+		t.result = t.commit(pc, op, gas, scope, depth)
+		t.recording = true
+	}
+	if op == vm.SSTORE && len(scope.Stack.Data()) > 0 {
+		addr := scope.Contract.Address()
+		if t.written[addr] == nil {
+			t.written[addr] = make(map[common.Hash]struct{})
+		}
+		t.written[addr][common.Hash(scope.Stack.Back(0).Bytes32())] = struct{}{}
+	}
+	t.steps++
+}
+
+func (t *stepTracer) CaptureFault(pc uint64, op vm.OpCode, gas, cost uint64, scope *vm.ScopeContext, depth int, err error) {
+}
+
+// commit assembles the state commitment of the execution right before a step.
+func (t *stepTracer) commit(pc uint64, op vm.OpCode, gas uint64, scope *vm.ScopeContext, depth int) *StepCommitment {
+	stack := make([]common.Hash, len(scope.Stack.Data()))
+	for i, word := range scope.Stack.Data() {
+		stack[i] = word.Bytes32()
+	}
+	memory := common.CopyBytes(scope.Memory.Data())
+	result := &StepCommitment{
+		Step:        t.steps,
+		Pc:          pc,
+		Op:          op.String(),
+		Depth:       depth,
+		Gas:         gas,
+		Stack:       stack,
+		MemoryHash:  crypto.Keccak256Hash(memory),
+		StorageRoot: t.storageRoot(),
+		Address:     scope.Contract.Address(),
+		Witness: &StepWitness{
+			Code:     common.CopyBytes(scope.Contract.Code),
+			Memory:   memory,
+			Accounts: make(map[common.Address]*StepAccount),
+			Storage:  make(map[common.Address]map[common.Hash]common.Hash),
+			Codes:    make(map[common.Address]hexutil.Bytes),
+		},
+	}
+	enc, _ := rlp.EncodeToBytes([]interface{}{
+		result.Step, result.Pc, uint64(result.Depth), result.Gas, result.Address,
+		result.Stack, result.MemoryHash, result.StorageRoot,
+	})
+	result.Commitment = crypto.Keccak256Hash(enc)
+	return result
+}
+
+// storageRoot returns the root of a trie holding the current values of all the
+// slots written so far, keyed by the hash of their address and slot.
+func (t *stepTracer) storageRoot() common.Hash {
+	type entry struct {
+		key   []byte
+		value common.Hash
+	}
+	var entries []entry
+	for addr, slots := range t.written {
+		for slot := range slots {
+			entries = append(entries, entry{
+				key:   crypto.Keccak256(addr.Bytes(), slot.Bytes()),
+				value: t.env.StateDB.GetState(addr, slot),
+			})
+		}
+	}
+	sort.Slice(entries, func(i, j int) bool { return bytes.Compare(entries[i].key, entries[j].key) < 0 })
+
+	st := trie.NewStackTrie(nil)
+	for _, entry := range entries {
+		st.Update(entry.key, entry.value.Bytes())
+	}
+	return st.Hash()
+}
+
+// RecordAccount implements vm.StateWitnessRecorder, recording the accounts
+// accessed by the target step.
+func (t *stepTracer) RecordAccount(addr common.Address) {
+	if !t.recording {
+		return
+	}
+	accounts := t.result.Witness.Accounts
+	if _, ok := accounts[addr]; ok {
+		return
+	}
+	if !t.env.StateDB.Exist(addr) {
+		accounts[addr] = nil
+		return
+	}
+	accounts[addr] = &StepAccount{
+		Nonce:    hexutil.Uint64(t.env.StateDB.GetNonce(addr)),
+		Balance:  (*hexutil.Big)(t.env.StateDB.GetBalance(addr)),
+		CodeHash: t.env.StateDB.GetCodeHash(addr),
+	}
+}
+
+// RecordStorage implements vm.StateWitnessRecorder, recording the storage slots
+// accessed by the target step.
+func (t *stepTracer) RecordStorage(addr common.Address, key common.Hash) {
+	if !t.recording {
+		return
+	}
+	t.RecordAccount(addr)
+
+	storage := t.result.Witness.Storage
+	if storage[addr] == nil {
+		storage[addr] = make(map[common.Hash]common.Hash)
+	}
+	if _, ok := storage[addr][key]; !ok {
+		storage[addr][key] = t.env.StateDB.GetState(addr, key)
+	}
+}
+
+// RecordCode implements vm.StateWitnessRecorder, recording the contract codes
+// accessed by the target step.
+func (t *stepTracer) RecordCode(addr common.Address) {
+	if !t.recording {
+		return
+	}
+	t.RecordAccount(addr)
+
+	codes := t.result.Witness.Codes
+	if _, ok := codes[addr]; !ok {
+		codes[addr] = common.CopyBytes(t.env.StateDB.GetCode(addr))
+	}
+}
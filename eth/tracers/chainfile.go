@@ -0,0 +1,300 @@
+// Copyright 2023 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package tracers
+
+import (
+	"bytes"
+	"compress/gzip"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+
+	"github.com/ethereum/go-ethereum/log"
+	"github.com/ethereum/go-ethereum/rpc"
+)
+
+// traceChainCheckpointFile is the name of the file tracking the progress of
+// TraceChainToFile within its output directory.
+const traceChainCheckpointFile = "checkpoint.json"
+
+// traceDirBackend is implemented by backends designating a directory for the
+// chain traces written by TraceChainToFile.
+type traceDirBackend interface {
+	TraceDir() string
+}
+
+// traceChainShardBlocks is the number of blocks whose traces are written into
+// a single shard file by TraceChainToFile.
+var traceChainShardBlocks = uint64(10000)
+
+// traceChainCheckpoint is the progress of tracing a chain segment into files.
+// The traces of the blocks up to and including Done are all in the listed
+// shards, so the tracing resumes right after it.
+type traceChainCheckpoint struct {
+	Start  uint64          `json:"start"`  // Block the segment starts after
+	End    uint64          `json:"end"`    // Last block of the segment
+	Config json.RawMessage `json:"config"` // Tracing configuration used
+	Done   uint64          `json:"done"`   // Last block whose traces are written
+	Shards []string        `json:"shards"` // Names of the completed shard files
+}
+
+// traceChainShard is a shard file being written, holding the traces of a range
+// of blocks as gzip compressed JSON lines.
+type traceChainShard struct {
+	name  string // Final name of the shard, once completed
+	last  uint64 // Last block of the range covered by the shard
+	file  *os.File
+	gz    *gzip.Writer
+	lines *json.Encoder
+}
+
+// TraceChainToFile traces the chain between two blocks (excluding start) like
+// TraceChain, writing the results into gzip compressed JSON lines files within
+// the given directory, one line per block with transactions. Every shard file
+// covers a fixed range of blocks, and the progress is checkpointed whenever a
+// shard is completed. Calling the method again with the same arguments resumes
+// an interrupted tracing from the last checkpoint. The names of the shard files
+// are returned once the whole segment is traced.
+//
+// The directory is relative to the trace directory of the node, which the files
+// are confined to: absolute paths and paths containing ".." are rejected.
+func (api *API) TraceChainToFile(ctx context.Context, start, end rpc.BlockNumber, config *TraceConfig, name string) ([]string, error) {
+	dir, err := api.resolveTraceDir(name)
+	if err != nil {
+		return nil, err
+	}
+	from, err := api.blockByNumber(ctx, start)
+	if err != nil {
+		return nil, err
+	}
+	to, err := api.blockByNumber(ctx, end)
+	if err != nil {
+		return nil, err
+	}
+	if from.Number().Cmp(to.Number()) >= 0 {
+		return nil, fmt.Errorf("end block (#%d) needs to come after start block (#%d)", end, start)
+	}
+	blob, err := json.Marshal(config)
+	if err != nil {
+		return nil, err
+	}
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return nil, err
+	}
+	// Resume from the checkpoint of a previous run, if any
+	checkpoint, err := readTraceChainCheckpoint(dir)
+	if err != nil {
+		return nil, err
+	}
+	if checkpoint == nil {
+		checkpoint = &traceChainCheckpoint{Start: from.NumberU64(), End: to.NumberU64(), Config: blob, Done: from.NumberU64(), Shards: []string{}}
+	} else if checkpoint.Start != from.NumberU64() || checkpoint.End != to.NumberU64() || !bytes.Equal(checkpoint.Config, blob) {
+		return nil, fmt.Errorf("directory %s holds the traces of another chain segment (#%d-#%d)", dir, checkpoint.Start, checkpoint.End)
+	}
+	shards := func() []string {
+		files := make([]string, len(checkpoint.Shards))
+		for i, name := range checkpoint.Shards {
+			files[i] = filepath.Join(dir, name)
+		}
+		return files
+	}
+	if checkpoint.Done >= checkpoint.End {
+		return shards(), nil
+	}
+	if checkpoint.Done > checkpoint.Start {
+		log.Info("Resuming chain tracing to files", "dir", dir, "start", checkpoint.Start, "end", checkpoint.End, "done", checkpoint.Done)
+		if from, err = api.blockByNumber(ctx, rpc.BlockNumber(checkpoint.Done)); err != nil {
+			return nil, err
+		}
+	}
+	// Abort the tracing if the request is cancelled
+	var (
+		closed = make(chan interface{})
+		once   sync.Once
+		abort  = func() { once.Do(func() { close(closed) }) }
+	)
+	defer abort()
+	go func() {
+		select {
+		case <-ctx.Done():
+			abort()
+		case <-closed:
+		}
+	}()
+	var (
+		shard  *traceChainShard
+		failed error
+	)
+	defer func() {
+		if shard != nil {
+			shard.discard()
+		}
+	}()
+	for res := range api.traceChain(from, to, config, closed) {
+		if failed != nil {
+			continue // Drain the results of the aborted tracing
+		}
+		number := uint64(res.Block)
+
+		// Complete the current shard if the block lies past it
+		if shard != nil && number > shard.last {
+			if failed = checkpoint.complete(dir, shard); failed != nil {
+				abort()
+				continue
+			}
+			shard = nil
+		}
+		if len(res.Traces) > 0 {
+			if shard == nil {
+				if shard, failed = newTraceChainShard(dir, number, checkpoint.Start, checkpoint.End); failed != nil {
+					abort()
+					continue
+				}
+			}
+			if failed = shard.lines.Encode(res); failed != nil {
+				abort()
+				continue
+			}
+		}
+		// The end block is always reported, complete the last shard with it
+		if number == checkpoint.End {
+			if shard != nil {
+				if failed = checkpoint.complete(dir, shard); failed != nil {
+					continue
+				}
+				shard = nil
+			}
+			checkpoint.Done = number
+			failed = checkpoint.write(dir)
+		}
+	}
+	if failed != nil {
+		return nil, failed
+	}
+	if checkpoint.Done < checkpoint.End {
+		if err := ctx.Err(); err != nil {
+			return nil, err
+		}
+		return nil, fmt.Errorf("chain tracing aborted after block #%d", checkpoint.Done)
+	}
+	return shards(), nil
+}
+
+// resolveTraceDir returns the path of a directory within the trace directory,
+// rejecting names which could point outside of it.
+func (api *API) resolveTraceDir(name string) (string, error) {
+	if api.traceDir == "" {
+		return "", errors.New("no trace directory available")
+	}
+	if name == "" || filepath.IsAbs(name) || filepath.VolumeName(name) != "" {
+		return "", fmt.Errorf("invalid trace directory %q: must be a relative path", name)
+	}
+	for _, elem := range strings.Split(filepath.ToSlash(name), "/") {
+		if elem == ".." {
+			return "", fmt.Errorf("invalid trace directory %q: must not contain \"..\"", name)
+		}
+	}
+	return filepath.Join(api.traceDir, name), nil
+}
+
+// newTraceChainShard creates the shard file covering the given block within the
+// chain segment, named after the range of blocks it covers.
+func newTraceChainShard(dir string, number uint64, start, end uint64) (*traceChainShard, error) {
+	var (
+		first = (number-1)/traceChainShardBlocks*traceChainShardBlocks + 1
+		last  = first + traceChainShardBlocks - 1
+	)
+	if first <= start {
+		first = start + 1
+	}
+	if last > end {
+		last = end
+	}
+	name := fmt.Sprintf("traces-%09d-%09d.jsonl.gz", first, last)
+	file, err := os.Create(filepath.Join(dir, name+".tmp"))
+	if err != nil {
+		return nil, err
+	}
+	gz := gzip.NewWriter(file)
+	return &traceChainShard{name: name, last: last, file: file, gz: gz, lines: json.NewEncoder(gz)}, nil
+}
+
+// finish flushes the shard and moves it to its final name.
+func (s *traceChainShard) finish(dir string) error {
+	if err := s.gz.Close(); err != nil {
+		return err
+	}
+	if err := s.file.Sync(); err != nil {
+		return err
+	}
+	if err := s.file.Close(); err != nil {
+		return err
+	}
+	return os.Rename(s.file.Name(), filepath.Join(dir, s.name))
+}
+
+// discard drops an incomplete shard.
+func (s *traceChainShard) discard() {
+	s.file.Close()
+	os.Remove(s.file.Name())
+}
+
+// complete finishes a shard and checkpoints the progress up to its last block.
+func (c *traceChainCheckpoint) complete(dir string, shard *traceChainShard) error {
+	if err := shard.finish(dir); err != nil {
+		return err
+	}
+	c.Shards = append(c.Shards, shard.name)
+	c.Done = shard.last
+	log.Info("Wrote chain trace shard", "file", shard.name, "done", c.Done, "end", c.End)
+	return c.write(dir)
+}
+
+// write atomically replaces the checkpoint file in the directory.
+func (c *traceChainCheckpoint) write(dir string) error {
+	blob, err := json.MarshalIndent(c, "", "  ")
+	if err != nil {
+		return err
+	}
+	path := filepath.Join(dir, traceChainCheckpointFile)
+	if err := os.WriteFile(path+".tmp", blob, 0644); err != nil {
+		return err
+	}
+	return os.Rename(path+".tmp", path)
+}
+
+// readTraceChainCheckpoint loads the checkpoint file of the directory, or nil if
+// there is none.
+func readTraceChainCheckpoint(dir string) (*traceChainCheckpoint, error) {
+	blob, err := os.ReadFile(filepath.Join(dir, traceChainCheckpointFile))
+	if errors.Is(err, os.ErrNotExist) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	checkpoint := new(traceChainCheckpoint)
+	if err := json.Unmarshal(blob, checkpoint); err != nil {
+		return nil, fmt.Errorf("invalid checkpoint file: %v", err)
+	}
+	return checkpoint, nil
+}
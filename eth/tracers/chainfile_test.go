@@ -0,0 +1,200 @@
+// Copyright 2023 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package tracers
+
+import (
+	"bufio"
+	"compress/gzip"
+	"context"
+	"encoding/json"
+	"math/big"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/ethereum/go-ethereum/core"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/params"
+)
+
+// readTraceChainShard decodes the block numbers and transaction counts of the
+// traces within a shard file.
+func readTraceChainShard(t *testing.T, path string) [][2]int {
+	t.Helper()
+
+	file, err := os.Open(path)
+	if err != nil {
+		t.Fatalf("failed to open shard: %v", err)
+	}
+	defer file.Close()
+
+	gz, err := gzip.NewReader(file)
+	if err != nil {
+		t.Fatalf("failed to decompress shard %s: %v", path, err)
+	}
+	var (
+		blocks  [][2]int
+		scanner = bufio.NewScanner(gz)
+	)
+	scanner.Buffer(nil, 1024*1024)
+	for scanner.Scan() {
+		var res blockTraceResult
+		if err := json.Unmarshal(scanner.Bytes(), &res); err != nil {
+			t.Fatalf("invalid trace line in %s: %v", path, err)
+		}
+		blocks = append(blocks, [2]int{int(res.Block), len(res.Traces)})
+	}
+	if err := scanner.Err(); err != nil {
+		t.Fatalf("failed to read shard %s: %v", path, err)
+	}
+	return blocks
+}
+
+// Tests that chain segments are traced into compressed JSON lines shards, and
+// that an interrupted tracing resumes from its checkpoint.
+func TestTraceChainToFile(t *testing.T) {
+	defer func(blocks uint64) { traceChainShardBlocks = blocks }(traceChainShardBlocks)
+	traceChainShardBlocks = 16
+
+	// Initialize test accounts, every block having as many transfers as its number
+	accounts := newAccounts(2)
+	genesis := &core.Genesis{
+		Config: params.TestChainConfig,
+		Alloc: core.GenesisAlloc{
+			accounts[0].addr: {Balance: big.NewInt(params.Ether)},
+		},
+	}
+	signer := types.HomesteadSigner{}
+
+	var nonce uint64
+	backend := newTestBackend(t, 50, genesis, func(i int, b *core.BlockGen) {
+		for j := 0; j < i+1; j++ {
+			tx, _ := types.SignTx(types.NewTransaction(nonce, accounts[1].addr, big.NewInt(1000), params.TxGas, b.BaseFee(), nil), signer, accounts[0].key)
+			b.AddTx(tx)
+			nonce += 1
+		}
+	})
+	defer backend.teardown()
+	api := NewAPI(backend)
+	api.traceDir = t.TempDir()
+
+	dir := filepath.Join(api.traceDir, "segment")
+	files, err := api.TraceChainToFile(context.Background(), 5, 50, nil, "segment")
+	if err != nil {
+		t.Fatalf("failed to trace chain: %v", err)
+	}
+	names := []string{
+		"traces-000000006-000000016.jsonl.gz",
+		"traces-000000017-000000032.jsonl.gz",
+		"traces-000000033-000000048.jsonl.gz",
+		"traces-000000049-000000050.jsonl.gz",
+	}
+	var want []string
+	for _, name := range names {
+		want = append(want, filepath.Join(dir, name))
+	}
+	if !reflect.DeepEqual(files, want) {
+		t.Fatalf("shard files mismatch: have %v, want %v", files, want)
+	}
+	next := 6
+	for _, file := range files {
+		for _, block := range readTraceChainShard(t, file) {
+			if block != [2]int{next, next} {
+				t.Fatalf("shard %s: block mismatch: have #%d with %d traces, want #%d with %d", file, block[0], block[1], next, next)
+			}
+			next++
+		}
+	}
+	if next != 51 {
+		t.Fatalf("missing blocks after #%d", next-1)
+	}
+	// Tracing a different segment into the same directory must fail
+	if _, err := api.TraceChainToFile(context.Background(), 5, 40, nil, "segment"); err == nil {
+		t.Fatalf("tracing of another segment accepted")
+	}
+	// Simulate a crash while writing the third shard and resume the tracing
+	checkpoint, err := readTraceChainCheckpoint(dir)
+	if err != nil || checkpoint == nil {
+		t.Fatalf("failed to read checkpoint: %v", err)
+	}
+	checkpoint.Done, checkpoint.Shards = 32, names[:2]
+	if err := checkpoint.write(dir); err != nil {
+		t.Fatalf("failed to write checkpoint: %v", err)
+	}
+	for _, file := range files[2:] {
+		os.Remove(file)
+	}
+	if err := os.WriteFile(files[2]+".tmp", []byte("partial"), 0644); err != nil {
+		t.Fatalf("failed to write partial shard: %v", err)
+	}
+	// Replace the completed shards to check that they are not traced again
+	if err := os.WriteFile(files[0], []byte("done"), 0644); err != nil {
+		t.Fatalf("failed to replace shard: %v", err)
+	}
+	resumed, err := api.TraceChainToFile(context.Background(), 5, 50, nil, "segment")
+	if err != nil {
+		t.Fatalf("failed to resume chain tracing: %v", err)
+	}
+	if !reflect.DeepEqual(resumed, files) {
+		t.Fatalf("resumed shard files mismatch: have %v, want %v", resumed, files)
+	}
+	if blob, _ := os.ReadFile(files[0]); string(blob) != "done" {
+		t.Errorf("completed shard traced again")
+	}
+	next = 33
+	for _, file := range files[2:] {
+		for _, block := range readTraceChainShard(t, file) {
+			if block != [2]int{next, next} {
+				t.Fatalf("shard %s: block mismatch: have #%d with %d traces, want #%d with %d", file, block[0], block[1], next, next)
+			}
+			next++
+		}
+	}
+	if next != 51 {
+		t.Fatalf("missing resumed blocks after #%d", next-1)
+	}
+	if _, err := os.Stat(files[2] + ".tmp"); !os.IsNotExist(err) {
+		t.Errorf("partial shard left behind: %v", err)
+	}
+}
+
+// Tests that chain traces can only be written within the trace directory.
+func TestTraceChainToFileDir(t *testing.T) {
+	api := &API{}
+	if _, err := api.resolveTraceDir("segment"); err == nil {
+		t.Errorf("trace directory resolved without a root")
+	}
+	api.traceDir = t.TempDir()
+
+	for _, name := range []string{"", "/tmp/segment", "..", "../segment", "a/../../segment", "a/../segment"} {
+		if dir, err := api.resolveTraceDir(name); err == nil {
+			t.Errorf("directory %q accepted: %s", name, dir)
+		}
+	}
+	for _, name := range []string{"segment", "a/segment", "./segment"} {
+		dir, err := api.resolveTraceDir(name)
+		if err != nil {
+			t.Errorf("directory %q rejected: %v", name, err)
+			continue
+		}
+		if rel, err := filepath.Rel(api.traceDir, dir); err != nil || strings.HasPrefix(rel, "..") {
+			t.Errorf("directory %q resolved outside of the trace directory: %s", name, dir)
+		}
+	}
+}
@@ -0,0 +1,391 @@
+// Copyright 2023 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package tracers
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"sync"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/ethdb"
+	"github.com/ethereum/go-ethereum/event"
+	"github.com/ethereum/go-ethereum/log"
+	"github.com/ethereum/go-ethereum/rpc"
+)
+
+// traceIndexBackfillBatch is the number of blocks backfilled at once. The tail of
+// the index only moves once all the blocks of a batch are traced.
+const traceIndexBackfillBatch = 1024
+
+// IndexBackend is the backend the trace indexer traces the chain with.
+type IndexBackend interface {
+	Backend
+	SubscribeChainHeadEvent(ch chan<- core.ChainHeadEvent) event.Subscription
+}
+
+// indexedBackend is implemented by backends maintaining a trace index, whose
+// traces are served without re-executing the transactions.
+type indexedBackend interface {
+	TraceIndexer() *TraceIndexer
+}
+
+// TraceIndexer maintains an index of the call traces of the transactions in the
+// chain, as produced by the flat call tracer, keyed by transaction hash. Blocks
+// are traced as they become the chain head, while the blocks preceding the first
+// one indexed are backfilled in the background, down to a configured history.
+// The traces of the blocks falling out of the history are pruned.
+//
+// Backfilling needs the historical state of the blocks, which pruning nodes only
+// retain for the recent blocks (or regenerate within the default reexec limit).
+// Once it runs out of state the backfill stops for good, leaving the history of
+// the index shorter than configured. Only archive nodes can backfill arbitrarily
+// far.
+type TraceIndexer struct {
+	api     *API
+	backend IndexBackend
+	db      ethdb.Database // Database storing the traces, separate from the chain
+	history uint64         // Number of recent blocks to retain, zero for no backfill nor pruning
+
+	stateless bool // Whether the backfill ran out of historical state, only accessed by the loop
+
+	closed chan interface{} // Channel closed on shutdown, aborting any tracing
+	wg     sync.WaitGroup
+}
+
+// NewTraceIndexer creates a trace indexer storing the traces into the given
+// database. The indexing only begins once started.
+func NewTraceIndexer(backend IndexBackend, db ethdb.Database, history uint64) *TraceIndexer {
+	return &TraceIndexer{
+		api:     &API{backend: backend},
+		backend: backend,
+		db:      db,
+		history: history,
+		closed:  make(chan interface{}),
+	}
+}
+
+// Start launches the background indexing.
+func (idx *TraceIndexer) Start() {
+	idx.wg.Add(1)
+	go idx.loop()
+}
+
+// Stop terminates the background indexing, waiting for any tracing in progress
+// to be aborted.
+func (idx *TraceIndexer) Stop() {
+	close(idx.closed)
+	idx.wg.Wait()
+}
+
+// loop keeps the index up to date with the chain head, backfilling the history
+// whenever there are no new blocks to index.
+func (idx *TraceIndexer) loop() {
+	defer idx.wg.Done()
+
+	headCh := make(chan core.ChainHeadEvent, 1) // Buffered to avoid locking up the event feed
+	sub := idx.backend.SubscribeChainHeadEvent(headCh)
+	defer sub.Unsubscribe()
+
+	for {
+		if err := idx.follow(); err != nil {
+			log.Warn("Failed to index chain head traces", "err", err)
+		}
+		if err := idx.prune(); err != nil {
+			log.Warn("Failed to prune trace index", "err", err)
+		}
+		more, err := idx.backfill()
+		if err != nil {
+			log.Warn("Failed to backfill trace index", "err", err)
+		}
+		if more && err == nil {
+			select {
+			case <-headCh:
+			case <-idx.closed:
+				return
+			default:
+			}
+			continue
+		}
+		select {
+		case <-headCh:
+		case <-idx.closed:
+			return
+		}
+	}
+}
+
+// follow indexes the blocks up to the chain head, rewinding the index first if
+// a reorg moved the chain away from the last indexed block.
+func (idx *TraceIndexer) follow() error {
+	ctx := context.Background()
+	head, err := idx.api.blockByNumber(ctx, rpc.LatestBlockNumber)
+	if err != nil {
+		return err
+	}
+	if head.NumberU64() == 0 {
+		return nil // Genesis is not traceable
+	}
+	indexed, err := idx.indexedHead(ctx, head.NumberU64())
+	if err != nil {
+		return err
+	}
+	fresh := indexed == nil
+	if fresh {
+		// Nothing usable indexed yet, start from the chain head
+		if indexed, err = idx.api.blockByHash(ctx, head.ParentHash()); err != nil {
+			return err
+		}
+	}
+	if indexed.NumberU64() >= head.NumberU64() {
+		return nil
+	}
+	_, err = idx.index(indexed, head, func(batch ethdb.Batch, res *blockTraceResult) {
+		if fresh {
+			WriteTraceIndexTail(batch, uint64(res.Block))
+			fresh = false
+		}
+		WriteTraceIndexHead(batch, res.Hash)
+	})
+	return err
+}
+
+// indexedHead returns the latest indexed block which is still canonical and not
+// past the given chain head, or nil if there is no such block.
+func (idx *TraceIndexer) indexedHead(ctx context.Context, head uint64) (*types.Block, error) {
+	hash := ReadTraceIndexHead(idx.db)
+	tail := ReadTraceIndexTail(idx.db)
+	if hash == (common.Hash{}) || tail == nil {
+		return nil, nil
+	}
+	header, err := idx.backend.HeaderByHash(ctx, hash)
+	if err != nil {
+		return nil, err
+	}
+	for header != nil && header.Number.Uint64() >= *tail {
+		if number := header.Number.Uint64(); number <= head {
+			canonical, err := idx.backend.HeaderByNumber(ctx, rpc.BlockNumber(number))
+			if err != nil {
+				return nil, err
+			}
+			if canonical != nil && canonical.Hash() == header.Hash() {
+				return idx.api.blockByHash(ctx, header.Hash())
+			}
+		}
+		if header, err = idx.backend.HeaderByHash(ctx, header.ParentHash); err != nil {
+			return nil, err
+		}
+	}
+	return nil, nil
+}
+
+// backfill indexes a batch of the blocks preceding the index tail, reporting
+// whether there are more blocks left to backfill.
+func (idx *TraceIndexer) backfill() (bool, error) {
+	ctx := context.Background()
+	tail := ReadTraceIndexTail(idx.db)
+	if tail == nil || idx.history == 0 || idx.stateless {
+		return false, nil
+	}
+	head, err := idx.backend.HeaderByNumber(ctx, rpc.LatestBlockNumber)
+	if err != nil {
+		return false, err
+	}
+	limit := idx.historyLimit(head.Number.Uint64())
+	if *tail <= limit {
+		return false, nil
+	}
+	from := limit
+	if *tail-from > traceIndexBackfillBatch {
+		from = *tail - traceIndexBackfillBatch
+	}
+	start, err := idx.api.blockByNumber(ctx, rpc.BlockNumber(from-1))
+	if err != nil {
+		return false, err
+	}
+	// Make sure the state to trace the batch on is available. If it's not, the
+	// state of the older blocks isn't either, so there's no point in retrying
+	_, release, err := idx.backend.StateAtBlock(ctx, start, defaultTraceReexec, nil, true, false)
+	if err != nil {
+		idx.stateless = true
+		log.Warn("Stopped backfilling trace index, historical state unavailable", "tail", *tail, "history", idx.history, "err", err)
+		return false, nil
+	}
+	release()
+
+	end, err := idx.api.blockByNumber(ctx, rpc.BlockNumber(*tail-1))
+	if err != nil {
+		return false, err
+	}
+	done, err := idx.index(start, end, nil)
+	if err != nil || !done {
+		return false, err
+	}
+	WriteTraceIndexTail(idx.db, from)
+	log.Debug("Backfilled trace index", "from", from, "to", end.NumberU64())
+	return from > limit, nil
+}
+
+// prune deletes the traces of the blocks which fell out of the configured history
+// as the chain head advanced, moving the tail of the index up. Without a history
+// configured, nothing is ever pruned.
+func (idx *TraceIndexer) prune() error {
+	ctx := context.Background()
+	tail := ReadTraceIndexTail(idx.db)
+	if tail == nil || idx.history == 0 {
+		return nil
+	}
+	head, err := idx.backend.HeaderByNumber(ctx, rpc.LatestBlockNumber)
+	if err != nil {
+		return err
+	}
+	limit := idx.historyLimit(head.Number.Uint64())
+	for number := *tail; number < limit; {
+		batch := idx.db.NewBatch()
+		for end := number + traceIndexBackfillBatch; number < limit && number < end; number++ {
+			block, err := idx.api.blockByNumber(ctx, rpc.BlockNumber(number))
+			if err != nil {
+				return err
+			}
+			for _, tx := range block.Transactions() {
+				// Leave the trace alone if the transaction was re-included later
+				if traced, _ := ReadTxTrace(idx.db, tx.Hash()); traced == block.Hash() {
+					DeleteTxTrace(batch, tx.Hash())
+				}
+			}
+		}
+		WriteTraceIndexTail(batch, number)
+		if err := batch.Write(); err != nil {
+			return err
+		}
+		select {
+		case <-idx.closed:
+			return nil
+		default:
+		}
+	}
+	if *tail < limit {
+		log.Debug("Pruned trace index", "from", *tail, "to", limit-1)
+	}
+	return nil
+}
+
+// historyLimit returns the number of the oldest block within the configured
+// history of the given chain head.
+func (idx *TraceIndexer) historyLimit(head uint64) uint64 {
+	limit := uint64(1) // Genesis is not traceable
+	if head >= idx.history {
+		limit = head - idx.history + 1
+	}
+	return limit
+}
+
+// index traces the blocks between start (excluded) and end (included), storing
+// the traces of each block along with whatever the callback adds to the batch.
+// It reports whether all the blocks were indexed.
+//
+// Transactions failing to trace, e.g. on the tracer timeout, don't abort the
+// indexing: they and the rest of their block are left out of the index, so
+// queries for them fall back to tracing on demand.
+func (idx *TraceIndexer) index(start, end *types.Block, callback func(ethdb.Batch, *blockTraceResult)) (bool, error) {
+	var (
+		abort = make(chan interface{})
+		once  sync.Once
+		stop  = func() { once.Do(func() { close(abort) }) }
+	)
+	defer stop()
+	go func() {
+		select {
+		case <-idx.closed:
+			stop()
+		case <-abort:
+		}
+	}()
+	var (
+		failed error
+		last   uint64
+	)
+	for res := range idx.api.traceChain(start, end, flatTraceConfig(), abort) {
+		if failed != nil {
+			continue // Drain the results of the aborted tracing
+		}
+		batch := idx.db.NewBatch()
+		for i, tx := range res.Traces {
+			if tx == nil || tx.Error != "" {
+				log.Warn("Skipped untraceable transactions in trace index", "block", res.Block, "index", i, "skipped", len(res.Traces)-i)
+				break
+			}
+			WriteTxTrace(batch, tx.TxHash, res.Hash, tx.Result.(json.RawMessage))
+		}
+		if callback != nil {
+			callback(batch, res)
+		}
+		if err := batch.Write(); err != nil {
+			failed = err
+			stop()
+			continue
+		}
+		last = uint64(res.Block)
+	}
+	return failed == nil && last == end.NumberU64(), failed
+}
+
+// txTrace returns the indexed call trace of a transaction, provided it was traced
+// within the given block.
+func (idx *TraceIndexer) txTrace(hash common.Hash, blockHash common.Hash) json.RawMessage {
+	if idx == nil {
+		return nil
+	}
+	traced, trace := ReadTxTrace(idx.db, hash)
+	if trace == nil || traced != blockHash {
+		return nil
+	}
+	return trace
+}
+
+// blockTraces returns the indexed call traces of all the transactions in a block,
+// or nil if any of them is missing.
+func (idx *TraceIndexer) blockTraces(block *types.Block) []*txTraceResult {
+	if idx == nil || len(block.Transactions()) == 0 {
+		return nil
+	}
+	results := make([]*txTraceResult, len(block.Transactions()))
+	for i, tx := range block.Transactions() {
+		trace := idx.txTrace(tx.Hash(), block.Hash())
+		if trace == nil {
+			return nil
+		}
+		results[i] = &txTraceResult{TxHash: tx.Hash(), Result: trace}
+	}
+	return results
+}
+
+// isIndexedConfig reports whether a tracing configuration produces the traces
+// stored by the trace indexer.
+func isIndexedConfig(config *TraceConfig) bool {
+	if config == nil || config.Tracer == nil || *config.Tracer != flatCallTracerName {
+		return false
+	}
+	var compact bytes.Buffer
+	if err := json.Compact(&compact, config.TracerConfig); err != nil {
+		return false
+	}
+	return bytes.Equal(compact.Bytes(), flatCallTracerConfig)
+}
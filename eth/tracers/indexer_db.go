@@ -0,0 +1,134 @@
+// Copyright 2023 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package tracers
+
+import (
+	"encoding/binary"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/ethdb"
+	"github.com/ethereum/go-ethereum/log"
+	"github.com/ethereum/go-ethereum/rlp"
+	"github.com/golang/snappy"
+)
+
+// The schema of the trace index database. It is kept apart from the chain
+// database, so the keys only need to be distinct from each other.
+var (
+	// traceIndexHeadKey tracks the latest block whose call traces have been indexed.
+	traceIndexHeadKey = []byte("TraceIndexHead")
+
+	// traceIndexTailKey tracks the oldest block whose call traces have been indexed.
+	traceIndexTailKey = []byte("TraceIndexTail")
+
+	txTracePrefix = []byte("tx-trace-") // txTracePrefix + hash -> transaction call trace
+)
+
+// txTraceKey = txTracePrefix + hash
+func txTraceKey(hash common.Hash) []byte {
+	return append(append([]byte{}, txTracePrefix...), hash.Bytes()...)
+}
+
+// storedTxTrace is the database representation of a transaction call trace,
+// compressed the same way as the ancient chain data.
+type storedTxTrace struct {
+	BlockHash common.Hash // Hash of the block the traced transaction was included in
+	Trace     []byte      // Snappy compressed call trace
+}
+
+// ReadTxTrace retrieves the call trace indexed for the given transaction, along
+// with the hash of the block it was traced in.
+func ReadTxTrace(db ethdb.KeyValueReader, hash common.Hash) (common.Hash, []byte) {
+	data, _ := db.Get(txTraceKey(hash))
+	if len(data) == 0 {
+		return common.Hash{}, nil
+	}
+	var stored storedTxTrace
+	if err := rlp.DecodeBytes(data, &stored); err != nil {
+		log.Error("Invalid transaction trace RLP", "hash", hash, "err", err)
+		return common.Hash{}, nil
+	}
+	trace, err := snappy.Decode(nil, stored.Trace)
+	if err != nil {
+		log.Error("Invalid transaction trace compression", "hash", hash, "err", err)
+		return common.Hash{}, nil
+	}
+	return stored.BlockHash, trace
+}
+
+// HasTxTrace checks whether a call trace was indexed for the given transaction.
+func HasTxTrace(db ethdb.KeyValueReader, hash common.Hash) bool {
+	ok, _ := db.Has(txTraceKey(hash))
+	return ok
+}
+
+// WriteTxTrace stores the call trace of a transaction included in the given block.
+func WriteTxTrace(db ethdb.KeyValueWriter, hash common.Hash, blockHash common.Hash, trace []byte) {
+	data, err := rlp.EncodeToBytes(&storedTxTrace{BlockHash: blockHash, Trace: snappy.Encode(nil, trace)})
+	if err != nil {
+		log.Crit("Failed to encode transaction trace", "err", err)
+	}
+	if err := db.Put(txTraceKey(hash), data); err != nil {
+		log.Crit("Failed to store transaction trace", "err", err)
+	}
+}
+
+// DeleteTxTrace removes the call trace of a transaction.
+func DeleteTxTrace(db ethdb.KeyValueWriter, hash common.Hash) {
+	if err := db.Delete(txTraceKey(hash)); err != nil {
+		log.Crit("Failed to delete transaction trace", "err", err)
+	}
+}
+
+// ReadTraceIndexHead retrieves the hash of the latest block whose call traces
+// have been indexed.
+func ReadTraceIndexHead(db ethdb.KeyValueReader) common.Hash {
+	data, _ := db.Get(traceIndexHeadKey)
+	if len(data) == 0 {
+		return common.Hash{}
+	}
+	return common.BytesToHash(data)
+}
+
+// WriteTraceIndexHead stores the hash of the latest block whose call traces
+// have been indexed.
+func WriteTraceIndexHead(db ethdb.KeyValueWriter, hash common.Hash) {
+	if err := db.Put(traceIndexHeadKey, hash.Bytes()); err != nil {
+		log.Crit("Failed to store the trace index head", "err", err)
+	}
+}
+
+// ReadTraceIndexTail retrieves the number of the oldest block whose call traces
+// have been indexed.
+func ReadTraceIndexTail(db ethdb.KeyValueReader) *uint64 {
+	data, _ := db.Get(traceIndexTailKey)
+	if len(data) != 8 {
+		return nil
+	}
+	number := binary.BigEndian.Uint64(data)
+	return &number
+}
+
+// WriteTraceIndexTail stores the number of the oldest block whose call traces
+// have been indexed.
+func WriteTraceIndexTail(db ethdb.KeyValueWriter, number uint64) {
+	enc := make([]byte, 8)
+	binary.BigEndian.PutUint64(enc, number)
+	if err := db.Put(traceIndexTailKey, enc); err != nil {
+		log.Crit("Failed to store the trace index tail", "err", err)
+	}
+}
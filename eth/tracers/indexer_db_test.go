@@ -0,0 +1,66 @@
+// Copyright 2023 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package tracers
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core/rawdb"
+)
+
+// Tests transaction trace storage and retrieval operations.
+func TestTxTraceStorage(t *testing.T) {
+	db := rawdb.NewMemoryDatabase()
+
+	var (
+		hash      = common.Hash{0x01}
+		blockHash = common.Hash{0x02}
+		trace     = []byte(`[{"action":{"callType":"call"},"type":"call"}]`)
+	)
+	if _, entry := ReadTxTrace(db, hash); entry != nil {
+		t.Fatalf("Non existent trace returned: %s", entry)
+	}
+	WriteTxTrace(db, hash, blockHash, trace)
+	if !HasTxTrace(db, hash) {
+		t.Fatalf("Stored trace not found")
+	}
+	block, entry := ReadTxTrace(db, hash)
+	if block != blockHash || !bytes.Equal(entry, trace) {
+		t.Fatalf("Retrieved trace mismatch: have %x %s, want %x %s", block, entry, blockHash, trace)
+	}
+	DeleteTxTrace(db, hash)
+	if _, entry := ReadTxTrace(db, hash); entry != nil {
+		t.Fatalf("Deleted trace returned: %s", entry)
+	}
+	// Check the progress markers of the indexer
+	if head := ReadTraceIndexHead(db); head != (common.Hash{}) {
+		t.Fatalf("Non existent index head returned: %x", head)
+	}
+	if tail := ReadTraceIndexTail(db); tail != nil {
+		t.Fatalf("Non existent index tail returned: %d", *tail)
+	}
+	WriteTraceIndexHead(db, blockHash)
+	WriteTraceIndexTail(db, 42)
+	if head := ReadTraceIndexHead(db); head != blockHash {
+		t.Fatalf("Index head mismatch: have %x, want %x", head, blockHash)
+	}
+	if tail := ReadTraceIndexTail(db); tail == nil || *tail != 42 {
+		t.Fatalf("Index tail mismatch: have %v, want 42", tail)
+	}
+}
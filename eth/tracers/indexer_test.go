@@ -0,0 +1,175 @@
+// Copyright 2023 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package tracers
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"math/big"
+	"testing"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core"
+	"github.com/ethereum/go-ethereum/core/rawdb"
+	"github.com/ethereum/go-ethereum/core/state"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/event"
+	"github.com/ethereum/go-ethereum/params"
+)
+
+func init() {
+	// The native tracers aren't available in this package, stub the indexed one
+	// out to fail tracing instead of falling back to the missing JS evaluator
+	DefaultDirectory.Register(flatCallTracerName, func(*Context, json.RawMessage) (Tracer, error) {
+		return nil, errors.New("tracer unavailable")
+	}, false)
+}
+
+// prunedIndexBackend is a trace index backend lacking the state of the blocks
+// before a given number, like a pruning node.
+type prunedIndexBackend struct {
+	*testBackend
+	pruned uint64 // Number of the first block with state available
+	misses int    // Number of requests for unavailable state
+}
+
+func (b *prunedIndexBackend) SubscribeChainHeadEvent(ch chan<- core.ChainHeadEvent) event.Subscription {
+	return b.chain.SubscribeChainHeadEvent(ch)
+}
+
+func (b *prunedIndexBackend) StateAtBlock(ctx context.Context, block *types.Block, reexec uint64, base *state.StateDB, readOnly bool, preferDisk bool) (*state.StateDB, StateReleaseFunc, error) {
+	if block.NumberU64() < b.pruned {
+		b.misses++
+		return nil, nil, errStateNotFound
+	}
+	return b.testBackend.StateAtBlock(ctx, block, reexec, base, readOnly, preferDisk)
+}
+
+// Tests that the trace index backfill stops once it runs out of historical state
+// instead of retrying on every new block.
+func TestTraceIndexerStateless(t *testing.T) {
+	t.Parallel()
+
+	accounts := newAccounts(1)
+	genesis := &core.Genesis{
+		Config: params.TestChainConfig,
+		Alloc:  core.GenesisAlloc{accounts[0].addr: {Balance: big.NewInt(params.Ether)}},
+	}
+	signer := types.HomesteadSigner{}
+	backend := &prunedIndexBackend{
+		testBackend: newTestBackend(t, 8, genesis, func(i int, b *core.BlockGen) {
+			tx, _ := types.SignTx(types.NewTransaction(uint64(i), common.Address{0xde, 0xad}, big.NewInt(1), params.TxGas, b.BaseFee(), nil), signer, accounts[0].key)
+			b.AddTx(tx)
+		}),
+		pruned: 4,
+	}
+	defer backend.teardown()
+
+	// Pretend the chain head was indexed, leaving the rest to backfill
+	idx := NewTraceIndexer(backend, rawdb.NewMemoryDatabase(), 100)
+	WriteTraceIndexTail(idx.db, 8)
+	WriteTraceIndexHead(idx.db, backend.chain.CurrentBlock().Hash())
+
+	for i := 0; i < 2; i++ {
+		more, err := idx.backfill()
+		if more || err != nil {
+			t.Fatalf("backfill %d: unexpected result: more %v, err %v", i, more, err)
+		}
+	}
+	if backend.misses != 1 {
+		t.Errorf("unavailable state requests mismatch: have %d, want %d", backend.misses, 1)
+	}
+	if tail := ReadTraceIndexTail(idx.db); tail == nil || *tail != 8 {
+		t.Errorf("index tail mismatch: have %v, want %d", tail, 8)
+	}
+}
+
+// Tests that transactions failing to trace are left out of the index without
+// stopping it from advancing.
+func TestTraceIndexerUntraceable(t *testing.T) {
+	t.Parallel()
+
+	accounts := newAccounts(1)
+	genesis := &core.Genesis{
+		Config: params.TestChainConfig,
+		Alloc:  core.GenesisAlloc{accounts[0].addr: {Balance: big.NewInt(params.Ether)}},
+	}
+	signer := types.HomesteadSigner{}
+	backend := &prunedIndexBackend{
+		testBackend: newTestBackend(t, 4, genesis, func(i int, b *core.BlockGen) {
+			tx, _ := types.SignTx(types.NewTransaction(uint64(i), common.Address{0xde, 0xad}, big.NewInt(1), params.TxGas, b.BaseFee(), nil), signer, accounts[0].key)
+			b.AddTx(tx)
+		}),
+	}
+	defer backend.teardown()
+
+	idx := NewTraceIndexer(backend, rawdb.NewMemoryDatabase(), 100)
+	if err := idx.follow(); err != nil {
+		t.Fatalf("failed to index chain: %v", err)
+	}
+	head := backend.chain.CurrentBlock()
+	if have := ReadTraceIndexHead(idx.db); have != head.Hash() {
+		t.Errorf("index head mismatch: have %x, want %x", have, head.Hash())
+	}
+	block := backend.chain.GetBlockByHash(head.Hash())
+	if HasTxTrace(idx.db, block.Transactions()[0].Hash()) {
+		t.Errorf("untraceable transaction indexed")
+	}
+}
+
+// Tests that the traces of the blocks falling out of the configured history are
+// pruned from the index.
+func TestTraceIndexerPrune(t *testing.T) {
+	t.Parallel()
+
+	accounts := newAccounts(1)
+	genesis := &core.Genesis{
+		Config: params.TestChainConfig,
+		Alloc:  core.GenesisAlloc{accounts[0].addr: {Balance: big.NewInt(params.Ether)}},
+	}
+	signer := types.HomesteadSigner{}
+	backend := &prunedIndexBackend{
+		testBackend: newTestBackend(t, 8, genesis, func(i int, b *core.BlockGen) {
+			tx, _ := types.SignTx(types.NewTransaction(uint64(i), common.Address{0xde, 0xad}, big.NewInt(1), params.TxGas, b.BaseFee(), nil), signer, accounts[0].key)
+			b.AddTx(tx)
+		}),
+	}
+	defer backend.teardown()
+
+	// Pretend the whole chain was indexed, then shrink the history to 3 blocks
+	idx := NewTraceIndexer(backend, rawdb.NewMemoryDatabase(), 3)
+	for number := uint64(1); number <= 8; number++ {
+		block := backend.chain.GetBlockByNumber(number)
+		WriteTxTrace(idx.db, block.Transactions()[0].Hash(), block.Hash(), []byte("[]"))
+	}
+	WriteTraceIndexTail(idx.db, 1)
+	WriteTraceIndexHead(idx.db, backend.chain.CurrentBlock().Hash())
+
+	if err := idx.prune(); err != nil {
+		t.Fatalf("failed to prune index: %v", err)
+	}
+	if tail := ReadTraceIndexTail(idx.db); tail == nil || *tail != 6 {
+		t.Errorf("index tail mismatch: have %v, want %d", tail, 6)
+	}
+	for number := uint64(1); number <= 8; number++ {
+		hash := backend.chain.GetBlockByNumber(number).Transactions()[0].Hash()
+		if have, want := HasTxTrace(idx.db, hash), number >= 6; have != want {
+			t.Errorf("block %d: trace presence mismatch: have %v, want %v", number, have, want)
+		}
+	}
+}
@@ -0,0 +1,138 @@
+// Copyright 2023 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package tracetest
+
+import (
+	"encoding/json"
+	"math/big"
+	"strconv"
+	"strings"
+	"testing"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core"
+	"github.com/ethereum/go-ethereum/core/rawdb"
+	"github.com/ethereum/go-ethereum/core/vm"
+	"github.com/ethereum/go-ethereum/eth/tracers"
+	"github.com/ethereum/go-ethereum/params"
+	"github.com/ethereum/go-ethereum/tests"
+)
+
+// Tests that the gas profiler attributes the gas spent by a transaction to the
+// calls, code ranges and opcodes spending it.
+func TestGasProfiler(t *testing.T) {
+	var (
+		origin   = common.HexToAddress("0x00000000000000000000000000000000feed")
+		caller   = common.HexToAddress("0x00000000000000000000000000000000000c0de1")
+		callee   = common.HexToAddress("0x00000000000000000000000000000000000c0de2")
+		failing  = common.HexToAddress("0x00000000000000000000000000000000000c0de3")
+		selector = []byte{0x11, 0x22, 0x33, 0x44}
+	)
+	// The caller jumps over an invalid opcode, then calls the callee with a
+	// selector and the failing contract without any, spending all gas sent
+	callerCode := []byte{
+		byte(vm.PUSH1), 0x04, byte(vm.JUMP), byte(vm.INVALID),
+		byte(vm.JUMPDEST),
+		byte(vm.PUSH4), selector[0], selector[1], selector[2], selector[3],
+		byte(vm.PUSH1), 0xe0, byte(vm.SHL), byte(vm.PUSH1), 0x00, byte(vm.MSTORE),
+		byte(vm.PUSH1), 0x00, byte(vm.PUSH1), 0x00, byte(vm.PUSH1), 0x04, byte(vm.PUSH1), 0x00, byte(vm.PUSH1), 0x00,
+		byte(vm.PUSH20)}
+	callerCode = append(callerCode, callee.Bytes()...)
+	callerCode = append(callerCode, byte(vm.GAS), byte(vm.CALL), byte(vm.POP),
+		byte(vm.PUSH1), 0x00, byte(vm.PUSH1), 0x00, byte(vm.PUSH1), 0x00, byte(vm.PUSH1), 0x00, byte(vm.PUSH1), 0x00,
+		byte(vm.PUSH20))
+	callerCode = append(callerCode, failing.Bytes()...)
+	callerCode = append(callerCode, byte(vm.PUSH2), 0x27, 0x10, byte(vm.CALL), byte(vm.STOP))
+
+	genesis := core.GenesisAlloc{
+		origin:  {Balance: big.NewInt(params.Ether)},
+		caller:  {Code: callerCode, Balance: new(big.Int)},
+		callee:  {Code: []byte{byte(vm.PUSH1), 0x01, byte(vm.PUSH1), 0x00, byte(vm.SSTORE), byte(vm.STOP)}, Balance: new(big.Int)},
+		failing: {Code: []byte{byte(vm.INVALID)}, Balance: new(big.Int)},
+	}
+	_, statedb := tests.MakePreState(rawdb.NewMemoryDatabase(), genesis, false)
+
+	tracer, err := tracers.DefaultDirectory.New("gasProfiler", nil, nil)
+	if err != nil {
+		t.Fatalf("failed to create gas profiler: %v", err)
+	}
+	var (
+		context = vm.BlockContext{
+			CanTransfer: core.CanTransfer,
+			Transfer:    core.Transfer,
+			BlockNumber: new(big.Int),
+			Difficulty:  new(big.Int),
+			GasLimit:    uint64(6000000),
+			BaseFee:     new(big.Int),
+		}
+		evm = vm.NewEVM(context, vm.TxContext{Origin: origin, GasPrice: new(big.Int)}, statedb, params.AllEthashProtocolChanges, vm.Config{Tracer: tracer, NoBaseFee: true})
+		msg = &core.Message{
+			To:        &caller,
+			From:      origin,
+			Value:     new(big.Int),
+			GasLimit:  100000,
+			GasPrice:  new(big.Int),
+			GasFeeCap: new(big.Int),
+			GasTipCap: new(big.Int),
+		}
+	)
+	res, err := core.NewStateTransition(evm, msg, new(core.GasPool).AddGas(msg.GasLimit)).TransitionDb()
+	if err != nil {
+		t.Fatalf("failed to execute transaction: %v", err)
+	}
+	blob, err := tracer.GetResult()
+	if err != nil {
+		t.Fatalf("failed to retrieve trace result: %v", err)
+	}
+	var folded string
+	if err := json.Unmarshal(blob, &folded); err != nil {
+		t.Fatalf("failed to decode trace result: %v", err)
+	}
+	// The profile must account for all the gas used, with no refunds given
+	var (
+		total uint64
+		lines = make(map[string]uint64)
+	)
+	for _, line := range strings.Split(folded, "\n") {
+		idx := strings.LastIndexByte(line, ' ')
+		gas, err := strconv.ParseUint(line[idx+1:], 10, 64)
+		if err != nil {
+			t.Fatalf("invalid profile line %q: %v", line, err)
+		}
+		lines[line[:idx]] = gas
+		total += gas
+	}
+	if total != res.UsedGas {
+		t.Errorf("profiled gas mismatch: have %d, want %d", total, res.UsedGas)
+	}
+	var (
+		root   = "0x00000000000000000000000000000000000c0de1:fallback"
+		nested = root + ";0x00000000000000000000000000000000000c0de2:0x11223344"
+	)
+	for stack, want := range map[string]uint64{
+		root + ";intrinsic":            params.TxGas,
+		root + ";pc:0x0-0x2;JUMP":      8,
+		root + ";pc:0x4-0x55;JUMPDEST": 1,
+		root + ";pc:0x4-0x55;CALL":     2 * params.ColdAccountAccessCostEIP2929, // Gas forwarded is charged to the callees
+		nested + ";pc:0x0-0x5;SSTORE":  params.SstoreSetGasEIP2200 + params.ColdSloadCostEIP2929,
+		root + ";0x00000000000000000000000000000000000c0de3:fallback;pc:0x0-0x0;INVALID": 10000, // All gas forwarded is spent on failure
+	} {
+		if have := lines[stack]; have != want {
+			t.Errorf("gas mismatch for %s: have %d, want %d", stack, have, want)
+		}
+	}
+}
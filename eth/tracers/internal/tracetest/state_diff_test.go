@@ -0,0 +1,182 @@
+// Copyright 2023 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package tracetest
+
+import (
+	"encoding/json"
+	"math/big"
+	"reflect"
+	"testing"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/common/hexutil"
+	"github.com/ethereum/go-ethereum/core"
+	"github.com/ethereum/go-ethereum/core/rawdb"
+	"github.com/ethereum/go-ethereum/core/vm"
+	"github.com/ethereum/go-ethereum/crypto"
+	"github.com/ethereum/go-ethereum/eth/tracers"
+	"github.com/ethereum/go-ethereum/params"
+	"github.com/ethereum/go-ethereum/tests"
+)
+
+// Tests that the state diff tracer marks the accounts created, destroyed and
+// modified, telling storage slots rewritten with their original value apart
+// from untouched ones.
+func TestStateDiffTracer(t *testing.T) {
+	var (
+		origin      = common.HexToAddress("0x00000000000000000000000000000000feed")
+		coinbase    = common.HexToAddress("0x000000000000000000000000000000000000c0bb")
+		contract    = common.HexToAddress("0x000000000000000000000000000000000000c0de")
+		reverter    = common.HexToAddress("0x000000000000000000000000000000000000dead")
+		destructor  = common.HexToAddress("0x000000000000000000000000000000000000d1e5")
+		beneficiary = common.HexToAddress("0x000000000000000000000000000000000000be7e")
+		child       = crypto.CreateAddress(contract, 1)
+	)
+	// The contract rewrites slot 0 with its value, changes slot 1, calls the
+	// reverter writing its own storage, creates a child writing its storage and
+	// calls the destructor, writing its storage and self destructing
+	contractCode := common.FromHex("6001600055" + "602a600155" +
+		"60006000600060006000" + "73" + reverter.Hex()[2:] + "5af150" +
+		"65600560005500600052" + "6006601a6000f050" +
+		"60006000600060006000" + "73" + destructor.Hex()[2:] + "5af150" +
+		"00")
+	genesis := core.GenesisAlloc{
+		origin: {Balance: big.NewInt(params.Ether)},
+		contract: {
+			Code:    contractCode,
+			Nonce:   1,
+			Balance: new(big.Int),
+			Storage: map[common.Hash]common.Hash{
+				common.HexToHash("0x00"): common.HexToHash("0x01"),
+				common.HexToHash("0x03"): common.HexToHash("0x07"),
+			},
+		},
+		reverter: {Code: common.FromHex("600960005560006000fd"), Balance: new(big.Int)},
+		destructor: {
+			Code:    common.FromHex("6004600055" + "73" + beneficiary.Hex()[2:] + "ff"),
+			Balance: big.NewInt(100),
+			Storage: map[common.Hash]common.Hash{common.HexToHash("0x00"): common.HexToHash("0x03")},
+		},
+	}
+	_, statedb := tests.MakePreState(rawdb.NewMemoryDatabase(), genesis, false)
+
+	tracer, err := tracers.DefaultDirectory.New("stateDiffTracer", nil, nil)
+	if err != nil {
+		t.Fatalf("failed to create state diff tracer: %v", err)
+	}
+	var (
+		context = vm.BlockContext{
+			CanTransfer: core.CanTransfer,
+			Transfer:    core.Transfer,
+			Coinbase:    coinbase,
+			BlockNumber: new(big.Int),
+			Difficulty:  new(big.Int),
+			GasLimit:    uint64(6000000),
+			BaseFee:     new(big.Int),
+		}
+		evm = vm.NewEVM(context, vm.TxContext{Origin: origin, GasPrice: big.NewInt(1)}, statedb, params.AllEthashProtocolChanges, vm.Config{Tracer: tracer})
+		msg = &core.Message{
+			To:        &contract,
+			From:      origin,
+			Value:     big.NewInt(5),
+			GasLimit:  200000,
+			GasPrice:  big.NewInt(1),
+			GasFeeCap: big.NewInt(1),
+			GasTipCap: big.NewInt(1),
+		}
+	)
+	res, err := core.NewStateTransition(evm, msg, new(core.GasPool).AddGas(msg.GasLimit)).TransitionDb()
+	if err != nil {
+		t.Fatalf("failed to execute transaction: %v", err)
+	}
+	if res.Err != nil {
+		t.Fatalf("transaction failed: %v", res.Err)
+	}
+	blob, err := tracer.GetResult()
+	if err != nil {
+		t.Fatalf("failed to retrieve trace result: %v", err)
+	}
+	var have map[common.Address]interface{}
+	if err := json.Unmarshal(blob, &have); err != nil {
+		t.Fatalf("failed to decode trace result: %v", err)
+	}
+	fee := new(big.Int).SetUint64(res.UsedGas)
+	want := map[common.Address]string{
+		origin: `{
+			"balance": {"*": {"from": "0xde0b6b3a7640000", "to": "` + hexutil.EncodeBig(new(big.Int).Sub(big.NewInt(params.Ether), new(big.Int).Add(fee, big.NewInt(5)))) + `"}},
+			"nonce": {"*": {"from": "0x0", "to": "0x1"}},
+			"code": "=",
+			"storage": {}
+		}`,
+		coinbase: `{
+			"balance": {"+": "` + hexutil.EncodeBig(fee) + `"},
+			"nonce": {"+": "0x0"},
+			"code": {"+": "0x"},
+			"storage": {}
+		}`,
+		contract: `{
+			"balance": {"*": {"from": "0x0", "to": "0x5"}},
+			"nonce": {"*": {"from": "0x1", "to": "0x2"}},
+			"code": "=",
+			"storage": {
+				"0x0000000000000000000000000000000000000000000000000000000000000000": "=",
+				"0x0000000000000000000000000000000000000000000000000000000000000001": {"*": {
+					"from": "0x0000000000000000000000000000000000000000000000000000000000000000",
+					"to": "0x000000000000000000000000000000000000000000000000000000000000002a"
+				}}
+			}
+		}`,
+		child: `{
+			"balance": {"+": "0x0"},
+			"nonce": {"+": "0x1"},
+			"code": {"+": "0x"},
+			"storage": {
+				"0x0000000000000000000000000000000000000000000000000000000000000000": {"+": "0x0000000000000000000000000000000000000000000000000000000000000005"}
+			}
+		}`,
+		destructor: `{
+			"balance": {"-": "0x64"},
+			"nonce": {"-": "0x0"},
+			"code": {"-": "0x600460005573000000000000000000000000000000000000be7eff"},
+			"storage": {
+				"0x0000000000000000000000000000000000000000000000000000000000000000": {"-": "0x0000000000000000000000000000000000000000000000000000000000000003"}
+			}
+		}`,
+		beneficiary: `{
+			"balance": {"+": "0x64"},
+			"nonce": {"+": "0x0"},
+			"code": {"+": "0x"},
+			"storage": {}
+		}`,
+	}
+	for addr, blob := range want {
+		var diff interface{}
+		if err := json.Unmarshal([]byte(blob), &diff); err != nil {
+			t.Fatalf("failed to decode expected diff of %x: %v", addr, err)
+		}
+		if !reflect.DeepEqual(have[addr], diff) {
+			res, _ := json.Marshal(have[addr])
+			t.Errorf("diff mismatch for %x:\nhave %s\nwant %s", addr, res, blob)
+		}
+	}
+	for addr := range have {
+		if _, ok := want[addr]; !ok {
+			res, _ := json.Marshal(have[addr])
+			t.Errorf("unexpected diff for %x: %s", addr, res)
+		}
+	}
+}
@@ -0,0 +1,162 @@
+// Copyright 2023 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package tracetest
+
+import (
+	"bytes"
+	"encoding/json"
+	"math/big"
+	"testing"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/common/hexutil"
+	"github.com/ethereum/go-ethereum/core"
+	"github.com/ethereum/go-ethereum/core/rawdb"
+	"github.com/ethereum/go-ethereum/core/vm"
+	"github.com/ethereum/go-ethereum/eth/tracers"
+	"github.com/ethereum/go-ethereum/params"
+	"github.com/ethereum/go-ethereum/tests"
+)
+
+// vmTraceResult is the decoded output of the vm tracer.
+type vmTraceResult struct {
+	Code hexutil.Bytes `json:"code"`
+	Ops  []struct {
+		Cost uint64 `json:"cost"`
+		Ex   *struct {
+			Mem *struct {
+				Data hexutil.Bytes `json:"data"`
+				Off  uint64        `json:"off"`
+			} `json:"mem"`
+			Push  []*hexutil.Big `json:"push"`
+			Store *struct {
+				Key *hexutil.Big `json:"key"`
+				Val *hexutil.Big `json:"val"`
+			} `json:"store"`
+			Used uint64 `json:"used"`
+		} `json:"ex"`
+		Pc  uint64         `json:"pc"`
+		Sub *vmTraceResult `json:"sub"`
+	} `json:"ops"`
+}
+
+// Tests that the vm tracer reports the opcodes executed along with their effects
+// on the stack, memory and storage, nesting the traces of calls.
+func TestVMTracer(t *testing.T) {
+	var (
+		origin = common.HexToAddress("0x00000000000000000000000000000000feed")
+		caller = common.HexToAddress("0x00000000000000000000000000000000000c0de1")
+		callee = common.HexToAddress("0x00000000000000000000000000000000000c0de2")
+	)
+	// The caller writes memory and storage, then calls the callee writing a
+	// single byte of memory
+	callerCode := []byte{
+		byte(vm.PUSH1), 0x2a, byte(vm.PUSH1), 0x00, byte(vm.MSTORE),
+		byte(vm.PUSH1), 0x01, byte(vm.PUSH1), 0x00, byte(vm.SSTORE),
+		byte(vm.PUSH1), 0x00, byte(vm.PUSH1), 0x00, byte(vm.PUSH1), 0x00, byte(vm.PUSH1), 0x00, byte(vm.PUSH1), 0x00,
+		byte(vm.PUSH20)}
+	callerCode = append(callerCode, callee.Bytes()...)
+	callerCode = append(callerCode, byte(vm.GAS), byte(vm.CALL), byte(vm.STOP))
+	calleeCode := []byte{byte(vm.PUSH1), 0x07, byte(vm.PUSH1), 0x00, byte(vm.MSTORE8), byte(vm.STOP)}
+
+	genesis := core.GenesisAlloc{
+		origin: {Balance: big.NewInt(params.Ether)},
+		caller: {Code: callerCode, Balance: new(big.Int)},
+		callee: {Code: calleeCode, Balance: new(big.Int)},
+	}
+	_, statedb := tests.MakePreState(rawdb.NewMemoryDatabase(), genesis, false)
+
+	tracer, err := tracers.DefaultDirectory.New("vmTracer", nil, nil)
+	if err != nil {
+		t.Fatalf("failed to create vm tracer: %v", err)
+	}
+	var (
+		context = vm.BlockContext{
+			CanTransfer: core.CanTransfer,
+			Transfer:    core.Transfer,
+			BlockNumber: new(big.Int),
+			Difficulty:  new(big.Int),
+			GasLimit:    uint64(6000000),
+			BaseFee:     new(big.Int),
+		}
+		evm = vm.NewEVM(context, vm.TxContext{Origin: origin, GasPrice: new(big.Int)}, statedb, params.AllEthashProtocolChanges, vm.Config{Tracer: tracer, NoBaseFee: true})
+		msg = &core.Message{
+			To:        &caller,
+			From:      origin,
+			Value:     new(big.Int),
+			GasLimit:  100000,
+			GasPrice:  new(big.Int),
+			GasFeeCap: new(big.Int),
+			GasTipCap: new(big.Int),
+		}
+	)
+	if _, err := core.NewStateTransition(evm, msg, new(core.GasPool).AddGas(msg.GasLimit)).TransitionDb(); err != nil {
+		t.Fatalf("failed to execute transaction: %v", err)
+	}
+	blob, err := tracer.GetResult()
+	if err != nil {
+		t.Fatalf("failed to retrieve trace result: %v", err)
+	}
+	var trace vmTraceResult
+	if err := json.Unmarshal(blob, &trace); err != nil {
+		t.Fatalf("failed to decode trace result: %v", err)
+	}
+	if !bytes.Equal(trace.Code, callerCode) {
+		t.Errorf("caller code mismatch: have %x, want %x", trace.Code, callerCode)
+	}
+	if len(trace.Ops) != 15 {
+		t.Fatalf("caller opcode count mismatch: have %d, want %d", len(trace.Ops), 15)
+	}
+	// Every executed opcode reports the gas left after it, lowered by its cost
+	for i, op := range trace.Ops {
+		if op.Ex == nil {
+			t.Fatalf("opcode %d: missing effects", i)
+		}
+		if i > 0 && op.Sub == nil && op.Ex.Used != trace.Ops[i-1].Ex.Used-op.Cost {
+			t.Errorf("opcode %d: gas left mismatch: have %d, want %d", i, op.Ex.Used, trace.Ops[i-1].Ex.Used-op.Cost)
+		}
+	}
+	if push := trace.Ops[0].Ex.Push; len(push) != 1 || push[0].ToInt().Uint64() != 0x2a {
+		t.Errorf("push mismatch: have %v, want [0x2a]", push)
+	}
+	if mem := trace.Ops[2].Ex.Mem; mem == nil || mem.Off != 0 || !bytes.Equal(mem.Data, common.LeftPadBytes([]byte{0x2a}, 32)) {
+		t.Errorf("memory write mismatch: have %+v", mem)
+	}
+	if store := trace.Ops[5].Ex.Store; store == nil || store.Key.ToInt().Sign() != 0 || store.Val.ToInt().Uint64() != 1 {
+		t.Errorf("storage write mismatch: have %+v", store)
+	}
+	// The call pushes its success and nests the trace of the callee
+	call := trace.Ops[13]
+	if call.Pc != 42 || len(call.Ex.Push) != 1 || call.Ex.Push[0].ToInt().Uint64() != 1 {
+		t.Errorf("call mismatch: pc %d, push %v", call.Pc, call.Ex.Push)
+	}
+	if call.Sub == nil {
+		t.Fatalf("missing callee trace")
+	}
+	if !bytes.Equal(call.Sub.Code, calleeCode) || len(call.Sub.Ops) != 4 {
+		t.Fatalf("callee trace mismatch: code %x, %d opcodes", call.Sub.Code, len(call.Sub.Ops))
+	}
+	if mem := call.Sub.Ops[2].Ex.Mem; mem == nil || mem.Off != 0 || !bytes.Equal(mem.Data, []byte{0x07}) {
+		t.Errorf("callee memory write mismatch: have %+v", mem)
+	}
+	if stop := call.Sub.Ops[3]; stop.Ex == nil || stop.Ex.Used != call.Sub.Ops[2].Ex.Used {
+		t.Errorf("callee stop mismatch: have %+v", stop.Ex)
+	}
+	if stop := trace.Ops[14]; stop.Ex == nil || stop.Ex.Used != call.Ex.Used {
+		t.Errorf("caller stop mismatch: have %+v", stop.Ex)
+	}
+}
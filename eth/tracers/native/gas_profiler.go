@@ -0,0 +1,245 @@
+// Copyright 2023 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package native
+
+import (
+	"encoding/json"
+	"fmt"
+	"math/big"
+	"sort"
+	"strings"
+	"sync/atomic"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core/vm"
+	"github.com/ethereum/go-ethereum/eth/tracers"
+)
+
+func init() {
+	tracers.DefaultDirectory.Register("gasProfiler", newGasProfiler, false)
+}
+
+// gasProfiler aggregates the gas spent by a transaction along the call stack,
+// attributing it to the called contract and function, the range of program
+// counters executed without jumping, and finally the opcode. The result is a
+// folded stack profile, one line per distinct stack with the gas spent there,
+// which renders directly as a flamegraph, e.g. with flamegraph.pl or speedscope.
+//
+// Example:
+//
+//	> debug.traceTransaction( "0x214e597e35da083692f5386141e69f47e973b2c56e7a8073b1ea08fd7571e9de", {tracer: "gasProfiler"})
+//	"0xe4aa…:0xa9059cbb;intrinsic 21068\n0xe4aa…:0xa9059cbb;pc:0x0-0xc;PUSH1 6\n…"
+//
+// The gas of the nested calls is attributed to the callees, not the calling
+// opcode, and gas consumed by failing operations to the opcode failing. The
+// figures don't account for the refunds given at the end of the transaction.
+type gasProfiler struct {
+	noopTracer
+	gasLimit  uint64                     // Gas limit of the transaction, zero if unknown
+	frames    []*gasProfileFrame         // Stack of the currently executing calls
+	samples   map[gasProfileKey]uint64   // Gas spent per stack and opcode
+	blocks    map[gasProfileBlock]uint64 // Highest program counter reached per straight-line code range
+	interrupt atomic.Bool                // Atomic flag to signal execution interruption
+	reason    error                      // Textual reason for the interruption
+}
+
+// gasProfileFrame is the profiling state of a call in progress.
+type gasProfileFrame struct {
+	stack string         // Folded stack of the call, including itself
+	code  common.Address // Address of the executing code
+	gas   uint64         // Gas available to the call
+
+	block  uint64 // Program counter starting the current straight-line code range
+	jumped bool   // Whether the last opcode was a jump, starting a new range
+
+	pending    *gasProfileKey // Last opcode executed, whose cost is not yet known
+	pendingGas uint64         // Gas available before the last opcode
+	childGas   uint64         // Gas used by the calls made by the last opcode
+}
+
+// gasProfileKey identifies an opcode executed at some point of the call stack,
+// or just the point itself for gas not spent by opcodes.
+type gasProfileKey struct {
+	stack  string
+	opcode bool // Whether the gas was spent by the opcode below
+	block  gasProfileBlock
+	op     vm.OpCode
+}
+
+// gasProfileBlock identifies a range of code executed without jumping.
+type gasProfileBlock struct {
+	code  common.Address
+	start uint64
+}
+
+// newGasProfiler returns a native go tracer which profiles the gas usage of a
+// transaction, and implements vm.EVMLogger.
+func newGasProfiler(ctx *tracers.Context, _ json.RawMessage) (tracers.Tracer, error) {
+	return &gasProfiler{
+		samples: make(map[gasProfileKey]uint64),
+		blocks:  make(map[gasProfileBlock]uint64),
+	}, nil
+}
+
+// CaptureTxStart implements the EVMLogger interface to record the gas limit of
+// the transaction, used to determine its intrinsic gas.
+func (t *gasProfiler) CaptureTxStart(gasLimit uint64) {
+	t.gasLimit = gasLimit
+}
+
+// CaptureStart implements the EVMLogger interface to initialize the tracing operation.
+func (t *gasProfiler) CaptureStart(env *vm.EVM, from common.Address, to common.Address, create bool, input []byte, gas uint64, value *big.Int) {
+	t.enter(to, create, input, gas)
+
+	// Gas not available for the execution was spent before it
+	if t.gasLimit > gas {
+		t.samples[gasProfileKey{stack: t.frames[0].stack + ";intrinsic"}] += t.gasLimit - gas
+	}
+}
+
+// CaptureEnd is called after the call finishes to finalize the tracing.
+func (t *gasProfiler) CaptureEnd(output []byte, gasUsed uint64, err error) {
+	t.exit(gasUsed)
+}
+
+// CaptureState implements the EVMLogger interface to trace a single step of VM execution.
+func (t *gasProfiler) CaptureState(pc uint64, op vm.OpCode, gas, cost uint64, scope *vm.ScopeContext, rData []byte, depth int, err error) {
+	if t.interrupt.Load() || len(t.frames) == 0 {
+		return
+	}
+	frame := t.frames[len(t.frames)-1]
+
+	// The cost of the previous opcode is known now, charge it
+	t.settle(frame, gas)
+
+	// Start a new code range on every jump, landing at a JUMPDEST
+	if op == vm.JUMPDEST || frame.jumped {
+		frame.block = pc
+	}
+	frame.jumped = op == vm.JUMP || op == vm.JUMPI
+
+	block := gasProfileBlock{code: frame.code, start: frame.block}
+	if end, ok := t.blocks[block]; !ok || pc > end {
+		t.blocks[block] = pc
+	}
+	frame.pending = &gasProfileKey{stack: frame.stack, opcode: true, block: block, op: op}
+	frame.pendingGas = gas
+}
+
+// CaptureEnter is called when EVM enters a new scope (via call, create or selfdestruct).
+func (t *gasProfiler) CaptureEnter(typ vm.OpCode, from common.Address, to common.Address, input []byte, gas uint64, value *big.Int) {
+	if t.interrupt.Load() {
+		return
+	}
+	t.enter(to, typ == vm.CREATE || typ == vm.CREATE2, input, gas)
+}
+
+// CaptureExit is called when EVM exits a scope, even if the scope didn't
+// execute any code.
+func (t *gasProfiler) CaptureExit(output []byte, gasUsed uint64, err error) {
+	if t.interrupt.Load() {
+		return
+	}
+	t.exit(gasUsed)
+
+	// Deduct the gas used by the call from the opcode making it
+	if len(t.frames) > 0 {
+		t.frames[len(t.frames)-1].childGas += gasUsed
+	}
+}
+
+// enter pushes a new call onto the profiled stack.
+func (t *gasProfiler) enter(to common.Address, create bool, input []byte, gas uint64) {
+	// Label the call by the code executed and the function called
+	var fn string
+	switch {
+	case create:
+		fn = "constructor"
+	case len(input) < 4:
+		fn = "fallback"
+	default:
+		fn = bytesToHex(input[:4])
+	}
+	label := bytesToHex(to[:]) + ":" + fn
+	if len(t.frames) > 0 {
+		label = t.frames[len(t.frames)-1].stack + ";" + label
+	}
+	t.frames = append(t.frames, &gasProfileFrame{stack: label, code: to, gas: gas})
+}
+
+// exit pops the current call off the profiled stack, charging the gas it used
+// but no opcode accounted for yet.
+func (t *gasProfiler) exit(gasUsed uint64) {
+	if len(t.frames) == 0 {
+		return
+	}
+	frame := t.frames[len(t.frames)-1]
+	t.frames = t.frames[:len(t.frames)-1]
+
+	// Calls not executing any code are charged as a whole, otherwise the last
+	// opcode gets all the gas not returned, including that of code deposits
+	// and failures
+	if frame.pending == nil {
+		if gasUsed > 0 {
+			t.samples[gasProfileKey{stack: frame.stack}] += gasUsed
+		}
+		return
+	}
+	var left uint64
+	if frame.gas > gasUsed {
+		left = frame.gas - gasUsed
+	}
+	t.settle(frame, left)
+}
+
+// settle charges the last opcode executed in a frame, given the gas available
+// after its execution.
+func (t *gasProfiler) settle(frame *gasProfileFrame, gas uint64) {
+	if frame.pending == nil {
+		return
+	}
+	if spent := frame.pendingGas - gas; frame.pendingGas > gas && spent > frame.childGas {
+		t.samples[*frame.pending] += spent - frame.childGas
+	}
+	frame.pending, frame.childGas = nil, 0
+}
+
+// GetResult returns the folded stack profile as a json string, with the lines
+// sorted for a deterministic output.
+func (t *gasProfiler) GetResult() (json.RawMessage, error) {
+	lines := make([]string, 0, len(t.samples))
+	for key, gas := range t.samples {
+		line := key.stack
+		if key.opcode {
+			line += fmt.Sprintf(";pc:%#x-%#x;%v", key.block.start, t.blocks[key.block], key.op)
+		}
+		lines = append(lines, fmt.Sprintf("%s %d", line, gas))
+	}
+	sort.Strings(lines)
+
+	res, err := json.Marshal(strings.Join(lines, "\n"))
+	if err != nil {
+		return nil, err
+	}
+	return res, t.reason
+}
+
+// Stop terminates execution of the tracer at the first opportune moment.
+func (t *gasProfiler) Stop(err error) {
+	t.reason = err
+	t.interrupt.Store(true)
+}
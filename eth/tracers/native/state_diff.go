@@ -0,0 +1,324 @@
+// Copyright 2023 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package native
+
+import (
+	"bytes"
+	"encoding/json"
+	"math/big"
+	"sync/atomic"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/common/hexutil"
+	"github.com/ethereum/go-ethereum/core/vm"
+	"github.com/ethereum/go-ethereum/crypto"
+	"github.com/ethereum/go-ethereum/eth/tracers"
+)
+
+func init() {
+	tracers.DefaultDirectory.Register("stateDiffTracer", newStateDiffTracer, false)
+}
+
+// Markers of the state diff entries, as used by OpenEthereum.
+const (
+	diffSame    = "=" // Value left unchanged
+	diffBorn    = "+" // Value created along with its account
+	diffDied    = "-" // Value deleted along with its account
+	diffChanged = "*" // Value changed
+)
+
+// diffEntry is the change of a single value of an account.
+type diffEntry struct {
+	marker   string
+	from, to interface{}
+}
+
+// MarshalJSON encodes the entry in the OpenEthereum format, a bare marker for
+// unchanged values and an object keyed by the marker otherwise.
+func (d diffEntry) MarshalJSON() ([]byte, error) {
+	switch d.marker {
+	case diffBorn:
+		return json.Marshal(map[string]interface{}{diffBorn: d.to})
+	case diffDied:
+		return json.Marshal(map[string]interface{}{diffDied: d.from})
+	case diffChanged:
+		return json.Marshal(map[string]interface{}{diffChanged: map[string]interface{}{"from": d.from, "to": d.to}})
+	default:
+		return json.Marshal(diffSame)
+	}
+}
+
+// accountDiff is the change of an account caused by a transaction.
+type accountDiff struct {
+	Balance diffEntry                 `json:"balance"`
+	Nonce   diffEntry                 `json:"nonce"`
+	Code    diffEntry                 `json:"code"`
+	Storage map[common.Hash]diffEntry `json:"storage"`
+}
+
+// stateDiffTracer reports the state changes made by a transaction in the style
+// of OpenEthereum's stateDiff: every account modified has a marker for its
+// balance, nonce and code, and for each storage slot written:
+//
+//   - "=" for values left unchanged
+//   - {"+": value} for values of accounts created by the transaction
+//   - {"-": value} for values of accounts destroyed by the transaction
+//   - {"*": {"from": original, "to": value}} for changed values
+//
+// Storage is compared against its original value, the one before the transaction.
+// Slots written but ending up with their original value are reported as "=",
+// slots not written at all, or only in reverted calls, are omitted. Of destroyed
+// accounts, whose storage is wiped entirely, only the slots written are listed.
+//
+// Example:
+//
+//	> debug.traceTransaction( "0x214e597e35da083692f5386141e69f47e973b2c56e7a8073b1ea08fd7571e9de", {tracer: "stateDiffTracer"})
+//	{
+//	  "0x0000000000000000000000000000000000c0ffee": {
+//	    "balance": "=",
+//	    "nonce": "=",
+//	    "code": "=",
+//	    "storage": {
+//	      "0x0000000000000000000000000000000000000000000000000000000000000000": "=",
+//	      "0x0000000000000000000000000000000000000000000000000000000000000001": {"*": {"from": "0x00…00", "to": "0x00…2a"}}
+//	    }
+//	  },
+//	  ...
+//	}
+type stateDiffTracer struct {
+	noopTracer
+	env       *vm.EVM
+	gasLimit  uint64                                  // Amount of gas bought for the whole tx
+	pre       map[common.Address]*account             // Accounts touched along with their state before the transaction
+	written   map[common.Address]map[common.Hash]bool // Storage slots written by the transaction
+	frames    [][]diffSlot                            // Slots first written by each call in progress
+	diff      map[common.Address]*accountDiff         // Changes made by the transaction, assembled at its end
+	interrupt atomic.Bool                             // Atomic flag to signal execution interruption
+	reason    error                                   // Textual reason for the interruption
+}
+
+// diffSlot identifies a storage slot of an account.
+type diffSlot struct {
+	addr common.Address
+	key  common.Hash
+}
+
+// newStateDiffTracer returns a native go tracer which reports the state changes
+// of a transaction, and implements vm.EVMLogger.
+func newStateDiffTracer(ctx *tracers.Context, _ json.RawMessage) (tracers.Tracer, error) {
+	return &stateDiffTracer{
+		pre:     make(map[common.Address]*account),
+		written: make(map[common.Address]map[common.Hash]bool),
+		diff:    make(map[common.Address]*accountDiff),
+	}, nil
+}
+
+func (t *stateDiffTracer) CaptureTxStart(gasLimit uint64) {
+	t.gasLimit = gasLimit
+}
+
+// CaptureStart implements the EVMLogger interface to initialize the tracing operation.
+func (t *stateDiffTracer) CaptureStart(env *vm.EVM, from common.Address, to common.Address, create bool, input []byte, gas uint64, value *big.Int) {
+	t.env = env
+	t.frames = [][]diffSlot{nil}
+
+	t.lookupAccount(from)
+	t.lookupAccount(to)
+	t.lookupAccount(env.Context.Coinbase)
+
+	// The sender already paid for the gas and value, and incremented its nonce,
+	// the recipient already received the value
+	cost := new(big.Int).Mul(env.TxContext.GasPrice, new(big.Int).SetUint64(t.gasLimit))
+	t.pre[from].Balance = new(big.Int).Add(t.pre[from].Balance, cost.Add(cost, value))
+	t.pre[from].Nonce--
+	t.pre[to].Balance = new(big.Int).Sub(t.pre[to].Balance, value)
+
+	// The created contract already has its nonce set
+	if create {
+		t.pre[to].Nonce = 0
+	}
+}
+
+// CaptureEnd is called after the call finishes to finalize the tracing.
+func (t *stateDiffTracer) CaptureEnd(output []byte, gasUsed uint64, err error) {
+	t.exit(err)
+}
+
+// CaptureState implements the EVMLogger interface to trace a single step of VM execution.
+func (t *stateDiffTracer) CaptureState(pc uint64, op vm.OpCode, gas, cost uint64, scope *vm.ScopeContext, rData []byte, depth int, err error) {
+	if err != nil || t.interrupt.Load() {
+		return
+	}
+	var (
+		stackData = scope.Stack.Data()
+		stackLen  = len(stackData)
+		caller    = scope.Contract.Address()
+	)
+	// Look up the accounts about to be modified before they are
+	switch {
+	case stackLen >= 2 && op == vm.SSTORE:
+		t.writeStorage(caller, common.Hash(stackData[stackLen-1].Bytes32()))
+	case stackLen >= 1 && op == vm.SELFDESTRUCT:
+		t.lookupAccount(common.Address(stackData[stackLen-1].Bytes20()))
+	case stackLen >= 5 && (op == vm.CALL || op == vm.CALLCODE):
+		t.lookupAccount(common.Address(stackData[stackLen-2].Bytes20()))
+	case op == vm.CREATE:
+		t.lookupAccount(crypto.CreateAddress(caller, t.env.StateDB.GetNonce(caller)))
+	case stackLen >= 4 && op == vm.CREATE2:
+		offset, size := stackData[stackLen-2], stackData[stackLen-3]
+		init := scope.Memory.GetCopy(int64(offset.Uint64()), int64(size.Uint64()))
+		t.lookupAccount(crypto.CreateAddress2(caller, stackData[stackLen-4].Bytes32(), crypto.Keccak256(init)))
+	}
+}
+
+// CaptureEnter is called when EVM enters a new scope (via call, create or selfdestruct).
+func (t *stateDiffTracer) CaptureEnter(typ vm.OpCode, from common.Address, to common.Address, input []byte, gas uint64, value *big.Int) {
+	if t.interrupt.Load() {
+		return
+	}
+	t.frames = append(t.frames, nil)
+}
+
+// CaptureExit is called when EVM exits a scope, even if the scope didn't
+// execute any code.
+func (t *stateDiffTracer) CaptureExit(output []byte, gasUsed uint64, err error) {
+	if t.interrupt.Load() {
+		return
+	}
+	t.exit(err)
+}
+
+// exit pops the current call off the stack, forgetting about the slots it wrote
+// if it failed, since its writes are reverted.
+func (t *stateDiffTracer) exit(err error) {
+	if len(t.frames) == 0 {
+		return
+	}
+	slots := t.frames[len(t.frames)-1]
+	t.frames = t.frames[:len(t.frames)-1]
+
+	if err != nil {
+		for _, slot := range slots {
+			delete(t.written[slot.addr], slot.key)
+		}
+		return
+	}
+	if len(t.frames) > 0 {
+		t.frames[len(t.frames)-1] = append(t.frames[len(t.frames)-1], slots...)
+	}
+}
+
+func (t *stateDiffTracer) CaptureTxEnd(restGas uint64) {
+	var (
+		db      = t.env.StateDB
+		eip158  = t.env.ChainConfig().IsEIP158(t.env.Context.BlockNumber)
+		isEmpty = func(a *account) bool { return a.Nonce == 0 && a.Balance.Sign() == 0 && len(a.Code) == 0 }
+	)
+	for addr, pre := range t.pre {
+		// Empty accounts are deleted once touched since EIP-158, so they count
+		// as not existing either way
+		var (
+			born = isEmpty(pre)
+			died = db.HasSuicided(addr) || (eip158 && db.Empty(addr))
+		)
+		if !eip158 {
+			born = born && !db.Exist(addr)
+		}
+		if born && died {
+			continue
+		}
+		diff := &accountDiff{
+			Balance: diffValue(born, died, (*hexutil.Big)(pre.Balance), (*hexutil.Big)(db.GetBalance(addr)), pre.Balance.Cmp(db.GetBalance(addr)) == 0),
+			Nonce:   diffValue(born, died, hexutil.Uint64(pre.Nonce), hexutil.Uint64(db.GetNonce(addr)), pre.Nonce == db.GetNonce(addr)),
+			Code:    diffValue(born, died, hexutil.Bytes(pre.Code), hexutil.Bytes(db.GetCode(addr)), bytes.Equal(pre.Code, db.GetCode(addr))),
+			Storage: make(map[common.Hash]diffEntry),
+		}
+		modified := diff.Balance.marker != diffSame || diff.Nonce.marker != diffSame || diff.Code.marker != diffSame
+		for key := range t.written[addr] {
+			from, to := pre.Storage[key], db.GetState(addr, key)
+			// Empty slots don't exist in created or destroyed accounts
+			if (born && to == (common.Hash{})) || (died && from == (common.Hash{})) {
+				continue
+			}
+			diff.Storage[key] = diffValue(born, died, from, to, from == to)
+			modified = true
+		}
+		if modified {
+			t.diff[addr] = diff
+		}
+	}
+}
+
+// diffValue creates the diff entry of a value of an account.
+func diffValue(born, died bool, from, to interface{}, same bool) diffEntry {
+	switch {
+	case born:
+		return diffEntry{marker: diffBorn, to: to}
+	case died:
+		return diffEntry{marker: diffDied, from: from}
+	case same:
+		return diffEntry{marker: diffSame}
+	default:
+		return diffEntry{marker: diffChanged, from: from, to: to}
+	}
+}
+
+// GetResult returns the json-encoded state diff, and any error arising from the
+// encoding or forceful termination (via `Stop`).
+func (t *stateDiffTracer) GetResult() (json.RawMessage, error) {
+	res, err := json.Marshal(t.diff)
+	if err != nil {
+		return nil, err
+	}
+	return json.RawMessage(res), t.reason
+}
+
+// Stop terminates execution of the tracer at the first opportune moment.
+func (t *stateDiffTracer) Stop(err error) {
+	t.reason = err
+	t.interrupt.Store(true)
+}
+
+// lookupAccount fetches details of an account about to be modified, if it's not
+// tracked yet.
+func (t *stateDiffTracer) lookupAccount(addr common.Address) {
+	if _, ok := t.pre[addr]; ok {
+		return
+	}
+	t.pre[addr] = &account{
+		Balance: t.env.StateDB.GetBalance(addr),
+		Nonce:   t.env.StateDB.GetNonce(addr),
+		Code:    t.env.StateDB.GetCode(addr),
+		Storage: make(map[common.Hash]common.Hash),
+	}
+}
+
+// writeStorage tracks a storage slot about to be written, along with its value
+// before the transaction.
+func (t *stateDiffTracer) writeStorage(addr common.Address, key common.Hash) {
+	t.lookupAccount(addr)
+	if _, ok := t.pre[addr].Storage[key]; !ok {
+		t.pre[addr].Storage[key] = t.env.StateDB.GetCommittedState(addr, key)
+	}
+	if t.written[addr] == nil {
+		t.written[addr] = make(map[common.Hash]bool)
+	}
+	if !t.written[addr][key] {
+		t.written[addr][key] = true
+		t.frames[len(t.frames)-1] = append(t.frames[len(t.frames)-1], diffSlot{addr: addr, key: key})
+	}
+}
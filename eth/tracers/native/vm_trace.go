@@ -0,0 +1,255 @@
+// Copyright 2023 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package native
+
+import (
+	"encoding/json"
+	"math/big"
+	"sync/atomic"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/common/hexutil"
+	"github.com/ethereum/go-ethereum/core/vm"
+	"github.com/ethereum/go-ethereum/eth/tracers"
+	"github.com/holiman/uint256"
+)
+
+func init() {
+	tracers.DefaultDirectory.Register("vmTracer", newVMTracer, false)
+}
+
+// vmTrace is the execution trace of a call in the OpenEthereum vmTrace format.
+type vmTrace struct {
+	Code hexutil.Bytes `json:"code"`
+	Ops  []*vmTraceOp  `json:"ops"`
+}
+
+// vmTraceOp is a single executed opcode.
+type vmTraceOp struct {
+	Cost uint64     `json:"cost"`
+	Ex   *vmTraceEx `json:"ex"` // Effects of the opcode, nil if it failed
+	Pc   uint64     `json:"pc"`
+	Sub  *vmTrace   `json:"sub"` // Trace of the call made by the opcode
+}
+
+// vmTraceEx contains the effects of an executed opcode.
+type vmTraceEx struct {
+	Mem   *vmTraceMem     `json:"mem"`   // Memory written
+	Push  []*hexutil.Big  `json:"push"`  // Stack items pushed, deepest first
+	Store *vmTraceStorage `json:"store"` // Storage slot written
+	Used  uint64          `json:"used"`  // Gas remaining after execution
+}
+
+// vmTraceMem is a region of memory written by an opcode.
+type vmTraceMem struct {
+	Data hexutil.Bytes `json:"data"`
+	Off  uint64        `json:"off"`
+}
+
+// vmTraceStorage is a storage slot written by an opcode.
+type vmTraceStorage struct {
+	Key *hexutil.Big `json:"key"`
+	Val *hexutil.Big `json:"val"`
+}
+
+// vmTracer records the opcodes executed by a transaction along with their
+// effects on the stack, memory and storage, in the vmTrace format of OpenEthereum.
+// Nested calls are reported as the sub trace of the opcode making them.
+type vmTracer struct {
+	noopTracer
+	root      *vmTrace
+	frames    []*vmTraceFrame
+	interrupt atomic.Bool // Atomic flag to signal execution interruption
+	reason    error       // Textual reason for the interruption
+}
+
+// vmTraceFrame is the tracing state of a call in progress.
+type vmTraceFrame struct {
+	trace *vmTrace
+	gas   uint64 // Gas available to the call
+
+	pending *vmTraceOp      // Last opcode executed, whose effects are not yet known
+	push    int             // Number of stack items pushed by the last opcode
+	memOff  uint64          // Offset of the memory written by the last opcode
+	memSize uint64          // Size of the memory written by the last opcode
+	store   *vmTraceStorage // Storage written by the last opcode
+}
+
+// newVMTracer returns a native go tracer which records the opcode level trace
+// of a transaction, and implements vm.EVMLogger.
+func newVMTracer(ctx *tracers.Context, _ json.RawMessage) (tracers.Tracer, error) {
+	return &vmTracer{}, nil
+}
+
+// CaptureStart implements the EVMLogger interface to initialize the tracing operation.
+func (t *vmTracer) CaptureStart(env *vm.EVM, from common.Address, to common.Address, create bool, input []byte, gas uint64, value *big.Int) {
+	t.root = &vmTrace{Code: hexutil.Bytes{}, Ops: []*vmTraceOp{}}
+	t.frames = []*vmTraceFrame{{trace: t.root, gas: gas}}
+}
+
+// CaptureEnd is called after the call finishes to finalize the tracing.
+func (t *vmTracer) CaptureEnd(output []byte, gasUsed uint64, err error) {
+	t.exit(gasUsed, err)
+}
+
+// CaptureState implements the EVMLogger interface to trace a single step of VM execution.
+func (t *vmTracer) CaptureState(pc uint64, op vm.OpCode, gas, cost uint64, scope *vm.ScopeContext, rData []byte, depth int, err error) {
+	if t.interrupt.Load() || len(t.frames) == 0 {
+		return
+	}
+	frame := t.frames[len(t.frames)-1]
+	if len(frame.trace.Ops) == 0 {
+		frame.trace.Code = common.CopyBytes(scope.Contract.Code)
+	}
+	// The effects of the previous opcode are visible now, record them
+	t.settle(frame, gas, scope)
+
+	traced := &vmTraceOp{Cost: cost, Pc: pc}
+	frame.trace.Ops = append(frame.trace.Ops, traced)
+
+	// Opcodes failing before execution have no effects
+	if err != nil {
+		return
+	}
+	frame.pending, frame.push, frame.memSize, frame.store = traced, vmTracePushes(op), 0, nil
+
+	stack := scope.Stack.Data()
+	peek := func(n int) *uint256.Int {
+		if len(stack) <= n {
+			return new(uint256.Int)
+		}
+		return &stack[len(stack)-1-n]
+	}
+	switch op {
+	case vm.MSTORE:
+		frame.memOff, frame.memSize = peek(0).Uint64(), 32
+	case vm.MSTORE8:
+		frame.memOff, frame.memSize = peek(0).Uint64(), 1
+	case vm.CALLDATACOPY, vm.CODECOPY, vm.RETURNDATACOPY:
+		frame.memOff, frame.memSize = peek(0).Uint64(), peek(2).Uint64()
+	case vm.EXTCODECOPY:
+		frame.memOff, frame.memSize = peek(1).Uint64(), peek(3).Uint64()
+	case vm.CALL, vm.CALLCODE:
+		frame.memOff, frame.memSize = peek(5).Uint64(), peek(6).Uint64()
+	case vm.DELEGATECALL, vm.STATICCALL:
+		frame.memOff, frame.memSize = peek(4).Uint64(), peek(5).Uint64()
+	case vm.SSTORE:
+		frame.store = &vmTraceStorage{Key: (*hexutil.Big)(peek(0).ToBig()), Val: (*hexutil.Big)(peek(1).ToBig())}
+	}
+}
+
+// CaptureEnter is called when EVM enters a new scope (via call, create or selfdestruct).
+func (t *vmTracer) CaptureEnter(typ vm.OpCode, from common.Address, to common.Address, input []byte, gas uint64, value *big.Int) {
+	if t.interrupt.Load() || len(t.frames) == 0 {
+		return
+	}
+	sub := &vmTrace{Code: hexutil.Bytes{}, Ops: []*vmTraceOp{}}
+
+	// Self destructs don't execute any code, don't report them as calls
+	if parent := t.frames[len(t.frames)-1]; typ != vm.SELFDESTRUCT && parent.pending != nil {
+		parent.pending.Sub = sub
+	}
+	t.frames = append(t.frames, &vmTraceFrame{trace: sub, gas: gas})
+}
+
+// CaptureExit is called when EVM exits a scope, even if the scope didn't
+// execute any code.
+func (t *vmTracer) CaptureExit(output []byte, gasUsed uint64, err error) {
+	if t.interrupt.Load() {
+		return
+	}
+	t.exit(gasUsed, err)
+}
+
+// exit pops the current call off the stack, recording the effects of its last
+// opcode, which ended the call.
+func (t *vmTracer) exit(gasUsed uint64, err error) {
+	if len(t.frames) == 0 {
+		return
+	}
+	frame := t.frames[len(t.frames)-1]
+	t.frames = t.frames[:len(t.frames)-1]
+
+	// Calls end with opcodes not pushing to the stack nor writing memory, so
+	// only the gas left is of interest. Failing opcodes have no effects.
+	if frame.pending == nil || (err != nil && err != vm.ErrExecutionReverted) {
+		return
+	}
+	var left uint64
+	if frame.gas > gasUsed {
+		left = frame.gas - gasUsed
+	}
+	frame.pending.Ex = &vmTraceEx{Push: []*hexutil.Big{}, Used: left}
+	frame.pending = nil
+}
+
+// settle records the effects of the last opcode executed in a frame, given the
+// gas and scope after its execution.
+func (t *vmTracer) settle(frame *vmTraceFrame, gas uint64, scope *vm.ScopeContext) {
+	if frame.pending == nil {
+		return
+	}
+	ex := &vmTraceEx{Push: []*hexutil.Big{}, Store: frame.store, Used: gas}
+
+	stack := scope.Stack.Data()
+	for i := len(stack) - frame.push; i < len(stack); i++ {
+		if i >= 0 {
+			ex.Push = append(ex.Push, (*hexutil.Big)(stack[i].ToBig()))
+		}
+	}
+	if frame.memSize > 0 && frame.memOff+frame.memSize <= uint64(scope.Memory.Len()) {
+		ex.Mem = &vmTraceMem{Data: scope.Memory.GetCopy(int64(frame.memOff), int64(frame.memSize)), Off: frame.memOff}
+	}
+	frame.pending.Ex = ex
+	frame.pending = nil
+}
+
+// vmTracePushes returns the number of stack items reported as pushed by an
+// opcode. Duplications and swaps report all the items they touched.
+func vmTracePushes(op vm.OpCode) int {
+	switch {
+	case op >= vm.DUP1 && op <= vm.DUP16:
+		return int(op-vm.DUP1) + 2
+	case op >= vm.SWAP1 && op <= vm.SWAP16:
+		return int(op-vm.SWAP1) + 2
+	case op >= vm.LOG0 && op <= vm.LOG4:
+		return 0
+	}
+	switch op {
+	case vm.STOP, vm.POP, vm.MSTORE, vm.MSTORE8, vm.SSTORE, vm.TSTORE, vm.JUMP, vm.JUMPI, vm.JUMPDEST,
+		vm.CALLDATACOPY, vm.CODECOPY, vm.EXTCODECOPY, vm.RETURNDATACOPY,
+		vm.RETURN, vm.REVERT, vm.INVALID, vm.SELFDESTRUCT:
+		return 0
+	}
+	return 1
+}
+
+// GetResult returns the json-encoded opcode level trace, and any error arising
+// from the encoding or forceful termination (via `Stop`).
+func (t *vmTracer) GetResult() (json.RawMessage, error) {
+	res, err := json.Marshal(t.root)
+	if err != nil {
+		return nil, err
+	}
+	return res, t.reason
+}
+
+// Stop terminates execution of the tracer at the first opportune moment.
+func (t *vmTracer) Stop(err error) {
+	t.reason = err
+	t.interrupt.Store(true)
+}
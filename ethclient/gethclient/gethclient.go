@@ -194,6 +194,35 @@ func (ec *Client) GetNodeInfo(ctx context.Context) (*p2p.NodeInfo, error) {
 	return &result, err
 }
 
+// GetTransactionWitness retrieves the state witness of the given transaction,
+// holding the accounts, storage slots and codes it accessed along with their
+// Merkle proofs against the pre- and post-state roots.
+func (ec *Client) GetTransactionWitness(ctx context.Context, hash common.Hash) (*types.TxExtra, error) {
+	var result *types.TxExtra
+	err := ec.c.CallContext(ctx, &result, "debug_getTransactionWitness", hash)
+	if err == nil && result == nil {
+		return nil, ethereum.NotFound
+	}
+	return result, err
+}
+
+// GetBlockWitness retrieves the state witnesses of all the transactions in the
+// given block. The block number can be nil, in which case the witnesses of the
+// latest known block are returned.
+func (ec *Client) GetBlockWitness(ctx context.Context, number *big.Int) ([]*types.TxExtra, error) {
+	var result []*types.TxExtra
+	err := ec.c.CallContext(ctx, &result, "debug_getBlockWitness", toBlockNumArg(number))
+	return result, err
+}
+
+// GetBlockWitnessByHash retrieves the state witnesses of all the transactions
+// in the block with the given hash.
+func (ec *Client) GetBlockWitnessByHash(ctx context.Context, hash common.Hash) ([]*types.TxExtra, error) {
+	var result []*types.TxExtra
+	err := ec.c.CallContext(ctx, &result, "debug_getBlockWitness", rpc.BlockNumberOrHashWithHash(hash, false))
+	return result, err
+}
+
 // SubscribeFullPendingTransactions subscribes to new pending transactions.
 func (ec *Client) SubscribeFullPendingTransactions(ctx context.Context, ch chan<- *types.Transaction) (*rpc.ClientSubscription, error) {
 	return ec.c.EthSubscribe(ctx, ch, "newPendingTransactions", true)
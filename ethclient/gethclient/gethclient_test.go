@@ -123,6 +123,9 @@ func TestGethClient(t *testing.T) {
 		}, {
 			"TestSubscribePendingTxs",
 			func(t *testing.T) { testSubscribeFullPendingTransactions(t, client) },
+		}, {
+			"TestGetWitness",
+			func(t *testing.T) { testGetWitness(t, client) },
 		}, {
 			"TestCallContract",
 			func(t *testing.T) { testCallContract(t, client) },
@@ -235,6 +238,20 @@ func testGetProof(t *testing.T, client *rpc.Client) {
 	}
 }
 
+func testGetWitness(t *testing.T, client *rpc.Client) {
+	ec := New(client)
+	witnesses, err := ec.GetBlockWitness(context.Background(), big.NewInt(1))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(witnesses) != 0 {
+		t.Fatalf("unexpected witnesses for empty block: %v", witnesses)
+	}
+	if _, err := ec.GetTransactionWitness(context.Background(), common.Hash{0x01}); err == nil {
+		t.Fatal("witness returned for unknown transaction")
+	}
+}
+
 func testGCStats(t *testing.T, client *rpc.Client) {
 	ec := New(client)
 	_, err := ec.GCStats(context.Background())
@@ -24,6 +24,7 @@ const (
 	DevCategory        = "DEVELOPER CHAIN"
 	EthashCategory     = "ETHASH"
 	TxPoolCategory     = "TRANSACTION POOL"
+	BlobPoolCategory   = "TRANSACTION POOL (BLOB)"
 	PerfCategory       = "PERFORMANCE TUNING"
 	AccountCategory    = "ACCOUNT"
 	APICategory        = "API AND CONSOLE"
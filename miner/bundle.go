@@ -0,0 +1,167 @@
+// Copyright 2023 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package miner
+
+import (
+	"errors"
+	"fmt"
+	"math/big"
+	"sort"
+
+	"github.com/ethereum/go-ethereum/core"
+	"github.com/ethereum/go-ethereum/core/txpool"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/log"
+)
+
+var (
+	// errBundleReverted is returned if a transaction of a bundle reverts during
+	// execution.
+	errBundleReverted = errors.New("bundle transaction reverted")
+
+	// errBundleUnprotected is returned if a bundle contains replay protected
+	// transactions before EIP-155.
+	errBundleUnprotected = errors.New("bundle transaction replay protected before EIP-155")
+
+	// errBundleWorthless is returned if a bundle pays nothing to the coinbase.
+	errBundleWorthless = errors.New("bundle pays nothing to the coinbase")
+)
+
+// simulatedBundle is a transaction bundle along with the results of executing
+// it on top of the state the sealing block is built on.
+type simulatedBundle struct {
+	bundle  *txpool.Bundle
+	payment *big.Int // Total payment to the coinbase, fees and direct transfers alike
+	gasUsed uint64   // Total gas used by all the transactions of the bundle
+	price   *big.Int // Effective coinbase payment per unit of gas
+}
+
+// newSimulatedBundle assembles the simulation results of a bundle, or returns an
+// error if the bundle pays nothing to the coinbase.
+func newSimulatedBundle(bundle *txpool.Bundle, payment *big.Int, gasUsed uint64) (*simulatedBundle, error) {
+	if payment.Sign() <= 0 || gasUsed == 0 {
+		return nil, fmt.Errorf("%w: payment %v", errBundleWorthless, payment)
+	}
+	return &simulatedBundle{
+		bundle:  bundle,
+		payment: payment,
+		gasUsed: gasUsed,
+		price:   new(big.Int).Div(payment, new(big.Int).SetUint64(gasUsed)),
+	}, nil
+}
+
+// simulateBundles executes each bundle on a copy of the given environment,
+// discarding the failing ones, and returns the rest ordered by their effective
+// coinbase payment per gas.
+func (w *worker) simulateBundles(env *environment, bundles []*txpool.Bundle) []*simulatedBundle {
+	simulated := make([]*simulatedBundle, 0, len(bundles))
+	for _, bundle := range bundles {
+		work := env.copy()
+		_, payment, gasUsed, err := w.applyBundle(work, bundle)
+		work.discard()
+
+		if err != nil {
+			log.Trace("Discarding failing bundle", "hash", bundle.Hash(), "err", err)
+			continue
+		}
+		sim, err := newSimulatedBundle(bundle, payment, gasUsed)
+		if err != nil {
+			log.Trace("Discarding worthless bundle", "hash", bundle.Hash(), "payment", payment)
+			continue
+		}
+		simulated = append(simulated, sim)
+	}
+	sort.SliceStable(simulated, func(i, j int) bool {
+		return simulated[i].price.Cmp(simulated[j].price) > 0
+	})
+	return simulated
+}
+
+// rankBundle inserts a simulated bundle into a list of bundles ordered by their
+// effective coinbase payment per gas, after the ones paying the same.
+func rankBundle(bundles []*simulatedBundle, bundle *simulatedBundle) []*simulatedBundle {
+	i := sort.Search(len(bundles), func(i int) bool {
+		return bundles[i].price.Cmp(bundle.price) < 0
+	})
+	bundles = append(bundles, nil)
+	copy(bundles[i+1:], bundles[i:])
+	bundles[i] = bundle
+	return bundles
+}
+
+// applyBundle executes all the transactions of a bundle in order on top of the
+// given environment, returning their logs, the total payment they made to the
+// coinbase and the gas they used. Execution is aborted at the first failing or
+// reverting transaction, leaving the environment half-modified, so it must be
+// a copy to throw away on failure.
+func (w *worker) applyBundle(env *environment, bundle *txpool.Bundle) ([]*types.Log, *big.Int, uint64, error) {
+	if env.gasPool == nil {
+		env.gasPool = new(core.GasPool).AddGas(env.header.GasLimit)
+	}
+	var (
+		balance = new(big.Int).Set(env.state.GetBalance(env.coinbase))
+		gasUsed = env.header.GasUsed
+		logs    []*types.Log
+	)
+	for _, tx := range bundle.Txs {
+		if tx.Protected() && !w.chainConfig.IsEIP155(env.header.Number) {
+			return nil, nil, 0, fmt.Errorf("%w: %x", errBundleUnprotected, tx.Hash())
+		}
+		env.state.SetTxContext(tx.Hash(), env.tcount)
+
+		txLogs, err := w.commitTransaction(env, tx)
+		if err != nil {
+			return nil, nil, 0, fmt.Errorf("transaction %x: %w", tx.Hash(), err)
+		}
+		if env.receipts[len(env.receipts)-1].Status != types.ReceiptStatusSuccessful {
+			return nil, nil, 0, fmt.Errorf("%w: %x", errBundleReverted, tx.Hash())
+		}
+		env.tcount++
+		logs = append(logs, txLogs...)
+	}
+	payment := new(big.Int).Sub(env.state.GetBalance(env.coinbase), balance)
+	return logs, payment, env.header.GasUsed - gasUsed, nil
+}
+
+// commitBundle includes a bundle into the sealing block atomically: it's applied
+// on a copy of the environment, which only replaces the original if all of the
+// bundle's transactions succeeded.
+//
+// The transactions committed since the bundle was simulated may have changed
+// what it pays. If it pays less per gas than it was ranked by, it's not included
+// but returned re-priced, to be ranked again against the other candidates.
+func (w *worker) commitBundle(env *environment, bundle *simulatedBundle) ([]*types.Log, *simulatedBundle, error) {
+	work := env.copy()
+	logs, payment, gasUsed, err := w.applyBundle(work, bundle.bundle)
+	if err != nil {
+		work.discard()
+		return nil, nil, err
+	}
+	repriced, err := newSimulatedBundle(bundle.bundle, payment, gasUsed)
+	if err != nil {
+		work.discard()
+		return nil, nil, err
+	}
+	if repriced.price.Cmp(bundle.price) < 0 {
+		work.discard()
+		return nil, repriced, nil
+	}
+	// Copied states don't run prefetchers, terminate the replaced one
+	env.discard()
+	*env = *work
+	return logs, nil, nil
+}
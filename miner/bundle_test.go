@@ -0,0 +1,145 @@
+// Copyright 2023 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package miner
+
+import (
+	"math/big"
+	"testing"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/consensus/ethash"
+	"github.com/ethereum/go-ethereum/core/rawdb"
+	"github.com/ethereum/go-ethereum/core/txpool"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/crypto"
+	"github.com/ethereum/go-ethereum/event"
+	"github.com/ethereum/go-ethereum/params"
+)
+
+// Tests that bundles are included atomically and ranked against the plain pool
+// transactions by their payment to the coinbase.
+func TestBundleInclusion(t *testing.T) {
+	var (
+		signer      = types.LatestSigner(ethashChainConfig)
+		unfunded, _ = crypto.GenerateKey()
+
+		// The pooled transaction tips 1/8 gwei on top of the first block's base fee
+		pooled = types.MustSignNewTx(testBankKey, signer, &types.LegacyTx{Nonce: 0, To: &testUserAddress, Gas: params.TxGas, GasPrice: big.NewInt(params.InitialBaseFee)})
+
+		// Bundle transactions either outbidding the pooled one or not
+		rich0 = types.MustSignNewTx(testBankKey, signer, &types.LegacyTx{Nonce: 0, To: &testUserAddress, Value: big.NewInt(1), Gas: params.TxGas, GasPrice: big.NewInt(2 * params.InitialBaseFee)})
+		rich1 = types.MustSignNewTx(testBankKey, signer, &types.LegacyTx{Nonce: 1, To: &testUserAddress, Value: big.NewInt(1), Gas: params.TxGas, GasPrice: big.NewInt(2 * params.InitialBaseFee)})
+		poor0 = types.MustSignNewTx(testBankKey, signer, &types.LegacyTx{Nonce: 0, To: &testUserAddress, Value: big.NewInt(2), Gas: params.TxGas, GasPrice: big.NewInt(params.InitialBaseFee * 9 / 10)})
+
+		// Bundle transaction failing due to lack of funds
+		broke = types.MustSignNewTx(unfunded, signer, &types.LegacyTx{Nonce: 0, To: &testUserAddress, Gas: params.TxGas, GasPrice: big.NewInt(2 * params.InitialBaseFee)})
+	)
+	tests := []struct {
+		bundles []*txpool.Bundle
+		want    []common.Hash
+	}{
+		// No bundles, only the pooled transaction is included
+		{
+			want: []common.Hash{pooled.Hash()},
+		},
+		// A bundle paying more replaces the pooled transaction
+		{
+			bundles: []*txpool.Bundle{{Txs: types.Transactions{rich0, rich1}, BlockNumber: 1}},
+			want:    []common.Hash{rich0.Hash(), rich1.Hash()},
+		},
+		// A bundle paying less is included after the pooled transaction, failing
+		{
+			bundles: []*txpool.Bundle{{Txs: types.Transactions{poor0}, BlockNumber: 1}},
+			want:    []common.Hash{pooled.Hash()},
+		},
+		// A bundle with a failing transaction is not included at all
+		{
+			bundles: []*txpool.Bundle{{Txs: types.Transactions{rich0, broke}, BlockNumber: 1}},
+			want:    []common.Hash{pooled.Hash()},
+		},
+		// Of two conflicting bundles the best paying one is included
+		{
+			bundles: []*txpool.Bundle{
+				{Txs: types.Transactions{poor0}, BlockNumber: 1},
+				{Txs: types.Transactions{rich0}, BlockNumber: 1},
+			},
+			want: []common.Hash{rich0.Hash()},
+		},
+		// Bundles not valid at the block's timestamp are ignored
+		{
+			bundles: []*txpool.Bundle{{Txs: types.Transactions{rich0, rich1}, BlockNumber: 1, MinTimestamp: 1000}},
+			want:    []common.Hash{pooled.Hash()},
+		},
+	}
+	for i, tt := range tests {
+		backend := newTestWorkerBackend(t, ethashChainConfig, ethash.NewFaker(), rawdb.NewMemoryDatabase(), 0)
+		if err := backend.txPool.AddRemotesSync([]*types.Transaction{pooled})[0]; err != nil {
+			t.Fatalf("test %d: failed to add pooled transaction: %v", i, err)
+		}
+		for j, bundle := range tt.bundles {
+			if err := backend.txPool.AddBundle(bundle); err != nil {
+				t.Fatalf("test %d: failed to add bundle %d: %v", i, j, err)
+			}
+		}
+		w := newWorker(testConfig, ethashChainConfig, ethash.NewFaker(), backend, new(event.TypeMux), nil, false)
+
+		r := w.getSealingBlock(&generateParams{
+			timestamp: 100,
+			coinbase:  common.Address{0xc0},
+			forceTime: true,
+		})
+		w.close()
+		backend.txPool.Stop()
+
+		if r.err != nil {
+			t.Fatalf("test %d: failed to generate block: %v", i, r.err)
+		}
+		txs := r.block.Transactions()
+		if len(txs) != len(tt.want) {
+			t.Errorf("test %d: transaction count mismatch: have %d, want %d", i, len(txs), len(tt.want))
+			continue
+		}
+		for j, tx := range txs {
+			if tx.Hash() != tt.want[j] {
+				t.Errorf("test %d, tx %d: hash mismatch: have %x, want %x", i, j, tx.Hash(), tt.want[j])
+			}
+		}
+	}
+}
+
+// Tests that re-priced bundles are ranked again after the ones paying as much.
+func TestRankBundle(t *testing.T) {
+	bundle := func(price int64) *simulatedBundle {
+		return &simulatedBundle{price: big.NewInt(price)}
+	}
+	bundles := []*simulatedBundle{bundle(5), bundle(3), bundle(3), bundle(1)}
+
+	repriced := bundle(3)
+	bundles = rankBundle(bundles, repriced)
+	if len(bundles) != 5 || bundles[3] != repriced {
+		t.Fatalf("re-priced bundle misplaced: have %v", bundles)
+	}
+	for i := 1; i < len(bundles); i++ {
+		if bundles[i-1].price.Cmp(bundles[i].price) < 0 {
+			t.Fatalf("bundles not ordered by price at %d", i)
+		}
+	}
+	last := bundle(0)
+	if bundles = rankBundle(bundles, last); bundles[len(bundles)-1] != last {
+		t.Fatalf("cheapest bundle not ranked last")
+	}
+}
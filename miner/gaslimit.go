@@ -0,0 +1,85 @@
+// Copyright 2023 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package miner
+
+import (
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/params"
+)
+
+// gasLimitTarget returns the gas limit to strive for in the block built on top
+// of the given parent, which the actual limit approaches in steps bounded by
+// the protocol.
+//
+// Unless a utilisation window is configured, the target is always the ceiling.
+// Otherwise it's elastic: the ceiling while the recent blocks are fuller than
+// the utilisation target, the floor while they are emptier, and the limit of
+// the parent once the target is met. An unset floor defaults to the gas limit
+// of the genesis block.
+//
+// Post-London, the base fee already steers the utilisation towards 1/elasticity
+// of the limit (half of it on mainnet), so the utilisation target should exceed
+// that for the limit to only grow under sustained demand the base fee cannot
+// price out.
+func (w *worker) gasLimitTarget(parent *types.Header) uint64 {
+	w.mu.RLock()
+	floor, ceil := w.config.GasFloor, w.config.GasCeil
+	w.mu.RUnlock()
+
+	window, target := w.config.GasWindow, w.config.GasTarget
+	if window == 0 {
+		return ceil
+	}
+	if floor == 0 {
+		floor = w.chain.Genesis().GasLimit()
+	}
+	if floor < params.MinGasLimit {
+		floor = params.MinGasLimit
+	}
+	if floor > ceil {
+		floor = ceil
+	}
+	// Measure the gas utilisation of the window of blocks ending at the parent
+	var used, limit uint64
+	for header := parent; header != nil && window > 0; window-- {
+		used += header.GasUsed
+		limit += header.GasLimit
+
+		if header.Number.Sign() == 0 {
+			break
+		}
+		header = w.chain.GetHeader(header.ParentHash, header.Number.Uint64()-1)
+	}
+	if limit == 0 {
+		return ceil
+	}
+	utilisation := float64(used) / float64(limit)
+	switch {
+	case utilisation > target:
+		return ceil
+	case utilisation < target:
+		return floor
+	default:
+		if parent.GasLimit < floor {
+			return floor
+		}
+		if parent.GasLimit > ceil {
+			return ceil
+		}
+		return parent.GasLimit
+	}
+}
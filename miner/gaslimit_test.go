@@ -0,0 +1,80 @@
+// Copyright 2023 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package miner
+
+import (
+	"math/big"
+	"testing"
+
+	"github.com/ethereum/go-ethereum/consensus/ethash"
+	"github.com/ethereum/go-ethereum/core/rawdb"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/params"
+)
+
+// Tests that the gas limit target follows the utilisation of the recent blocks.
+func TestGasLimitTarget(t *testing.T) {
+	backend := newTestWorkerBackend(t, ethashChainConfig, ethash.NewFaker(), rawdb.NewMemoryDatabase(), 0)
+	defer backend.txPool.Stop()
+
+	genesis := backend.chain.Genesis().Header()
+	tests := []struct {
+		floor, ceil uint64
+		window      uint64
+		target      float64
+		used, limit uint64 // Gas used and limit of the parent block
+		want        uint64
+	}{
+		// No window always targets the ceiling
+		{floor: 10_000_000, ceil: 30_000_000, used: 0, limit: 20_000_000, want: 30_000_000},
+
+		// Busy, idle and balanced parents
+		{floor: 10_000_000, ceil: 30_000_000, window: 1, target: 0.5, used: 15_000_000, limit: 20_000_000, want: 30_000_000},
+		{floor: 10_000_000, ceil: 30_000_000, window: 1, target: 0.5, used: 5_000_000, limit: 20_000_000, want: 10_000_000},
+		{floor: 10_000_000, ceil: 30_000_000, window: 1, target: 0.5, used: 10_000_000, limit: 20_000_000, want: 20_000_000},
+
+		// Balanced parents outside of the bounds are clamped
+		{floor: 10_000_000, ceil: 30_000_000, window: 1, target: 0.5, used: 20_000_000, limit: 40_000_000, want: 30_000_000},
+		{floor: 10_000_000, ceil: 30_000_000, window: 1, target: 0.5, used: 2_500_000, limit: 5_000_000, want: 10_000_000},
+
+		// Unset floors default to the genesis gas limit, floors below the protocol
+		// minimum or above the ceiling are capped
+		{floor: 0, ceil: 30_000_000, window: 1, target: 0.5, used: 0, limit: 20_000_000, want: genesis.GasLimit},
+		{floor: 1, ceil: 30_000_000, window: 1, target: 0.5, used: 0, limit: 20_000_000, want: params.MinGasLimit},
+		{floor: 40_000_000, ceil: 30_000_000, window: 1, target: 0.5, used: 0, limit: 20_000_000, want: 30_000_000},
+
+		// The window spans the empty genesis block too, lowering the utilisation,
+		// and ends there even if longer than the chain
+		{floor: 10_000_000, ceil: 30_000_000, window: 2, target: 0.5, used: genesis.GasLimit * 2, limit: genesis.GasLimit * 4, want: 10_000_000},
+		{floor: 10_000_000, ceil: 30_000_000, window: 100, target: 0.5, used: genesis.GasLimit * 3, limit: genesis.GasLimit * 4, want: 30_000_000},
+	}
+	for i, tt := range tests {
+		w := &worker{
+			config: &Config{GasFloor: tt.floor, GasCeil: tt.ceil, GasWindow: tt.window, GasTarget: tt.target},
+			chain:  backend.chain,
+		}
+		parent := &types.Header{
+			ParentHash: genesis.Hash(),
+			Number:     big.NewInt(1),
+			GasUsed:    tt.used,
+			GasLimit:   tt.limit,
+		}
+		if have := w.gasLimitTarget(parent); have != tt.want {
+			t.Errorf("test %d: gas limit target mismatch: have %d, want %d", i, have, tt.want)
+		}
+	}
+}
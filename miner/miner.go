@@ -45,20 +45,25 @@ type Backend interface {
 
 // Config is the configuration parameters of mining.
 type Config struct {
-	Etherbase common.Address `toml:",omitempty"` // Public address for block mining rewards
-	ExtraData hexutil.Bytes  `toml:",omitempty"` // Block extra data set by the miner
-	GasFloor  uint64         // Target gas floor for mined blocks.
-	GasCeil   uint64         // Target gas ceiling for mined blocks.
-	GasPrice  *big.Int       // Minimum gas price for mining a transaction
-	Recommit  time.Duration  // The time interval for miner to re-create mining work.
+	Etherbase  common.Address `toml:",omitempty"` // Public address for block mining rewards
+	ExtraData  hexutil.Bytes  `toml:",omitempty"` // Block extra data set by the miner
+	GasFloor   uint64         // Target gas floor for mined blocks.
+	GasCeil    uint64         // Target gas ceiling for mined blocks.
+	GasWindow  uint64         // Number of recent blocks to measure the gas utilisation over, 0 to always target the ceiling.
+	GasTarget  float64        // Gas utilisation to target by moving the gas limit between the floor and the ceiling, above the EIP-1559 target.
+	GasReserve uint64         // Gas reserved in each block for system transactions.
+	GasPrice   *big.Int       // Minimum gas price for mining a transaction
+	Recommit   time.Duration  // The time interval for miner to re-create mining work.
+	Ordering   string         // Name of the strategy to order transactions in mined blocks with.
 
 	NewPayloadTimeout time.Duration // The maximum time allowance for creating a new payload
 }
 
 // DefaultConfig contains default settings for miner.
 var DefaultConfig = Config{
-	GasCeil:  30000000,
-	GasPrice: big.NewInt(params.GWei),
+	GasCeil:   30000000,
+	GasTarget: 0.75,
+	GasPrice:  big.NewInt(params.GWei),
 
 	// The default recommit time is chosen as two seconds since
 	// consensus-layer usually will wait a half slot of time(6s)
@@ -234,6 +239,13 @@ func (miner *Miner) SetGasCeil(ceil uint64) {
 	miner.worker.setGasCeil(ceil)
 }
 
+// SetSystemTxs registers the callback supplying the system transactions to be
+// included at the top of every block built, within the gas reserved for them.
+// A nil callback disables system transactions.
+func (miner *Miner) SetSystemTxs(fn SystemTxsFunc) {
+	miner.worker.setSystemTxs(fn)
+}
+
 // SubscribePendingLogs starts delivering logs from pending transactions
 // to the given channel.
 func (miner *Miner) SubscribePendingLogs(ch chan<- []*types.Log) event.Subscription {
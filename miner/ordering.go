@@ -0,0 +1,259 @@
+// Copyright 2023 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package miner
+
+import (
+	"bytes"
+	"container/heap"
+	"encoding/binary"
+	"fmt"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/crypto"
+)
+
+// Names of the built-in transaction ordering strategies.
+const (
+	PriceOrdering = "price" // Highest effective tip first, the default
+	FIFOOrdering  = "fifo"  // Earliest arrival in the transaction pool first
+)
+
+// TransactionSet is a set of transactions yielded one by one in the order they
+// are to be included into a block, honouring the nonce order of each account.
+// It is implemented by types.TransactionsByPriceAndNonce.
+type TransactionSet interface {
+	// Peek returns the next transaction to include, or nil if the set is empty.
+	Peek() *types.Transaction
+
+	// Shift replaces the current transaction with the next one from the same
+	// account.
+	Shift()
+
+	// Pop removes the current transaction along with all the subsequent ones
+	// from the same account.
+	Pop()
+}
+
+// OrderingContext contains the information about the block being built that an
+// ordering strategy may base its decisions on.
+type OrderingContext struct {
+	Signer  types.Signer                // Signer to recover the transaction senders with
+	Header  *types.Header               // Header of the block being built
+	Arrival func(common.Hash) time.Time // Time a transaction arrived in the pool, zero if unknown
+}
+
+// OrderingStrategy decides the order in which the pending transactions of the
+// pool are included into a block being built.
+type OrderingStrategy interface {
+	// Order creates the transaction set to fill a block with from the given
+	// transactions, grouped by account and sorted by nonce. The map is owned
+	// by the strategy afterwards.
+	Order(ctx *OrderingContext, txs map[common.Address]types.Transactions) TransactionSet
+}
+
+var (
+	orderingLock       sync.RWMutex
+	orderingStrategies = map[string]OrderingStrategy{
+		PriceOrdering: new(priceOrdering),
+		FIFOOrdering:  new(fifoOrdering),
+	}
+)
+
+// RegisterOrderingStrategy makes a custom transaction ordering strategy available
+// for selection by name in the miner config. It must be called before the miner
+// is created, and panics if the name is already taken.
+func RegisterOrderingStrategy(name string, strategy OrderingStrategy) {
+	orderingLock.Lock()
+	defer orderingLock.Unlock()
+
+	if _, ok := orderingStrategies[name]; ok {
+		panic(fmt.Sprintf("ordering strategy %q already registered", name))
+	}
+	orderingStrategies[name] = strategy
+}
+
+// ValidateOrdering returns an error if no transaction ordering strategy is
+// registered under the given name, the empty name denoting the default one.
+func ValidateOrdering(name string) error {
+	_, err := lookupOrderingStrategy(name)
+	return err
+}
+
+// lookupOrderingStrategy retrieves a registered ordering strategy by name, the
+// empty name denoting the default one.
+func lookupOrderingStrategy(name string) (OrderingStrategy, error) {
+	if name == "" {
+		name = PriceOrdering
+	}
+	orderingLock.RLock()
+	defer orderingLock.RUnlock()
+
+	strategy, ok := orderingStrategies[name]
+	if !ok {
+		return nil, fmt.Errorf("unknown transaction ordering strategy %q", name)
+	}
+	return strategy, nil
+}
+
+// priceOrdering orders transactions by their effective tip, breaking ties by the
+// time they were first seen.
+type priceOrdering struct{}
+
+func (priceOrdering) Order(ctx *OrderingContext, txs map[common.Address]types.Transactions) TransactionSet {
+	return types.NewTransactionsByPriceAndNonce(ctx.Signer, txs, ctx.Header.BaseFee)
+}
+
+// fifoOrdering orders transactions by the time they arrived in the pool, first
+// come first served. Transactions with unknown arrival times go last.
+type fifoOrdering struct{}
+
+func (fifoOrdering) Order(ctx *OrderingContext, txs map[common.Address]types.Transactions) TransactionSet {
+	arrivals := make(map[common.Hash]time.Time)
+	for _, list := range txs {
+		for _, tx := range list {
+			arrivals[tx.Hash()] = ctx.Arrival(tx.Hash())
+		}
+	}
+	return newOrderedSet(ctx.Signer, txs, func(a, b *types.Transaction) bool {
+		ta, tb := arrivals[a.Hash()], arrivals[b.Hash()]
+		switch {
+		case ta.IsZero() != tb.IsZero():
+			return tb.IsZero()
+		case !ta.Equal(tb):
+			return ta.Before(tb)
+		default:
+			return bytes.Compare(a.Hash().Bytes(), b.Hash().Bytes()) < 0
+		}
+	})
+}
+
+// ShuffleOrdering orders transactions pseudo-randomly, deterministically derived
+// from a fixed seed and the number of the block being built, so every node with
+// the same seed and pending transactions produces the same order. It's not one
+// of the built-in strategies, but may be registered under any name.
+type ShuffleOrdering struct {
+	Seed uint64
+}
+
+func (s *ShuffleOrdering) Order(ctx *OrderingContext, txs map[common.Address]types.Transactions) TransactionSet {
+	var salt [16]byte
+	binary.BigEndian.PutUint64(salt[:8], s.Seed)
+	binary.BigEndian.PutUint64(salt[8:], ctx.Header.Number.Uint64())
+
+	keys := make(map[common.Hash]common.Hash)
+	for _, list := range txs {
+		for _, tx := range list {
+			hash := tx.Hash()
+			keys[hash] = crypto.Keccak256Hash(salt[:], hash[:])
+		}
+	}
+	return newOrderedSet(ctx.Signer, txs, func(a, b *types.Transaction) bool {
+		return bytes.Compare(keys[a.Hash()].Bytes(), keys[b.Hash()].Bytes()) < 0
+	})
+}
+
+// orderedSet is a transaction set yielding the heads of the accounts' nonce
+// sorted transaction lists in an arbitrary order.
+type orderedSet struct {
+	txs   map[common.Address]types.Transactions // Per account nonce-sorted list of remaining transactions
+	heads orderedHeads                          // Next transaction for each account
+}
+
+// newOrderedSet creates a transaction set yielding the account heads in the
+// order defined by the given less function.
+func newOrderedSet(signer types.Signer, txs map[common.Address]types.Transactions, less func(a, b *types.Transaction) bool) *orderedSet {
+	heads := orderedHeads{less: less}
+	for from, list := range txs {
+		if len(list) == 0 {
+			delete(txs, from)
+			continue
+		}
+		// Remove the account if its sender doesn't match
+		if acc, _ := types.Sender(signer, list[0]); acc != from {
+			delete(txs, from)
+			continue
+		}
+		heads.txs = append(heads.txs, orderedHead{from: from, tx: list[0]})
+		txs[from] = list[1:]
+	}
+	// Sort the initial heads to avoid depending on the map iteration order
+	// if the ordering is ambiguous
+	sort.Slice(heads.txs, func(i, j int) bool {
+		return bytes.Compare(heads.txs[i].from[:], heads.txs[j].from[:]) < 0
+	})
+	heap.Init(&heads)
+
+	return &orderedSet{txs: txs, heads: heads}
+}
+
+// Peek returns the next transaction to include.
+func (s *orderedSet) Peek() *types.Transaction {
+	if len(s.heads.txs) == 0 {
+		return nil
+	}
+	return s.heads.txs[0].tx
+}
+
+// Shift replaces the current transaction with the next one from the same account.
+func (s *orderedSet) Shift() {
+	from := s.heads.txs[0].from
+	if list := s.txs[from]; len(list) > 0 {
+		s.heads.txs[0].tx, s.txs[from] = list[0], list[1:]
+		heap.Fix(&s.heads, 0)
+		return
+	}
+	heap.Pop(&s.heads)
+}
+
+// Pop removes the current transaction along with all the subsequent ones from
+// the same account.
+func (s *orderedSet) Pop() {
+	delete(s.txs, s.heads.txs[0].from)
+	heap.Pop(&s.heads)
+}
+
+// orderedHead is the next transaction of an account.
+type orderedHead struct {
+	from common.Address
+	tx   *types.Transaction
+}
+
+// orderedHeads implements heap.Interface over account heads with a custom order.
+type orderedHeads struct {
+	txs  []orderedHead
+	less func(a, b *types.Transaction) bool
+}
+
+func (h orderedHeads) Len() int           { return len(h.txs) }
+func (h orderedHeads) Less(i, j int) bool { return h.less(h.txs[i].tx, h.txs[j].tx) }
+func (h orderedHeads) Swap(i, j int)      { h.txs[i], h.txs[j] = h.txs[j], h.txs[i] }
+
+func (h *orderedHeads) Push(x interface{}) {
+	h.txs = append(h.txs, x.(orderedHead))
+}
+
+func (h *orderedHeads) Pop() interface{} {
+	old := h.txs
+	n := len(old)
+	x := old[n-1]
+	h.txs = old[0 : n-1]
+	return x
+}
@@ -0,0 +1,197 @@
+// Copyright 2023 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package miner
+
+import (
+	"crypto/ecdsa"
+	"math/big"
+	"testing"
+	"time"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/crypto"
+)
+
+// orderingTestTxs creates a batch of transactions from a few accounts, along
+// with pseudo arrival times interleaving the accounts.
+func orderingTestTxs(signer types.Signer) (map[common.Address]types.Transactions, map[common.Hash]time.Time) {
+	var (
+		keys     = make([]*ecdsa.PrivateKey, 3)
+		txs      = make(map[common.Address]types.Transactions)
+		arrivals = make(map[common.Hash]time.Time)
+		start    = time.Unix(1000, 0)
+	)
+	for i := range keys {
+		keys[i], _ = crypto.GenerateKey()
+	}
+	for nonce := uint64(0); nonce < 4; nonce++ {
+		for i, key := range keys {
+			// Make the accounts arriving later pay more
+			tx := types.MustSignNewTx(key, signer, &types.LegacyTx{Nonce: nonce, To: &common.Address{}, Gas: 21000, GasPrice: big.NewInt(int64(i + 1))})
+			from := crypto.PubkeyToAddress(key.PublicKey)
+
+			txs[from] = append(txs[from], tx)
+			if nonce != 3 || i != 0 {
+				arrivals[tx.Hash()] = start.Add(time.Duration(int(nonce)*len(keys)+i) * time.Second)
+			}
+		}
+	}
+	return txs, arrivals
+}
+
+// copyTxs duplicates the per account transaction lists, since ordering strategies
+// take ownership of them.
+func copyTxs(txs map[common.Address]types.Transactions) map[common.Address]types.Transactions {
+	cpy := make(map[common.Address]types.Transactions, len(txs))
+	for from, list := range txs {
+		cpy[from] = append(types.Transactions(nil), list...)
+	}
+	return cpy
+}
+
+// drainSet retrieves all the transactions from a set, in order.
+func drainSet(set TransactionSet) types.Transactions {
+	var txs types.Transactions
+	for tx := set.Peek(); tx != nil; tx = set.Peek() {
+		txs = append(txs, tx)
+		set.Shift()
+	}
+	return txs
+}
+
+// checkNonceOrder ensures that the transactions of every account are yielded
+// in nonce order.
+func checkNonceOrder(t *testing.T, signer types.Signer, txs types.Transactions) {
+	t.Helper()
+
+	next := make(map[common.Address]uint64)
+	for i, tx := range txs {
+		from, _ := types.Sender(signer, tx)
+		if tx.Nonce() != next[from] {
+			t.Errorf("tx %d: nonce mismatch for %x: have %d, want %d", i, from, tx.Nonce(), next[from])
+		}
+		next[from] = tx.Nonce() + 1
+	}
+}
+
+// Tests that the FIFO strategy yields the transactions in the order they arrived
+// in the pool, with the ones of unknown arrival last.
+func TestFIFOOrdering(t *testing.T) {
+	signer := types.HomesteadSigner{}
+	txs, arrivals := orderingTestTxs(signer)
+
+	strategy, err := lookupOrderingStrategy(FIFOOrdering)
+	if err != nil {
+		t.Fatalf("failed to look up strategy: %v", err)
+	}
+	ctx := &OrderingContext{
+		Signer:  signer,
+		Header:  &types.Header{Number: big.NewInt(1)},
+		Arrival: func(hash common.Hash) time.Time { return arrivals[hash] },
+	}
+	ordered := drainSet(strategy.Order(ctx, copyTxs(txs)))
+	if len(ordered) != 12 {
+		t.Fatalf("transaction count mismatch: have %d, want %d", len(ordered), 12)
+	}
+	checkNonceOrder(t, signer, ordered)
+
+	for i := 1; i < len(ordered)-1; i++ {
+		if prev, cur := arrivals[ordered[i-1].Hash()], arrivals[ordered[i].Hash()]; !prev.Before(cur) {
+			t.Errorf("tx %d: arrived at %v, before the previous one at %v", i, cur, prev)
+		}
+	}
+	if last := ordered[len(ordered)-1]; !arrivals[last.Hash()].IsZero() {
+		t.Errorf("transaction of unknown arrival not last")
+	}
+}
+
+// Tests that the shuffle strategy produces the same order for the same seed and
+// block, but a different one otherwise, always honouring the nonce order.
+func TestShuffleOrdering(t *testing.T) {
+	signer := types.HomesteadSigner{}
+	txs, _ := orderingTestTxs(signer)
+
+	order := func(seed uint64, number int64) types.Transactions {
+		ctx := &OrderingContext{
+			Signer:  signer,
+			Header:  &types.Header{Number: big.NewInt(number)},
+			Arrival: func(common.Hash) time.Time { return time.Time{} },
+		}
+		ordered := drainSet((&ShuffleOrdering{Seed: seed}).Order(ctx, copyTxs(txs)))
+		if len(ordered) != 12 {
+			t.Fatalf("transaction count mismatch: have %d, want %d", len(ordered), 12)
+		}
+		checkNonceOrder(t, signer, ordered)
+		return ordered
+	}
+	same := func(a, b types.Transactions) bool {
+		for i := range a {
+			if a[i].Hash() != b[i].Hash() {
+				return false
+			}
+		}
+		return true
+	}
+	base := order(1, 1)
+	if !same(base, order(1, 1)) {
+		t.Errorf("order mismatch for identical seed and block")
+	}
+	// There are 12!/(4!^3) = 34650 valid orders, so collisions across all of the
+	// variations below are practically impossible
+	var differs bool
+	for i := int64(2); i < 10; i++ {
+		if !same(base, order(1, i)) || !same(base, order(uint64(i), 1)) {
+			differs = true
+		}
+	}
+	if !differs {
+		t.Errorf("order identical across seeds and blocks")
+	}
+}
+
+// Tests that custom strategies can be registered and selected by name.
+func TestOrderingRegistration(t *testing.T) {
+	if strategy, err := lookupOrderingStrategy(""); err != nil {
+		t.Errorf("failed to look up default strategy: %v", err)
+	} else if _, ok := strategy.(*priceOrdering); !ok {
+		t.Errorf("default strategy mismatch: have %T, want %T", strategy, new(priceOrdering))
+	}
+	if _, err := lookupOrderingStrategy("ordering-test-custom"); err == nil {
+		t.Errorf("unregistered strategy found")
+	}
+	if err := ValidateOrdering("ordering-test-custom"); err == nil {
+		t.Errorf("unregistered strategy accepted")
+	}
+	custom := &ShuffleOrdering{Seed: 1}
+	RegisterOrderingStrategy("ordering-test-custom", custom)
+
+	if strategy, err := lookupOrderingStrategy("ordering-test-custom"); err != nil {
+		t.Errorf("failed to look up registered strategy: %v", err)
+	} else if strategy != custom {
+		t.Errorf("registered strategy mismatch: have %v, want %v", strategy, custom)
+	}
+	if err := ValidateOrdering("ordering-test-custom"); err != nil {
+		t.Errorf("registered strategy rejected: %v", err)
+	}
+	defer func() {
+		if recover() == nil {
+			t.Errorf("duplicate registration didn't panic")
+		}
+	}()
+	RegisterOrderingStrategy(FIFOOrdering, custom)
+}
@@ -72,6 +72,21 @@ func TestBuildPayload(t *testing.T) {
 	full := payload.ResolveFull()
 	verify(full, len(pendingTxs))
 
+	// Ensure the witnesses of the full payload are attached
+	witnesses := payload.Witnesses()
+	if len(witnesses) != len(full.ExecutionPayload.Transactions) {
+		t.Fatalf("Unexpected witness count: have %d, want %d", len(witnesses), len(full.ExecutionPayload.Transactions))
+	}
+	for i, enc := range full.ExecutionPayload.Transactions {
+		var tx types.Transaction
+		if err := tx.UnmarshalBinary(enc); err != nil {
+			t.Fatalf("Failed to decode transaction: %v", err)
+		}
+		if witnesses[i].TxHash != tx.Hash() {
+			t.Fatalf("Witness %d mismatch: have %x, want %x", i, witnesses[i].TxHash, tx.Hash())
+		}
+	}
+
 	// Ensure resolve can be called multiple times and the
 	// result should be unchanged
 	dataOne := payload.Resolve()
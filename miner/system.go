@@ -0,0 +1,74 @@
+// Copyright 2023 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package miner
+
+import (
+	"github.com/ethereum/go-ethereum/core"
+	"github.com/ethereum/go-ethereum/core/state"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/log"
+)
+
+// SystemTxsFunc supplies the operator transactions, such as oracle updates or
+// witness commitments, to include at the top of a block being built, ahead of
+// any user transaction. The header is that of the block being built, the state
+// is a copy of the one it's built on, any modification of it being discarded.
+type SystemTxsFunc func(header *types.Header, state *state.StateDB) types.Transactions
+
+// setSystemTxs sets the callback supplying the system transactions.
+func (w *worker) setSystemTxs(fn SystemTxsFunc) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	w.systemTxs = fn
+}
+
+// commitSystemTransactions includes the transactions supplied by the system
+// callback into the block, within the gas reserved for them. Any gas they leave
+// unused is available to the user transactions following them.
+func (w *worker) commitSystemTransactions(env *environment) {
+	w.mu.RLock()
+	fn, reserve := w.systemTxs, w.config.GasReserve
+	w.mu.RUnlock()
+
+	if fn == nil || reserve == 0 {
+		return
+	}
+	if env.gasPool == nil {
+		env.gasPool = new(core.GasPool).AddGas(env.header.GasLimit)
+	}
+	if reserve > env.gasPool.Gas() {
+		reserve = env.gasPool.Gas()
+	}
+	start := env.header.GasUsed
+	for _, tx := range fn(types.CopyHeader(env.header), env.state.Copy()) {
+		// Blob transactions can't be included without their sidecars
+		if tx.Type() == types.BlobTxType {
+			log.Warn("Skipping system blob transaction", "hash", tx.Hash())
+			continue
+		}
+		if left := reserve - (env.header.GasUsed - start); tx.Gas() > left {
+			log.Warn("Skipping system transaction exceeding the reserved gas", "hash", tx.Hash(), "gas", tx.Gas(), "left", left)
+			continue
+		}
+		env.state.SetTxContext(tx.Hash(), env.tcount)
+		if _, err := w.commitTransaction(env, tx); err != nil {
+			log.Warn("Failed to include system transaction", "hash", tx.Hash(), "err", err)
+			continue
+		}
+		env.tcount++
+	}
+}
@@ -0,0 +1,108 @@
+// Copyright 2023 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package miner
+
+import (
+	"math/big"
+	"testing"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/consensus/ethash"
+	"github.com/ethereum/go-ethereum/core"
+	"github.com/ethereum/go-ethereum/core/rawdb"
+	"github.com/ethereum/go-ethereum/core/state"
+	"github.com/ethereum/go-ethereum/core/txpool"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/event"
+	"github.com/ethereum/go-ethereum/params"
+)
+
+// Tests that system transactions are included ahead of the pooled ones, within
+// the gas reserved for them.
+func TestSystemTransactions(t *testing.T) {
+	var (
+		signer = types.LatestSigner(ethashChainConfig)
+		system = func(nonce uint64, gas uint64) *types.Transaction {
+			return types.MustSignNewTx(testBankKey, signer, &types.LegacyTx{Nonce: nonce, To: &testUserAddress, Gas: gas, GasPrice: big.NewInt(params.InitialBaseFee)})
+		}
+		// The first block funds the user, the system transactions are sent by the bank
+		fund   = types.MustSignNewTx(testBankKey, signer, &types.LegacyTx{Nonce: 0, To: &testUserAddress, Value: big.NewInt(params.Ether / 10), Gas: params.TxGas, GasPrice: big.NewInt(params.InitialBaseFee)})
+		pooled = types.MustSignNewTx(testUserKey, signer, &types.LegacyTx{Nonce: 0, To: &testBankAddress, Gas: params.TxGas, GasPrice: big.NewInt(params.InitialBaseFee)})
+
+		sys0 = system(1, params.TxGas)
+		sys1 = system(2, 3*params.TxGas) // Exceeds the remaining reservation
+		sys2 = system(2, params.TxGas)
+	)
+	tests := []struct {
+		reserve uint64
+		want    []common.Hash
+	}{
+		// Without reservation no system transactions are included
+		{reserve: 0, want: []common.Hash{pooled.Hash()}},
+
+		// System transactions go first, skipping the ones not fitting
+		{reserve: 2*params.TxGas + params.TxGas/2, want: []common.Hash{sys0.Hash(), sys2.Hash(), pooled.Hash()}},
+	}
+	for i, tt := range tests {
+		backend := newTestWorkerBackend(t, ethashChainConfig, ethash.NewFaker(), rawdb.NewMemoryDatabase(), 0)
+		blocks, _ := core.GenerateChain(ethashChainConfig, backend.chain.Genesis(), ethash.NewFaker(), backend.db, 1, func(i int, gen *core.BlockGen) {
+			gen.AddTx(fund)
+		})
+		if _, err := backend.chain.InsertChain(blocks); err != nil {
+			t.Fatalf("test %d: failed to insert chain: %v", i, err)
+		}
+		// Recreate the pool on top of the new head, for the user to be funded
+		backend.txPool.Stop()
+		backend.txPool = txpool.New(testTxPoolConfig, ethashChainConfig, backend.chain, nil)
+		if err := backend.txPool.AddRemotesSync([]*types.Transaction{pooled})[0]; err != nil {
+			t.Fatalf("test %d: failed to add pooled transaction: %v", i, err)
+		}
+		config := *testConfig
+		config.GasReserve = tt.reserve
+		w := newWorker(&config, ethashChainConfig, ethash.NewFaker(), backend, new(event.TypeMux), nil, false)
+
+		w.setSystemTxs(func(header *types.Header, statedb *state.StateDB) types.Transactions {
+			if header.Number.Uint64() != 2 {
+				t.Errorf("test %d: header number mismatch: have %v, want %d", i, header.Number, 2)
+			}
+			// Modifications of the state must not leak into the block
+			statedb.SetBalance(testBankAddress, new(big.Int))
+			return types.Transactions{sys0, sys1, sys2}
+		})
+		r := w.getSealingBlock(&generateParams{
+			timestamp: 100,
+			coinbase:  common.Address{0xc0},
+			forceTime: true,
+		})
+		w.close()
+		backend.txPool.Stop()
+
+		if r.err != nil {
+			t.Fatalf("test %d: failed to generate block: %v", i, r.err)
+		}
+		txs := r.block.Transactions()
+		if len(txs) != len(tt.want) {
+			t.Errorf("test %d: transaction count mismatch: have %d, want %d", i, len(txs), len(tt.want))
+			continue
+		}
+		for j, tx := range txs {
+			if tx.Hash() != tt.want[j] {
+				t.Errorf("test %d, tx %d: hash mismatch: have %x, want %x", i, j, tx.Hash(), tt.want[j])
+			}
+		}
+	}
+}
@@ -68,6 +68,9 @@ const (
 
 	// staleThreshold is the maximum depth of the acceptable stale block.
 	staleThreshold = 7
+
+	// maxBlobsPerBlock is the maximum number of blobs a sealing block may carry.
+	maxBlobsPerBlock = int(params.BlobTxMaxDataGasPerBlock / params.BlobTxDataGasPerBlob)
 )
 
 var (
@@ -88,6 +91,8 @@ type environment struct {
 	header   *types.Header
 	txs      []*types.Transaction
 	receipts []*types.Receipt
+	sidecars []*types.BlobTxSidecar
+	blobs    int
 }
 
 // copy creates a deep copy of environment.
@@ -99,6 +104,7 @@ func (env *environment) copy() *environment {
 		coinbase: env.coinbase,
 		header:   types.CopyHeader(env.header),
 		receipts: copyReceipts(env.receipts),
+		blobs:    env.blobs,
 	}
 	if env.gasPool != nil {
 		gasPool := *env.gasPool
@@ -106,6 +112,9 @@ func (env *environment) copy() *environment {
 	}
 	cpy.txs = make([]*types.Transaction, len(env.txs))
 	copy(cpy.txs, env.txs)
+
+	cpy.sidecars = make([]*types.BlobTxSidecar, len(env.sidecars))
+	copy(cpy.sidecars, env.sidecars)
 	return cpy
 }
 
@@ -142,9 +151,11 @@ type newWorkReq struct {
 
 // newPayloadResult represents a result struct corresponds to payload generation.
 type newPayloadResult struct {
-	err   error
-	block *types.Block
-	fees  *big.Int
+	err       error
+	block     *types.Block
+	fees      *big.Int               // total block fees
+	sidecars  []*types.BlobTxSidecar // collected blobs of blob transactions
+	witnesses []*types.TxExtra       // state witnesses of the transactions, if recorded
 }
 
 // getWorkReq represents a request for getting a new sealing work with provided parameters.
@@ -192,9 +203,10 @@ type worker struct {
 
 	current *environment // An environment for current running cycle.
 
-	mu       sync.RWMutex // The lock used to protect the coinbase and extra fields
-	coinbase common.Address
-	extra    []byte
+	mu        sync.RWMutex // The lock used to protect the coinbase, extra and system transaction fields
+	coinbase  common.Address
+	extra     []byte
+	systemTxs SystemTxsFunc
 
 	pendingMu    sync.RWMutex
 	pendingTasks map[common.Hash]*task
@@ -220,6 +232,9 @@ type worker struct {
 	// payload in proof-of-stake stage.
 	recommit time.Duration
 
+	// ordering is the strategy to order the transactions of sealing blocks with.
+	ordering OrderingStrategy
+
 	// External functions
 	isLocalBlock func(header *types.Header) bool // Function used to determine whether the specified block is mined by local miner.
 
@@ -277,6 +292,29 @@ func newWorker(config *Config, chainConfig *params.ChainConfig, engine consensus
 	}
 	worker.newpayloadTimeout = newpayloadTimeout
 
+	// Resolve the transaction ordering strategy. Unknown ones are rejected on
+	// startup by ValidateOrdering, fall back to the default for direct users.
+	ordering, err := lookupOrderingStrategy(worker.config.Ordering)
+	if err != nil {
+		log.Error("Unknown miner transaction ordering", "provided", worker.config.Ordering, "updated", PriceOrdering, "err", err)
+		ordering, _ = lookupOrderingStrategy(PriceOrdering)
+	}
+	worker.ordering = ordering
+
+	// Sanitize the gas utilisation target if the gas limit is to be controlled.
+	if target := worker.config.GasTarget; worker.config.GasWindow > 0 && (target <= 0 || target > 1) {
+		log.Warn("Sanitizing miner gas utilisation target", "provided", target, "updated", DefaultConfig.GasTarget)
+		worker.config.GasTarget = DefaultConfig.GasTarget
+	}
+	if worker.config.GasWindow > 0 {
+		if worker.config.GasFloor == 0 {
+			log.Warn("Miner gas floor unset, defaulting to the genesis gas limit", "floor", worker.chain.Genesis().GasLimit())
+		}
+		if threshold := 1 / float64(chainConfig.ElasticityMultiplier()); chainConfig.LondonBlock != nil && worker.config.GasTarget <= threshold {
+			log.Warn("Miner gas utilisation target not above the EIP-1559 target", "target", worker.config.GasTarget, "eip1559", threshold)
+		}
+	}
+
 	worker.wg.Add(4)
 	go worker.mainLoop()
 	go worker.newWorkLoop(recommit)
@@ -515,12 +553,7 @@ func (w *worker) mainLoop() {
 			w.commitWork(req.interrupt, req.timestamp)
 
 		case req := <-w.getWorkCh:
-			block, fees, err := w.generateWork(req.params)
-			req.result <- &newPayloadResult{
-				err:   err,
-				block: block,
-				fees:  fees,
-			}
+			req.result <- w.generateWork(req.params)
 
 		case ev := <-w.txsCh:
 			// Apply transactions to the pending state if we're not sealing
@@ -538,9 +571,9 @@ func (w *worker) mainLoop() {
 					acc, _ := types.Sender(w.current.signer, tx)
 					txs[acc] = append(txs[acc], tx)
 				}
-				txset := types.NewTransactionsByPriceAndNonce(w.current.signer, txs, w.current.header.BaseFee)
+				txset := w.orderTransactions(w.current, txs)
 				tcount := w.current.tcount
-				w.commitTransactions(w.current, txset, nil)
+				w.commitTransactions(w.current, txset, nil, nil)
 
 				// Only update the snapshot if any new transactions were added
 				// to the pending block
@@ -681,7 +714,7 @@ func (w *worker) resultLoop() {
 				"elapsed", common.PrettyDuration(time.Since(task.createdAt)))
 
 			// Broadcast the block and announce chain insertion event
-			w.mux.Post(core.NewMinedBlockEvent{Block: block})
+			w.mux.Post(core.NewMinedBlockEvent{Block: block, Witnesses: task.state.TxWitnesses()})
 
 		case <-w.exitCh:
 			return
@@ -728,6 +761,9 @@ func (w *worker) updateSnapshot(env *environment) {
 }
 
 func (w *worker) commitTransaction(env *environment, tx *types.Transaction) ([]*types.Log, error) {
+	if w.chain.WitnessEnabled(env.header.Number.Uint64()) {
+		return w.commitTransactionWithWitness(env, tx)
+	}
 	var (
 		snap = env.state.Snapshot()
 		gp   = env.gasPool.Gas()
@@ -744,7 +780,27 @@ func (w *worker) commitTransaction(env *environment, tx *types.Transaction) ([]*
 	return receipt.Logs, nil
 }
 
-func (w *worker) commitTransactions(env *environment, txs *types.TransactionsByPriceAndNonce, interrupt *atomic.Int32) error {
+// commitTransactionWithWitness applies a transaction like commitTransaction while
+// recording its state witness into the state, so that it is persisted along with
+// the sealed block.
+func (w *worker) commitTransactionWithWitness(env *environment, tx *types.Transaction) ([]*types.Log, error) {
+	gp := env.gasPool.Gas()
+	receipt, witness, err := core.ApplyTransactionWithWitness(w.chainConfig, w.chain, &env.coinbase, env.gasPool, env.state, env.header, tx, &env.header.GasUsed, *w.chain.GetVMConfig())
+	if err != nil {
+		// The state is reverted by the witness recording itself
+		env.gasPool.SetGas(gp)
+		return nil, err
+	}
+	env.txs = append(env.txs, tx)
+	env.receipts = append(env.receipts, receipt)
+	env.state.AddTxWitness(witness)
+
+	return receipt.Logs, nil
+}
+
+// commitTransactions fills the sealing block with the given transactions, ranking
+// the given simulated bundles against them by their effective payment per gas.
+func (w *worker) commitTransactions(env *environment, txs TransactionSet, bundles []*simulatedBundle, interrupt *atomic.Int32) error {
 	gasLimit := env.header.GasLimit
 	if env.gasPool == nil {
 		env.gasPool = new(core.GasPool).AddGas(gasLimit)
@@ -763,8 +819,26 @@ func (w *worker) commitTransactions(env *environment, txs *types.TransactionsByP
 			log.Trace("Not enough gas for further transactions", "have", env.gasPool, "want", params.TxGas)
 			break
 		}
-		// Retrieve the next transaction and abort if all done.
+		// Retrieve the next transaction and include the next bundle instead if
+		// it pays more to the coinbase per unit of gas.
 		tx := txs.Peek()
+		if len(bundles) > 0 && (tx == nil || bundles[0].price.Cmp(tx.EffectiveGasTipValue(env.header.BaseFee)) > 0) {
+			bundle := bundles[0]
+			bundles = bundles[1:]
+
+			logs, repriced, err := w.commitBundle(env, bundle)
+			switch {
+			case err != nil:
+				log.Debug("Bundle failed, skipped", "hash", bundle.bundle.Hash(), "err", err)
+			case repriced != nil:
+				log.Trace("Bundle repriced, reranked", "hash", bundle.bundle.Hash(), "price", bundle.price, "repriced", repriced.price)
+				bundles = rankBundle(bundles, repriced)
+			default:
+				coalescedLogs = append(coalescedLogs, logs...)
+			}
+			continue
+		}
+		// Abort if all done.
 		if tx == nil {
 			break
 		}
@@ -780,6 +854,26 @@ func (w *worker) commitTransactions(env *environment, txs *types.TransactionsByP
 			txs.Pop()
 			continue
 		}
+		// Blob transactions are only included along with their sidecars, as long
+		// as the block has room left for their blobs.
+		var sidecar *types.BlobTxSidecar
+		if tx.Type() == types.BlobTxType {
+			if env.header.DataGasUsed == nil {
+				log.Trace("Ignoring blob transaction before Cancun", "hash", tx.Hash())
+				txs.Pop()
+				continue
+			}
+			if left := maxBlobsPerBlock - env.blobs; left < len(tx.BlobHashes()) {
+				log.Trace("Not enough blob space left for transaction", "hash", tx.Hash(), "left", left, "needed", len(tx.BlobHashes()))
+				txs.Pop()
+				continue
+			}
+			if sidecar = w.blobSidecar(tx.Hash()); sidecar == nil {
+				log.Trace("Ignoring blob transaction without sidecar", "hash", tx.Hash())
+				txs.Pop()
+				continue
+			}
+		}
 		// Start executing the transaction
 		env.state.SetTxContext(tx.Hash(), env.tcount)
 
@@ -794,6 +888,11 @@ func (w *worker) commitTransactions(env *environment, txs *types.TransactionsByP
 			// Everything ok, collect the logs and shift in the next transaction from the same account
 			coalescedLogs = append(coalescedLogs, logs...)
 			env.tcount++
+			if sidecar != nil {
+				env.sidecars = append(env.sidecars, sidecar)
+				env.blobs += len(tx.BlobHashes())
+				*env.header.DataGasUsed += tx.BlobGas()
+			}
 			txs.Shift()
 
 		default:
@@ -829,6 +928,7 @@ type generateParams struct {
 	coinbase    common.Address    // The fee recipient address for including transaction
 	random      common.Hash       // The randomness generated by beacon chain, empty before the merge
 	withdrawals types.Withdrawals // List of withdrawals to include in block.
+	beaconRoot  *common.Hash      // The beacon root of the parent block, nil before Cancun
 	noTxs       bool              // Flag whether an empty block without any transaction is expected
 }
 
@@ -861,7 +961,7 @@ func (w *worker) prepareWork(genParams *generateParams) (*environment, error) {
 	header := &types.Header{
 		ParentHash: parent.Hash(),
 		Number:     new(big.Int).Add(parent.Number, common.Big1),
-		GasLimit:   core.CalcGasLimit(parent.GasLimit, w.config.GasCeil),
+		GasLimit:   core.CalcGasLimit(parent.GasLimit, w.gasLimitTarget(parent)),
 		Time:       timestamp,
 		Coinbase:   genParams.coinbase,
 	}
@@ -878,8 +978,22 @@ func (w *worker) prepareWork(genParams *generateParams) (*environment, error) {
 		header.BaseFee = misc.CalcBaseFee(w.chainConfig, parent)
 		if !w.chainConfig.IsLondon(parent.Number) {
 			parentGasLimit := parent.GasLimit * w.chainConfig.ElasticityMultiplier()
-			header.GasLimit = core.CalcGasLimit(parentGasLimit, w.config.GasCeil)
+			header.GasLimit = core.CalcGasLimit(parentGasLimit, w.gasLimitTarget(parent))
+		}
+	}
+	// Set the data gas fields and the beacon root if we are past Cancun
+	if w.chainConfig.IsCancun(header.Number, header.Time) {
+		var excessDataGas uint64
+		if w.chainConfig.IsCancun(parent.Number, parent.Time) {
+			excessDataGas = misc.CalcExcessDataGas(*parent.ExcessDataGas, *parent.DataGasUsed)
+		} else {
+			// For the first post-fork block, both parent.data_gas_used and
+			// parent.excess_data_gas are evaluated as 0
+			excessDataGas = misc.CalcExcessDataGas(0, 0)
 		}
+		header.ExcessDataGas = &excessDataGas
+		header.DataGasUsed = new(uint64)
+		header.ParentBeaconRoot = genParams.beaconRoot
 	}
 	// Run the consensus preparation with the default or customized consensus engine.
 	if err := w.engine.Prepare(w.chain, header); err != nil {
@@ -905,32 +1019,61 @@ func (w *worker) fillTransactions(interrupt *atomic.Int32, env *environment) err
 	// Fill the block with all available pending transactions.
 	pending := w.eth.TxPool().Pending(true)
 	localTxs, remoteTxs := make(map[common.Address]types.Transactions), pending
-	for _, account := range w.eth.TxPool().Locals() {
-		if txs := remoteTxs[account]; len(txs) > 0 {
-			delete(remoteTxs, account)
-			localTxs[account] = txs
+
+	// Only the price ordering prioritizes the local transactions, the others
+	// order all pending transactions as a single set
+	if _, ok := w.ordering.(*priceOrdering); ok {
+		for _, account := range w.eth.TxPool().Locals() {
+			if txs := remoteTxs[account]; len(txs) > 0 {
+				delete(remoteTxs, account)
+				localTxs[account] = txs
+			}
 		}
 	}
+	// Include the system transactions ahead of everything else
+	w.commitSystemTransactions(env)
+
+	// Local transactions take precedence over everything, bundles are ranked
+	// against the remaining ones
 	if len(localTxs) > 0 {
-		txs := types.NewTransactionsByPriceAndNonce(env.signer, localTxs, env.header.BaseFee)
-		if err := w.commitTransactions(env, txs, interrupt); err != nil {
+		txs := w.orderTransactions(env, localTxs)
+		if err := w.commitTransactions(env, txs, nil, interrupt); err != nil {
 			return err
 		}
 	}
-	if len(remoteTxs) > 0 {
-		txs := types.NewTransactionsByPriceAndNonce(env.signer, remoteTxs, env.header.BaseFee)
-		if err := w.commitTransactions(env, txs, interrupt); err != nil {
+	// Simulate the bundles targeting the sealing block on top of the local
+	// transactions. They are re-priced as they get included.
+	var bundles []*simulatedBundle
+	if candidates := w.eth.TxPool().Bundles(env.header.Number.Uint64(), env.header.Time); len(candidates) > 0 {
+		bundles = w.simulateBundles(env, candidates)
+	}
+	if len(remoteTxs) > 0 || len(bundles) > 0 {
+		txs := w.orderTransactions(env, remoteTxs)
+		if err := w.commitTransactions(env, txs, bundles, interrupt); err != nil {
 			return err
 		}
 	}
 	return nil
 }
 
-// generateWork generates a sealing block based on the given parameters.
-func (w *worker) generateWork(params *generateParams) (*types.Block, *big.Int, error) {
+// orderTransactions creates the set of transactions to fill the given sealing
+// block with, ordered by the configured strategy.
+func (w *worker) orderTransactions(env *environment, txs map[common.Address]types.Transactions) TransactionSet {
+	ctx := &OrderingContext{
+		Signer:  env.signer,
+		Header:  env.header,
+		Arrival: w.eth.TxPool().Arrival,
+	}
+	return w.ordering.Order(ctx, txs)
+}
+
+// generateWork generates a sealing block based on the given parameters, along
+// with the sidecars of its blob transactions and the state witnesses of its
+// transactions if they are to be recorded.
+func (w *worker) generateWork(params *generateParams) *newPayloadResult {
 	work, err := w.prepareWork(params)
 	if err != nil {
-		return nil, nil, err
+		return &newPayloadResult{err: err}
 	}
 	defer work.discard()
 
@@ -948,9 +1091,24 @@ func (w *worker) generateWork(params *generateParams) (*types.Block, *big.Int, e
 	}
 	block, err := w.engine.FinalizeAndAssemble(w.chain, work.header, work.state, work.txs, nil, work.receipts, params.withdrawals)
 	if err != nil {
-		return nil, nil, err
+		return &newPayloadResult{err: err}
+	}
+	return &newPayloadResult{
+		block:     block,
+		fees:      totalFees(block, work.receipts),
+		sidecars:  work.sidecars,
+		witnesses: work.state.TxWitnesses(),
+	}
+}
+
+// blobSidecar retrieves the sidecar of a pooled blob transaction, or nil if the
+// blob pool is disabled or doesn't hold the transaction anymore.
+func (w *worker) blobSidecar(hash common.Hash) *types.BlobTxSidecar {
+	blobs := w.eth.TxPool().BlobPool()
+	if blobs == nil {
+		return nil
 	}
-	return block, totalFees(block, work.receipts), nil
+	return blobs.Sidecar(hash)
 }
 
 // commitWork generates several new sealing tasks based on the parent block
@@ -1056,31 +1214,20 @@ func (w *worker) commit(env *environment, interval func(), update bool, start ti
 	return nil
 }
 
-// getSealingBlock generates the sealing block based on the given parameters.
-// The generation result will be passed back via the given channel no matter
-// the generation itself succeeds or not.
-func (w *worker) getSealingBlock(parent common.Hash, timestamp uint64, coinbase common.Address, random common.Hash, withdrawals types.Withdrawals, noTxs bool) (*types.Block, *big.Int, error) {
+// getSealingBlock generates the sealing block based on the given parameters,
+// along with the sidecars of its blob transactions and the state witnesses of
+// its transactions if they are recorded. The generation result will be passed
+// back via the given channel no matter the generation itself succeeds or not.
+func (w *worker) getSealingBlock(params *generateParams) *newPayloadResult {
 	req := &getWorkReq{
-		params: &generateParams{
-			timestamp:   timestamp,
-			forceTime:   true,
-			parentHash:  parent,
-			coinbase:    coinbase,
-			random:      random,
-			withdrawals: withdrawals,
-			noTxs:       noTxs,
-		},
+		params: params,
 		result: make(chan *newPayloadResult, 1),
 	}
 	select {
 	case w.getWorkCh <- req:
-		result := <-req.result
-		if result.err != nil {
-			return nil, nil, result.err
-		}
-		return result.block, result.fees, nil
+		return <-req.result
 	case <-w.exitCh:
-		return nil, nil, errors.New("miner closed")
+		return &newPayloadResult{err: errors.New("miner closed")}
 	}
 }
 
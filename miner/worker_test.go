@@ -17,6 +17,7 @@
 package miner
 
 import (
+	"bytes"
 	"math/big"
 	"sync/atomic"
 	"testing"
@@ -36,6 +37,7 @@ import (
 	"github.com/ethereum/go-ethereum/ethdb"
 	"github.com/ethereum/go-ethereum/event"
 	"github.com/ethereum/go-ethereum/params"
+	"github.com/ethereum/go-ethereum/rlp"
 )
 
 const (
@@ -128,14 +130,14 @@ func newTestWorkerBackend(t *testing.T, chainConfig *params.ChainConfig, engine
 	default:
 		t.Fatalf("unexpected consensus engine type: %T", engine)
 	}
-	chain, err := core.NewBlockChain(db, &core.CacheConfig{TrieDirtyDisabled: true}, gspec, nil, engine, vm.Config{}, nil, nil)
+	chain, err := core.NewBlockChain(db, &core.CacheConfig{TrieDirtyDisabled: true, WitnessNoLimit: true}, gspec, nil, engine, vm.Config{}, nil, nil)
 	if err != nil {
 		t.Fatalf("core.NewBlockChain failed: %v", err)
 	}
 	return &testWorkerBackend{
 		db:      db,
 		chain:   chain,
-		txPool:  txpool.New(testTxPoolConfig, chainConfig, chain),
+		txPool:  txpool.New(testTxPoolConfig, chainConfig, chain, nil),
 		genesis: gspec,
 	}
 }
@@ -174,7 +176,8 @@ func TestGenerateAndImportBlock(t *testing.T) {
 	defer w.close()
 
 	// This test chain imports the mined blocks.
-	chain, _ := core.NewBlockChain(rawdb.NewMemoryDatabase(), nil, b.genesis, nil, engine, vm.Config{}, nil, nil)
+	importDb := rawdb.NewMemoryDatabase()
+	chain, _ := core.NewBlockChain(importDb, &core.CacheConfig{TrieDirtyDisabled: true, WitnessNoLimit: true}, b.genesis, nil, engine, vm.Config{}, nil, nil)
 	defer chain.Stop()
 
 	// Ignore empty commit here for less noise.
@@ -199,6 +202,20 @@ func TestGenerateAndImportBlock(t *testing.T) {
 			if _, err := chain.InsertChain([]*types.Block{block}); err != nil {
 				t.Fatalf("failed to insert new mined block %d: %v", block.NumberU64(), err)
 			}
+			// The witnesses recorded while mining must match the imported ones
+			witnesses := ev.Data.(core.NewMinedBlockEvent).Witnesses
+			if len(witnesses) != len(block.Transactions()) {
+				t.Fatalf("block %d: witness count mismatch: have %d, want %d", block.NumberU64(), len(witnesses), len(block.Transactions()))
+			}
+			for j, witness := range witnesses {
+				enc, _ := rlp.EncodeToBytes(witness)
+				if want := rawdb.ReadTxWitnessRLP(importDb, block.Hash(), witness.TxHash); !bytes.Equal(enc, want) {
+					t.Fatalf("block %d: witness %d mismatch", block.NumberU64(), j)
+				}
+				if !rawdb.HasTxWitness(db, block.Hash(), witness.TxHash) {
+					t.Fatalf("block %d: witness %d not persisted", block.NumberU64(), j)
+				}
+			}
 		case <-time.After(3 * time.Second): // Worker needs 1s to include new changes.
 			t.Fatalf("timeout")
 		}
@@ -448,32 +465,44 @@ func testGetSealingWork(t *testing.T, chainConfig *params.ChainConfig, engine co
 
 	// This API should work even when the automatic sealing is not enabled
 	for _, c := range cases {
-		block, _, err := w.getSealingBlock(c.parent, timestamp, c.coinbase, c.random, nil, false)
+		r := w.getSealingBlock(&generateParams{
+			parentHash: c.parent,
+			timestamp:  timestamp,
+			coinbase:   c.coinbase,
+			random:     c.random,
+			forceTime:  true,
+		})
 		if c.expectErr {
-			if err == nil {
+			if r.err == nil {
 				t.Error("Expect error but get nil")
 			}
 		} else {
-			if err != nil {
-				t.Errorf("Unexpected error %v", err)
+			if r.err != nil {
+				t.Errorf("Unexpected error %v", r.err)
 			}
-			assertBlock(block, c.expectNumber, c.coinbase, c.random)
+			assertBlock(r.block, c.expectNumber, c.coinbase, c.random)
 		}
 	}
 
 	// This API should work even when the automatic sealing is enabled
 	w.start()
 	for _, c := range cases {
-		block, _, err := w.getSealingBlock(c.parent, timestamp, c.coinbase, c.random, nil, false)
+		r := w.getSealingBlock(&generateParams{
+			parentHash: c.parent,
+			timestamp:  timestamp,
+			coinbase:   c.coinbase,
+			random:     c.random,
+			forceTime:  true,
+		})
 		if c.expectErr {
-			if err == nil {
+			if r.err == nil {
 				t.Error("Expect error but get nil")
 			}
 		} else {
-			if err != nil {
-				t.Errorf("Unexpected error %v", err)
+			if r.err != nil {
+				t.Errorf("Unexpected error %v", r.err)
 			}
-			assertBlock(block, c.expectNumber, c.coinbase, c.random)
+			assertBlock(r.block, c.expectNumber, c.coinbase, c.random)
 		}
 	}
 }